@@ -0,0 +1,156 @@
+package kuhulrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Client is a generated-style Twirp client for KuhulService, speaking the
+// JSON-over-HTTP transport NewKuhulServer answers. BaseURL is the server's
+// origin (e.g. "http://localhost:11434"); PathPrefix is appended to it.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client against baseURL using http.DefaultClient.
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: baseURL, HTTPClient: http.DefaultClient}
+}
+
+func (c *Client) call(ctx context.Context, method string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+PathPrefix+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient(c).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var twErr twirpError
+		if err := json.NewDecoder(resp.Body).Decode(&twErr); err == nil && twErr.Msg != "" {
+			return fmt.Errorf("kuhulrpc: %s: %s", twErr.Code, twErr.Msg)
+		}
+		return fmt.Errorf("kuhulrpc: %s returned status %d", method, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func httpClient(c *Client) *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Execute calls the Execute RPC.
+func (c *Client) Execute(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, error) {
+	var out ExecuteResponse
+	if err := c.call(ctx, "Execute", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Dispatch calls the Dispatch RPC.
+func (c *Client) Dispatch(ctx context.Context, req *DispatchRequest) (*ExecuteResponse, error) {
+	var out ExecuteResponse
+	if err := c.call(ctx, "Dispatch", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Load calls the Load RPC.
+func (c *Client) Load(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, error) {
+	var out ExecuteResponse
+	if err := c.call(ctx, "Load", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// State calls the State RPC.
+func (c *Client) State(ctx context.Context) (*ExecuteResponse, error) {
+	var out ExecuteResponse
+	if err := c.call(ctx, "State", &StateRequest{}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Fingerprint calls the Fingerprint RPC.
+func (c *Client) Fingerprint(ctx context.Context, req *FingerprintRequest) (*FingerprintResponse, error) {
+	var out FingerprintResponse
+	if err := c.call(ctx, "Fingerprint", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Infer calls the unary Infer RPC.
+func (c *Client) Infer(ctx context.Context, req *InferRequest) (*InferResponse, error) {
+	var out InferResponse
+	if err := c.call(ctx, "Infer", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// InferStream calls the streaming InferStream RPC, invoking onChunk once per
+// InferStreamChunk frame in order; it returns after the terminal
+// (Done or Error) chunk, or sooner if onChunk returns an error.
+func (c *Client) InferStream(ctx context.Context, req *InferRequest, onChunk func(*InferStreamChunk) error) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+PathPrefix+"InferStream", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient(c).Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var twErr twirpError
+		if err := json.NewDecoder(resp.Body).Decode(&twErr); err == nil && twErr.Msg != "" {
+			return fmt.Errorf("kuhulrpc: %s: %s", twErr.Code, twErr.Msg)
+		}
+		return fmt.Errorf("kuhulrpc: InferStream returned status %d", resp.StatusCode)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var chunk InferStreamChunk
+		if err := dec.Decode(&chunk); err != nil {
+			return err
+		}
+		if err := onChunk(&chunk); err != nil {
+			return err
+		}
+		if chunk.Done || chunk.Error != "" {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package kuhulrpc is the Go binding for kuhul.proto's KuhulService: the
+// message types below mirror the .proto one-for-one, and server.go/client.go
+// provide a Twirp-style server and client over them.
+//
+// A full protoc + protoc-gen-twirp toolchain would generate pb.go/twirp.go
+// from kuhul.proto directly, including protobuf-over-HTTP marshaling; that
+// toolchain isn't wired into this tree yet, so NewKuhulServer and Client
+// only speak Twirp's JSON-over-HTTP transport for now
+// (still a real Twirp content type, just not the binary one). Regenerating
+// against kuhul.proto once protoc-gen-twirp is added should be a drop-in
+// replacement for this package.
+package kuhulrpc
+
+import "context"
+
+// ExecuteRequest mirrors the ExecuteRequest message in kuhul.proto.
+type ExecuteRequest struct {
+	Source    string `json:"source"`
+	Mode      string `json:"mode,omitempty"`
+	TimeoutMs int64  `json:"timeout_ms,omitempty"`
+}
+
+// DispatchRequest mirrors the DispatchRequest message in kuhul.proto. Params
+// and Body are carried JSON-encoded (rather than a native map field) so the
+// message stays a plain, fixed proto3 shape regardless of what a handler's
+// params/body happen to contain.
+type DispatchRequest struct {
+	Handler    string `json:"handler"`
+	ParamsJSON string `json:"params_json,omitempty"`
+	BodyJSON   string `json:"body_json,omitempty"`
+}
+
+// ExecuteResponse mirrors the ExecuteResponse message in kuhul.proto and is
+// shared by Execute, Dispatch, Load, and State.
+type ExecuteResponse struct {
+	Ok         bool   `json:"ok"`
+	ResultJSON string `json:"result_json,omitempty"`
+	Error      string `json:"error,omitempty"`
+	SCXQ2      string `json:"scxq2,omitempty"`
+}
+
+// StateRequest mirrors the (empty) StateRequest message in kuhul.proto.
+type StateRequest struct{}
+
+// FingerprintRequest mirrors the FingerprintRequest message in kuhul.proto.
+type FingerprintRequest struct {
+	DataJSON string `json:"data_json"`
+}
+
+// FingerprintResponse mirrors the FingerprintResponse message in kuhul.proto.
+type FingerprintResponse struct {
+	Ok          bool   `json:"ok"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// InferRequest mirrors the InferRequest message in kuhul.proto.
+type InferRequest struct {
+	Runner      string  `json:"runner,omitempty"`
+	Model       string  `json:"model"`
+	Prompt      string  `json:"prompt"`
+	Mode        string  `json:"mode,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+	TopP        float64 `json:"top_p,omitempty"`
+	MaxTokens   int32   `json:"max_tokens,omitempty"`
+}
+
+// InferResponse mirrors the InferResponse message in kuhul.proto.
+type InferResponse struct {
+	Ok           bool   `json:"ok"`
+	Text         string `json:"text,omitempty"`
+	Error        string `json:"error,omitempty"`
+	InputTokens  int32  `json:"input_tokens,omitempty"`
+	OutputTokens int32  `json:"output_tokens,omitempty"`
+	SCXQ2        string `json:"scxq2,omitempty"`
+}
+
+// InferStreamChunk mirrors the InferStreamChunk message in kuhul.proto: one
+// frame of an InferStream response, either a partial Delta or, once Done,
+// the terminal Completion.
+type InferStreamChunk struct {
+	Done       bool           `json:"done,omitempty"`
+	Delta      string         `json:"delta,omitempty"`
+	Completion *InferResponse `json:"completion,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// KuhulService is the server-side interface Twirp dispatches RPCs to;
+// NewKuhulServer wraps an implementation of it as an http.Handler, and the
+// server package's kuhulRPCService (built on top of globalKuhulState) is the
+// one Ollama itself serves. InferStream takes a send func instead of returning a
+// channel so the HTTP transport can flush each chunk as it's produced
+// without needing a second goroutine to drain a channel.
+type KuhulService interface {
+	Execute(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, error)
+	Dispatch(ctx context.Context, req *DispatchRequest) (*ExecuteResponse, error)
+	Load(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, error)
+	State(ctx context.Context, req *StateRequest) (*ExecuteResponse, error)
+	Fingerprint(ctx context.Context, req *FingerprintRequest) (*FingerprintResponse, error)
+	Infer(ctx context.Context, req *InferRequest) (*InferResponse, error)
+	InferStream(ctx context.Context, req *InferRequest, send func(*InferStreamChunk) error) error
+}
@@ -0,0 +1,131 @@
+package kuhulrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// PathPrefix is where a generated Twirp client expects this service's RPCs,
+// following Twirp's /twirp/{package}.{Service}/{Method} convention.
+const PathPrefix = "/twirp/kuhulrpc.KuhulService/"
+
+// NewKuhulServer wraps svc as an http.Handler answering Twirp's
+// JSON-over-HTTP transport at PathPrefix+"Execute", PathPrefix+"Dispatch",
+// and so on. InferStream is the one method that isn't plain request/response:
+// it writes one InferStreamChunk JSON object per line as svc.InferStream
+// calls send, so a client reads it the same way it would an NDJSON body from
+// server.XJSONInferHandler's @stream:true path.
+func NewKuhulServer(svc KuhulService) http.Handler {
+	return &kuhulServer{svc: svc}
+}
+
+// NewPublicKuhulServer wraps svc like NewKuhulServer, but refuses Load and
+// State the same way the public gin router no longer mounts
+// KuhulLoadHandler/KuhulStateHandler: those two mutate/expose the shared
+// interpreter and belong on server.AdminServer instead, behind its token
+// check.
+func NewPublicKuhulServer(svc KuhulService) http.Handler {
+	return &kuhulServer{svc: svc, deny: map[string]bool{"Load": true, "State": true}}
+}
+
+type kuhulServer struct {
+	svc  KuhulService
+	deny map[string]bool
+}
+
+func (s *kuhulServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, PathPrefix)
+	if method == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+	if s.deny[method] {
+		writeTwirpError(w, http.StatusNotFound, "bad_route", "unknown method "+method)
+		return
+	}
+
+	switch method {
+	case "Execute":
+		var req ExecuteRequest
+		s.unary(w, r, &req, func() (interface{}, error) { return s.svc.Execute(r.Context(), &req) })
+	case "Dispatch":
+		var req DispatchRequest
+		s.unary(w, r, &req, func() (interface{}, error) { return s.svc.Dispatch(r.Context(), &req) })
+	case "Load":
+		var req ExecuteRequest
+		s.unary(w, r, &req, func() (interface{}, error) { return s.svc.Load(r.Context(), &req) })
+	case "State":
+		var req StateRequest
+		s.unary(w, r, &req, func() (interface{}, error) { return s.svc.State(r.Context(), &req) })
+	case "Fingerprint":
+		var req FingerprintRequest
+		s.unary(w, r, &req, func() (interface{}, error) { return s.svc.Fingerprint(r.Context(), &req) })
+	case "Infer":
+		var req InferRequest
+		s.unary(w, r, &req, func() (interface{}, error) { return s.svc.Infer(r.Context(), &req) })
+	case "InferStream":
+		s.serveInferStream(w, r)
+	default:
+		writeTwirpError(w, http.StatusNotFound, "bad_route", "unknown method "+method)
+	}
+}
+
+// unary decodes r's JSON body into req, calls rpc, and writes its result back
+// as JSON, the shape every non-streaming KuhulService method shares.
+func (s *kuhulServer) unary(w http.ResponseWriter, r *http.Request, req interface{}, rpc func() (interface{}, error)) {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeTwirpError(w, http.StatusBadRequest, "malformed", "invalid JSON body: "+err.Error())
+		return
+	}
+
+	out, err := rpc()
+	if err != nil {
+		writeTwirpError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+func (s *kuhulServer) serveInferStream(w http.ResponseWriter, r *http.Request) {
+	var req InferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeTwirpError(w, http.StatusBadRequest, "malformed", "invalid JSON body: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	err := s.svc.InferStream(r.Context(), &req, func(chunk *InferStreamChunk) error {
+		if err := enc.Encode(chunk); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		enc.Encode(&InferStreamChunk{Error: err.Error()})
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// twirpError is Twirp's standard JSON error envelope.
+type twirpError struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+func writeTwirpError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(twirpError{Code: code, Msg: msg})
+}
@@ -0,0 +1,142 @@
+package xjson
+
+import (
+	"container/list"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cache stores @completion responses keyed by an InferRequest's
+// Fingerprint, so ResolveOrInfer can skip re-running a runner for a request
+// it has already answered. LRUCache and DiskCache are the two
+// implementations xjson ships; a downstream package can supply its own.
+type Cache interface {
+	Get(fingerprint string) (*CompletionResponse, bool)
+	Put(fingerprint string, resp *CompletionResponse)
+}
+
+// LRUCache is an in-memory Cache bounded to Capacity entries, evicting the
+// least-recently-used one (by Get or Put) once it's full.
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // front = most recently used
+}
+
+type lruEntry struct {
+	fingerprint string
+	resp        *CompletionResponse
+}
+
+// NewLRUCache returns an LRUCache holding at most capacity entries.
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *LRUCache) Get(fingerprint string) (*CompletionResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[fingerprint]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).resp, true
+}
+
+func (c *LRUCache) Put(fingerprint string, resp *CompletionResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[fingerprint]; ok {
+		elem.Value.(*lruEntry).resp = resp
+		c.order.MoveToFront(elem)
+		return
+	}
+	elem := c.order.PushFront(&lruEntry{fingerprint: fingerprint, resp: resp})
+	c.items[fingerprint] = elem
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(*lruEntry).fingerprint)
+		}
+	}
+}
+
+// DiskCache is a Cache that persists each entry as an XJSON @completion
+// envelope file under Dir, named by its SCXQ2 fingerprint, so entries
+// survive a process restart. It does no in-memory bookkeeping of its own
+// (no eviction, no size cap); pair it with an LRUCache via a two-tier Cache
+// if that's needed.
+type DiskCache struct {
+	Dir string
+}
+
+// NewDiskCache returns a DiskCache rooted at dir. dir is created lazily on
+// the first Put, not here.
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{Dir: dir}
+}
+
+func (c *DiskCache) Get(fingerprint string) (*CompletionResponse, bool) {
+	data, err := os.ReadFile(c.path(fingerprint))
+	if err != nil {
+		return nil, false
+	}
+	envelope, err := ParseXJSON(data)
+	if err != nil || envelope.Completion == nil {
+		return nil, false
+	}
+	return envelope.Completion, true
+}
+
+func (c *DiskCache) Put(fingerprint string, resp *CompletionResponse) {
+	data, err := resp.ToJSON()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return
+	}
+	_ = os.WriteFile(c.path(fingerprint), data, 0o644)
+}
+
+func (c *DiskCache) path(fingerprint string) string {
+	return filepath.Join(c.Dir, fingerprint+".xjson")
+}
+
+// Runner executes an InferRequest against the actual model backend — the
+// seam ResolveOrInfer calls on a cache miss, played by whatever dispatches
+// to lam_o.infer in the caller (e.g. server.Server wrapping
+// kuhul.Interpreter.Dispatch).
+type Runner interface {
+	Infer(ctx context.Context, req *InferRequest) (*CompletionResponse, error)
+}
+
+// ResolveOrInfer returns the cached @completion for req's Fingerprint if
+// cache has one, otherwise calls runner.Infer and stores the result under
+// that fingerprint before returning it. cache may be nil, in which case
+// every call goes straight to runner.
+func ResolveOrInfer(ctx context.Context, req *InferRequest, runner Runner, cache Cache) (*CompletionResponse, error) {
+	fingerprint := req.Fingerprint()
+	if cache != nil {
+		if resp, ok := cache.Get(fingerprint); ok {
+			return resp, nil
+		}
+	}
+	resp, err := runner.Infer(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		cache.Put(fingerprint, resp)
+	}
+	return resp, nil
+}
@@ -0,0 +1,175 @@
+package xjson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError describes a single XJSON contract violation: the
+// "@"-prefixed field path that failed (e.g. "@infer.@params.temperature")
+// and why.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors aggregates every ValidationError a single Validate call
+// found, as the error InferRequest.Validate and ParseXJSON(..., WithValidation())
+// return when a contract check fails.
+type ValidationErrors struct {
+	Errors []ValidationError
+}
+
+func (e *ValidationErrors) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, v := range e.Errors {
+		msgs[i] = v.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Schema adds @mode-specific rules for one envelope kind on top of xjson's
+// own built-in required-field/type/enum/range checks (see validateInfer,
+// validateCompletion, validateErrorResponse). RegisterSchema installs one so
+// a downstream package can plug in a custom @mode without forking xjson.
+type Schema struct {
+	// Mode restricts the schema to InferRequest.Mode == Mode (only
+	// meaningful for Kind == "@infer"); empty applies to every mode for
+	// Kind. A non-empty Mode registered for "@infer" also makes Validate's
+	// @mode enum check accept that mode.
+	Mode string
+	// Check runs schema-specific rules against env, returning any
+	// violations found. Run in addition to, not instead of, the built-in
+	// checks.
+	Check func(env *XJSONEnvelope) []ValidationError
+}
+
+var schemas = map[string][]*Schema{}
+
+// RegisterSchema adds schema to the set checked for kind ("@infer",
+// "@completion", or "@error"), alongside any already registered for that
+// kind — every matching schema runs, none replace another. Safe to call
+// from a downstream package's init().
+func RegisterSchema(kind string, schema *Schema) {
+	schemas[kind] = append(schemas[kind], schema)
+}
+
+// knownInferMode reports whether mode is one of xjson's built-in @infer
+// modes, or one a downstream package declared support for by registering an
+// "@infer" Schema with that exact Mode.
+func knownInferMode(mode string) bool {
+	switch mode {
+	case "", "chat", "code", "embedding":
+		return true
+	}
+	for _, s := range schemas["@infer"] {
+		if s.Mode == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func runSchemas(kind, mode string, env *XJSONEnvelope) []ValidationError {
+	var errs []ValidationError
+	for _, s := range schemas[kind] {
+		if s.Mode != "" && s.Mode != mode {
+			continue
+		}
+		errs = append(errs, s.Check(env)...)
+	}
+	return errs
+}
+
+// Validate checks env against xjson's built-in contract for its envelope
+// kind — required @-fields, types, enum values (@mode, @runner), numeric
+// ranges on @params, and cross-field constraints like @stream requiring a
+// runner that supports it — plus any Schemas RegisterSchema added for that
+// kind/mode. It collects every violation rather than stopping at the first,
+// and returns nil if env is valid.
+func Validate(env *XJSONEnvelope) []ValidationError {
+	var errs []ValidationError
+	switch {
+	case env.Infer != nil:
+		errs = append(errs, validateInfer(env.Infer)...)
+		errs = append(errs, runSchemas("@infer", env.Infer.Mode, env)...)
+	case env.Completion != nil:
+		errs = append(errs, validateCompletion(env.Completion)...)
+		errs = append(errs, runSchemas("@completion", "", env)...)
+	case env.Error != nil:
+		errs = append(errs, validateErrorResponse(env.Error)...)
+		errs = append(errs, runSchemas("@error", "", env)...)
+	}
+	return errs
+}
+
+// streamingRunners lists @runner values known to support @stream:true.
+var streamingRunners = map[string]bool{
+	"lam.o": true,
+}
+
+func validateInfer(r *InferRequest) []ValidationError {
+	var errs []ValidationError
+	if r.Runner == "" {
+		errs = append(errs, ValidationError{"@infer.@runner", "is required"})
+	}
+	if r.Model == "" {
+		errs = append(errs, ValidationError{"@infer.@model", "is required"})
+	}
+	if r.Prompt == "" && len(r.Context) == 0 {
+		errs = append(errs, ValidationError{"@infer.@prompt", "or @context is required"})
+	}
+	if !knownInferMode(r.Mode) {
+		errs = append(errs, ValidationError{"@infer.@mode", fmt.Sprintf("unknown mode %q", r.Mode)})
+	}
+	if r.Stream && r.Runner != "" && !streamingRunners[r.Runner] {
+		errs = append(errs, ValidationError{"@infer.@stream", fmt.Sprintf("runner %q does not support streaming", r.Runner)})
+	}
+	if r.Params != nil {
+		errs = append(errs, validateParams(r.Params)...)
+	}
+	return errs
+}
+
+func validateParams(p *InferParams) []ValidationError {
+	var errs []ValidationError
+	if p.Temperature < 0 || p.Temperature > 2 {
+		errs = append(errs, ValidationError{"@infer.@params.temperature", fmt.Sprintf("must be between 0 and 2, got %v", p.Temperature)})
+	}
+	if p.TopP < 0 || p.TopP > 1 {
+		errs = append(errs, ValidationError{"@infer.@params.top_p", fmt.Sprintf("must be between 0 and 1, got %v", p.TopP)})
+	}
+	if p.MaxTokens < 0 {
+		errs = append(errs, ValidationError{"@infer.@params.max_tokens", fmt.Sprintf("must be greater than 0, got %d", p.MaxTokens)})
+	}
+	return errs
+}
+
+func validateCompletion(r *CompletionResponse) []ValidationError {
+	var errs []ValidationError
+	if r.Model == "" {
+		errs = append(errs, ValidationError{"@completion.@model", "is required"})
+	}
+	if r.Runner == "" {
+		errs = append(errs, ValidationError{"@completion.@runner", "is required"})
+	}
+	return errs
+}
+
+func validateErrorResponse(r *ErrorResponse) []ValidationError {
+	var errs []ValidationError
+	if r.Runner == "" {
+		errs = append(errs, ValidationError{"@error.@runner", "is required"})
+	}
+	if r.Message == "" {
+		errs = append(errs, ValidationError{"@error.@message", "is required"})
+	}
+	if r.Code == 0 {
+		errs = append(errs, ValidationError{"@error.@code", "is required"})
+	}
+	return errs
+}
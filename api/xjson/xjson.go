@@ -31,8 +31,12 @@
 package xjson
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/ollama/ollama/kuhul/scxq2"
@@ -106,10 +110,23 @@ type ErrorResponse struct {
 	Details string `json:"@details,omitempty"`
 }
 
+// DeltaResponse represents an @delta XJSON streaming frame: one partial
+// chunk of a response still in progress. A stream is a sequence of these
+// followed by a terminal @completion frame (Done=true, @scxq2 set) carrying
+// the full text, so a caller that only understands @completion can ignore
+// the deltas and just wait for the last frame.
+type DeltaResponse struct {
+	Model  string `json:"@model"`
+	Runner string `json:"@runner"`
+	Text   string `json:"@text"`
+	Index  int    `json:"@index"`
+}
+
 // XJSONEnvelope wraps XJSON messages
 type XJSONEnvelope struct {
 	Infer      *InferRequest       `json:"@infer,omitempty"`
 	Completion *CompletionResponse `json:"@completion,omitempty"`
+	Delta      *DeltaResponse      `json:"@delta,omitempty"`
 	Error      *ErrorResponse      `json:"@error,omitempty"`
 }
 
@@ -153,16 +170,13 @@ func (r *InferRequest) WithMode(mode string) *InferRequest {
 	return r
 }
 
-// Validate validates the inference request
+// Validate validates the inference request against xjson's schema-backed
+// contract (required fields, @mode, @params ranges, and the
+// @stream/@runner cross-field constraint — see Validate), returning a
+// *ValidationErrors describing every violation found, or nil if it's valid.
 func (r *InferRequest) Validate() error {
-	if r.Runner == "" {
-		return fmt.Errorf("@runner is required")
-	}
-	if r.Model == "" {
-		return fmt.Errorf("@model is required")
-	}
-	if r.Prompt == "" && len(r.Context) == 0 {
-		return fmt.Errorf("@prompt or @context is required")
+	if errs := Validate(&XJSONEnvelope{Infer: r}); len(errs) > 0 {
+		return &ValidationErrors{Errors: errs}
 	}
 	return nil
 }
@@ -173,7 +187,13 @@ func (r *InferRequest) ToJSON() ([]byte, error) {
 	return json.MarshalIndent(envelope, "", "  ")
 }
 
-// Fingerprint generates an SCXQ2 fingerprint for the request
+// Fingerprint generates an SCXQ2 fingerprint for the request. It builds an
+// explicit map[string]interface{} rather than handing scxq2.Fingerprint a
+// *InferParams struct directly: canonicalize only sorts and recurses into
+// map[string]interface{}/[]interface{}, so a struct value would be
+// marshaled as-is and any drift in its field order (or in how its floats
+// are formatted) would change the fingerprint without changing the
+// request's meaning.
 func (r *InferRequest) Fingerprint() string {
 	data := map[string]interface{}{
 		"runner": r.Runner,
@@ -186,11 +206,28 @@ func (r *InferRequest) Fingerprint() string {
 		},
 	}
 	if r.Params != nil {
-		data["params"] = r.Params
+		data["params"] = map[string]interface{}{
+			"temperature":       canonicalFloat(r.Params.Temperature),
+			"top_p":             canonicalFloat(r.Params.TopP),
+			"top_k":             r.Params.TopK,
+			"max_tokens":        r.Params.MaxTokens,
+			"repeat_penalty":    canonicalFloat(r.Params.RepeatPenalty),
+			"presence_penalty":  canonicalFloat(r.Params.PresencePenalty),
+			"frequency_penalty": canonicalFloat(r.Params.FrequencyPenalty),
+			"seed":              r.Params.Seed,
+			"stop":              r.Params.Stop,
+		}
 	}
 	return scxq2.Fingerprint(data)
 }
 
+// canonicalFloat rounds f to 6 decimal places so two semantically-equal
+// params (e.g. 0.7 vs 0.7000000001 from a round-tripped JSON float) always
+// fingerprint the same.
+func canonicalFloat(f float64) float64 {
+	return math.Round(f*1e6) / 1e6
+}
+
 // NewCompletionResponse creates a completion response
 func NewCompletionResponse(model, runner, text string) *CompletionResponse {
 	resp := &CompletionResponse{
@@ -245,6 +282,22 @@ func (r *CompletionResponse) ToJSON() ([]byte, error) {
 	return json.MarshalIndent(envelope, "", "  ")
 }
 
+// NewDeltaResponse creates a streaming delta frame
+func NewDeltaResponse(model, runner, text string, index int) *DeltaResponse {
+	return &DeltaResponse{
+		Model:  model,
+		Runner: runner,
+		Text:   text,
+		Index:  index,
+	}
+}
+
+// ToJSON converts the delta to XJSON format
+func (r *DeltaResponse) ToJSON() ([]byte, error) {
+	envelope := XJSONEnvelope{Delta: r}
+	return json.Marshal(envelope)
+}
+
 // NewErrorResponse creates an error response
 func NewErrorResponse(runner, message string, code int) *ErrorResponse {
 	return &ErrorResponse{
@@ -266,12 +319,38 @@ func (r *ErrorResponse) ToJSON() ([]byte, error) {
 	return json.MarshalIndent(envelope, "", "  ")
 }
 
-// ParseXJSON parses an XJSON envelope
-func ParseXJSON(data []byte) (*XJSONEnvelope, error) {
+// ParseOption configures ParseXJSON's behavior beyond a bare syntax parse.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	validate bool
+}
+
+// WithValidation makes ParseXJSON additionally run Validate on the parsed
+// envelope, returning a *ValidationErrors instead of a nil error when the
+// envelope parses but fails its contract.
+func WithValidation() ParseOption {
+	return func(c *parseConfig) { c.validate = true }
+}
+
+// ParseXJSON parses an XJSON envelope. With WithValidation, it also checks
+// the envelope against Validate and returns a *ValidationErrors describing
+// every contract violation instead of just a JSON syntax error.
+func ParseXJSON(data []byte, opts ...ParseOption) (*XJSONEnvelope, error) {
 	var envelope XJSONEnvelope
 	if err := json.Unmarshal(data, &envelope); err != nil {
 		return nil, fmt.Errorf("failed to parse XJSON: %w", err)
 	}
+
+	var cfg parseConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.validate {
+		if errs := Validate(&envelope); len(errs) > 0 {
+			return &envelope, &ValidationErrors{Errors: errs}
+		}
+	}
 	return &envelope, nil
 }
 
@@ -290,6 +369,11 @@ func (e *XJSONEnvelope) IsError() bool {
 	return e.Error != nil
 }
 
+// IsDelta checks if the envelope contains an @delta streaming frame
+func (e *XJSONEnvelope) IsDelta() bool {
+	return e.Delta != nil
+}
+
 // CreateInferRequest is a helper to create an @infer request
 func CreateInferRequest(opts map[string]interface{}) *InferRequest {
 	req := &InferRequest{
@@ -389,3 +473,175 @@ func CreateError(opts map[string]interface{}) *ErrorResponse {
 
 	return err
 }
+
+// StreamReader consumes a streamed XJSON response (NDJSON, or SSE with
+// "data: "-prefixed lines) one envelope at a time, the way a caller of
+// server.XJSONInferHandler with @stream: true reads @delta frames followed
+// by a terminal @completion frame.
+type StreamReader struct {
+	scanner *bufio.Scanner
+}
+
+// NewStreamReader wraps r, which may be either newline-delimited JSON or a
+// text/event-stream body; Next handles both.
+func NewStreamReader(r io.Reader) *StreamReader {
+	return &StreamReader{scanner: bufio.NewScanner(r)}
+}
+
+// Next returns the next envelope in the stream, or io.EOF once the stream
+// is exhausted. Blank lines (SSE framing) and bare ": "-comment lines are
+// skipped rather than treated as malformed frames.
+func (s *StreamReader) Next() (*XJSONEnvelope, error) {
+	for s.scanner.Scan() {
+		line := strings.TrimSpace(s.scanner.Text())
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "data:")
+		line = strings.TrimSpace(line)
+
+		return ParseXJSON([]byte(line))
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Decoder reads a sequence of XJSON envelopes concatenated back-to-back with
+// no delimiter, the way json.Encoder.Encode leaves them when a writer calls
+// it repeatedly on the same stream. Unlike StreamReader it doesn't need
+// NDJSON newlines or SSE "data:" framing, since encoding/json's own decoder
+// already knows where one JSON value ends and the next begins.
+type Decoder struct {
+	dec *json.Decoder
+}
+
+// NewDecoder returns a Decoder reading envelopes from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r)}
+}
+
+// Decode reads and returns the next envelope, or io.EOF once r is exhausted.
+func (d *Decoder) Decode() (*XJSONEnvelope, error) {
+	var envelope XJSONEnvelope
+	if err := d.dec.Decode(&envelope); err != nil {
+		return nil, err
+	}
+	return &envelope, nil
+}
+
+// MergeDelta folds a partial @completion frame into acc, the way a caller
+// reassembles the @stream:true frames a runner sends for a single
+// NewInferRequest: @text is appended, @tokens.output (and the recomputed
+// @tokens.total) is bumped, @tokens.input is adopted from whichever frame
+// carries it (the input count is fixed for the whole response, not
+// per-delta), and @done/@metrics/@scxq2 adopt the delta's values so the
+// terminal frame's fields end up on the accumulator. acc may be nil, in
+// which case MergeDelta allocates it from delta's Model/Runner.
+func MergeDelta(acc *CompletionResponse, delta *CompletionResponse) *CompletionResponse {
+	if acc == nil {
+		acc = &CompletionResponse{Model: delta.Model, Runner: delta.Runner}
+	}
+	acc.Text += delta.Text
+	if delta.Tokens != nil {
+		if acc.Tokens == nil {
+			acc.Tokens = &TokenStats{}
+		}
+		if delta.Tokens.Input > 0 {
+			acc.Tokens.Input = delta.Tokens.Input
+		}
+		acc.Tokens.Output += delta.Tokens.Output
+		acc.Tokens.Total = acc.Tokens.Input + acc.Tokens.Output
+	}
+	if delta.Metrics != nil {
+		acc.Metrics = delta.Metrics
+	}
+	if delta.SCXQ2 != "" {
+		acc.SCXQ2 = delta.SCXQ2
+	}
+	acc.Done = delta.Done
+	return acc
+}
+
+// CompletionStream reads the sequence of partial @completion XJSON frames a
+// runner sends for a single NewInferRequest with Stream set, folding each
+// one into a running CompletionResponse via MergeDelta so a caller sees one
+// growing response instead of hand-rolling JSON splitting over NDJSON or
+// SSE framing itself.
+type CompletionStream struct {
+	r    *StreamReader
+	acc  *CompletionResponse
+	done bool
+}
+
+// NewCompletionStream wraps r the same way NewStreamReader does: it accepts
+// either newline-delimited JSON or an SSE "data: "-prefixed body.
+func NewCompletionStream(r io.Reader) *CompletionStream {
+	return &CompletionStream{r: NewStreamReader(r)}
+}
+
+// Next reads and merges the next @completion frame, returning the
+// accumulated response so far. It returns io.EOF once the terminal frame
+// (@done:true) has already been returned. An @error frame in the stream is
+// surfaced as an error rather than merged; any other envelope shape is
+// likewise an error, since a completion stream shouldn't contain one.
+func (s *CompletionStream) Next() (*CompletionResponse, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+	envelope, err := s.r.Next()
+	if err != nil {
+		return nil, err
+	}
+	if envelope.Error != nil {
+		return nil, fmt.Errorf("@completion stream: %s", envelope.Error.Message)
+	}
+	if envelope.Completion == nil {
+		return nil, fmt.Errorf("@completion stream: expected an @completion frame")
+	}
+	s.acc = MergeDelta(s.acc, envelope.Completion)
+	if s.acc.Done {
+		s.done = true
+	}
+	return s.acc, nil
+}
+
+// Encoder writes a sequence of partial @completion XJSON frames to an
+// underlying stream: Write emits one @done:false frame per call, and Close
+// emits the terminal @done:true frame with an SCXQ2 fingerprint. It's the
+// write side of CompletionStream.
+type Encoder struct {
+	model       string
+	runner      string
+	inputTokens int
+	enc         *json.Encoder
+}
+
+// NewEncoder returns an Encoder that writes @completion frames for the given
+// model/runner to w as newline-delimited JSON. inputTokens is the prompt's
+// token count, known up front and constant across the whole stream, so it's
+// set once here rather than passed to every Write call.
+func NewEncoder(w io.Writer, model, runner string, inputTokens int) *Encoder {
+	return &Encoder{model: model, runner: runner, inputTokens: inputTokens, enc: json.NewEncoder(w)}
+}
+
+// Write emits a partial @completion frame (@done:false) carrying text as the
+// next delta and tokensOut as that delta's @tokens.output contribution.
+func (e *Encoder) Write(text string, tokensOut int) error {
+	resp := &CompletionResponse{
+		Model:  e.model,
+		Runner: e.runner,
+		Text:   text,
+		Tokens: &TokenStats{Input: e.inputTokens, Output: tokensOut, Total: e.inputTokens + tokensOut},
+	}
+	return e.enc.Encode(XJSONEnvelope{Completion: resp})
+}
+
+// Close emits the terminal @completion frame (@done:true), fingerprinted the
+// same way NewCompletionResponse fingerprints a non-streamed response.
+func (e *Encoder) Close() error {
+	resp := &CompletionResponse{Model: e.model, Runner: e.runner, Done: true}
+	resp.SCXQ2 = resp.Fingerprint()
+	return e.enc.Encode(XJSONEnvelope{Completion: resp})
+}
@@ -1,13 +1,12 @@
 package cmd
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/ollama/ollama/kuhul"
+	"github.com/ollama/ollama/kuhul/diag"
 	"github.com/spf13/cobra"
 )
 
@@ -112,12 +111,14 @@ func tokenizeKuhul(source string, jsonOutput bool) error {
 }
 
 func parseKuhul(source string, jsonOutput bool) error {
-	program, errors := kuhul.Parse(source)
-	if len(errors) > 0 {
-		for _, err := range errors {
-			fmt.Fprintf(os.Stderr, "Parse error: %s\n", err)
+	program, diagnostics := kuhul.ParseWithDiagnostics(source)
+	if len(diagnostics) > 0 {
+		for _, d := range diagnostics {
+			fmt.Fprintln(os.Stderr, diag.Render(source, d))
+		}
+		if hasErrorDiagnostic(diagnostics) {
+			return fmt.Errorf("parsing failed with %d diagnostic(s)", len(diagnostics))
 		}
-		return fmt.Errorf("parsing failed with %d errors", len(errors))
 	}
 
 	if jsonOutput {
@@ -128,129 +129,13 @@ func parseKuhul(source string, jsonOutput bool) error {
 	return nil
 }
 
-func runKuhulREPL() error {
-	fmt.Println("K'UHUL REPL v" + kuhul.Version)
-	fmt.Println("The law: ASX = XCFE = XJSON = KUHUL = AST = ATOMIC_BLOCK")
-	fmt.Println("Type 'exit' to quit, 'help' for commands")
-	fmt.Println()
-
-	interp := kuhul.NewInterpreter()
-	scanner := bufio.NewScanner(os.Stdin)
-
-	for {
-		fmt.Print("kuhul> ")
-		if !scanner.Scan() {
-			break
-		}
-
-		line := strings.TrimSpace(scanner.Text())
-
-		if line == "" {
-			continue
-		}
-
-		if line == "exit" || line == "quit" {
-			fmt.Println("Goodbye!")
-			break
-		}
-
-		if line == "help" {
-			printREPLHelp()
-			continue
-		}
-
-		if line == "state" {
-			state := interp.GetState()
-			outputJSON(state.GetState())
-			continue
-		}
-
-		if strings.HasPrefix(line, "load ") {
-			filename := strings.TrimPrefix(line, "load ")
-			content, err := os.ReadFile(filename)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				continue
-			}
-			_, errors := interp.Load(string(content))
-			if len(errors) > 0 {
-				for _, e := range errors {
-					fmt.Fprintf(os.Stderr, "Parse error: %s\n", e)
-				}
-				continue
-			}
-			fmt.Println("Loaded:", filename)
-			continue
-		}
-
-		if line == "run" {
-			result, err := interp.Run()
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				continue
-			}
-			outputJSON(result)
-			continue
-		}
-
-		if strings.HasPrefix(line, "dispatch ") {
-			handlerName := strings.TrimPrefix(line, "dispatch ")
-			result, err := interp.Dispatch(handlerName, nil)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				continue
-			}
-			outputJSON(result)
-			continue
-		}
-
-		if strings.HasPrefix(line, "fp ") {
-			data := strings.TrimPrefix(line, "fp ")
-			fp := kuhul.Fingerprint(data)
-			fmt.Println(fp)
-			continue
-		}
-
-		// Evaluate expression
-		result, err := interp.Eval(line)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			continue
-		}
-
-		if result != nil {
-			outputJSON(result)
+func hasErrorDiagnostic(diagnostics []diag.Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == diag.SeverityError {
+			return true
 		}
 	}
-
-	return nil
-}
-
-func printREPLHelp() {
-	fmt.Println(`K'UHUL REPL Commands:
-  help              Show this help
-  exit, quit        Exit the REPL
-  state             Show runtime state
-  load <file>       Load a K'UHUL file
-  run               Run the loaded program
-  dispatch <name>   Dispatch to a handler
-  fp <data>         Generate SCXQ2 fingerprint
-
-K'UHUL Syntax:
-  ⟁Pop⟁ name {...}  Declaration (manifest, config)
-  ⟁Wo⟁ name = val   Assignment (variables)
-  ⟁Sek⟁ vector      Control flow (if, loop)
-  ⟁Xul⟁ name        Block definition start
-  ⟁Ch'en⟁ {...}     Return/emit from block
-  ⟁Yax⟁ name        Reference a value
-  ⟁K'ayab⟁ i from   Loop construct
-  ⟁Kumk'u⟁          End loop
-
-Examples:
-  ⟁Wo⟁ x = 10
-  ⟁Wo⟁ y = [1, 2, 3]
-  ⟁Wo⟁ data = {"name": "test"}
-`)
+	return false
 }
 
 func outputResult(result interface{}, jsonOutput bool) error {
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ollama/ollama/kuhul/printer"
+	"github.com/spf13/cobra"
+)
+
+var kuhulFmtCmd = &cobra.Command{
+	Use:   "fmt [files...]",
+	Short: "Format K'UHUL source files",
+	Long: `Reformat K'UHUL (.khl) source into its canonical form: aligned @param:
+columns, two-space indented block bodies, and re-serialized JSON payloads.
+
+With no files, kuhulfmt reads from stdin and writes the formatted result to
+stdout.`,
+	Example: `  ollama kuhul fmt program.khl
+  ollama kuhul fmt -w program.khl
+  ollama kuhul fmt -d program.khl
+  ollama kuhul fmt -l ./programs/*.khl
+  cat program.khl | ollama kuhul fmt`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		write, _ := cmd.Flags().GetBool("write")
+		showDiff, _ := cmd.Flags().GetBool("diff")
+		list, _ := cmd.Flags().GetBool("list")
+
+		if len(args) == 0 {
+			return fmtStdin(cmd, showDiff)
+		}
+
+		for _, filename := range args {
+			if _, err := fmtFile(cmd, filename, write, showDiff, list); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	kuhulFmtCmd.Flags().BoolP("write", "w", false, "Write result to (source) file instead of stdout")
+	kuhulFmtCmd.Flags().BoolP("diff", "d", false, "Display a unified diff instead of rewriting")
+	kuhulFmtCmd.Flags().BoolP("list", "l", false, "List files whose formatting differs from kuhulfmt's")
+	kuhulCmd.AddCommand(kuhulFmtCmd)
+}
+
+func fmtStdin(cmd *cobra.Command, showDiff bool) error {
+	content, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+
+	formatted, err := printer.Format(string(content), nil)
+	if err != nil {
+		return err
+	}
+
+	if showDiff {
+		fmt.Fprint(cmd.OutOrStdout(), printer.UnifiedDiff("<stdin>", string(content), formatted))
+		return nil
+	}
+
+	fmt.Fprint(cmd.OutOrStdout(), formatted)
+	return nil
+}
+
+// fmtFile formats a single file and reports whether its on-disk contents
+// differed from the canonical formatting.
+func fmtFile(cmd *cobra.Command, filename string, write, showDiff, list bool) (bool, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	formatted, err := printer.Format(string(content), nil)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	unformatted := formatted != string(content)
+
+	switch {
+	case list:
+		if unformatted {
+			fmt.Fprintln(cmd.OutOrStdout(), filename)
+		}
+	case showDiff:
+		if unformatted {
+			fmt.Fprint(cmd.OutOrStdout(), printer.UnifiedDiff(filename, string(content), formatted))
+		}
+	case write:
+		if unformatted {
+			info, err := os.Stat(filename)
+			if err != nil {
+				return unformatted, fmt.Errorf("failed to stat %s: %w", filename, err)
+			}
+			if err := os.WriteFile(filename, []byte(formatted), info.Mode()); err != nil {
+				return unformatted, fmt.Errorf("failed to write %s: %w", filename, err)
+			}
+		}
+	default:
+		fmt.Fprint(cmd.OutOrStdout(), formatted)
+	}
+
+	return unformatted, nil
+}
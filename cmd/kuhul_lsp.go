@@ -0,0 +1,479 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ollama/ollama/kuhul"
+	"github.com/ollama/ollama/kuhul/ast"
+	"github.com/spf13/cobra"
+)
+
+var kuhulLspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Start a K'UHUL language server over stdio",
+	Long: `Start a Language Server Protocol server for K'UHUL, communicating over
+stdio using standard LSP Content-Length framed JSON-RPC. Intended to be
+launched by an editor, not run interactively.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runKuhulLSP(cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	kuhulCmd.AddCommand(kuhulLspCmd)
+}
+
+// lspDocument is the last-parsed state of one open file.
+type lspDocument struct {
+	text        string
+	program     *kuhul.Program
+	diagnostics []kuhul.Diagnostic
+}
+
+// kuhulLSPServer holds per-connection state: one lspDocument per open URI.
+type kuhulLSPServer struct {
+	out       io.Writer
+	documents map[string]*lspDocument
+}
+
+func runKuhulLSP(in io.Reader, out io.Writer) error {
+	srv := &kuhulLSPServer{out: out, documents: make(map[string]*lspDocument)}
+	reader := bufio.NewReader(in)
+
+	for {
+		raw, err := readLSPMessage(reader)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		exit, err := srv.handle(raw)
+		if err != nil {
+			return err
+		}
+		if exit {
+			return nil
+		}
+	}
+}
+
+// readLSPMessage reads one `Content-Length: N\r\n\r\n<N bytes>` framed
+// message, per the LSP base protocol.
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	contentLength := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length header %q: %w", value, err)
+			}
+			contentLength = n
+		}
+	}
+	if contentLength < 0 {
+		return nil, fmt.Errorf("message missing Content-Length header")
+	}
+
+	body := make([]byte, contentLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// JSON-RPC envelope types.
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *kuhulLSPServer) send(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
+
+// respond replies to a request. Notifications (no id) have nothing to
+// respond to, so respond is a no-op for them — callers don't need to
+// distinguish requests from notifications themselves.
+func (s *kuhulLSPServer) respond(id json.RawMessage, result interface{}, rpcErr *rpcError) error {
+	if len(id) == 0 {
+		return nil
+	}
+	return s.send(rpcResponse{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr})
+}
+
+func (s *kuhulLSPServer) notify(method string, params interface{}) error {
+	return s.send(rpcNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// LSP position/range/location types (a small subset of the spec).
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Message  string   `json:"message"`
+}
+
+type lspCompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// LSP completion item kinds we use (subset of the spec's enum).
+const (
+	completionKindFunction = 3
+	completionKindVariable = 6
+	completionKindKeyword  = 14
+)
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type didOpenParams struct {
+	TextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     lspPosition            `json:"position"`
+}
+
+// handle dispatches one JSON-RPC message and reports whether the server
+// should exit (in response to an "exit" notification).
+func (s *kuhulLSPServer) handle(raw []byte) (exit bool, err error) {
+	var msg rpcMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return false, nil // malformed message; nothing sensible to reply with
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return false, s.respond(msg.ID, lspCapabilities(), nil)
+	case "initialized", "$/cancelRequest":
+		return false, nil
+	case "shutdown":
+		return false, s.respond(msg.ID, nil, nil)
+	case "exit":
+		return true, nil
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return false, nil
+		}
+		return false, s.parseAndPublish(p.TextDocument.URI, p.TextDocument.Text)
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil || len(p.ContentChanges) == 0 {
+			return false, nil
+		}
+		return false, s.parseAndPublish(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+	case "textDocument/definition":
+		var p textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return false, s.respond(msg.ID, nil, nil)
+		}
+		return false, s.handleDefinition(msg.ID, p)
+	case "textDocument/hover":
+		var p textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return false, s.respond(msg.ID, nil, nil)
+		}
+		return false, s.handleHover(msg.ID, p)
+	case "textDocument/completion":
+		var p textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return false, s.respond(msg.ID, nil, nil)
+		}
+		return false, s.handleCompletion(msg.ID, p)
+	default:
+		if len(msg.ID) > 0 {
+			return false, s.respond(msg.ID, nil, &rpcError{Code: -32601, Message: "method not found: " + msg.Method})
+		}
+		return false, nil
+	}
+}
+
+func lspCapabilities() map[string]interface{} {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":   1, // Full document sync
+			"definitionProvider": true,
+			"hoverProvider":      true,
+			"completionProvider": map[string]interface{}{},
+		},
+	}
+}
+
+// parseAndPublish reparses text with kuhul.ParseWithDiagnostics, updates the
+// document's cached state, and pushes a textDocument/publishDiagnostics
+// notification, as textDocument/didOpen and didChange require.
+func (s *kuhulLSPServer) parseAndPublish(uri, text string) error {
+	program, diagnostics := kuhul.ParseWithDiagnostics(text)
+	s.documents[uri] = &lspDocument{text: text, program: program, diagnostics: diagnostics}
+
+	lspDiagnostics := make([]lspDiagnostic, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		lspDiagnostics = append(lspDiagnostics, toLSPDiagnostic(d))
+	}
+
+	return s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": lspDiagnostics,
+	})
+}
+
+func toLSPDiagnostic(d kuhul.Diagnostic) lspDiagnostic {
+	severity := 1 // Error
+	switch d.Severity {
+	case kuhul.SeverityWarning:
+		severity = 2
+	case kuhul.SeverityNote:
+		severity = 3
+	}
+
+	start := lspPosition{Line: zeroIndex(d.Span.StartLine), Character: zeroIndex(d.Span.StartCol)}
+	end := lspPosition{Line: zeroIndex(d.Span.EndLine), Character: zeroIndex(d.Span.EndCol)}
+	return lspDiagnostic{
+		Range:    lspRange{Start: start, End: end},
+		Severity: severity,
+		Code:     d.Code,
+		Message:  d.Message,
+	}
+}
+
+// yaxRefPattern matches a "⟁Yax⟁ name" value reference so handleDefinition
+// can tell which identifier the cursor is sitting on.
+var yaxRefPattern = regexp.MustCompile(`⟁Yax⟁\s*(\w+)`)
+
+// handleDefinition resolves a ⟁Yax⟁ reference under the cursor to the
+// ast.Declaration or ast.Assignment position that defines it.
+func (s *kuhulLSPServer) handleDefinition(id json.RawMessage, params textDocumentPositionParams) error {
+	doc := s.documents[params.TextDocument.URI]
+	if doc == nil {
+		return s.respond(id, nil, nil)
+	}
+
+	name, ok := identifierAtPosition(doc.text, params.Position)
+	if !ok {
+		return s.respond(id, nil, nil)
+	}
+
+	for _, a := range doc.program.Assignments {
+		if a.Name == name {
+			return s.respond(id, locationFor(params.TextDocument.URI, a.Pos()), nil)
+		}
+	}
+	for _, d := range doc.program.Declarations {
+		if d.Name == name {
+			return s.respond(id, locationFor(params.TextDocument.URI, d.Pos()), nil)
+		}
+	}
+	return s.respond(id, nil, nil)
+}
+
+func identifierAtPosition(text string, pos lspPosition) (string, bool) {
+	lines := strings.Split(text, "\n")
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return "", false
+	}
+	line := lines[pos.Line]
+	for _, m := range yaxRefPattern.FindAllStringSubmatchIndex(line, -1) {
+		nameStart, nameEnd := m[2], m[3]
+		if pos.Character >= nameStart && pos.Character <= nameEnd {
+			return line[nameStart:nameEnd], true
+		}
+	}
+	return "", false
+}
+
+func locationFor(uri string, pos ast.Position) lspLocation {
+	p := lspPosition{Line: zeroIndex(pos.Line), Character: zeroIndex(pos.Column)}
+	return lspLocation{URI: uri, Range: lspRange{Start: p, End: p}}
+}
+
+// handleHover renders the top-level AST node that starts on the cursor's
+// line as pretty-printed JSON.
+func (s *kuhulLSPServer) handleHover(id json.RawMessage, params textDocumentPositionParams) error {
+	doc := s.documents[params.TextDocument.URI]
+	if doc == nil {
+		return s.respond(id, nil, nil)
+	}
+
+	node := nodeAtLine(doc.program, params.Position.Line+1)
+	if node == nil {
+		return s.respond(id, nil, nil)
+	}
+
+	b, err := json.MarshalIndent(node, "", "  ")
+	if err != nil {
+		return s.respond(id, nil, nil)
+	}
+
+	return s.respond(id, map[string]interface{}{
+		"contents": map[string]interface{}{
+			"kind":  "markdown",
+			"value": "```json\n" + string(b) + "\n```",
+		},
+	}, nil)
+}
+
+// nodeAtLine finds the top-level AST node whose position starts at line
+// (1-based), searching every category ast.Program tracks.
+func nodeAtLine(program *kuhul.Program, line int) ast.Node {
+	var found ast.Node
+	consider := func(n ast.Node) {
+		if n.Pos().Line == line {
+			found = n
+		}
+	}
+
+	for _, d := range program.Declarations {
+		consider(d)
+	}
+	for _, a := range program.Assignments {
+		consider(a)
+	}
+	for _, b := range program.Blocks {
+		consider(b)
+	}
+	for _, cv := range program.ControlVectors {
+		consider(cv)
+	}
+	for _, ab := range program.AtomicBlocks {
+		consider(ab)
+	}
+	for _, cb := range program.CoolBlocks {
+		consider(cb)
+	}
+	for _, cv := range program.CoolVectors {
+		consider(cv)
+	}
+	for _, cvar := range program.CoolVariables {
+		consider(cvar)
+	}
+	return found
+}
+
+// kuhulMarkerKeywords are always offered as completions: the Mayan glyph
+// markers and the C@@L block/vector/variable family.
+var kuhulMarkerKeywords = []string{
+	"⟁Pop⟁", "⟁Wo⟁", "⟁Sek⟁", "⟁Xul⟁", "⟁Ch'en⟁",
+	"⟁Yax⟁", "⟁K'ayab⟁", "⟁Shen⟁", "⟁Kumk'u⟁",
+	"C@@L BLOCK", "C@@L ATOMIC_VECTOR", "C@@L ATOMIC_VARIABLE",
+}
+
+// handleCompletion offers the marker keywords plus every name already
+// declared in the document: ⟁Pop⟁ declarations, C@@L BLOCKs, and
+// C@@L ATOMIC_VECTORs.
+func (s *kuhulLSPServer) handleCompletion(id json.RawMessage, params textDocumentPositionParams) error {
+	items := make([]lspCompletionItem, 0, len(kuhulMarkerKeywords))
+	for _, kw := range kuhulMarkerKeywords {
+		items = append(items, lspCompletionItem{Label: kw, Kind: completionKindKeyword})
+	}
+
+	doc := s.documents[params.TextDocument.URI]
+	if doc != nil {
+		for _, d := range doc.program.Declarations {
+			items = append(items, lspCompletionItem{Label: d.Name, Kind: completionKindVariable, Detail: "⟁Pop⟁ declaration"})
+		}
+
+		coolBlockNames := make([]string, 0, len(doc.program.CoolBlocks))
+		for name := range doc.program.CoolBlocks {
+			coolBlockNames = append(coolBlockNames, name)
+		}
+		sort.Strings(coolBlockNames)
+		for _, name := range coolBlockNames {
+			items = append(items, lspCompletionItem{Label: name, Kind: completionKindFunction, Detail: "C@@L BLOCK"})
+		}
+
+		coolVectorNames := make([]string, 0, len(doc.program.CoolVectors))
+		for name := range doc.program.CoolVectors {
+			coolVectorNames = append(coolVectorNames, name)
+		}
+		sort.Strings(coolVectorNames)
+		for _, name := range coolVectorNames {
+			items = append(items, lspCompletionItem{Label: name, Kind: completionKindFunction, Detail: "C@@L ATOMIC_VECTOR"})
+		}
+	}
+
+	return s.respond(id, items, nil)
+}
+
+// zeroIndex converts a 1-based lexer/parser line or column into the 0-based
+// numbering LSP positions use, floored at 0 for uninitialized positions.
+func zeroIndex(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return n - 1
+}
@@ -0,0 +1,397 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/ollama/ollama/kuhul"
+	"github.com/ollama/ollama/kuhul/diag"
+)
+
+// replCommand describes one built-in REPL command. The command table drives
+// both dispatch and the generated `help` output.
+type replCommand struct {
+	name    string
+	aliases []string
+	help    string
+	run     func(interp *kuhul.Interpreter, args string) bool // false stops the REPL
+}
+
+// replCommands is populated in init rather than here: several of its run
+// funcs are simple closures, but printREPLHelp (registered by "help") reads
+// replCommands itself, so a composite-literal initializer would be an
+// initialization cycle.
+var replCommands []replCommand
+
+func init() {
+	replCommands = []replCommand{
+		{
+			name:    "exit",
+			aliases: []string{"quit"},
+			help:    "Exit the REPL",
+			run: func(interp *kuhul.Interpreter, args string) bool {
+				fmt.Println("Goodbye!")
+				return false
+			},
+		},
+		{
+			name: "help",
+			help: "Show this help",
+			run: func(interp *kuhul.Interpreter, args string) bool {
+				printREPLHelp()
+				return true
+			},
+		},
+		{
+			name: "state",
+			help: "Show runtime state",
+			run: func(interp *kuhul.Interpreter, args string) bool {
+				outputJSON(interp.GetState().GetState())
+				return true
+			},
+		},
+		{
+			name: "load",
+			help: "<file>       Load a K'UHUL file",
+			run: func(interp *kuhul.Interpreter, args string) bool {
+				if args == "" {
+					fmt.Fprintln(os.Stderr, "Error: usage: load <file>")
+					return true
+				}
+				content, err := os.ReadFile(args)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					return true
+				}
+				source := string(content)
+				_, diagnostics := kuhul.ParseWithDiagnostics(source)
+				if hasErrorDiagnostic(diagnostics) {
+					for _, d := range diagnostics {
+						fmt.Fprintln(os.Stderr, diag.Render(source, d))
+					}
+					return true
+				}
+				interp.Load(source)
+				fmt.Println("Loaded:", args)
+				return true
+			},
+		},
+		{
+			name: "run",
+			help: "Run the loaded program",
+			run: func(interp *kuhul.Interpreter, args string) bool {
+				result, err := interp.Run()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					return true
+				}
+				outputJSON(result)
+				return true
+			},
+		},
+		{
+			name: "dispatch",
+			help: "<name>   Dispatch to a handler",
+			run: func(interp *kuhul.Interpreter, args string) bool {
+				result, err := interp.Dispatch(args, nil)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					return true
+				}
+				outputJSON(result)
+				return true
+			},
+		},
+		{
+			name: "fp",
+			help: "<data>   Generate SCXQ2 fingerprint",
+			run: func(interp *kuhul.Interpreter, args string) bool {
+				fmt.Println(kuhul.Fingerprint(args))
+				return true
+			},
+		},
+		{
+			name: "subscribe",
+			help: "<event> <handler>   Dispatch <handler> whenever <event> is emitted",
+			run: func(interp *kuhul.Interpreter, args string) bool {
+				event, handlerName, ok := splitTwo(args)
+				if !ok {
+					fmt.Fprintln(os.Stderr, "Error: usage: subscribe <event> <handler>")
+					return true
+				}
+				interp.GetState().Events.AddObserver(event, handlerName, func(event string, source interface{}) {
+					ctx := &kuhul.Context{
+						Handler: handlerName,
+						Params:  make(map[string]interface{}),
+						Body:    map[string]interface{}{"event": event, "source": source},
+						Runtime: interp.GetState(),
+						Env:     interp.GetState().Variables,
+					}
+					result, err := interp.Dispatch(handlerName, ctx)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error: event %q handler %q: %v\n", event, handlerName, err)
+						return
+					}
+					outputJSON(result)
+				})
+				fmt.Printf("Subscribed %s to %s\n", handlerName, event)
+				return true
+			},
+		},
+		{
+			name: "emit",
+			help: "<event> <json>   Post an event to its observers",
+			run: func(interp *kuhul.Interpreter, args string) bool {
+				event, data, ok := splitTwo(args)
+				if !ok {
+					event = strings.TrimSpace(args)
+					data = ""
+				}
+				if event == "" {
+					fmt.Fprintln(os.Stderr, "Error: usage: emit <event> <json>")
+					return true
+				}
+
+				var payload interface{}
+				if data != "" {
+					if err := json.Unmarshal([]byte(data), &payload); err != nil {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+						return true
+					}
+				}
+				interp.GetState().Events.PostEvent(event, payload)
+				return true
+			},
+		},
+	}
+}
+
+// splitTwo splits "first rest" into its two space-separated parts. ok is
+// false if args has no second part.
+func splitTwo(args string) (first, rest string, ok bool) {
+	parts := strings.SplitN(strings.TrimSpace(args), " ", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return parts[0], "", false
+	}
+	return parts[0], strings.TrimSpace(parts[1]), true
+}
+
+// replCompleter offers tab completion over the built-in command table and
+// over identifiers already bound in the interpreter's environment.
+type replCompleter struct {
+	interp *kuhul.Interpreter
+}
+
+func (c *replCompleter) Do(line []rune, pos int) (newLine [][]rune, length int) {
+	word := currentWord(string(line[:pos]))
+
+	var candidates []string
+	for _, cmd := range replCommands {
+		candidates = append(candidates, cmd.name)
+		candidates = append(candidates, cmd.aliases...)
+	}
+	if c.interp != nil {
+		candidates = append(candidates, c.interp.GetState().Variables.AllKeys()...)
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) && candidate != word {
+			newLine = append(newLine, []rune(candidate[len(word):]))
+		}
+	}
+	return newLine, len(word)
+}
+
+func currentWord(s string) string {
+	if idx := strings.LastIndexAny(s, " \t"); idx != -1 {
+		return s[idx+1:]
+	}
+	return s
+}
+
+// isInputComplete reports whether buf forms a complete K'UHUL statement: every
+// ⟁Xul⟁ block has a matching ⟁Ch'en⟁, and every JSON `{`/`[` opened is closed.
+func isInputComplete(buf string) bool {
+	xul, chen := 0, 0
+	for _, tok := range kuhul.Tokenize(buf) {
+		switch tok.Type {
+		case kuhul.TokenXUL:
+			xul++
+		case kuhul.TokenCHEN:
+			chen++
+		}
+	}
+	if xul > chen {
+		return false
+	}
+	return bracesBalanced(buf)
+}
+
+func bracesBalanced(s string) bool {
+	depth := 0
+	inString := false
+	escaped := false
+	for _, r := range s {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				inString = false
+			}
+			continue
+		}
+		switch r {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+func historyFilePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".kuhul_history"
+	}
+	return filepath.Join(home, ".kuhul_history")
+}
+
+// dispatchREPLLine resolves a complete statement to a built-in command or,
+// failing that, evaluates it as a K'UHUL expression. It reports whether the
+// REPL should keep running.
+func dispatchREPLLine(interp *kuhul.Interpreter, line string) bool {
+	name, args := line, ""
+	if idx := strings.IndexAny(line, " \t"); idx != -1 {
+		name, args = line[:idx], strings.TrimSpace(line[idx+1:])
+	}
+
+	for _, cmd := range replCommands {
+		if cmd.name == name || contains(cmd.aliases, name) {
+			return cmd.run(interp, args)
+		}
+	}
+
+	result, err := interp.Eval(line)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return true
+	}
+	if result != nil {
+		outputJSON(result)
+	}
+	return true
+}
+
+func contains(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func printREPLHelp() {
+	fmt.Println("K'UHUL REPL Commands:")
+	for _, cmd := range replCommands {
+		names := cmd.name
+		if len(cmd.aliases) > 0 {
+			names = names + ", " + strings.Join(cmd.aliases, ", ")
+		}
+		fmt.Printf("  %-18s %s\n", names, cmd.help)
+	}
+	fmt.Print(`
+K'UHUL Syntax:
+  ⟁Pop⟁ name {...}  Declaration (manifest, config)
+  ⟁Wo⟁ name = val   Assignment (variables)
+  ⟁Sek⟁ vector      Control flow (if, loop)
+  ⟁Xul⟁ name        Block definition start
+  ⟁Ch'en⟁ {...}     Return/emit from block
+  ⟁Yax⟁ name        Reference a value
+  ⟁K'ayab⟁ i from   Loop construct
+  ⟁Kumk'u⟁          End loop
+
+Examples:
+  ⟁Wo⟁ x = 10
+  ⟁Wo⟁ y = [1, 2, 3]
+  ⟁Wo⟁ data = {"name": "test"}
+`)
+}
+
+func runKuhulREPL() error {
+	fmt.Println("K'UHUL REPL v" + kuhul.Version)
+	fmt.Println("The law: ASX = XCFE = XJSON = KUHUL = AST = ATOMIC_BLOCK")
+	fmt.Println("Type 'exit' to quit, 'help' for commands")
+	fmt.Println()
+
+	interp := kuhul.NewInterpreter()
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "kuhul> ",
+		HistoryFile:     historyFilePath(),
+		AutoComplete:    &replCompleter{interp: interp},
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start REPL: %w", err)
+	}
+	defer rl.Close()
+
+	var buf strings.Builder
+	for {
+		line, err := rl.Readline()
+		switch {
+		case err == readline.ErrInterrupt:
+			// Abort whatever partial block is being typed, but keep the REPL alive.
+			buf.Reset()
+			rl.SetPrompt("kuhul> ")
+			continue
+		case err == io.EOF:
+			fmt.Println("Goodbye!")
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if buf.Len() == 0 && strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+
+		if !isInputComplete(buf.String()) {
+			rl.SetPrompt("...   > ")
+			continue
+		}
+
+		input := strings.TrimSpace(buf.String())
+		buf.Reset()
+		rl.SetPrompt("kuhul> ")
+
+		if input == "" {
+			continue
+		}
+
+		if !dispatchREPLLine(interp, input) {
+			break
+		}
+	}
+
+	return nil
+}
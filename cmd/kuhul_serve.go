@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ollama/ollama/kuhul"
+	"github.com/ollama/ollama/kuhul/serve"
+	"github.com/spf13/cobra"
+)
+
+var kuhulServeCmd = &cobra.Command{
+	Use:   "serve [file.khl]",
+	Short: "Serve K'UHUL handlers over HTTP",
+	Long: `Load a K'UHUL program and mount its C@@L BLOCK handlers as an HTTP
+microservice: POST /dispatch/{name}, GET /state, GET /handlers, and
+GET /fingerprint. With no file, an empty interpreter is served.`,
+	Example: `  ollama kuhul serve program.khl
+  ollama kuhul serve --addr :9090 program.khl`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+
+		interp := kuhul.NewInterpreter()
+		if len(args) > 0 {
+			content, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read file: %w", err)
+			}
+			if _, errs := interp.Load(string(content)); len(errs) > 0 {
+				return fmt.Errorf("failed to load %s: %s", args[0], errs[0])
+			}
+		}
+
+		fmt.Fprintf(cmd.ErrOrStderr(), "serving K'UHUL handlers on %s\n", addr)
+		return serve.Serve(interp, addr)
+	},
+}
+
+func init() {
+	kuhulServeCmd.Flags().String("addr", ":8787", "address to listen on")
+	kuhulCmd.AddCommand(kuhulServeCmd)
+}
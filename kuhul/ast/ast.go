@@ -39,22 +39,22 @@ const (
 	NodeEmit   NodeType = "Emit"
 
 	// Atomic block nodes
-	NodeAtomicBlock    NodeType = "AtomicBlock"
-	NodeCoolBlock      NodeType = "CoolBlock"
-	NodeCoolVector     NodeType = "CoolVector"
-	NodeCoolVariable   NodeType = "CoolVariable"
+	NodeAtomicBlock  NodeType = "AtomicBlock"
+	NodeCoolBlock    NodeType = "CoolBlock"
+	NodeCoolVector   NodeType = "CoolVector"
+	NodeCoolVariable NodeType = "CoolVariable"
 
 	// Expression nodes
-	NodeIdentifier   NodeType = "Identifier"
-	NodeLiteral      NodeType = "Literal"
-	NodeBinaryExpr   NodeType = "BinaryExpr"
-	NodeUnaryExpr    NodeType = "UnaryExpr"
-	NodeCallExpr     NodeType = "CallExpr"
-	NodeMemberExpr   NodeType = "MemberExpr"
-	NodeIndexExpr    NodeType = "IndexExpr"
-	NodeArrayExpr    NodeType = "ArrayExpr"
-	NodeObjectExpr   NodeType = "ObjectExpr"
-	NodeLambda       NodeType = "Lambda"
+	NodeIdentifier NodeType = "Identifier"
+	NodeLiteral    NodeType = "Literal"
+	NodeBinaryExpr NodeType = "BinaryExpr"
+	NodeUnaryExpr  NodeType = "UnaryExpr"
+	NodeCallExpr   NodeType = "CallExpr"
+	NodeMemberExpr NodeType = "MemberExpr"
+	NodeIndexExpr  NodeType = "IndexExpr"
+	NodeArrayExpr  NodeType = "ArrayExpr"
+	NodeObjectExpr NodeType = "ObjectExpr"
+	NodeLambda     NodeType = "Lambda"
 
 	// Pack nodes
 	NodePack       NodeType = "Pack"
@@ -79,6 +79,13 @@ type Node interface {
 type BaseNode struct {
 	NodeType NodeType `json:"type"`
 	Position Position `json:"pos"`
+
+	// Trivia holds comment tokens attached by parser.ParseWithTrivia.
+	// Parse (and everything downstream of it) leaves this nil, so
+	// interpreting/running a program is unaffected; only kuhul/printer
+	// reads it.
+	LeadingComments []string `json:"leadingComments,omitempty"`
+	TrailingComment string   `json:"trailingComment,omitempty"`
 }
 
 func (n *BaseNode) Type() NodeType { return n.NodeType }
@@ -87,28 +94,30 @@ func (n *BaseNode) Pos() Position  { return n.Position }
 // Program represents a complete K'UHUL program
 type Program struct {
 	BaseNode
-	Version       string                  `json:"version"`
-	Manifest      *Manifest               `json:"manifest,omitempty"`
-	Declarations  []*Declaration          `json:"declarations"`
-	Assignments   []*Assignment           `json:"assignments"`
-	Blocks        []*BlockDefinition      `json:"blocks"`
-	AtomicBlocks  map[string]*AtomicBlock `json:"atomicBlocks"`
-	CoolBlocks    map[string]*CoolBlock   `json:"coolBlocks"`
-	CoolVectors   map[string]*CoolVector  `json:"coolVectors"`
-	CoolVariables map[string]*CoolVariable `json:"coolVariables"`
+	Version        string                   `json:"version"`
+	Manifest       *Manifest                `json:"manifest,omitempty"`
+	Declarations   []*Declaration           `json:"declarations"`
+	Assignments    []*Assignment            `json:"assignments"`
+	Blocks         []*BlockDefinition       `json:"blocks"`
+	ControlVectors []*ControlVector         `json:"controlVectors"`
+	AtomicBlocks   map[string]*AtomicBlock  `json:"atomicBlocks"`
+	CoolBlocks     map[string]*CoolBlock    `json:"coolBlocks"`
+	CoolVectors    map[string]*CoolVector   `json:"coolVectors"`
+	CoolVariables  map[string]*CoolVariable `json:"coolVariables"`
 }
 
 func NewProgram() *Program {
 	return &Program{
-		BaseNode:      BaseNode{NodeType: NodeProgram},
-		Version:       "1.0.0",
-		Declarations:  make([]*Declaration, 0),
-		Assignments:   make([]*Assignment, 0),
-		Blocks:        make([]*BlockDefinition, 0),
-		AtomicBlocks:  make(map[string]*AtomicBlock),
-		CoolBlocks:    make(map[string]*CoolBlock),
-		CoolVectors:   make(map[string]*CoolVector),
-		CoolVariables: make(map[string]*CoolVariable),
+		BaseNode:       BaseNode{NodeType: NodeProgram},
+		Version:        "1.0.0",
+		Declarations:   make([]*Declaration, 0),
+		Assignments:    make([]*Assignment, 0),
+		Blocks:         make([]*BlockDefinition, 0),
+		ControlVectors: make([]*ControlVector, 0),
+		AtomicBlocks:   make(map[string]*AtomicBlock),
+		CoolBlocks:     make(map[string]*CoolBlock),
+		CoolVectors:    make(map[string]*CoolVector),
+		CoolVariables:  make(map[string]*CoolVariable),
 	}
 }
 
@@ -128,7 +137,13 @@ type Manifest struct {
 	KuhulFolds  map[string]interface{} `json:"kuhul_folds,omitempty"`
 	RestMesh    map[string]interface{} `json:"rest_mesh,omitempty"`
 	SiteContent map[string]interface{} `json:"site_content,omitempty"`
-	Raw         map[string]interface{} `json:"raw,omitempty"`
+	// Environments holds named overlay blocks (dev/staging/prod, or
+	// whatever the program calls them), each a map that may set "vars",
+	// "handlers", and "tapes" to deep-merge over the base manifest when
+	// that environment is selected. Wrangler-style: the same program runs
+	// against different tape sets or handler params without editing it.
+	Environments map[string]interface{} `json:"environments,omitempty"`
+	Raw          map[string]interface{} `json:"raw,omitempty"`
 }
 
 func (n *Manifest) String() string {
@@ -208,6 +223,7 @@ type CoolBlock struct {
 	BaseNode
 	Name    string                 `json:"name"`
 	Handler string                 `json:"handler,omitempty"`
+	OnEvent string                 `json:"onEvent,omitempty"` // @on: registers as an events.EventPump observer
 	Params  map[string]interface{} `json:"params"`
 	Body    []interface{}          `json:"body"`
 }
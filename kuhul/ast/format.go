@@ -0,0 +1,279 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FormatOptions controls ast.Format's output. The zero value is usable: an
+// empty Indent falls back to two spaces, matching kuhul/printer's
+// DefaultConfig.
+type FormatOptions struct {
+	// Indent is the string used for one level of indentation inside
+	// ⟁Xul⟁ ... ⟁Ch'en⟁ bodies and C@@L block bodies.
+	Indent string
+}
+
+// Format renders n as canonical K'UHUL surface syntax — the Pop/Wo/Sek/Xul/
+// Ch'en/C@@L glyphs the parser accepts — instead of the json.MarshalIndent
+// dump every node's String() method falls back to today. Unlike
+// kuhul/printer.Fprint, Format accepts any Node, not just *Program, so
+// callers built on Walk/Rewrite can re-render just the subtree they
+// rewrote.
+//
+// Format does not reproduce kuhul/printer's trivia (comment) handling or
+// its fixed canonical section ordering for a whole *Program; callers that
+// need a byte-for-byte reformatting of real source, comments included,
+// should use kuhul/printer.Format instead. This is the lighter,
+// tooling-facing counterpart used for round-tripping a single node.
+func Format(n Node, opts FormatOptions) string {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	f := &formatter{opts: opts}
+	f.node(n, "")
+	return f.buf.String()
+}
+
+type formatter struct {
+	buf  strings.Builder
+	opts FormatOptions
+}
+
+func (f *formatter) node(n Node, indent string) {
+	switch node := n.(type) {
+	case nil:
+		f.buf.WriteString("null")
+	case *Program:
+		f.program(node)
+	case *Manifest:
+		fmt.Fprintf(&f.buf, "⟁Pop⟁ manifest_ast %s", f.renderJSON(node.Raw, indent))
+	case *Declaration:
+		f.buf.WriteString("⟁Pop⟁ " + node.Name)
+		if node.Value != nil {
+			f.buf.WriteString(" " + f.renderValue(node.Value, indent))
+		}
+	case *Assignment:
+		fmt.Fprintf(&f.buf, "⟁Wo⟁ %s = %s", node.Name, f.renderValue(node.Value, indent))
+	case *ControlVector:
+		f.buf.WriteString("⟁Sek⟁ " + node.VectorType + "\n")
+		f.params(node.Params, indent+f.opts.Indent)
+	case *BlockDefinition:
+		f.buf.WriteString("⟁Xul⟁ " + node.Name + "\n")
+		f.params(node.Params, indent+f.opts.Indent)
+		for _, child := range node.Body {
+			f.buf.WriteString(indent + f.opts.Indent)
+			f.node(child, indent+f.opts.Indent)
+			f.buf.WriteString("\n")
+		}
+		f.buf.WriteString(indent + "⟁Ch'en⟁")
+	case *ReturnStatement:
+		f.buf.WriteString("⟁Ch'en⟁")
+		if node.Value != nil {
+			f.buf.WriteString(" " + f.renderValue(node.Value, indent))
+		}
+	case *AtomicBlock:
+		fmt.Fprintf(&f.buf, "ATOMIC_BLOCK_%s %s", node.Name, f.renderJSON(node.Content, indent))
+	case *CoolBlock:
+		f.buf.WriteString("C@@L BLOCK " + node.Name + "\n")
+		f.params(node.Params, indent+f.opts.Indent)
+		for _, v := range node.Body {
+			f.buf.WriteString(indent + f.opts.Indent)
+			f.buf.WriteString(f.renderValue(v, indent+f.opts.Indent))
+			f.buf.WriteString("\n")
+		}
+	case *CoolVector:
+		f.buf.WriteString("C@@L ATOMIC_VECTOR " + node.Name + "\n")
+		f.params(node.Params, indent+f.opts.Indent)
+	case *CoolVariable:
+		f.buf.WriteString("C@@L ATOMIC_VARIABLE " + node.Name + "\n")
+		params := map[string]interface{}{"scope": node.Scope}
+		if node.DefaultValue != nil {
+			params["default"] = node.DefaultValue
+		}
+		f.params(params, indent+f.opts.Indent)
+	case *Identifier:
+		f.buf.WriteString(node.Name)
+	case *Literal:
+		f.buf.WriteString(f.renderValue(node.Value, indent))
+	case *BinaryExpr:
+		f.node(node.Left, indent)
+		f.buf.WriteString(" " + node.Operator + " ")
+		f.node(node.Right, indent)
+	case *UnaryExpr:
+		f.buf.WriteString(node.Operator)
+		f.node(node.Operand, indent)
+	case *CallExpr:
+		f.node(node.Callee, indent)
+		f.buf.WriteString("(")
+		for i, arg := range node.Arguments {
+			if i > 0 {
+				f.buf.WriteString(", ")
+			}
+			f.node(arg, indent)
+		}
+		f.buf.WriteString(")")
+	case *MemberExpr:
+		f.node(node.Object, indent)
+		f.buf.WriteString("." + node.Property)
+	case *IndexExpr:
+		f.node(node.Object, indent)
+		f.buf.WriteString("[")
+		f.node(node.Index, indent)
+		f.buf.WriteString("]")
+	case *ArrayExpr:
+		f.buf.WriteString("[")
+		for i, el := range node.Elements {
+			if i > 0 {
+				f.buf.WriteString(", ")
+			}
+			f.node(el, indent)
+		}
+		f.buf.WriteString("]")
+	case *ObjectExpr:
+		f.buf.WriteString("{")
+		keys := make([]string, 0, len(node.Properties))
+		for k := range node.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			if i > 0 {
+				f.buf.WriteString(", ")
+			}
+			f.buf.WriteString(strconv.Quote(k) + ": ")
+			f.node(node.Properties[k], indent)
+		}
+		f.buf.WriteString("}")
+	case *Lambda:
+		f.buf.WriteString("lambda(" + strings.Join(node.Params, ", ") + ") ")
+		f.node(node.Body, indent)
+	case *Pack:
+		f.buf.WriteString("Pack " + node.Name)
+		if node.Action != "" {
+			f.buf.WriteString("." + node.Action)
+		}
+		f.buf.WriteString(f.renderJSON(node.Params, indent))
+	default:
+		f.buf.WriteString(node.String())
+	}
+}
+
+func (f *formatter) program(p *Program) {
+	wrote := false
+	blank := func() {
+		if wrote {
+			f.buf.WriteString("\n")
+		}
+		wrote = true
+	}
+
+	if p.Manifest != nil {
+		blank()
+		f.node(p.Manifest, "")
+		f.buf.WriteString("\n")
+	}
+	for _, d := range p.Declarations {
+		if d.Name == "manifest_ast" {
+			continue // already emitted above from p.Manifest
+		}
+		blank()
+		f.node(d, "")
+		f.buf.WriteString("\n")
+	}
+	for _, a := range p.Assignments {
+		blank()
+		f.node(a, "")
+		f.buf.WriteString("\n")
+	}
+	for _, cv := range p.ControlVectors {
+		blank()
+		f.node(cv, "")
+	}
+	for _, b := range p.Blocks {
+		blank()
+		f.node(b, "")
+		f.buf.WriteString("\n")
+	}
+	for _, name := range sortedKeysAtomic(p.AtomicBlocks) {
+		blank()
+		f.node(p.AtomicBlocks[name], "")
+		f.buf.WriteString("\n")
+	}
+	for _, name := range sortedKeysCoolVariable(p.CoolVariables) {
+		blank()
+		f.node(p.CoolVariables[name], "")
+	}
+	for _, name := range sortedKeysCoolVector(p.CoolVectors) {
+		blank()
+		f.node(p.CoolVectors[name], "")
+	}
+	for _, name := range sortedKeysCoolBlock(p.CoolBlocks) {
+		blank()
+		f.node(p.CoolBlocks[name], "")
+	}
+}
+
+// params renders @param: value lines sorted by key, aligning the colons to
+// the widest key — the same layout kuhul/printer uses.
+func (f *formatter) params(params map[string]interface{}, prefix string) {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		return
+	}
+
+	width := 0
+	for _, k := range keys {
+		if len(k) > width {
+			width = len(k)
+		}
+	}
+	for _, k := range keys {
+		at := "@" + k + ":"
+		pad := strings.Repeat(" ", width-len(k))
+		fmt.Fprintf(&f.buf, "%s%s%s %s\n", prefix, at, pad, f.renderValue(params[k], prefix))
+	}
+}
+
+// renderValue renders v either as a real expression (when it holds an
+// ast.Node — ⟁Wo⟁ assignment values and CoolBlock body entries are the two
+// places that happens) or as a plain literal.
+func (f *formatter) renderValue(v interface{}, indent string) string {
+	if n, ok := v.(Node); ok {
+		inner := &formatter{opts: f.opts}
+		inner.node(n, indent)
+		return inner.buf.String()
+	}
+
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(t)
+	case bool:
+		return strconv.FormatBool(t)
+	case map[string]interface{}, []interface{}:
+		return f.renderJSON(t, indent)
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprintf("%v", t)
+		}
+		return string(b)
+	}
+}
+
+func (f *formatter) renderJSON(v interface{}, indent string) string {
+	b, err := json.MarshalIndent(v, indent, f.opts.Indent)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
@@ -0,0 +1,188 @@
+package ast
+
+// Rewrite returns a new tree with f applied bottom-up: every node reachable
+// from n has its children rewritten first, then f is called on the node
+// itself and its return value takes that node's place in the result. n is
+// never mutated in place — Rewrite copies each node it touches before
+// handing it to f, so callers can safely hold onto the original tree.
+//
+// A nil Node (including a nil n) passes straight through without calling f,
+// matching the convention the rest of this package follows for the zero
+// value of Value/Object/Callee-style Node fields.
+func Rewrite(n Node, f func(Node) Node) Node {
+	if n == nil {
+		return nil
+	}
+
+	switch node := n.(type) {
+	case *Program:
+		cp := *node
+		if node.Manifest != nil {
+			cp.Manifest = Rewrite(node.Manifest, f).(*Manifest)
+		}
+		cp.Declarations = make([]*Declaration, len(node.Declarations))
+		for i, d := range node.Declarations {
+			cp.Declarations[i] = Rewrite(d, f).(*Declaration)
+		}
+		cp.Assignments = make([]*Assignment, len(node.Assignments))
+		for i, a := range node.Assignments {
+			cp.Assignments[i] = Rewrite(a, f).(*Assignment)
+		}
+		cp.Blocks = make([]*BlockDefinition, len(node.Blocks))
+		for i, b := range node.Blocks {
+			cp.Blocks[i] = Rewrite(b, f).(*BlockDefinition)
+		}
+		cp.ControlVectors = make([]*ControlVector, len(node.ControlVectors))
+		for i, cv := range node.ControlVectors {
+			cp.ControlVectors[i] = Rewrite(cv, f).(*ControlVector)
+		}
+		cp.AtomicBlocks = make(map[string]*AtomicBlock, len(node.AtomicBlocks))
+		for k, v := range node.AtomicBlocks {
+			cp.AtomicBlocks[k] = Rewrite(v, f).(*AtomicBlock)
+		}
+		cp.CoolBlocks = make(map[string]*CoolBlock, len(node.CoolBlocks))
+		for k, v := range node.CoolBlocks {
+			cp.CoolBlocks[k] = Rewrite(v, f).(*CoolBlock)
+		}
+		cp.CoolVectors = make(map[string]*CoolVector, len(node.CoolVectors))
+		for k, v := range node.CoolVectors {
+			cp.CoolVectors[k] = Rewrite(v, f).(*CoolVector)
+		}
+		cp.CoolVariables = make(map[string]*CoolVariable, len(node.CoolVariables))
+		for k, v := range node.CoolVariables {
+			cp.CoolVariables[k] = Rewrite(v, f).(*CoolVariable)
+		}
+		return f(&cp)
+
+	case *Manifest:
+		cp := *node
+		return f(&cp)
+
+	case *Declaration:
+		cp := *node
+		cp.Value = rewriteValue(node.Value, f)
+		return f(&cp)
+
+	case *Assignment:
+		cp := *node
+		cp.Value = rewriteValue(node.Value, f)
+		return f(&cp)
+
+	case *ControlVector:
+		cp := *node
+		cp.Body = rewriteNodeSlice(node.Body, f)
+		return f(&cp)
+
+	case *BlockDefinition:
+		cp := *node
+		cp.Body = rewriteNodeSlice(node.Body, f)
+		return f(&cp)
+
+	case *ReturnStatement:
+		cp := *node
+		cp.Value = rewriteValue(node.Value, f)
+		return f(&cp)
+
+	case *AtomicBlock:
+		cp := *node
+		return f(&cp)
+
+	case *CoolBlock:
+		cp := *node
+		cp.Body = make([]interface{}, len(node.Body))
+		for i, v := range node.Body {
+			cp.Body[i] = rewriteValue(v, f)
+		}
+		return f(&cp)
+
+	case *CoolVector:
+		cp := *node
+		return f(&cp)
+
+	case *CoolVariable:
+		cp := *node
+		return f(&cp)
+
+	case *Identifier:
+		cp := *node
+		return f(&cp)
+
+	case *Literal:
+		cp := *node
+		return f(&cp)
+
+	case *BinaryExpr:
+		cp := *node
+		cp.Left = Rewrite(node.Left, f)
+		cp.Right = Rewrite(node.Right, f)
+		return f(&cp)
+
+	case *UnaryExpr:
+		cp := *node
+		cp.Operand = Rewrite(node.Operand, f)
+		return f(&cp)
+
+	case *CallExpr:
+		cp := *node
+		cp.Callee = Rewrite(node.Callee, f)
+		cp.Arguments = rewriteNodeSlice(node.Arguments, f)
+		return f(&cp)
+
+	case *MemberExpr:
+		cp := *node
+		cp.Object = Rewrite(node.Object, f)
+		return f(&cp)
+
+	case *IndexExpr:
+		cp := *node
+		cp.Object = Rewrite(node.Object, f)
+		cp.Index = Rewrite(node.Index, f)
+		return f(&cp)
+
+	case *ArrayExpr:
+		cp := *node
+		cp.Elements = rewriteNodeSlice(node.Elements, f)
+		return f(&cp)
+
+	case *ObjectExpr:
+		cp := *node
+		cp.Properties = make(map[string]Node, len(node.Properties))
+		for k, v := range node.Properties {
+			cp.Properties[k] = Rewrite(v, f)
+		}
+		return f(&cp)
+
+	case *Lambda:
+		cp := *node
+		cp.Body = Rewrite(node.Body, f)
+		return f(&cp)
+
+	case *Pack:
+		cp := *node
+		return f(&cp)
+
+	default:
+		return f(n)
+	}
+}
+
+// rewriteValue rewrites v in place when it holds an ast.Node (the same
+// interface{}-but-sometimes-a-Node fields Walk special-cases); any other
+// value — a plain literal — passes through unchanged.
+func rewriteValue(v interface{}, f func(Node) Node) interface{} {
+	if n, ok := v.(Node); ok {
+		return Rewrite(n, f)
+	}
+	return v
+}
+
+func rewriteNodeSlice(nodes []Node, f func(Node) Node) []Node {
+	if nodes == nil {
+		return nil
+	}
+	out := make([]Node, len(nodes))
+	for i, n := range nodes {
+		out[i] = Rewrite(n, f)
+	}
+	return out
+}
@@ -0,0 +1,198 @@
+package ast
+
+import "sort"
+
+// Visitor is implemented by callers that want to traverse an *ast.Program
+// generically instead of type-switching on every concrete Node themselves.
+// Walk calls Enter before descending into a node's children; if Enter
+// returns ok=false, Walk skips those children entirely (and does not call
+// Leave for that node). Otherwise Walk descends using the Visitor Enter
+// returned — usually v itself — and calls its Leave once every child has
+// been walked.
+type Visitor interface {
+	Enter(n Node) (w Visitor, ok bool)
+	Leave(n Node)
+}
+
+// Walk recurses through n and every node reachable from it: the slice and
+// map fields every concrete node type holds (including Program's
+// AtomicBlocks/CoolBlocks/CoolVectors/CoolVariables maps, walked in sorted
+// key order for determinism), plus the interface{} Value fields on
+// Declaration, Assignment, and ReturnStatement when they hold a Node rather
+// than a plain literal. A nil n is a no-op, matching go/ast.Walk.
+func Walk(v Visitor, n Node) {
+	if n == nil {
+		return
+	}
+	w, ok := v.Enter(n)
+	if !ok {
+		return
+	}
+
+	switch node := n.(type) {
+	case *Program:
+		if node.Manifest != nil {
+			Walk(w, node.Manifest)
+		}
+		for _, d := range node.Declarations {
+			Walk(w, d)
+		}
+		for _, a := range node.Assignments {
+			Walk(w, a)
+		}
+		for _, b := range node.Blocks {
+			Walk(w, b)
+		}
+		for _, cv := range node.ControlVectors {
+			Walk(w, cv)
+		}
+		for _, name := range sortedKeysAtomic(node.AtomicBlocks) {
+			Walk(w, node.AtomicBlocks[name])
+		}
+		for _, name := range sortedKeysCoolBlock(node.CoolBlocks) {
+			Walk(w, node.CoolBlocks[name])
+		}
+		for _, name := range sortedKeysCoolVector(node.CoolVectors) {
+			Walk(w, node.CoolVectors[name])
+		}
+		for _, name := range sortedKeysCoolVariable(node.CoolVariables) {
+			Walk(w, node.CoolVariables[name])
+		}
+	case *Manifest:
+		// Raw/Tapes/KuhulFolds/etc. are plain JSON-like maps, not ast.Node.
+	case *Declaration:
+		walkValue(w, node.Value)
+	case *Assignment:
+		walkValue(w, node.Value)
+	case *ControlVector:
+		for _, n := range node.Body {
+			Walk(w, n)
+		}
+	case *BlockDefinition:
+		for _, n := range node.Body {
+			Walk(w, n)
+		}
+	case *ReturnStatement:
+		walkValue(w, node.Value)
+	case *AtomicBlock:
+		// Content is a plain map[string]interface{}, not ast.Node.
+	case *CoolBlock:
+		for _, n := range node.Body {
+			walkValue(w, n)
+		}
+	case *CoolVector:
+		// Params is a plain map[string]interface{}, not ast.Node.
+	case *CoolVariable:
+		// DefaultValue is a plain literal, not ast.Node.
+	case *Identifier:
+		// leaf
+	case *Literal:
+		// leaf
+	case *BinaryExpr:
+		Walk(w, node.Left)
+		Walk(w, node.Right)
+	case *UnaryExpr:
+		Walk(w, node.Operand)
+	case *CallExpr:
+		Walk(w, node.Callee)
+		for _, arg := range node.Arguments {
+			Walk(w, arg)
+		}
+	case *MemberExpr:
+		Walk(w, node.Object)
+	case *IndexExpr:
+		Walk(w, node.Object)
+		Walk(w, node.Index)
+	case *ArrayExpr:
+		for _, el := range node.Elements {
+			Walk(w, el)
+		}
+	case *ObjectExpr:
+		for _, key := range sortedKeysNode(node.Properties) {
+			Walk(w, node.Properties[key])
+		}
+	case *Lambda:
+		Walk(w, node.Body)
+	case *Pack:
+		// Params is a plain map[string]interface{}, not ast.Node.
+	}
+
+	w.Leave(n)
+}
+
+// walkValue descends into v only when it holds an ast.Node; Declaration,
+// Assignment, ReturnStatement, and CoolBlock bodies all store either a
+// plain literal or a real expression/statement Node in an interface{}
+// field, and only the latter has children worth walking.
+func walkValue(w Visitor, v interface{}) {
+	if n, ok := v.(Node); ok {
+		Walk(w, n)
+	}
+}
+
+// Inspect traverses n in depth-first order, calling f before visiting each
+// node's children. If f returns false, Inspect skips that node's children,
+// mirroring go/ast.Inspect.
+func Inspect(n Node, f func(Node) bool) {
+	Walk(inspector(f), n)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Enter(n Node) (Visitor, bool) {
+	if f(n) {
+		return f, true
+	}
+	return nil, false
+}
+
+func (f inspector) Leave(Node) {}
+
+// The sortedKeys* helpers give Walk (and Format) a deterministic traversal
+// order over Program's map-backed sections, matching how kuhul/printer
+// already sorts them when emitting source.
+
+func sortedKeysAtomic(m map[string]*AtomicBlock) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeysCoolBlock(m map[string]*CoolBlock) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeysCoolVector(m map[string]*CoolVector) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeysCoolVariable(m map[string]*CoolVariable) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeysNode(m map[string]Node) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
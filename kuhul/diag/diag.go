@@ -0,0 +1,191 @@
+// Package diag provides structured diagnostics for the K'UHUL toolchain.
+//
+// Diagnostics carry a severity, a source span (line/column and byte offset),
+// a human-readable message, and an optional stable error code (e.g. KHL0007)
+// that tools can use for filtering or documentation lookups.
+package diag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity classifies a diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	default:
+		return "unknown"
+	}
+}
+
+// Span identifies a region of source text, both as line/column pairs and as
+// byte offsets into the original source string.
+type Span struct {
+	StartLine   int
+	StartCol    int
+	EndLine     int
+	EndCol      int
+	StartOffset int
+	EndOffset   int
+}
+
+// Diagnostic is a single error, warning, or note produced while processing
+// K'UHUL source.
+type Diagnostic struct {
+	Severity Severity
+	Span     Span
+	Message  string
+	Code     string // optional, e.g. "KHL0007"
+	// Suggestion, if set, is a "did you mean" correction for a typo'd
+	// keyword or marker name (e.g. "⟁K'ayab⟁" for a glyph spelled
+	// "⟁Kayab⟁") — see Suggest.
+	Suggestion string
+}
+
+// String formats the diagnostic as "severity[code]: message at line:col",
+// with a trailing "(did you mean X?)" when Suggestion is set.
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	b.WriteString(d.Severity.String())
+	if d.Code != "" {
+		fmt.Fprintf(&b, "[%s]", d.Code)
+	}
+	fmt.Fprintf(&b, ": %s at %d:%d", d.Message, d.Span.StartLine, d.Span.StartCol)
+	if d.Suggestion != "" {
+		fmt.Fprintf(&b, " (did you mean %s?)", d.Suggestion)
+	}
+	return b.String()
+}
+
+// ErrorHandler receives diagnostics as they are produced, allowing callers to
+// stream them instead of waiting for a batch at the end of a pass (mirrors
+// the go/parser and syzkaller errorHandler models).
+type ErrorHandler interface {
+	HandleDiagnostic(d Diagnostic)
+}
+
+// Collector is an ErrorHandler that accumulates diagnostics in order.
+type Collector struct {
+	Diagnostics []Diagnostic
+}
+
+// HandleDiagnostic appends d to the collector.
+func (c *Collector) HandleDiagnostic(d Diagnostic) {
+	c.Diagnostics = append(c.Diagnostics, d)
+}
+
+// HasErrors reports whether any collected diagnostic has error severity.
+func (c *Collector) HasErrors() bool {
+	for _, d := range c.Diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Render renders a diagnostic against source, printing the offending source
+// line followed by a caret underline pointing at the span.
+func Render(source string, d Diagnostic) string {
+	lines := strings.Split(source, "\n")
+	lineIdx := d.Span.StartLine - 1
+	if lineIdx < 0 || lineIdx >= len(lines) {
+		return d.String()
+	}
+
+	line := lines[lineIdx]
+	col := d.Span.StartCol
+	if col < 1 {
+		col = 1
+	}
+
+	width := d.Span.EndCol - d.Span.StartCol
+	if d.Span.EndLine != d.Span.StartLine || width < 1 {
+		width = 1
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", d.String())
+	fmt.Fprintf(&b, "  %d | %s\n", d.Span.StartLine, line)
+	gutter := len(fmt.Sprintf("%d", d.Span.StartLine)) + 5
+	b.WriteString(strings.Repeat(" ", gutter+col-1))
+	b.WriteString(strings.Repeat("^", width))
+	return b.String()
+}
+
+// suggestMaxDistance bounds how many edits a candidate may be from word and
+// still count as a plausible typo for Suggest — beyond this the words are
+// considered unrelated rather than a correction.
+const suggestMaxDistance = 2
+
+// Suggest returns whichever of candidates is closest to word by Levenshtein
+// distance, for a Diagnostic.Suggestion "did you mean" hint — e.g. a lexer
+// matching an unrecognized "⟁Kayab⟁" glyph against MayanMarkers' keys, or a
+// parser matching an unknown identifier against its keyword table. Returns
+// "" if word is an exact match already (no correction needed) or if no
+// candidate is within suggestMaxDistance edits.
+func Suggest(word string, candidates []string) string {
+	best := ""
+	bestDist := suggestMaxDistance + 1
+	for _, c := range candidates {
+		if c == word {
+			return ""
+		}
+		d := levenshtein(word, c)
+		if d < bestDist {
+			bestDist, best = d, c
+		}
+	}
+	if bestDist > suggestMaxDistance {
+		return ""
+	}
+	return best
+}
+
+// levenshtein computes the edit distance between a and b by rune, using the
+// standard two-row dynamic-programming table.
+func levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
+
+func min3(a, b, c int) int {
+	m := a
+	if b < m {
+		m = b
+	}
+	if c < m {
+		m = c
+	}
+	return m
+}
@@ -0,0 +1,68 @@
+// Package events provides a thread-safe event pump used to decouple C@@L
+// BLOCK handlers from the interpreter's synchronous dispatch path: a block
+// can register as an observer of a named event instead of being invoked
+// directly, and any `⟁Sek⟁ emit` vector can post to that event.
+package events
+
+import "sync"
+
+// EventCallback is invoked when an observed event is posted. source is
+// whatever was passed to PostEvent, not the value passed to AddObserver.
+type EventCallback func(event string, source interface{})
+
+// EventPump fans a named event out to every observer registered for it.
+type EventPump struct {
+	mu        sync.RWMutex
+	observers map[string]map[interface{}][]EventCallback // event -> source -> callbacks
+}
+
+// NewEventPump creates an empty EventPump.
+func NewEventPump() *EventPump {
+	return &EventPump{
+		observers: make(map[string]map[interface{}][]EventCallback),
+	}
+}
+
+// AddObserver registers cb to run whenever event is posted. source is an
+// identity for the observer (e.g. a handler name) and is only used to look
+// the observer back up for RemoveObserver; multiple callbacks may share it.
+func (p *EventPump) AddObserver(event string, source interface{}, cb EventCallback) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bySource, ok := p.observers[event]
+	if !ok {
+		bySource = make(map[interface{}][]EventCallback)
+		p.observers[event] = bySource
+	}
+	bySource[source] = append(bySource[source], cb)
+}
+
+// RemoveObserver unregisters every callback source previously registered
+// for event via AddObserver.
+func (p *EventPump) RemoveObserver(event string, source interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if bySource, ok := p.observers[event]; ok {
+		delete(bySource, source)
+	}
+}
+
+// PostEvent notifies every observer of event, passing source through to each
+// callback. The observers lock is never held while callbacks run: the set of
+// callbacks is snapshotted under a read lock and released before any of them
+// are invoked, so a callback that reentrantly calls AddObserver or
+// RemoveObserver (even for the event it was called from) cannot deadlock.
+func (p *EventPump) PostEvent(event string, source interface{}) {
+	p.mu.RLock()
+	var snapshot []EventCallback
+	for _, cbs := range p.observers[event] {
+		snapshot = append(snapshot, cbs...)
+	}
+	p.mu.RUnlock()
+
+	for _, cb := range snapshot {
+		cb(event, source)
+	}
+}
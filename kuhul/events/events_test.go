@@ -0,0 +1,64 @@
+package events
+
+import "testing"
+
+func TestPostEventDeliversToObserver(t *testing.T) {
+	pump := NewEventPump()
+
+	var got string
+	pump.AddObserver("greet", "handler_a", func(event string, source interface{}) {
+		got = event
+	})
+
+	pump.PostEvent("greet", nil)
+
+	if got != "greet" {
+		t.Fatalf("expected observer to be called with %q, got %q", "greet", got)
+	}
+}
+
+func TestRemoveObserverStopsDelivery(t *testing.T) {
+	pump := NewEventPump()
+
+	calls := 0
+	pump.AddObserver("tick", "handler_a", func(event string, source interface{}) {
+		calls++
+	})
+	pump.RemoveObserver("tick", "handler_a")
+
+	pump.PostEvent("tick", nil)
+
+	if calls != 0 {
+		t.Fatalf("expected no calls after RemoveObserver, got %d", calls)
+	}
+}
+
+// TestPostEventReentrantRegistrationDoesNotDeadlock exercises the case the
+// lock-release-before-invoke design exists for: a callback that, while being
+// invoked by PostEvent, turns around and registers a new observer for the
+// same event. If PostEvent held its lock across the callback invocation this
+// would deadlock.
+func TestPostEventReentrantRegistrationDoesNotDeadlock(t *testing.T) {
+	pump := NewEventPump()
+
+	secondCalled := false
+	pump.AddObserver("boot", "handler_a", func(event string, source interface{}) {
+		pump.AddObserver("boot", "handler_b", func(event string, source interface{}) {
+			secondCalled = true
+		})
+	})
+
+	pump.PostEvent("boot", nil)
+
+	// The observer registered reentrantly during the first PostEvent must not
+	// have been invoked as part of that same post (it arrived after the
+	// snapshot was taken) -- it should only fire on the next post.
+	if secondCalled {
+		t.Fatalf("observer registered during PostEvent should not fire in the same post")
+	}
+
+	pump.PostEvent("boot", nil)
+	if !secondCalled {
+		t.Fatalf("observer registered during the previous PostEvent should fire on the next post")
+	}
+}
@@ -0,0 +1,107 @@
+// Package kerror defines the structured error type shared by the K'UHUL
+// lexer, parser, and interpreter. It lives in its own leaf package (mirroring
+// kuhul/diag) so kuhul/runtime can construct errors without importing the
+// top-level kuhul package, which imports kuhul/runtime itself.
+package kerror
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Code is a machine-readable identifier for a failure mode, so callers can
+// branch on what went wrong instead of pattern-matching Message.
+type Code string
+
+const (
+	// UnknownGlyph marks a ⟁...⟁ marker whose contents don't match any
+	// known Mayan marker.
+	UnknownGlyph Code = "UnknownGlyph"
+	// UnterminatedJSON marks a {...}/[...] payload whose braces/brackets
+	// never balanced before EOF.
+	UnterminatedJSON Code = "UnterminatedJSON"
+	// UnterminatedString marks a "..." string literal with no closing
+	// quote before EOF.
+	UnterminatedString Code = "UnterminatedString"
+	// UnterminatedComment marks a /* ... */ block comment with no closing
+	// "*/" before EOF.
+	UnterminatedComment Code = "UnterminatedComment"
+	// PushingInvalidType marks a push() call whose first argument is not
+	// an array.
+	PushingInvalidType Code = "PushingInvalidType"
+	// IndexOutOfRange marks an index expression whose index falls outside
+	// the bounds of the array being indexed.
+	IndexOutOfRange Code = "IndexOutOfRange"
+	// HandlerNotFound marks a Dispatch call naming an unregistered C@@L
+	// BLOCK handler.
+	HandlerNotFound Code = "HandlerNotFound"
+	// MaxCallDepthExceeded marks a handler call chain that exceeded the
+	// interpreter's maximum call depth.
+	MaxCallDepthExceeded Code = "MaxCallDepthExceeded"
+	// ManifestConflict marks a key present in both a file-loaded manifest
+	// and an inline ⟁Pop⟁ manifest_ast with different values. The inline
+	// value always wins; this just surfaces that a silent override happened.
+	ManifestConflict Code = "ManifestConflict"
+	// DeadlineExceeded marks a RunContext/EvalContext/Dispatch call whose
+	// context was canceled or past its deadline before (or during)
+	// execution.
+	DeadlineExceeded Code = "DeadlineExceeded"
+	// LimitExceeded marks a runtime.Limits cap (instructions, heap bytes,
+	// output bytes, or an unlisted handler) a sandboxed Interpreter hit;
+	// see runtime.LimitExceededError.Kind for which one.
+	LimitExceeded Code = "LimitExceeded"
+	// IntegerOverflow marks an int64 arithmetic operation that overflowed,
+	// raised instead of wrapping when the Interpreter's overflow-check mode
+	// is on; see runtime.Interpreter.SetOverflowCheck.
+	IntegerOverflow Code = "IntegerOverflow"
+)
+
+// Error is a structured K'UHUL error: the offending token, its source
+// location, a machine-readable Code, and a human message.
+type Error struct {
+	Code    Code
+	Message string
+	Token   string
+	Line    int
+	Column  int
+	Offset  int
+}
+
+// New builds an Error for the offending token at line:column (1-based) /
+// byte offset, with Message formatted like fmt.Sprintf.
+func New(code Code, token string, line, column, offset int, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Token:   token,
+		Line:    line,
+		Column:  column,
+		Offset:  offset,
+	}
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s at %d:%d", e.Code, e.Message, e.Line, e.Column)
+}
+
+// Format renders e against source as the offending line with a caret
+// pointing at Column, the same shape diag.Render produces for diagnostics.
+func (e *Error) Format(source string) string {
+	lines := strings.Split(source, "\n")
+	if e.Line < 1 || e.Line > len(lines) {
+		return e.Error()
+	}
+
+	line := lines[e.Line-1]
+	col := e.Column
+	if col < 1 {
+		col = 1
+	}
+	if col > len(line)+1 {
+		col = len(line) + 1
+	}
+
+	caret := strings.Repeat(" ", col-1) + "^"
+	return fmt.Sprintf("%s\n%s\n%s", e.Error(), line, caret)
+}
@@ -40,8 +40,13 @@
 package kuhul
 
 import (
+	"context"
+
 	"github.com/ollama/ollama/kuhul/ast"
+	"github.com/ollama/ollama/kuhul/diag"
+	"github.com/ollama/ollama/kuhul/kerror"
 	"github.com/ollama/ollama/kuhul/lexer"
+	"github.com/ollama/ollama/kuhul/manifest"
 	"github.com/ollama/ollama/kuhul/parser"
 	"github.com/ollama/ollama/kuhul/runtime"
 	"github.com/ollama/ollama/kuhul/scxq2"
@@ -55,30 +60,80 @@ func Tokenize(source string) []lexer.Token {
 	return l.Tokenize()
 }
 
+// TokenizeWithErrors tokenizes K'UHUL source code the same way Tokenize does,
+// but also returns any structured KuhulErrors the lexer raised along the way
+// (an unrecognized glyph marker, or a JSON payload/string/block comment that
+// never closed before EOF — each unclosed one also shows up as an ERROR token
+// in the returned slice, while an unrecognized marker shows up as an IDENT
+// token).
+func TokenizeWithErrors(source string) ([]lexer.Token, []*KuhulError) {
+	l := lexer.New(source)
+	tokens := l.Tokenize()
+	return tokens, l.Errors()
+}
+
 // Parse parses K'UHUL source code into an AST
 func Parse(source string) (*ast.Program, []string) {
 	return parser.Parse(source)
 }
 
+// ParseWithDiagnostics parses K'UHUL source code into an AST, returning
+// structured diagnostics (severity, source span, optional error code)
+// instead of bare strings.
+func ParseWithDiagnostics(source string) (*ast.Program, []diag.Diagnostic) {
+	return parser.ParseWithDiagnostics(source)
+}
+
+// ParseWithTrivia parses K'UHUL source code the same way Parse does, but
+// attaches leading/trailing comments to top-level AST nodes so tools such as
+// kuhul/printer can round-trip them.
+func ParseWithTrivia(source string) (*ast.Program, []string) {
+	return parser.ParseWithTrivia(source)
+}
+
 // NewInterpreter creates a new K'UHUL interpreter
 func NewInterpreter() *runtime.Interpreter {
 	return runtime.NewInterpreter()
 }
 
-// Run parses and executes K'UHUL source code
+// LoadManifest reads a program manifest from an external .toml, .yaml, or
+// .json file, for use with Interpreter.LoadWithManifest as a base layer
+// underneath an inline ⟁Pop⟁ manifest_ast.
+func LoadManifest(path string) (*Manifest, error) {
+	return manifest.Load(path)
+}
+
+// Run parses and executes K'UHUL source code. It's RunContext against
+// context.Background(), for callers that don't need a deadline.
 func Run(source string) (interface{}, error) {
+	return RunContext(context.Background(), source)
+}
+
+// RunContext parses and executes K'UHUL source code the same way Run does,
+// but aborts with an ErrDeadlineExceeded KuhulError if ctx is canceled or
+// past its deadline before the program finishes (see
+// runtime.Interpreter.RunContext for where that's checked).
+func RunContext(ctx context.Context, source string) (interface{}, error) {
 	interp := runtime.NewInterpreter()
 	_, errors := interp.Load(source)
 	if len(errors) > 0 {
 		return nil, &ParseError{Errors: errors}
 	}
-	return interp.Run()
+	return interp.RunContext(ctx)
 }
 
-// Eval evaluates a K'UHUL expression
+// Eval evaluates a K'UHUL expression. It's EvalContext against
+// context.Background(), for callers that don't need a deadline.
 func Eval(source string) (interface{}, error) {
+	return EvalContext(context.Background(), source)
+}
+
+// EvalContext evaluates a K'UHUL expression the same way Eval does, but
+// aborts with an ErrDeadlineExceeded KuhulError if ctx is canceled or past
+// its deadline before evaluation finishes.
+func EvalContext(ctx context.Context, source string) (interface{}, error) {
 	interp := runtime.NewInterpreter()
-	return interp.Eval(source)
+	return interp.EvalContext(ctx, source)
 }
 
 // Fingerprint generates an SCXQ2 fingerprint for data
@@ -115,15 +170,22 @@ func (e *ParseError) Error() string {
 
 // Exported types for external use
 type (
-	Token        = lexer.Token
-	TokenType    = lexer.TokenType
-	Program      = ast.Program
-	Interpreter  = runtime.Interpreter
-	RuntimeState = runtime.RuntimeState
-	Environment  = runtime.Environment
-	Context      = runtime.Context
-	Handler      = runtime.Handler
-	BuiltinFunc  = runtime.BuiltinFunc
+	Token          = lexer.Token
+	TokenType      = lexer.TokenType
+	Program        = ast.Program
+	Interpreter    = runtime.Interpreter
+	RuntimeState   = runtime.RuntimeState
+	Environment    = runtime.Environment
+	Context        = runtime.Context
+	Handler        = runtime.Handler
+	Builtin        = runtime.Builtin
+	Diagnostic     = diag.Diagnostic
+	Severity       = diag.Severity
+	KuhulError     = kerror.Error
+	ErrorCode      = kerror.Code
+	Tensor         = runtime.Tensor
+	BuiltinBackend = runtime.BuiltinBackend
+	Manifest       = ast.Manifest
 )
 
 // Re-export token types
@@ -140,5 +202,33 @@ const (
 	TokenEOF   = lexer.EOF
 )
 
+// Re-export diagnostic severities
+const (
+	SeverityError   = diag.SeverityError
+	SeverityWarning = diag.SeverityWarning
+	SeverityNote    = diag.SeverityNote
+)
+
+// Re-export KuhulError codes
+const (
+	ErrUnknownGlyph         = kerror.UnknownGlyph
+	ErrUnterminatedJSON     = kerror.UnterminatedJSON
+	ErrPushingInvalidType   = kerror.PushingInvalidType
+	ErrIndexOutOfRange      = kerror.IndexOutOfRange
+	ErrHandlerNotFound      = kerror.HandlerNotFound
+	ErrMaxCallDepthExceeded = kerror.MaxCallDepthExceeded
+	ErrDeadlineExceeded     = kerror.DeadlineExceeded
+	ErrLimitExceeded        = kerror.LimitExceeded
+	ErrIntegerOverflow      = kerror.IntegerOverflow
+	ErrUnterminatedString   = kerror.UnterminatedString
+	ErrUnterminatedComment  = kerror.UnterminatedComment
+)
+
 // Builtins provides access to built-in functions
 var Builtins = runtime.Builtins
+
+// CallBuiltin invokes a registered built-in by name, validating its arity and
+// argument types the same way K'UHUL source calling it would.
+func CallBuiltin(name string, args ...interface{}) (interface{}, error) {
+	return runtime.Call(name, args...)
+}
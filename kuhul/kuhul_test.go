@@ -3,6 +3,9 @@ package kuhul
 import (
 	"strings"
 	"testing"
+
+	"github.com/ollama/ollama/kuhul/kerror"
+	"github.com/ollama/ollama/kuhul/lexer"
 )
 
 func TestTokenize(t *testing.T) {
@@ -292,7 +295,6 @@ func TestCompress(t *testing.T) {
 func TestBuiltins(t *testing.T) {
 	// Test matrix_multiply
 	t.Run("matrix_multiply", func(t *testing.T) {
-		mm := Builtins["matrix_multiply"]
 		a := []interface{}{
 			[]interface{}{1.0, 2.0},
 			[]interface{}{3.0, 4.0},
@@ -301,7 +303,10 @@ func TestBuiltins(t *testing.T) {
 			[]interface{}{5.0, 6.0},
 			[]interface{}{7.0, 8.0},
 		}
-		result := mm(a, b)
+		result, err := CallBuiltin("matrix_multiply", a, b)
+		if err != nil {
+			t.Fatalf("matrix_multiply returned error: %v", err)
+		}
 		if result == nil {
 			t.Fatal("matrix_multiply returned nil")
 		}
@@ -315,8 +320,10 @@ func TestBuiltins(t *testing.T) {
 
 	// Test softmax
 	t.Run("softmax", func(t *testing.T) {
-		sm := Builtins["softmax"]
-		result := sm([]interface{}{1.0, 2.0, 3.0})
+		result, err := CallBuiltin("softmax", []interface{}{1.0, 2.0, 3.0})
+		if err != nil {
+			t.Fatalf("softmax returned error: %v", err)
+		}
 		if result == nil {
 			t.Fatal("softmax returned nil")
 		}
@@ -336,12 +343,14 @@ func TestBuiltins(t *testing.T) {
 
 	// Test transpose
 	t.Run("transpose", func(t *testing.T) {
-		tr := Builtins["transpose"]
 		mat := []interface{}{
 			[]interface{}{1.0, 2.0, 3.0},
 			[]interface{}{4.0, 5.0, 6.0},
 		}
-		result := tr(mat)
+		result, err := CallBuiltin("transpose", mat)
+		if err != nil {
+			t.Fatalf("transpose returned error: %v", err)
+		}
 		if result == nil {
 			t.Fatal("transpose returned nil")
 		}
@@ -351,6 +360,49 @@ func TestBuiltins(t *testing.T) {
 			}
 		}
 	})
+
+	// Test that the numeric tower stays in int64 for integer inputs instead
+	// of coercing through float64 and losing precision above 2^53.
+	t.Run("int64 precision", func(t *testing.T) {
+		big := int64(1) << 60
+		result, err := CallBuiltin("abs", big)
+		if err != nil {
+			t.Fatalf("abs returned error: %v", err)
+		}
+		if result != big {
+			t.Errorf("abs(%d) = %v (%T), want %d (int64)", big, result, result, big)
+		}
+
+		result, err = CallBuiltin("max", int64(3), int64(7))
+		if err != nil {
+			t.Fatalf("max returned error: %v", err)
+		}
+		if result != int64(7) {
+			t.Errorf("max(3, 7) = %v (%T), want int64(7)", result, result)
+		}
+	})
+
+	// Test mod/gcd/lcm and the bitwise builtins.
+	t.Run("integer tower", func(t *testing.T) {
+		if result, err := CallBuiltin("mod", int64(-7), int64(3)); err != nil || result != int64(2) {
+			t.Errorf("mod(-7, 3) = %v, %v; want 2, nil", result, err)
+		}
+		if result, err := CallBuiltin("gcd", int64(12), int64(18)); err != nil || result != int64(6) {
+			t.Errorf("gcd(12, 18) = %v, %v; want 6, nil", result, err)
+		}
+		if result, err := CallBuiltin("lcm", int64(4), int64(6)); err != nil || result != int64(12) {
+			t.Errorf("lcm(4, 6) = %v, %v; want 12, nil", result, err)
+		}
+		if result, err := CallBuiltin("band", int64(6), int64(3)); err != nil || result != int64(2) {
+			t.Errorf("band(6, 3) = %v, %v; want 2, nil", result, err)
+		}
+		if result, err := CallBuiltin("bshl", int64(1), int64(4)); err != nil || result != int64(16) {
+			t.Errorf("bshl(1, 4) = %v, %v; want 16, nil", result, err)
+		}
+		if _, err := CallBuiltin("band", 1.5, int64(3)); err == nil {
+			t.Error("band(1.5, 3) should reject a non-integer argument")
+		}
+	})
 }
 
 func TestInterpreter(t *testing.T) {
@@ -435,6 +487,65 @@ func TestLlamaTokenizer(t *testing.T) {
 	}
 }
 
+func TestLexerErrorRecovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		wantCode kerror.Code
+	}{
+		{"unterminated string", `⟁Wo⟁ x = "never closed`, kerror.UnterminatedString},
+		{"unterminated block comment", "/* never closed", kerror.UnterminatedComment},
+		{"unterminated json object", `{"a": 1`, kerror.UnterminatedJSON},
+		{"unterminated json array", `[1, 2`, kerror.UnterminatedJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, errs := TokenizeWithErrors(tt.source)
+			if len(errs) != 1 {
+				t.Fatalf("got %d errors, want 1: %v", len(errs), errs)
+			}
+			if errs[0].Code != tt.wantCode {
+				t.Errorf("error code = %s, want %s", errs[0].Code, tt.wantCode)
+			}
+
+			foundError := false
+			for _, tok := range tokens {
+				if tok.Type == lexer.ERROR {
+					foundError = true
+				}
+			}
+			if !foundError {
+				t.Error("expected an ERROR token in the stream")
+			}
+		})
+	}
+}
+
+func TestLexerReaderIter(t *testing.T) {
+	source := `⟁Wo⟁ x = {"a": 1, "b": [1, 2, 3]}`
+	l, err := lexer.NewReader(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+
+	var got []lexer.Token
+	l.Iter(func(tok lexer.Token) bool {
+		got = append(got, tok)
+		return true
+	})
+
+	want := lexer.New(source).Tokenize()
+	if len(got) != len(want) {
+		t.Fatalf("got %d tokens from Iter, want %d from Tokenize", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Type != want[i].Type || got[i].Literal != want[i].Literal {
+			t.Errorf("token %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
 func TestMayanGlyphs(t *testing.T) {
 	// Test all Mayan glyph markers
 	tests := []struct {
@@ -464,3 +575,107 @@ func TestMayanGlyphs(t *testing.T) {
 		})
 	}
 }
+
+func TestParseWithDiagnostics(t *testing.T) {
+	// A bogus token at top level should produce an error diagnostic with a
+	// span and recover instead of cascading into further errors.
+	source := "~~~\n⟁Wo⟁ x = 10"
+
+	program, diagnostics := ParseWithDiagnostics(source)
+	if len(diagnostics) == 0 {
+		t.Fatal("expected at least one diagnostic")
+	}
+
+	first := diagnostics[0]
+	if first.Severity != SeverityError {
+		t.Errorf("expected first diagnostic to be an error, got severity %v", first.Severity)
+	}
+	if first.Span.StartLine != 1 {
+		t.Errorf("expected diagnostic on line 1, got %d", first.Span.StartLine)
+	}
+	if first.Code == "" {
+		t.Error("expected diagnostic to carry an error code")
+	}
+
+	// Recovery should still pick up the valid assignment on line 2.
+	if len(program.Assignments) != 1 {
+		t.Errorf("expected recovery to parse the trailing assignment, got %d assignments", len(program.Assignments))
+	}
+}
+
+func TestExpressionAssignment(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   interface{}
+	}{
+		{
+			name:   "arithmetic precedence",
+			source: `⟁Wo⟁ x = 3 + 4 * 2`,
+			want:   11.0,
+		},
+		{
+			name:   "comparison",
+			source: `⟁Wo⟁ x = 4 > 3`,
+			want:   true,
+		},
+		{
+			name:   "unary minus",
+			source: `⟁Wo⟁ x = -2 * 3`,
+			want:   -6.0,
+		},
+		{
+			name:   "builtin call",
+			source: `⟁Wo⟁ x = abs(-5)`,
+			want:   5.0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := NewInterpreter()
+			if _, errors := interp.Load(tt.source); len(errors) > 0 {
+				t.Fatalf("Load() errors: %v", errors)
+			}
+			if _, err := interp.Run(); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			got, ok := interp.GetVariable("x")
+			if !ok {
+				t.Fatal("expected x to be set")
+			}
+			if got != tt.want {
+				t.Errorf("x = %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventPumpWiring(t *testing.T) {
+	interp := NewInterpreter()
+
+	// A CoolBlock with @on registers as an observer instead of being
+	// dispatched directly; ⟁Sek⟁ emit posts the event that wakes it.
+	source := `
+		C@@L BLOCK boot_on_greet
+			@handler: kernel_boot
+			@on: "greet"
+
+		⟁Sek⟁ emit
+			@event: "greet"
+	`
+
+	_, errors := interp.Load(source)
+	if len(errors) > 0 {
+		t.Fatalf("Load() errors: %v", errors)
+	}
+
+	if _, err := interp.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	state := interp.GetState()
+	if !state.Booted {
+		t.Fatal("expected emitting \"greet\" to invoke the kernel_boot handler registered via @on")
+	}
+}
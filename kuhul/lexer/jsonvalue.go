@@ -0,0 +1,293 @@
+package lexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// JSONKind identifies which JSON type a JSONValue holds.
+type JSONKind int
+
+const (
+	JSONNull JSONKind = iota
+	JSONBool
+	JSONNumber
+	JSONString
+	JSONArray
+	JSONObjectKind
+)
+
+// JSONValue is a parsed JSON value that keeps object keys in source order,
+// unlike encoding/json's map[string]interface{} (Go map iteration order is
+// randomized on every pass). Token.JSON holds one of these for every JSON
+// token readJSONBlock/readJSONArray produce, alongside the order-erasing
+// Token.Value the parser package already type-asserts as
+// map[string]interface{}/[]interface{}.
+type JSONValue struct {
+	Kind   JSONKind
+	Str    string
+	Num    float64
+	Bool   bool
+	Array  []*JSONValue
+	Object *JSONObject
+}
+
+// Interface converts v to the plain interface{} shape encoding/json.Unmarshal
+// would produce (map[string]interface{}, []interface{}, string, float64,
+// bool, or nil), discarding the key order JSONValue otherwise preserves.
+func (v *JSONValue) Interface() interface{} {
+	if v == nil {
+		return nil
+	}
+	switch v.Kind {
+	case JSONBool:
+		return v.Bool
+	case JSONNumber:
+		return v.Num
+	case JSONString:
+		return v.Str
+	case JSONArray:
+		out := make([]interface{}, len(v.Array))
+		for i, e := range v.Array {
+			out[i] = e.Interface()
+		}
+		return out
+	case JSONObjectKind:
+		out := make(map[string]interface{}, len(v.Object.keys))
+		for _, k := range v.Object.keys {
+			out[k] = v.Object.values[k].Interface()
+		}
+		return out
+	default: // JSONNull
+		return nil
+	}
+}
+
+// JSONObject is an insertion-ordered string -> *JSONValue map, so an XJSON
+// envelope's @-prefixed keys keep the order they were written in instead of
+// Go's randomized map order.
+type JSONObject struct {
+	keys   []string
+	values map[string]*JSONValue
+}
+
+// Keys returns the object's keys in the order they first appeared in source.
+func (o *JSONObject) Keys() []string {
+	return append([]string(nil), o.keys...)
+}
+
+// Get returns the value stored under key and whether it was present.
+func (o *JSONObject) Get(key string) (*JSONValue, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+func (o *JSONObject) set(key string, v *JSONValue) {
+	if o.values == nil {
+		o.values = make(map[string]*JSONValue)
+	}
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+// parseJSONValue parses a single RFC 8259 JSON value out of data starting at
+// pos, returning the value and the offset one past its last byte. It's a
+// small hand-rolled recursive-descent parser rather than encoding/json,
+// since the latter can't hand back object keys in source order.
+func parseJSONValue(data []byte, pos int) (*JSONValue, int, error) {
+	pos = skipJSONSpace(data, pos)
+	if pos >= len(data) {
+		return nil, pos, fmt.Errorf("unexpected end of JSON input")
+	}
+
+	switch data[pos] {
+	case '{':
+		return parseJSONObject(data, pos)
+	case '[':
+		return parseJSONArray(data, pos)
+	case '"':
+		s, next, err := parseJSONString(data, pos)
+		if err != nil {
+			return nil, next, err
+		}
+		return &JSONValue{Kind: JSONString, Str: s}, next, nil
+	case 't':
+		if hasPrefixAt(data, pos, "true") {
+			return &JSONValue{Kind: JSONBool, Bool: true}, pos + 4, nil
+		}
+	case 'f':
+		if hasPrefixAt(data, pos, "false") {
+			return &JSONValue{Kind: JSONBool, Bool: false}, pos + 5, nil
+		}
+	case 'n':
+		if hasPrefixAt(data, pos, "null") {
+			return &JSONValue{Kind: JSONNull}, pos + 4, nil
+		}
+	}
+
+	if data[pos] == '-' || (data[pos] >= '0' && data[pos] <= '9') {
+		return parseJSONNumber(data, pos)
+	}
+
+	return nil, pos, fmt.Errorf("unexpected character %q at offset %d", data[pos], pos)
+}
+
+func hasPrefixAt(data []byte, pos int, s string) bool {
+	end := pos + len(s)
+	return end <= len(data) && string(data[pos:end]) == s
+}
+
+func skipJSONSpace(data []byte, pos int) int {
+	for pos < len(data) {
+		switch data[pos] {
+		case ' ', '\t', '\r', '\n':
+			pos++
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+func parseJSONObject(data []byte, pos int) (*JSONValue, int, error) {
+	pos++ // skip '{'
+	obj := &JSONObject{}
+	pos = skipJSONSpace(data, pos)
+	if pos < len(data) && data[pos] == '}' {
+		return &JSONValue{Kind: JSONObjectKind, Object: obj}, pos + 1, nil
+	}
+
+	for {
+		pos = skipJSONSpace(data, pos)
+		if pos >= len(data) || data[pos] != '"' {
+			return nil, pos, fmt.Errorf("expected object key at offset %d", pos)
+		}
+		key, next, err := parseJSONString(data, pos)
+		if err != nil {
+			return nil, next, err
+		}
+		pos = skipJSONSpace(data, next)
+		if pos >= len(data) || data[pos] != ':' {
+			return nil, pos, fmt.Errorf("expected ':' at offset %d", pos)
+		}
+		pos++
+
+		val, next, err := parseJSONValue(data, pos)
+		if err != nil {
+			return nil, next, err
+		}
+		obj.set(key, val)
+		pos = skipJSONSpace(data, next)
+
+		if pos >= len(data) {
+			return nil, pos, fmt.Errorf("unterminated object")
+		}
+		if data[pos] == ',' {
+			pos++
+			continue
+		}
+		if data[pos] == '}' {
+			return &JSONValue{Kind: JSONObjectKind, Object: obj}, pos + 1, nil
+		}
+		return nil, pos, fmt.Errorf("expected ',' or '}' at offset %d", pos)
+	}
+}
+
+func parseJSONArray(data []byte, pos int) (*JSONValue, int, error) {
+	pos++ // skip '['
+	var arr []*JSONValue
+	pos = skipJSONSpace(data, pos)
+	if pos < len(data) && data[pos] == ']' {
+		return &JSONValue{Kind: JSONArray, Array: arr}, pos + 1, nil
+	}
+
+	for {
+		val, next, err := parseJSONValue(data, pos)
+		if err != nil {
+			return nil, next, err
+		}
+		arr = append(arr, val)
+		pos = skipJSONSpace(data, next)
+
+		if pos >= len(data) {
+			return nil, pos, fmt.Errorf("unterminated array")
+		}
+		if data[pos] == ',' {
+			pos++
+			continue
+		}
+		if data[pos] == ']' {
+			return &JSONValue{Kind: JSONArray, Array: arr}, pos + 1, nil
+		}
+		return nil, pos, fmt.Errorf("expected ',' or ']' at offset %d", pos)
+	}
+}
+
+// parseJSONString reads a quoted string starting at pos (pointing at the
+// opening '"'), returning its unescaped value and the offset one past the
+// closing '"'. It only needs to track the escape marker itself to find that
+// closing quote — a \" or \\ can't be mistaken for an unescaped delimiter —
+// so the actual unescaping (including \uXXXX and surrogate pairs) is left to
+// encoding/json rather than reimplemented here.
+func parseJSONString(data []byte, pos int) (string, int, error) {
+	pos++ // skip opening quote
+	start := pos
+	for pos < len(data) {
+		switch data[pos] {
+		case '"':
+			s, err := unquoteJSONString(data[start:pos])
+			return s, pos + 1, err
+		case '\\':
+			pos += 2
+			continue
+		}
+		pos++
+	}
+	return "", pos, fmt.Errorf("unterminated string starting at offset %d", start)
+}
+
+func unquoteJSONString(raw []byte) (string, error) {
+	quoted := make([]byte, 0, len(raw)+2)
+	quoted = append(quoted, '"')
+	quoted = append(quoted, raw...)
+	quoted = append(quoted, '"')
+	var s string
+	if err := json.Unmarshal(quoted, &s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+func parseJSONNumber(data []byte, pos int) (*JSONValue, int, error) {
+	start := pos
+	if data[pos] == '-' {
+		pos++
+	}
+	for pos < len(data) && data[pos] >= '0' && data[pos] <= '9' {
+		pos++
+	}
+	if pos < len(data) && data[pos] == '.' {
+		pos++
+		for pos < len(data) && data[pos] >= '0' && data[pos] <= '9' {
+			pos++
+		}
+	}
+	if pos < len(data) && (data[pos] == 'e' || data[pos] == 'E') {
+		pos++
+		if pos < len(data) && (data[pos] == '+' || data[pos] == '-') {
+			pos++
+		}
+		for pos < len(data) && data[pos] >= '0' && data[pos] <= '9' {
+			pos++
+		}
+	}
+
+	f, err := strconv.ParseFloat(string(data[start:pos]), 64)
+	if err != nil {
+		return nil, pos, fmt.Errorf("invalid number at offset %d: %w", start, err)
+	}
+	return &JSONValue{Kind: JSONNumber, Num: f}, pos, nil
+}
@@ -1,28 +1,99 @@
 package lexer
 
 import (
-	"encoding/json"
+	"io"
 	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
+	"unsafe"
+
+	"github.com/ollama/ollama/kuhul/diag"
+	"github.com/ollama/ollama/kuhul/kerror"
 )
 
+// lexerDiagCodes maps each kerror.Code the lexer raises to its stable
+// diagnostic code, continuing the "KHLNNNN" numbering Parser.error/warn use
+// (see parser.go) so a caller can't tell whether a diagnostic came from the
+// lexer or the parser by its code's shape.
+var lexerDiagCodes = map[kerror.Code]string{
+	kerror.UnterminatedString:  "KHL0010",
+	kerror.UnterminatedJSON:    "KHL0011",
+	kerror.UnterminatedComment: "KHL0012",
+	kerror.UnknownGlyph:        "KHL0013",
+}
+
 // Lexer tokenizes K'UHUL source code
 type Lexer struct {
-	source  string
-	pos     int  // current position in source
+	data    []byte
+	pos     int  // current position in data
 	readPos int  // next position to read
 	ch      rune // current character
 	line    int  // current line number (1-based)
 	col     int  // current column number (1-based)
 	tokens  []Token
+	errors  []*kerror.Error
+
+	// CopyLiterals makes Token.Literal fields that would otherwise alias l.data
+	// (see sliceLiteral) into independent copies instead. Off by default: most
+	// callers tokenize once and discard the Lexer together with its data, so
+	// the alias is safe and avoids a per-token allocation — the point of
+	// NewReader for XJSON envelopes where @prompt/@context can run to
+	// megabytes. Turn it on if you'll mutate or drop the buffer you handed to
+	// New/NewReader while tokens from it are still alive.
+	CopyLiterals bool
+
+	// dialect scopes keyword/marker lookups (see lookupIdent, lookupMarker)
+	// to an overlay registered via RegisterKeywordFor/RegisterGlyphFor, on
+	// top of the shared Keywords/MayanMarkers tables. Empty by default,
+	// meaning only the shared tables are consulted. Set via WithDialect.
+	dialect string
+}
+
+// WithDialect scopes l's keyword and Mayan-marker lookups to name: any
+// token registered via RegisterKeywordFor(name, ...) or
+// RegisterGlyphFor(name, ...) is recognized by l in addition to the
+// tokens every Lexer recognizes, letting multiple dialects keep their own
+// extensions without colliding. Returns l for chaining, e.g.
+// lexer.New(src).WithDialect("myorg").
+func (l *Lexer) WithDialect(name string) *Lexer {
+	l.dialect = name
+	return l
+}
+
+// lookupIdent is LookupIdent scoped to l's dialect.
+func (l *Lexer) lookupIdent(ident string) TokenType {
+	return lookupKeyword(l.dialect, ident)
+}
+
+// lookupMarker is MayanMarkers' lookup scoped to l's dialect.
+func (l *Lexer) lookupMarker(content string) (TokenType, bool) {
+	return lookupMarker(l.dialect, content)
 }
 
 // New creates a new Lexer for the given source
 func New(source string) *Lexer {
+	return newLexer([]byte(source))
+}
+
+// NewReader creates a Lexer over all of r's bytes, read upfront. This isn't
+// literally bounded-memory streaming — a single JSON/string token can span
+// the whole input, so its Literal ultimately needs the complete span in
+// memory regardless of how it got there — but it skips New's redundant
+// []byte(source) copy (io.ReadAll's buffer becomes l.data directly), and
+// paired with Iter it lets a caller start consuming tokens without first
+// materializing the full []Token slice Tokenize builds.
+func NewReader(r io.Reader) (*Lexer, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return newLexer(data), nil
+}
+
+func newLexer(data []byte) *Lexer {
 	l := &Lexer{
-		source: source,
+		data:   data,
 		line:   1,
 		col:    1,
 		tokens: make([]Token, 0),
@@ -43,12 +114,90 @@ func (l *Lexer) Tokenize() []Token {
 	return l.tokens
 }
 
+// Iter is a push-style iterator over l's tokens: it calls yield with each
+// token in turn, stopping as soon as yield returns false or once it has
+// yielded the final EOF token, whichever comes first. Unlike Tokenize, it
+// never builds a []Token slice, so a caller that only needs to look at the
+// first few tokens (or wants to bail out early on an ERROR token) doesn't
+// pay for the rest.
+func (l *Lexer) Iter(yield func(Token) bool) {
+	for {
+		tok := l.NextToken()
+		if !yield(tok) {
+			return
+		}
+		if tok.Type == EOF {
+			return
+		}
+	}
+}
+
+// sliceLiteral returns l.data[start:end] as a Token.Literal. With
+// CopyLiterals off (the default) it aliases l.data directly via
+// unsafe.String instead of copying, which is safe as long as l.data outlives
+// the returned tokens — see CopyLiterals for when that doesn't hold.
+func (l *Lexer) sliceLiteral(start, end int) string {
+	if end <= start {
+		return ""
+	}
+	b := l.data[start:end]
+	if l.CopyLiterals {
+		return string(b)
+	}
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// Errors returns the structured errors accumulated by Tokenize: an
+// unrecognized ⟁...⟁ marker, a JSON payload that never closed, or a
+// string/block comment that ran off the end of the source without its
+// closing delimiter. Each unterminated string, comment or JSON payload also
+// surfaces as an ERROR token in the stream Tokenize returns, at the offset
+// recorded here, so a caller walking tokens can report the failure inline
+// instead of only at the end; an unrecognized marker comes back as an IDENT
+// token instead (see readMayanMarker).
+func (l *Lexer) Errors() []*kerror.Error {
+	return l.errors
+}
+
+// Diagnostics returns the same failures Errors does, translated into
+// diag.Diagnostic so a caller (or a tool like kuhulfmt) can render lexer and
+// parser failures with the same diag.Render instead of handling
+// *kerror.Error and diag.Diagnostic as two different shapes. An
+// UnknownGlyph diagnostic carries a Suggestion naming the closest known
+// Mayan marker, when one is close enough to plausibly be a typo (see
+// suggestMarker).
+func (l *Lexer) Diagnostics() []diag.Diagnostic {
+	diags := make([]diag.Diagnostic, len(l.errors))
+	for i, e := range l.errors {
+		d := diag.Diagnostic{
+			Severity: diag.SeverityError,
+			Code:     lexerDiagCodes[e.Code],
+			Message:  e.Message,
+			Span: diag.Span{
+				StartLine:   e.Line,
+				StartCol:    e.Column,
+				EndLine:     e.Line,
+				EndCol:      e.Column + len([]rune(e.Token)),
+				StartOffset: e.Offset,
+				EndOffset:   e.Offset + len(e.Token),
+			},
+		}
+		if e.Code == kerror.UnknownGlyph {
+			d.Suggestion = l.suggestMarker(e.Token)
+		}
+		diags[i] = d
+	}
+	return diags
+}
+
 // NextToken returns the next token from the source
 func (l *Lexer) NextToken() Token {
 	l.skipWhitespace()
 
 	if l.ch == 0 {
-		return l.makeToken(EOF, "")
+		tok := l.makeToken(EOF, "")
+		tok.StartOffset, tok.EndOffset = l.pos, l.pos
+		return tok
 	}
 
 	// Check for Mayan glyph markers (⟁...⟁)
@@ -108,20 +257,39 @@ func (l *Lexer) NextToken() Token {
 
 	// Newline
 	if l.ch == '\n' {
+		startOffset := l.pos
 		tok := l.makeToken(NEWLINE, "\n")
 		l.readChar()
+		tok.StartOffset, tok.EndOffset = startOffset, l.pos
 		return tok
 	}
 
 	// Unknown character
+	startOffset := l.pos
 	ch := l.ch
 	l.readChar()
-	return l.makeToken(ILLEGAL, string(ch))
+	tok = l.makeToken(ILLEGAL, string(ch))
+	tok.StartOffset, tok.EndOffset = startOffset, l.pos
+	return tok
+}
+
+// suggestMarker returns the known Mayan marker closest to marker (rendered
+// the same "⟁...⟁" way), for a Diagnostic.Suggestion on an UnknownGlyph
+// diagnostic — e.g. "⟁Kayab⟁" suggests "⟁K'ayab⟁". Empty if nothing is
+// within diag.Suggest's edit-distance threshold. Candidates include l's
+// dialect overlay alongside the shared markers.
+func (l *Lexer) suggestMarker(marker string) string {
+	content := strings.TrimSuffix(strings.TrimPrefix(marker, "⟁"), "⟁")
+	match := diag.Suggest(content, markerCandidates(l.dialect))
+	if match == "" {
+		return ""
+	}
+	return "⟁" + match + "⟁"
 }
 
 // readMayanMarker reads a Mayan glyph marker (⟁...⟁)
 func (l *Lexer) readMayanMarker() Token {
-	startLine, startCol := l.line, l.col
+	startLine, startCol, startOffset := l.line, l.col, l.pos
 	l.readChar() // skip opening ⟁
 
 	var content strings.Builder
@@ -138,12 +306,14 @@ func (l *Lexer) readMayanMarker() Token {
 	marker := "⟁" + markerContent + "⟁"
 
 	// Check for known Mayan markers
-	if tokType, ok := MayanMarkers[markerContent]; ok {
+	if tokType, ok := l.lookupMarker(markerContent); ok {
 		return Token{
-			Type:    tokType,
-			Literal: marker,
-			Line:    startLine,
-			Column:  startCol,
+			Type:        tokType,
+			Literal:     marker,
+			Line:        startLine,
+			Column:      startCol,
+			StartOffset: startOffset,
+			EndOffset:   l.pos,
 		}
 	}
 
@@ -152,31 +322,38 @@ func (l *Lexer) readMayanMarker() Token {
 		blockName := strings.TrimPrefix(markerContent, " ATOMIC_BLOCK_")
 		blockName = strings.TrimSpace(blockName)
 		return Token{
-			Type:    ATOMIC_BLOCK,
-			Literal: marker,
-			Value:   blockName,
-			Line:    startLine,
-			Column:  startCol,
+			Type:        ATOMIC_BLOCK,
+			Literal:     marker,
+			Value:       blockName,
+			Line:        startLine,
+			Column:      startCol,
+			StartOffset: startOffset,
+			EndOffset:   l.pos,
 		}
 	}
 
-	// Unknown marker, treat as identifier
+	// Unknown marker: record it and fall back to treating it as an
+	// identifier so the parser can still attempt recovery.
+	l.errors = append(l.errors, kerror.New(kerror.UnknownGlyph, marker, startLine, startCol, startOffset,
+		"unrecognized glyph marker %q", marker))
 	return Token{
-		Type:    IDENT,
-		Literal: marker,
-		Line:    startLine,
-		Column:  startCol,
+		Type:        IDENT,
+		Literal:     marker,
+		Line:        startLine,
+		Column:      startCol,
+		StartOffset: startOffset,
+		EndOffset:   l.pos,
 	}
 }
 
-// readCoolMarker reads a C@@L marker
+// readCoolMarker reads a C@@L marker. Its bytes are unmodified from source,
+// so the Literal is sliced out of l.data (see sliceLiteral) rather than
+// rebuilt rune-by-rune.
 func (l *Lexer) readCoolMarker() Token {
-	startLine, startCol := l.line, l.col
+	startLine, startCol, startOffset := l.line, l.col, l.pos
 
-	var content strings.Builder
 	// Read C@@L
 	for i := 0; i < 4; i++ {
-		content.WriteRune(l.ch)
 		l.readChar()
 	}
 
@@ -184,70 +361,81 @@ func (l *Lexer) readCoolMarker() Token {
 
 	// Read the rest of the line
 	for l.ch != 0 && l.ch != '\n' {
-		content.WriteRune(l.ch)
 		l.readChar()
 	}
 
-	literal := strings.TrimSpace(content.String())
+	literal := strings.TrimSpace(l.sliceLiteral(startOffset, l.pos))
+	endOffset := l.pos
 
 	// Determine marker type
 	if strings.Contains(literal, "ATOMIC_VECTOR") {
 		return Token{
-			Type:    COOL_VECTOR,
-			Literal: literal,
-			Line:    startLine,
-			Column:  startCol,
+			Type:        COOL_VECTOR,
+			Literal:     literal,
+			Line:        startLine,
+			Column:      startCol,
+			StartOffset: startOffset,
+			EndOffset:   endOffset,
 		}
 	}
 	if strings.Contains(literal, "ATOMIC_VARIABLE") {
 		return Token{
-			Type:    COOL_VARIABLE,
-			Literal: literal,
-			Line:    startLine,
-			Column:  startCol,
+			Type:        COOL_VARIABLE,
+			Literal:     literal,
+			Line:        startLine,
+			Column:      startCol,
+			StartOffset: startOffset,
+			EndOffset:   endOffset,
 		}
 	}
 	if strings.Contains(literal, "BLOCK") {
 		return Token{
-			Type:    COOL_BLOCK,
-			Literal: literal,
-			Line:    startLine,
-			Column:  startCol,
+			Type:        COOL_BLOCK,
+			Literal:     literal,
+			Line:        startLine,
+			Column:      startCol,
+			StartOffset: startOffset,
+			EndOffset:   endOffset,
 		}
 	}
 
 	return Token{
-		Type:    IDENT,
-		Literal: literal,
-		Line:    startLine,
-		Column:  startCol,
+		Type:        IDENT,
+		Literal:     literal,
+		Line:        startLine,
+		Column:      startCol,
+		StartOffset: startOffset,
+		EndOffset:   endOffset,
 	}
 }
 
-// readAtom reads an @identifier
+// readAtom reads an @identifier. Its bytes are unmodified from source, so
+// the Literal is sliced out of l.data (see sliceLiteral) rather than
+// rebuilt rune-by-rune.
 func (l *Lexer) readAtom() Token {
-	startLine, startCol := l.line, l.col
-
-	var content strings.Builder
-	content.WriteRune(l.ch) // @
-	l.readChar()
+	startLine, startCol, startOffset := l.line, l.col, l.pos
 
+	l.readChar() // @
 	for l.ch != 0 && (isAlphaNum(l.ch) || l.ch == '_') {
-		content.WriteRune(l.ch)
 		l.readChar()
 	}
 
 	return Token{
-		Type:    AT,
-		Literal: content.String(),
-		Line:    startLine,
-		Column:  startCol,
+		Type:        AT,
+		Literal:     l.sliceLiteral(startOffset, l.pos),
+		Line:        startLine,
+		Column:      startCol,
+		StartOffset: startOffset,
+		EndOffset:   l.pos,
 	}
 }
 
-// readString reads a string literal
+// readString reads a string literal. It stops at EOF rather than looping
+// forever if the closing quote never arrives, recording an
+// UnterminatedString error and returning an ERROR token for the unclosed
+// span instead of a STRING token with whatever was read so far.
 func (l *Lexer) readString() Token {
-	startLine, startCol := l.line, l.col
+	startLine, startCol, startOffset := l.line, l.col, l.pos
 	l.readChar() // skip opening quote
 
 	var content strings.Builder
@@ -261,221 +449,313 @@ func (l *Lexer) readString() Token {
 		}
 	}
 
+	if l.ch == 0 {
+		literal := content.String()
+		l.errors = append(l.errors, kerror.New(kerror.UnterminatedString, literal, startLine, startCol, startOffset,
+			"unterminated string starting at %d:%d", startLine, startCol))
+		return Token{
+			Type:        ERROR,
+			Literal:     literal,
+			Line:        startLine,
+			Column:      startCol,
+			StartOffset: startOffset,
+			EndOffset:   l.pos,
+		}
+	}
+
 	l.readChar() // skip closing quote
 
 	return Token{
-		Type:    STRING,
-		Literal: content.String(),
-		Value:   content.String(),
-		Line:    startLine,
-		Column:  startCol,
+		Type:        STRING,
+		Literal:     content.String(),
+		Value:       content.String(),
+		Line:        startLine,
+		Column:      startCol,
+		StartOffset: startOffset,
+		EndOffset:   l.pos,
 	}
 }
 
-// readJSONBlock reads a JSON object {...}
+// readJSONBlock reads a JSON object {...}. The loop only needs l.ch != 0 to
+// bound it — readChar already advances l.line/l.col over any newline inside
+// the object, so this doesn't re-track them itself (doing so used to
+// double-count every line the payload spanned). It tracks whether it's
+// inside a string so a '}' or '"' in a string value (e.g. "a lot of {braces}
+// here") doesn't end the object early — depth only changes on a structural
+// brace — which plain brace-counting used to get wrong. The object's bytes
+// are unmodified from source, so its Literal is sliced straight out of
+// l.data (see sliceLiteral) rather than rebuilt rune-by-rune — this is the
+// payoff for XJSON envelopes where @prompt/@context can run to megabytes.
 func (l *Lexer) readJSONBlock() Token {
-	startLine, startCol := l.line, l.col
+	startLine, startCol, startOffset := l.line, l.col, l.pos
 	depth := 0
-	var content strings.Builder
+	inString := false
 
 	for l.ch != 0 {
-		if l.ch == '{' {
+		if inString {
+			if l.ch == '\\' {
+				l.readChar()
+				if l.ch != 0 {
+					l.readChar()
+				}
+				continue
+			}
+			if l.ch == '"' {
+				inString = false
+			}
+		} else if l.ch == '"' {
+			inString = true
+		} else if l.ch == '{' {
 			depth++
 		} else if l.ch == '}' {
 			depth--
 			if depth == 0 {
-				content.WriteRune(l.ch)
 				l.readChar()
 				break
 			}
 		}
 
-		if l.ch == '\n' {
-			l.line++
-			l.col = 1
-		}
-
-		content.WriteRune(l.ch)
 		l.readChar()
 	}
 
-	literal := content.String()
+	endOffset := l.pos
+	literal := l.sliceLiteral(startOffset, endOffset)
+
+	if depth != 0 {
+		l.errors = append(l.errors, kerror.New(kerror.UnterminatedJSON, literal, startLine, startCol, startOffset,
+			"unterminated JSON object starting at %d:%d", startLine, startCol))
+		return Token{
+			Type:        ERROR,
+			Literal:     literal,
+			Line:        startLine,
+			Column:      startCol,
+			StartOffset: startOffset,
+			EndOffset:   endOffset,
+		}
+	}
 
-	// Try to parse as JSON
-	var parsed interface{}
-	if err := json.Unmarshal([]byte(literal), &parsed); err == nil {
+	// Try to parse as JSON, preserving key order via JSONValue (see
+	// jsonvalue.go) — Value stays the order-erasing map[string]interface{}/
+	// []interface{} shape parser.go already type-asserts against.
+	if val, _, err := parseJSONValue(l.data[startOffset:endOffset], 0); err == nil {
 		return Token{
-			Type:    JSON,
-			Literal: literal,
-			Value:   parsed,
-			Line:    startLine,
-			Column:  startCol,
+			Type:        JSON,
+			Literal:     literal,
+			Value:       val.Interface(),
+			JSON:        val,
+			Line:        startLine,
+			Column:      startCol,
+			StartOffset: startOffset,
+			EndOffset:   endOffset,
 		}
 	}
 
 	// Not valid JSON, return as string
 	return Token{
-		Type:    STRING,
-		Literal: literal,
-		Line:    startLine,
-		Column:  startCol,
+		Type:        STRING,
+		Literal:     literal,
+		Line:        startLine,
+		Column:      startCol,
+		StartOffset: startOffset,
+		EndOffset:   endOffset,
 	}
 }
 
-// readJSONArray reads a JSON array [...]
+// readJSONArray reads a JSON array [...]. See readJSONBlock for why it
+// doesn't re-track l.line/l.col itself, why it tracks string state before
+// adjusting depth, and why its Literal is sliced out of l.data instead of
+// built rune-by-rune.
 func (l *Lexer) readJSONArray() Token {
-	startLine, startCol := l.line, l.col
+	startLine, startCol, startOffset := l.line, l.col, l.pos
 	depth := 0
-	var content strings.Builder
+	inString := false
 
 	for l.ch != 0 {
-		if l.ch == '[' {
+		if inString {
+			if l.ch == '\\' {
+				l.readChar()
+				if l.ch != 0 {
+					l.readChar()
+				}
+				continue
+			}
+			if l.ch == '"' {
+				inString = false
+			}
+		} else if l.ch == '"' {
+			inString = true
+		} else if l.ch == '[' {
 			depth++
 		} else if l.ch == ']' {
 			depth--
 			if depth == 0 {
-				content.WriteRune(l.ch)
 				l.readChar()
 				break
 			}
 		}
 
-		if l.ch == '\n' {
-			l.line++
-			l.col = 1
-		}
-
-		content.WriteRune(l.ch)
 		l.readChar()
 	}
 
-	literal := content.String()
+	endOffset := l.pos
+	literal := l.sliceLiteral(startOffset, endOffset)
 
-	// Try to parse as JSON
-	var parsed interface{}
-	if err := json.Unmarshal([]byte(literal), &parsed); err == nil {
+	if depth != 0 {
+		l.errors = append(l.errors, kerror.New(kerror.UnterminatedJSON, literal, startLine, startCol, startOffset,
+			"unterminated JSON array starting at %d:%d", startLine, startCol))
 		return Token{
-			Type:    JSON,
-			Literal: literal,
-			Value:   parsed,
-			Line:    startLine,
-			Column:  startCol,
+			Type:        ERROR,
+			Literal:     literal,
+			Line:        startLine,
+			Column:      startCol,
+			StartOffset: startOffset,
+			EndOffset:   endOffset,
+		}
+	}
+
+	// Try to parse as JSON, preserving key order via JSONValue — see
+	// readJSONBlock.
+	if val, _, err := parseJSONValue(l.data[startOffset:endOffset], 0); err == nil {
+		return Token{
+			Type:        JSON,
+			Literal:     literal,
+			Value:       val.Interface(),
+			JSON:        val,
+			Line:        startLine,
+			Column:      startCol,
+			StartOffset: startOffset,
+			EndOffset:   endOffset,
 		}
 	}
 
 	// Not valid JSON, return as string
 	return Token{
-		Type:    STRING,
-		Literal: literal,
-		Line:    startLine,
-		Column:  startCol,
+		Type:        STRING,
+		Literal:     literal,
+		Line:        startLine,
+		Column:      startCol,
+		StartOffset: startOffset,
+		EndOffset:   endOffset,
 	}
 }
 
-// readNumber reads a number literal
+// readNumber reads a number literal. Its bytes are unmodified from source,
+// so the Literal is sliced out of l.data (see sliceLiteral) once the span is
+// known, instead of being rebuilt rune-by-rune.
 func (l *Lexer) readNumber() Token {
-	startLine, startCol := l.line, l.col
-	var content strings.Builder
+	startLine, startCol, startOffset := l.line, l.col, l.pos
 
 	if l.ch == '-' {
-		content.WriteRune(l.ch)
 		l.readChar()
 	}
 
 	for isDigit(l.ch) {
-		content.WriteRune(l.ch)
 		l.readChar()
 	}
 
 	// Check for decimal point
 	if l.ch == '.' && isDigit(l.peekChar()) {
-		content.WriteRune(l.ch)
 		l.readChar()
 		for isDigit(l.ch) {
-			content.WriteRune(l.ch)
 			l.readChar()
 		}
 	}
 
 	// Check for exponent
 	if l.ch == 'e' || l.ch == 'E' {
-		content.WriteRune(l.ch)
 		l.readChar()
 		if l.ch == '+' || l.ch == '-' {
-			content.WriteRune(l.ch)
 			l.readChar()
 		}
 		for isDigit(l.ch) {
-			content.WriteRune(l.ch)
 			l.readChar()
 		}
 	}
 
-	literal := content.String()
+	literal := l.sliceLiteral(startOffset, l.pos)
 	value, _ := strconv.ParseFloat(literal, 64)
 
 	return Token{
-		Type:    NUMBER,
-		Literal: literal,
-		Value:   value,
-		Line:    startLine,
-		Column:  startCol,
+		Type:        NUMBER,
+		Literal:     literal,
+		Value:       value,
+		Line:        startLine,
+		Column:      startCol,
+		StartOffset: startOffset,
+		EndOffset:   l.pos,
 	}
 }
 
-// readIdentifier reads an identifier or keyword
+// readIdentifier reads an identifier or keyword. Its bytes are unmodified
+// from source, so the Literal is sliced out of l.data (see sliceLiteral)
+// rather than rebuilt rune-by-rune.
 func (l *Lexer) readIdentifier() Token {
-	startLine, startCol := l.line, l.col
-	var content strings.Builder
+	startLine, startCol, startOffset := l.line, l.col, l.pos
 
 	for l.ch != 0 && (isAlphaNum(l.ch) || l.ch == '_' || l.ch == '.') {
-		content.WriteRune(l.ch)
 		l.readChar()
 	}
 
-	literal := content.String()
-	tokType := LookupIdent(literal)
+	literal := l.sliceLiteral(startOffset, l.pos)
+	tokType := l.lookupIdent(literal)
 
 	return Token{
-		Type:    tokType,
-		Literal: literal,
-		Line:    startLine,
-		Column:  startCol,
+		Type:        tokType,
+		Literal:     literal,
+		Line:        startLine,
+		Column:      startCol,
+		StartOffset: startOffset,
+		EndOffset:   l.pos,
 	}
 }
 
-// readBlockComment reads a /* ... */ comment
+// readBlockComment reads a /* ... */ comment. It stops at EOF rather than
+// looping forever if the closing "*/" never arrives, recording an
+// UnterminatedComment error and returning an ERROR token for the unclosed
+// span. It also doesn't re-track l.line/l.col itself — see readJSONBlock —
+// and, since the comment body is unmodified from source, its Literal is
+// sliced out of l.data (see sliceLiteral) rather than rebuilt rune-by-rune.
 func (l *Lexer) readBlockComment() Token {
-	startLine, startCol := l.line, l.col
+	startLine, startCol, startOffset := l.line, l.col, l.pos
 	l.readChar() // /
 	l.readChar() // *
 
-	var content strings.Builder
+	contentStart := l.pos
 	for l.ch != 0 {
 		if l.ch == '*' && l.peekChar() == '/' {
+			contentEnd := l.pos
 			l.readChar()
 			l.readChar()
-			break
-		}
-		if l.ch == '\n' {
-			l.line++
-			l.col = 1
+			return Token{
+				Type:        BLOCK_COMMENT,
+				Literal:     strings.TrimSpace(l.sliceLiteral(contentStart, contentEnd)),
+				Line:        startLine,
+				Column:      startCol,
+				StartOffset: startOffset,
+				EndOffset:   l.pos,
+			}
 		}
-		content.WriteRune(l.ch)
 		l.readChar()
 	}
 
+	literal := strings.TrimSpace(l.sliceLiteral(contentStart, l.pos))
+	l.errors = append(l.errors, kerror.New(kerror.UnterminatedComment, literal, startLine, startCol, startOffset,
+		"unterminated block comment starting at %d:%d", startLine, startCol))
 	return Token{
-		Type:    BLOCK_COMMENT,
-		Literal: strings.TrimSpace(content.String()),
-		Line:    startLine,
-		Column:  startCol,
+		Type:        ERROR,
+		Literal:     literal,
+		Line:        startLine,
+		Column:      startCol,
+		StartOffset: startOffset,
+		EndOffset:   l.pos,
 	}
 }
 
-// readLineComment reads a // or # comment
+// readLineComment reads a // or # comment. Its bytes are unmodified from
+// source, so the Literal is sliced out of l.data (see sliceLiteral) rather
+// than rebuilt rune-by-rune.
 func (l *Lexer) readLineComment() Token {
-	startLine, startCol := l.line, l.col
+	startLine, startCol, startOffset := l.line, l.col, l.pos
 
 	if l.ch == '#' {
 		l.readChar()
@@ -484,75 +764,68 @@ func (l *Lexer) readLineComment() Token {
 		l.readChar() // /
 	}
 
-	var content strings.Builder
+	contentStart := l.pos
 	for l.ch != 0 && l.ch != '\n' {
-		content.WriteRune(l.ch)
 		l.readChar()
 	}
 
 	return Token{
-		Type:    COMMENT,
-		Literal: strings.TrimSpace(content.String()),
-		Line:    startLine,
-		Column:  startCol,
+		Type:        COMMENT,
+		Literal:     strings.TrimSpace(l.sliceLiteral(contentStart, l.pos)),
+		Line:        startLine,
+		Column:      startCol,
+		StartOffset: startOffset,
+		EndOffset:   l.pos,
 	}
 }
 
 // readOperator reads operators and delimiters
 func (l *Lexer) readOperator() Token {
-	startLine, startCol := l.line, l.col
+	startLine, startCol, startOffset := l.line, l.col, l.pos
 	ch := l.ch
 
+	tok2 := func(t TokenType, lit string) Token {
+		l.readChar()
+		l.readChar()
+		return Token{Type: t, Literal: lit, Line: startLine, Column: startCol, StartOffset: startOffset, EndOffset: l.pos}
+	}
+	tok1 := func(t TokenType, lit string) Token {
+		l.readChar()
+		return Token{Type: t, Literal: lit, Line: startLine, Column: startCol, StartOffset: startOffset, EndOffset: l.pos}
+	}
+
 	// Two-character operators
 	switch ch {
 	case '=':
 		if l.peekChar() == '=' {
-			l.readChar()
-			l.readChar()
-			return Token{Type: EQ, Literal: "==", Line: startLine, Column: startCol}
+			return tok2(EQ, "==")
 		}
-		l.readChar()
-		return Token{Type: ASSIGN, Literal: "=", Line: startLine, Column: startCol}
+		return tok1(ASSIGN, "=")
 	case '!':
 		if l.peekChar() == '=' {
-			l.readChar()
-			l.readChar()
-			return Token{Type: NEQ, Literal: "!=", Line: startLine, Column: startCol}
+			return tok2(NEQ, "!=")
 		}
-		l.readChar()
-		return Token{Type: NOT, Literal: "!", Line: startLine, Column: startCol}
+		return tok1(NOT, "!")
 	case '<':
 		if l.peekChar() == '=' {
-			l.readChar()
-			l.readChar()
-			return Token{Type: LTE, Literal: "<=", Line: startLine, Column: startCol}
+			return tok2(LTE, "<=")
 		}
-		l.readChar()
-		return Token{Type: LT, Literal: "<", Line: startLine, Column: startCol}
+		return tok1(LT, "<")
 	case '>':
 		if l.peekChar() == '=' {
-			l.readChar()
-			l.readChar()
-			return Token{Type: GTE, Literal: ">=", Line: startLine, Column: startCol}
+			return tok2(GTE, ">=")
 		}
-		l.readChar()
-		return Token{Type: GT, Literal: ">", Line: startLine, Column: startCol}
+		return tok1(GT, ">")
 	case '&':
 		if l.peekChar() == '&' {
-			l.readChar()
-			l.readChar()
-			return Token{Type: AND, Literal: "&&", Line: startLine, Column: startCol}
+			return tok2(AND, "&&")
 		}
-		l.readChar()
-		return Token{Type: BAND, Literal: "&", Line: startLine, Column: startCol}
+		return tok1(BAND, "&")
 	case '|':
 		if l.peekChar() == '|' {
-			l.readChar()
-			l.readChar()
-			return Token{Type: OR, Literal: "||", Line: startLine, Column: startCol}
+			return tok2(OR, "||")
 		}
-		l.readChar()
-		return Token{Type: BOR, Literal: "|", Line: startLine, Column: startCol}
+		return tok1(BOR, "|")
 	}
 
 	// Single-character operators
@@ -593,16 +866,10 @@ func (l *Lexer) readOperator() Token {
 	case ']':
 		tokType = RBRACKET
 	default:
-		return Token{Type: ILLEGAL, Line: startLine, Column: startCol}
+		return Token{Type: ILLEGAL, Line: startLine, Column: startCol, StartOffset: startOffset, EndOffset: startOffset}
 	}
 
-	l.readChar()
-	return Token{
-		Type:    tokType,
-		Literal: string(ch),
-		Line:    startLine,
-		Column:  startCol,
-	}
+	return tok1(tokType, string(ch))
 }
 
 // readEscapeChar reads an escape character
@@ -632,10 +899,11 @@ func (l *Lexer) readEscapeChar() rune {
 
 // readChar advances to the next character
 func (l *Lexer) readChar() {
-	if l.readPos >= len(l.source) {
+	if l.readPos >= len(l.data) {
 		l.ch = 0
+		l.pos = len(l.data)
 	} else {
-		r, size := utf8.DecodeRuneInString(l.source[l.readPos:])
+		r, size := utf8.DecodeRune(l.data[l.readPos:])
 		l.ch = r
 		l.pos = l.readPos
 		l.readPos += size
@@ -651,20 +919,20 @@ func (l *Lexer) readChar() {
 
 // peekChar returns the next character without advancing
 func (l *Lexer) peekChar() rune {
-	if l.readPos >= len(l.source) {
+	if l.readPos >= len(l.data) {
 		return 0
 	}
-	r, _ := utf8.DecodeRuneInString(l.source[l.readPos:])
+	r, _ := utf8.DecodeRune(l.data[l.readPos:])
 	return r
 }
 
 // matchAhead checks if the source matches a string starting at current position
 func (l *Lexer) matchAhead(s string) bool {
 	end := l.pos + len(s)
-	if end > len(l.source) {
+	if end > len(l.data) {
 		return false
 	}
-	return l.source[l.pos:end] == s
+	return string(l.data[l.pos:end]) == s
 }
 
 // skipWhitespace skips spaces, tabs, and carriage returns (but not newlines)
@@ -684,10 +952,12 @@ func (l *Lexer) skipInlineWhitespace() {
 // makeToken creates a new token with current position
 func (l *Lexer) makeToken(tokType TokenType, literal string) Token {
 	return Token{
-		Type:    tokType,
-		Literal: literal,
-		Line:    l.line,
-		Column:  l.col,
+		Type:        tokType,
+		Literal:     literal,
+		Line:        l.line,
+		Column:      l.col,
+		StartOffset: l.pos,
+		EndOffset:   l.pos,
 	}
 }
 
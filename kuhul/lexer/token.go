@@ -13,6 +13,10 @@ const (
 	NEWLINE
 	COMMENT
 	BLOCK_COMMENT
+	// ERROR marks a token the lexer couldn't finish reading (an
+	// unterminated string/comment/JSON payload) — see Lexer.Errors for the
+	// accompanying diagnostic.
+	ERROR
 
 	// Mayan glyph markers
 	POP   // ⟁Pop⟁ - declaration
@@ -103,95 +107,6 @@ const (
 	SOFTMAX         // softmax
 )
 
-var tokenNames = map[TokenType]string{
-	ILLEGAL:       "ILLEGAL",
-	EOF:           "EOF",
-	NEWLINE:       "NEWLINE",
-	COMMENT:       "COMMENT",
-	BLOCK_COMMENT: "BLOCK_COMMENT",
-
-	POP:   "POP",
-	WO:    "WO",
-	SEK:   "SEK",
-	XUL:   "XUL",
-	CHEN:  "CHEN",
-	YAX:   "YAX",
-	KAYAB: "KAYAB",
-	SHEN:  "SHEN",
-	THEN:  "THEN",
-	ELSE:  "ELSE",
-	KUMKU: "KUMKU",
-
-	COOL_BLOCK:    "COOL_BLOCK",
-	COOL_VECTOR:   "COOL_VECTOR",
-	COOL_VARIABLE: "COOL_VARIABLE",
-	ATOMIC_BLOCK:  "ATOMIC_BLOCK",
-
-	IDENT:  "IDENT",
-	NUMBER: "NUMBER",
-	STRING: "STRING",
-	JSON:   "JSON",
-
-	PLUS:     "+",
-	MINUS:    "-",
-	STAR:     "*",
-	SLASH:    "/",
-	PERCENT:  "%",
-	ASSIGN:   "=",
-	EQ:       "==",
-	NEQ:      "!=",
-	LT:       "<",
-	GT:       ">",
-	LTE:      "<=",
-	GTE:      ">=",
-	AND:      "&&",
-	OR:       "||",
-	NOT:      "!",
-	BAND:     "&",
-	BOR:      "|",
-	XOR:      "^",
-	DOT:      ".",
-	COMMA:    ",",
-	COLON:    ":",
-	SEMICOL:  ";",
-	QUESTION: "?",
-
-	LPAREN:   "(",
-	RPAREN:   ")",
-	LBRACE:   "{",
-	RBRACE:   "}",
-	LBRACKET: "[",
-	RBRACKET: "]",
-
-	AT: "@",
-
-	DEFINE_FUNCTION: "define_function",
-	DEFINE_CLASS:    "define_class",
-	DEFINE_MACRO:    "define_macro",
-	RETURN:          "return",
-	IF:              "if",
-	FOR:             "for",
-	WHILE:           "while",
-	IN:              "in",
-	FROM:            "from",
-	TO:              "to",
-	NEW:             "new",
-	TRUE:            "true",
-	FALSE:           "false",
-	NULL:            "null",
-	MAP:             "map",
-	GO:              "go",
-	CHANNEL:         "channel",
-	OBSERVABLE:      "observable",
-	SUBSCRIBE:       "subscribe",
-	QUERY:           "query",
-	ASSERT_FACT:     "assert_fact",
-	DEFINE_RULE:     "define_rule",
-	MATRIX_MULTIPLY: "matrix_multiply",
-	TRANSPOSE:       "transpose",
-	SOFTMAX:         "softmax",
-}
-
 func (t TokenType) String() string {
 	if name, ok := tokenNames[t]; ok {
 		return name
@@ -199,50 +114,24 @@ func (t TokenType) String() string {
 	return fmt.Sprintf("TOKEN(%d)", t)
 }
 
-// Keywords maps keyword strings to token types
-var Keywords = map[string]TokenType{
-	"define_function": DEFINE_FUNCTION,
-	"define_class":    DEFINE_CLASS,
-	"define_macro":    DEFINE_MACRO,
-	"return":          RETURN,
-	"if":              IF,
-	"for":             FOR,
-	"while":           WHILE,
-	"in":              IN,
-	"from":            FROM,
-	"to":              TO,
-	"new":             NEW,
-	"true":            TRUE,
-	"false":           FALSE,
-	"null":            NULL,
-	"map":             MAP,
-	"go":              GO,
-	"channel":         CHANNEL,
-	"observable":      OBSERVABLE,
-	"subscribe":       SUBSCRIBE,
-	"query":           QUERY,
-	"assert_fact":     ASSERT_FACT,
-	"define_rule":     DEFINE_RULE,
-	"matrix_multiply": MATRIX_MULTIPLY,
-	"transpose":       TRANSPOSE,
-	"softmax":         SOFTMAX,
-}
-
-// LookupIdent checks if an identifier is a keyword
+// LookupIdent checks if an identifier is a keyword, in the shared
+// (dialect-less) Keywords table. See token_spec.go for where tokenNames,
+// Keywords, and MayanMarkers are actually populated, and lookupKeyword for
+// the dialect-aware form a *Lexer uses internally.
 func LookupIdent(ident string) TokenType {
-	if tok, ok := Keywords[ident]; ok {
-		return tok
-	}
-	return IDENT
+	return lookupKeyword("", ident)
 }
 
 // Token represents a lexical token
 type Token struct {
-	Type    TokenType   // Token type
-	Literal string      // Literal value (source text)
-	Value   interface{} // Parsed value (for numbers, JSON, etc.)
-	Line    int         // Line number (1-based)
-	Column  int         // Column number (1-based)
+	Type        TokenType   // Token type
+	Literal     string      // Literal value (source text)
+	Value       interface{} // Parsed value (for numbers, JSON, etc.)
+	JSON        *JSONValue  // Order-preserving parse of Value for JSON tokens; nil otherwise
+	Line        int         // Line number (1-based)
+	Column      int         // Column number (1-based)
+	StartOffset int         // Byte offset of the first character (0-based)
+	EndOffset   int         // Byte offset one past the last character (0-based, exclusive)
 }
 
 func (t Token) String() string {
@@ -252,17 +141,3 @@ func (t Token) String() string {
 	return fmt.Sprintf("%s at %d:%d", t.Type, t.Line, t.Column)
 }
 
-// MayanMarkers maps glyph content to token types
-var MayanMarkers = map[string]TokenType{
-	"Pop":     POP,
-	"Wo":      WO,
-	"Sek":     SEK,
-	"Xul":     XUL,
-	"Ch'en":   CHEN,
-	"Yax":     YAX,
-	"K'ayab":  KAYAB,
-	"Shen":    SHEN,
-	"then":    THEN,
-	"else":    ELSE,
-	"Kumk'u":  KUMKU,
-}
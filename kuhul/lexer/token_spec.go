@@ -0,0 +1,301 @@
+package lexer
+
+import "sync"
+
+// TokenKind classifies a TokenSpec entry by how the lexer recognizes it: a
+// bare-word keyword ("define_function", looked up via Keywords), a ⟁glyph⟁
+// marker ("Pop", looked up via MayanMarkers), or a fixed symbol/operator/
+// delimiter (PLUS, LPAREN, ...) that the lexer recognizes directly in
+// NextToken's switch and only needs a tokenNames entry for.
+type TokenKind int
+
+const (
+	TokenKindSymbol TokenKind = iota
+	TokenKindKeyword
+	TokenKindMarker
+)
+
+// KeywordCategory groups a keyword for documentation/completion tooling;
+// the lexer itself never branches on it. RegisterKeyword callers are free
+// to pass a category beyond the built-ins below — it's stored, not
+// validated.
+type KeywordCategory string
+
+const (
+	CategoryDeclaration KeywordCategory = "declaration"
+	CategoryControlFlow KeywordCategory = "control_flow"
+	CategoryLiteral     KeywordCategory = "literal"
+	CategoryBuiltin     KeywordCategory = "builtin"
+)
+
+// TokenSpec is one entry in tokenSpecs, the single declarative source
+// tokenNames, Keywords, and MayanMarkers are all derived from at package
+// init — adding a language keyword or glyph marker means adding one
+// TokenSpec instead of keeping three maps in sync by hand.
+type TokenSpec struct {
+	Type     TokenType
+	Name     string // tokenNames' value, e.g. "POP" or "define_function"
+	Literal  string // Keywords'/MayanMarkers' key, if Kind requires one
+	Kind     TokenKind
+	Category KeywordCategory // only meaningful when Kind == TokenKindKeyword
+}
+
+// tokenSpecs is the canonical table every built-in TokenType is declared
+// in; init() below derives tokenNames, Keywords, and MayanMarkers from it.
+var tokenSpecs = []TokenSpec{
+	{Type: ILLEGAL, Name: "ILLEGAL", Kind: TokenKindSymbol},
+	{Type: EOF, Name: "EOF", Kind: TokenKindSymbol},
+	{Type: NEWLINE, Name: "NEWLINE", Kind: TokenKindSymbol},
+	{Type: COMMENT, Name: "COMMENT", Kind: TokenKindSymbol},
+	{Type: BLOCK_COMMENT, Name: "BLOCK_COMMENT", Kind: TokenKindSymbol},
+	{Type: ERROR, Name: "ERROR", Kind: TokenKindSymbol},
+
+	{Type: POP, Name: "POP", Literal: "Pop", Kind: TokenKindMarker},
+	{Type: WO, Name: "WO", Literal: "Wo", Kind: TokenKindMarker},
+	{Type: SEK, Name: "SEK", Literal: "Sek", Kind: TokenKindMarker},
+	{Type: XUL, Name: "XUL", Literal: "Xul", Kind: TokenKindMarker},
+	{Type: CHEN, Name: "CHEN", Literal: "Ch'en", Kind: TokenKindMarker},
+	{Type: YAX, Name: "YAX", Literal: "Yax", Kind: TokenKindMarker},
+	{Type: KAYAB, Name: "KAYAB", Literal: "K'ayab", Kind: TokenKindMarker},
+	{Type: SHEN, Name: "SHEN", Literal: "Shen", Kind: TokenKindMarker},
+	{Type: THEN, Name: "THEN", Literal: "then", Kind: TokenKindMarker},
+	{Type: ELSE, Name: "ELSE", Literal: "else", Kind: TokenKindMarker},
+	{Type: KUMKU, Name: "KUMKU", Literal: "Kumk'u", Kind: TokenKindMarker},
+
+	{Type: COOL_BLOCK, Name: "COOL_BLOCK", Kind: TokenKindSymbol},
+	{Type: COOL_VECTOR, Name: "COOL_VECTOR", Kind: TokenKindSymbol},
+	{Type: COOL_VARIABLE, Name: "COOL_VARIABLE", Kind: TokenKindSymbol},
+	{Type: ATOMIC_BLOCK, Name: "ATOMIC_BLOCK", Kind: TokenKindSymbol},
+
+	{Type: IDENT, Name: "IDENT", Kind: TokenKindSymbol},
+	{Type: NUMBER, Name: "NUMBER", Kind: TokenKindSymbol},
+	{Type: STRING, Name: "STRING", Kind: TokenKindSymbol},
+	{Type: JSON, Name: "JSON", Kind: TokenKindSymbol},
+
+	{Type: PLUS, Name: "+", Kind: TokenKindSymbol},
+	{Type: MINUS, Name: "-", Kind: TokenKindSymbol},
+	{Type: STAR, Name: "*", Kind: TokenKindSymbol},
+	{Type: SLASH, Name: "/", Kind: TokenKindSymbol},
+	{Type: PERCENT, Name: "%", Kind: TokenKindSymbol},
+	{Type: ASSIGN, Name: "=", Kind: TokenKindSymbol},
+	{Type: EQ, Name: "==", Kind: TokenKindSymbol},
+	{Type: NEQ, Name: "!=", Kind: TokenKindSymbol},
+	{Type: LT, Name: "<", Kind: TokenKindSymbol},
+	{Type: GT, Name: ">", Kind: TokenKindSymbol},
+	{Type: LTE, Name: "<=", Kind: TokenKindSymbol},
+	{Type: GTE, Name: ">=", Kind: TokenKindSymbol},
+	{Type: AND, Name: "&&", Kind: TokenKindSymbol},
+	{Type: OR, Name: "||", Kind: TokenKindSymbol},
+	{Type: NOT, Name: "!", Kind: TokenKindSymbol},
+	{Type: BAND, Name: "&", Kind: TokenKindSymbol},
+	{Type: BOR, Name: "|", Kind: TokenKindSymbol},
+	{Type: XOR, Name: "^", Kind: TokenKindSymbol},
+	{Type: DOT, Name: ".", Kind: TokenKindSymbol},
+	{Type: COMMA, Name: ",", Kind: TokenKindSymbol},
+	{Type: COLON, Name: ":", Kind: TokenKindSymbol},
+	{Type: SEMICOL, Name: ";", Kind: TokenKindSymbol},
+	{Type: QUESTION, Name: "?", Kind: TokenKindSymbol},
+
+	{Type: LPAREN, Name: "(", Kind: TokenKindSymbol},
+	{Type: RPAREN, Name: ")", Kind: TokenKindSymbol},
+	{Type: LBRACE, Name: "{", Kind: TokenKindSymbol},
+	{Type: RBRACE, Name: "}", Kind: TokenKindSymbol},
+	{Type: LBRACKET, Name: "[", Kind: TokenKindSymbol},
+	{Type: RBRACKET, Name: "]", Kind: TokenKindSymbol},
+
+	{Type: AT, Name: "@", Kind: TokenKindSymbol},
+
+	{Type: DEFINE_FUNCTION, Name: "define_function", Literal: "define_function", Kind: TokenKindKeyword, Category: CategoryDeclaration},
+	{Type: DEFINE_CLASS, Name: "define_class", Literal: "define_class", Kind: TokenKindKeyword, Category: CategoryDeclaration},
+	{Type: DEFINE_MACRO, Name: "define_macro", Literal: "define_macro", Kind: TokenKindKeyword, Category: CategoryDeclaration},
+	{Type: RETURN, Name: "return", Literal: "return", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: IF, Name: "if", Literal: "if", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: FOR, Name: "for", Literal: "for", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: WHILE, Name: "while", Literal: "while", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: IN, Name: "in", Literal: "in", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: FROM, Name: "from", Literal: "from", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: TO, Name: "to", Literal: "to", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: NEW, Name: "new", Literal: "new", Kind: TokenKindKeyword, Category: CategoryDeclaration},
+	{Type: TRUE, Name: "true", Literal: "true", Kind: TokenKindKeyword, Category: CategoryLiteral},
+	{Type: FALSE, Name: "false", Literal: "false", Kind: TokenKindKeyword, Category: CategoryLiteral},
+	{Type: NULL, Name: "null", Literal: "null", Kind: TokenKindKeyword, Category: CategoryLiteral},
+	{Type: MAP, Name: "map", Literal: "map", Kind: TokenKindKeyword, Category: CategoryDeclaration},
+	{Type: GO, Name: "go", Literal: "go", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: CHANNEL, Name: "channel", Literal: "channel", Kind: TokenKindKeyword, Category: CategoryDeclaration},
+	{Type: OBSERVABLE, Name: "observable", Literal: "observable", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: SUBSCRIBE, Name: "subscribe", Literal: "subscribe", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: QUERY, Name: "query", Literal: "query", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: ASSERT_FACT, Name: "assert_fact", Literal: "assert_fact", Kind: TokenKindKeyword, Category: CategoryControlFlow},
+	{Type: DEFINE_RULE, Name: "define_rule", Literal: "define_rule", Kind: TokenKindKeyword, Category: CategoryDeclaration},
+	{Type: MATRIX_MULTIPLY, Name: "matrix_multiply", Literal: "matrix_multiply", Kind: TokenKindKeyword, Category: CategoryBuiltin},
+	{Type: TRANSPOSE, Name: "transpose", Literal: "transpose", Kind: TokenKindKeyword, Category: CategoryBuiltin},
+	{Type: SOFTMAX, Name: "softmax", Literal: "softmax", Kind: TokenKindKeyword, Category: CategoryBuiltin},
+}
+
+// UserTokenBase is the first TokenType RegisterKeyword/RegisterGlyph hand
+// out, reserved well above the built-in tokenSpecs range so a future
+// built-in addition can never collide with a token a pack registered at
+// runtime.
+const UserTokenBase TokenType = 10000
+
+var (
+	// tokenTableMu guards tokenNames, Keywords, MayanMarkers,
+	// keywordCategories, dialectKeywords, dialectMarkers, and
+	// nextUserToken — the only mutable lexer-wide state, all of which a
+	// pack's RegisterKeyword/RegisterGlyph call can write to concurrently
+	// with an in-flight Lexer reading them.
+	tokenTableMu sync.RWMutex
+
+	tokenNames        = map[TokenType]string{}
+	Keywords          = map[string]TokenType{}
+	MayanMarkers      = map[string]TokenType{}
+	keywordCategories = map[TokenType]KeywordCategory{}
+
+	// dialectKeywords/dialectMarkers are RegisterKeywordFor/
+	// RegisterGlyphFor's per-dialect overlays on top of the tables above;
+	// a Lexer with WithDialect(name) set checks its dialect's overlay
+	// before falling back to the shared tables, so two dialects can both
+	// register, say, "render" as a keyword without colliding.
+	dialectKeywords = map[string]map[string]TokenType{}
+	dialectMarkers  = map[string]map[string]TokenType{}
+
+	nextUserToken = UserTokenBase
+)
+
+func init() {
+	for _, spec := range tokenSpecs {
+		tokenNames[spec.Type] = spec.Name
+		switch spec.Kind {
+		case TokenKindKeyword:
+			Keywords[spec.Literal] = spec.Type
+			keywordCategories[spec.Type] = spec.Category
+		case TokenKindMarker:
+			MayanMarkers[spec.Literal] = spec.Type
+		}
+	}
+}
+
+// RegisterKeyword allocates a new TokenType in the user-defined range
+// (see UserTokenBase) and adds literal to the shared Keywords table under
+// it, so every Lexer (regardless of dialect) recognizes literal as a
+// keyword from then on. Returns the allocated TokenType. Use
+// RegisterKeywordFor instead to scope the registration to one dialect.
+func RegisterKeyword(literal string, category KeywordCategory) TokenType {
+	return RegisterKeywordFor("", literal, category)
+}
+
+// RegisterKeywordFor is RegisterKeyword scoped to dialect: a Lexer must
+// have WithDialect(dialect) set to see it. dialect == "" is equivalent to
+// RegisterKeyword.
+func RegisterKeywordFor(dialect, literal string, category KeywordCategory) TokenType {
+	tokenTableMu.Lock()
+	defer tokenTableMu.Unlock()
+
+	t := nextUserToken
+	nextUserToken++
+	tokenNames[t] = literal
+	keywordCategories[t] = category
+
+	if dialect == "" {
+		Keywords[literal] = t
+		return t
+	}
+	table := dialectKeywords[dialect]
+	if table == nil {
+		table = make(map[string]TokenType)
+		dialectKeywords[dialect] = table
+	}
+	table[literal] = t
+	return t
+}
+
+// RegisterGlyph allocates a new TokenType in the user-defined range and
+// adds name to the shared MayanMarkers table under it, so "⟁name⟁" lexes
+// as that token from then on. Use RegisterGlyphFor instead to scope the
+// registration to one dialect.
+func RegisterGlyph(name string) TokenType {
+	return RegisterGlyphFor("", name)
+}
+
+// RegisterGlyphFor is RegisterGlyph scoped to dialect: a Lexer must have
+// WithDialect(dialect) set to see it. dialect == "" is equivalent to
+// RegisterGlyph.
+func RegisterGlyphFor(dialect, name string) TokenType {
+	tokenTableMu.Lock()
+	defer tokenTableMu.Unlock()
+
+	t := nextUserToken
+	nextUserToken++
+	tokenNames[t] = name
+
+	if dialect == "" {
+		MayanMarkers[name] = t
+		return t
+	}
+	table := dialectMarkers[dialect]
+	if table == nil {
+		table = make(map[string]TokenType)
+		dialectMarkers[dialect] = table
+	}
+	table[name] = t
+	return t
+}
+
+// KeywordCategoryOf reports the category a built-in or RegisterKeyword-
+// registered TokenType was declared with, and false for any other token
+// (including every TokenKindMarker/TokenKindSymbol entry).
+func KeywordCategoryOf(t TokenType) (KeywordCategory, bool) {
+	tokenTableMu.RLock()
+	defer tokenTableMu.RUnlock()
+	cat, ok := keywordCategories[t]
+	return cat, ok
+}
+
+// lookupKeyword is LookupIdent scoped to dialect: it checks dialect's
+// keyword overlay first (if dialect != "" and one exists), then the shared
+// Keywords table, returning IDENT if ident matches neither.
+func lookupKeyword(dialect, ident string) TokenType {
+	tokenTableMu.RLock()
+	defer tokenTableMu.RUnlock()
+	if dialect != "" {
+		if t, ok := dialectKeywords[dialect][ident]; ok {
+			return t
+		}
+	}
+	if t, ok := Keywords[ident]; ok {
+		return t
+	}
+	return IDENT
+}
+
+// lookupMarker is MayanMarkers' lookup scoped to dialect, mirroring
+// lookupKeyword.
+func lookupMarker(dialect, content string) (TokenType, bool) {
+	tokenTableMu.RLock()
+	defer tokenTableMu.RUnlock()
+	if dialect != "" {
+		if t, ok := dialectMarkers[dialect][content]; ok {
+			return t, true
+		}
+	}
+	t, ok := MayanMarkers[content]
+	return t, ok
+}
+
+// markerCandidates returns every known marker name (content between the
+// ⟁⟁ wrapper) for dialect plus the shared table, for suggestMarker's
+// closest-match search.
+func markerCandidates(dialect string) []string {
+	tokenTableMu.RLock()
+	defer tokenTableMu.RUnlock()
+	candidates := make([]string, 0, len(MayanMarkers))
+	for k := range MayanMarkers {
+		candidates = append(candidates, k)
+	}
+	if dialect != "" {
+		for k := range dialectMarkers[dialect] {
+			candidates = append(candidates, k)
+		}
+	}
+	return candidates
+}
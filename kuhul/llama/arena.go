@@ -0,0 +1,86 @@
+package llama
+
+import "sync"
+
+// arena pools the seqLen x dim scratch matrices Attention.Forward and
+// FFN.Forward need for intermediate results that never escape the call
+// (the attention output buffer before WO's projection, and FFN's
+// hidden/gate buffers) so autoregressive generation doesn't allocate and
+// immediately discard one of these every token. get's returned matrix is
+// zeroed; callers must pass it back to put once they're done with it.
+type arena struct {
+	pool sync.Pool
+}
+
+func newArena() *arena {
+	return &arena{pool: sync.Pool{New: func() interface{} { return new([][]float64) }}}
+}
+
+// get returns a rows x cols matrix, reusing row slices from a previously
+// put matrix when it has enough capacity and growing (never shrinking
+// capacity) otherwise.
+func (ar *arena) get(rows, cols int) [][]float64 {
+	ptr := ar.pool.Get().(*[][]float64)
+	m := *ptr
+	if cap(m) < rows {
+		grown := make([][]float64, rows)
+		copy(grown, m)
+		m = grown
+	} else {
+		m = m[:rows]
+	}
+	for i := 0; i < rows; i++ {
+		if cap(m[i]) < cols {
+			m[i] = make([]float64, cols)
+			continue
+		}
+		m[i] = m[i][:cols]
+		for j := range m[i] {
+			m[i][j] = 0
+		}
+	}
+	*ptr = m
+	return m
+}
+
+// put returns m to the pool for a future get to reuse.
+func (ar *arena) put(m [][]float64) {
+	ar.pool.Put(&m)
+}
+
+// vecArena pools the per-(head,row) attention-scores buffer, whose length
+// (prevLen+row+1) grows over the course of a Generate call but is always
+// read and discarded within the same Attention.Forward iteration.
+type vecArena struct {
+	pool sync.Pool
+}
+
+func newVecArena() *vecArena {
+	return &vecArena{pool: sync.Pool{New: func() interface{} { return new([]float64) }}}
+}
+
+func (va *vecArena) get(n int) []float64 {
+	ptr := va.pool.Get().(*[]float64)
+	v := *ptr
+	if cap(v) < n {
+		v = make([]float64, n)
+	} else {
+		v = v[:n]
+	}
+	*ptr = v
+	return v
+}
+
+func (va *vecArena) put(v []float64) {
+	va.pool.Put(&v)
+}
+
+// attnArena/scoresArena and ffnArena hold Attention.Forward's and
+// FFN.Forward's respective scratch buffers, kept separate so reuse stays
+// size-stable instead of thrashing between differently shaped requests
+// from the two call sites.
+var (
+	attnArena   = newArena()
+	scoresArena = newVecArena()
+	ffnArena    = newArena()
+)
@@ -0,0 +1,249 @@
+package llama
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+// Backend executes the numeric kernels Attention.Forward, FFN.Forward, and
+// LayerNorm.Forward call into. goBackend (this file) is the default: a pure
+// Go implementation that tiles and parallelizes MatMul/MatMulT. A cgo
+// backend that dispatches MatMul/MatMulT to an OpenBLAS/Accelerate dgemm is
+// compiled in under the "blas" build tag (see backend_blas.go); it falls
+// back to goBackend for everything else, the same way kuhul/runtime's
+// cudaBackend leaves Softmax/Gemv to its cpuBackend because they're
+// bandwidth-bound and don't earn back a device round-trip.
+type Backend interface {
+	// MatMul computes a x b into out (shapes [m][k], [k][n], [m][n]).
+	MatMul(a, b, out [][]float64)
+	// MatMulT computes a x bT' into out, where bT is b already transposed
+	// (shape [n][k]) — for callers that keep a standing transposed copy of
+	// a weight matrix reused across many calls, so the transpose cost is
+	// paid once at load time instead of once per MatMul.
+	MatMulT(a, bT, out [][]float64)
+	// Softmax normalizes x into a probability distribution in place
+	// (numerically stabilized) and returns it.
+	Softmax(x []float64) []float64
+	// LayerNorm normalizes x to zero mean / unit variance, then scales by
+	// gamma and shifts by beta.
+	LayerNorm(x, gamma, beta []float64, eps float64) []float64
+	// RMSNorm normalizes x by its root-mean-square magnitude only (no mean
+	// centering, no beta) — the norm real Llama checkpoints use.
+	RMSNorm(x, gamma []float64, eps float64) []float64
+	// RoPE rotates vec in place for position pos using table's precomputed
+	// angles.
+	RoPE(vec []float64, pos int, table *RopeTable)
+}
+
+// activeBackend is the Backend every model forward pass runs its numeric
+// kernels through. newBLASBackend returns nil unless this binary was built
+// with -tags blas; when it returns nil, activeBackend starts out as goBackend.
+var activeBackend Backend = pickDefaultBackend()
+
+func pickDefaultBackend() Backend {
+	if b := newBLASBackend(); b != nil {
+		return b
+	}
+	return newGoBackend()
+}
+
+// SetBackend installs b as the Backend every subsequent Forward call uses.
+// It is not safe to call while a Generate call is in flight.
+func SetBackend(b Backend) { activeBackend = b }
+
+// matMulTile is the edge length of the square tiles goBackend.MatMul blocks
+// its loops into; 64x64 float64 tiles (32KB per operand) comfortably fit
+// alongside each other in a typical 256KB-512KB L2 cache.
+const matMulTile = 64
+
+// goBackend is the default Backend: plain Go, no cgo. MatMul transposes b
+// once per call so the inner product loop walks both operands with stride
+// 1, blocks the row and column loops into matMulTile x matMulTile tiles for
+// cache reuse (the inner product loop runs over the full k), and parallelizes across output row-tiles with a worker pool sized
+// to GOMAXPROCS.
+type goBackend struct{}
+
+func newGoBackend() *goBackend { return &goBackend{} }
+
+func (*goBackend) MatMul(a, b, out [][]float64) {
+	if len(a) == 0 || len(b) == 0 {
+		return
+	}
+	bT := transposeOnce(b)
+	matMulTiledParallel(a, bT, out)
+}
+
+func (*goBackend) MatMulT(a, bT, out [][]float64) {
+	if len(a) == 0 || len(bT) == 0 {
+		return
+	}
+	matMulTiledParallel(a, bT, out)
+}
+
+// transposeOnce returns b transposed: bT[j][l] = b[l][j]. Named for the
+// doc comment's claim of one transpose per MatMul call, as opposed to
+// MatMulT's caller-amortized transpose.
+func transposeOnce(b [][]float64) [][]float64 {
+	k, n := len(b), len(b[0])
+	bT := make([][]float64, n)
+	for j := 0; j < n; j++ {
+		bT[j] = make([]float64, k)
+		for l := 0; l < k; l++ {
+			bT[j][l] = b[l][j]
+		}
+	}
+	return bT
+}
+
+// matMulTiledParallel computes a x bT' into out (bT already transposed, so
+// bT[j] is the j-th output column laid out contiguously) with a demux/
+// worker pipeline: a demuxer feeds row-tile boundaries over a channel, and
+// workers (sized to GOMAXPROCS) each pull tiles and run matMulTileBlock
+// until the channel closes. Each inner dot product walks a[i] and bT[j]
+// both with stride 1.
+func matMulTiledParallel(a, bT, out [][]float64) {
+	m, k, n := len(a), len(a[0]), len(bT)
+
+	rowTiles := (m + matMulTile - 1) / matMulTile
+	workers := runtime.GOMAXPROCS(0)
+	if workers > rowTiles {
+		workers = rowTiles
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	type tile struct{ rowStart, rowEnd int }
+	tiles := make(chan tile)
+	go func() {
+		defer close(tiles)
+		for rowStart := 0; rowStart < m; rowStart += matMulTile {
+			rowEnd := rowStart + matMulTile
+			if rowEnd > m {
+				rowEnd = m
+			}
+			tiles <- tile{rowStart, rowEnd}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for t := range tiles {
+				matMulTileBlock(a, bT, out, t.rowStart, t.rowEnd, k, n)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// matMulTileBlock computes out[rowStart:rowEnd] for a single row-tile,
+// itself blocked over column tiles so each bT tile stays resident in cache
+// across the tile's rows; the inner product loop is not tiled.
+func matMulTileBlock(a, bT, out [][]float64, rowStart, rowEnd, k, n int) {
+	for colStart := 0; colStart < n; colStart += matMulTile {
+		colEnd := colStart + matMulTile
+		if colEnd > n {
+			colEnd = n
+		}
+		for i := rowStart; i < rowEnd; i++ {
+			ai := a[i]
+			oi := out[i]
+			for j := colStart; j < colEnd; j++ {
+				bj := bT[j]
+				limit := k
+				if len(ai) < limit {
+					limit = len(ai)
+				}
+				if len(bj) < limit {
+					limit = len(bj)
+				}
+				sum := 0.0
+				for l := 0; l < limit; l++ {
+					sum += ai[l] * bj[l]
+				}
+				oi[j] = sum
+			}
+		}
+	}
+}
+
+// Softmax normalizes x into a probability distribution in place (and
+// returns it, for call sites that chain off the result).
+func (*goBackend) Softmax(x []float64) []float64 {
+	if len(x) == 0 {
+		return x
+	}
+	max := x[0]
+	for _, v := range x[1:] {
+		if v > max {
+			max = v
+		}
+	}
+	sum := 0.0
+	for i, v := range x {
+		x[i] = math.Exp(v - max)
+		sum += x[i]
+	}
+	for i := range x {
+		x[i] /= sum
+	}
+	return x
+}
+
+func (*goBackend) LayerNorm(x, gamma, beta []float64, eps float64) []float64 {
+	n := len(x)
+	if n == 0 {
+		return x
+	}
+	mean := 0.0
+	for _, v := range x {
+		mean += v
+	}
+	mean /= float64(n)
+
+	variance := 0.0
+	for _, v := range x {
+		diff := v - mean
+		variance += diff * diff
+	}
+	variance /= float64(n)
+
+	result := make([]float64, n)
+	std := math.Sqrt(variance + eps)
+	for i, v := range x {
+		result[i] = (v-mean)/std*gamma[i] + beta[i]
+	}
+	return result
+}
+
+func (*goBackend) RMSNorm(x, gamma []float64, eps float64) []float64 {
+	n := len(x)
+	if n == 0 {
+		return x
+	}
+	sumSq := 0.0
+	for _, v := range x {
+		sumSq += v * v
+	}
+	rms := math.Sqrt(sumSq/float64(n) + eps)
+
+	result := make([]float64, n)
+	for i, v := range x {
+		result[i] = v / rms * gamma[i]
+	}
+	return result
+}
+
+func (*goBackend) RoPE(vec []float64, pos int, table *RopeTable) {
+	half := len(vec) / 2
+	cos, sin := table.Cos[pos], table.Sin[pos]
+	for i := 0; i < half; i++ {
+		x1, x2 := vec[i], vec[i+half]
+		vec[i] = x1*cos[i] - x2*sin[i]
+		vec[i+half] = x2*cos[i] + x1*sin[i]
+	}
+}
@@ -0,0 +1,83 @@
+//go:build blas
+
+package llama
+
+/*
+#cgo LDFLAGS: -lopenblas
+#include <cblas.h>
+*/
+import "C"
+
+import "unsafe"
+
+// blasBackend runs MatMul/MatMulT through OpenBLAS's (or, on a Mac with
+// CGO_LDFLAGS="-framework Accelerate", Accelerate's compatible) cblas_dgemm.
+// Like kuhul/runtime's cudaBackend, it leaves the bandwidth-bound kernels
+// (Softmax, LayerNorm, RMSNorm, RoPE) to goBackend rather than shipping
+// device/library variants that wouldn't earn back their call overhead.
+type blasBackend struct {
+	goBackend
+}
+
+// newBLASBackend is only implemented when this binary is built with
+// `-tags blas` (requires cgo and an OpenBLAS or Accelerate dgemm on the
+// host's linker path); pickDefaultBackend falls back to goBackend when it
+// returns nil.
+func newBLASBackend() Backend { return &blasBackend{} }
+
+func (b *blasBackend) MatMul(a, bmat, out [][]float64) {
+	if len(a) == 0 || len(bmat) == 0 {
+		return
+	}
+	m, k, n := len(a), len(a[0]), len(bmat[0])
+	aFlat := flattenRows(a, k)
+	bFlat := flattenRows(bmat, n)
+	outFlat := make([]float64, m*n)
+
+	C.cblas_dgemm(C.CblasRowMajor, C.CblasNoTrans, C.CblasNoTrans,
+		C.blasint(m), C.blasint(n), C.blasint(k),
+		1.0,
+		(*C.double)(unsafe.Pointer(&aFlat[0])), C.blasint(k),
+		(*C.double)(unsafe.Pointer(&bFlat[0])), C.blasint(n),
+		0.0,
+		(*C.double)(unsafe.Pointer(&outFlat[0])), C.blasint(n))
+
+	unflattenRows(outFlat, out, n)
+}
+
+// MatMulT computes a x bT' (bT already transposed, shape [n][k]) by asking
+// cblas_dgemm to transpose its B operand itself, so the caller's standing
+// transposed copy is used directly with no extra transpose pass.
+func (b *blasBackend) MatMulT(a, bT, out [][]float64) {
+	if len(a) == 0 || len(bT) == 0 {
+		return
+	}
+	m, k, n := len(a), len(a[0]), len(bT)
+	aFlat := flattenRows(a, k)
+	bTFlat := flattenRows(bT, k)
+	outFlat := make([]float64, m*n)
+
+	C.cblas_dgemm(C.CblasRowMajor, C.CblasNoTrans, C.CblasTrans,
+		C.blasint(m), C.blasint(n), C.blasint(k),
+		1.0,
+		(*C.double)(unsafe.Pointer(&aFlat[0])), C.blasint(k),
+		(*C.double)(unsafe.Pointer(&bTFlat[0])), C.blasint(k),
+		0.0,
+		(*C.double)(unsafe.Pointer(&outFlat[0])), C.blasint(n))
+
+	unflattenRows(outFlat, out, n)
+}
+
+func flattenRows(m [][]float64, cols int) []float64 {
+	flat := make([]float64, len(m)*cols)
+	for i, row := range m {
+		copy(flat[i*cols:(i+1)*cols], row)
+	}
+	return flat
+}
+
+func unflattenRows(flat []float64, out [][]float64, cols int) {
+	for i := range out {
+		copy(out[i], flat[i*cols:(i+1)*cols])
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !blas
+
+package llama
+
+// newBLASBackend is only implemented when this binary is built with
+// `-tags blas` (requires cgo and an OpenBLAS or Accelerate dgemm on the
+// host's linker path); pickDefaultBackend falls back to goBackend when it
+// returns nil.
+func newBLASBackend() Backend { return nil }
@@ -0,0 +1,523 @@
+package llama
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"syscall"
+)
+
+const ggufMagic = 0x46554747 // "GGUF", little-endian
+
+// ggufValueType identifies one metadata value's wire representation.
+type ggufValueType uint32
+
+const (
+	ggufValUint8 ggufValueType = iota
+	ggufValInt8
+	ggufValUint16
+	ggufValInt16
+	ggufValUint32
+	ggufValInt32
+	ggufValFloat32
+	ggufValBool
+	ggufValString
+	ggufValArray
+	ggufValUint64
+	ggufValInt64
+	ggufValFloat64
+)
+
+// ggufReader decodes GGUF's little-endian binary layout out of an mmap'd
+// file. It accumulates the first error it hits (mirroring kuhul/printer's
+// sticky-error writer) and every subsequent read becomes a no-op returning
+// the zero value, so LoadGGUF only needs one error check at the end instead
+// of threading err through every field read.
+type ggufReader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (r *ggufReader) need(n int) bool {
+	if r.err != nil {
+		return false
+	}
+	if r.off+n > len(r.data) {
+		r.err = fmt.Errorf("unexpected end of file at offset %d (need %d more bytes)", r.off, n)
+		return false
+	}
+	return true
+}
+
+func (r *ggufReader) u8() uint8 {
+	if !r.need(1) {
+		return 0
+	}
+	v := r.data[r.off]
+	r.off++
+	return v
+}
+
+func (r *ggufReader) u16() uint16 {
+	if !r.need(2) {
+		return 0
+	}
+	v := binary.LittleEndian.Uint16(r.data[r.off:])
+	r.off += 2
+	return v
+}
+
+func (r *ggufReader) u32() uint32 {
+	if !r.need(4) {
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(r.data[r.off:])
+	r.off += 4
+	return v
+}
+
+func (r *ggufReader) u64() uint64 {
+	if !r.need(8) {
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(r.data[r.off:])
+	r.off += 8
+	return v
+}
+
+func (r *ggufReader) f32() float32 {
+	return math.Float32frombits(r.u32())
+}
+
+func (r *ggufReader) f64() float64 {
+	return math.Float64frombits(r.u64())
+}
+
+func (r *ggufReader) str() string {
+	n := r.u64()
+	if !r.need(int(n)) {
+		return ""
+	}
+	s := string(r.data[r.off : r.off+int(n)])
+	r.off += int(n)
+	return s
+}
+
+// value reads one metadata value of the given wire type, recursing for
+// ggufValArray.
+func (r *ggufReader) value(t ggufValueType) interface{} {
+	switch t {
+	case ggufValUint8:
+		return r.u8()
+	case ggufValInt8:
+		return int8(r.u8())
+	case ggufValUint16:
+		return r.u16()
+	case ggufValInt16:
+		return int16(r.u16())
+	case ggufValUint32:
+		return r.u32()
+	case ggufValInt32:
+		return int32(r.u32())
+	case ggufValFloat32:
+		return r.f32()
+	case ggufValBool:
+		return r.u8() != 0
+	case ggufValString:
+		return r.str()
+	case ggufValUint64:
+		return r.u64()
+	case ggufValInt64:
+		return int64(r.u64())
+	case ggufValFloat64:
+		return r.f64()
+	case ggufValArray:
+		elemType := ggufValueType(r.u32())
+		n := r.u64()
+		arr := make([]interface{}, 0, n)
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			arr = append(arr, r.value(elemType))
+		}
+		return arr
+	default:
+		r.err = fmt.Errorf("unknown gguf value type %d", t)
+		return nil
+	}
+}
+
+// ggufTensorInfo is one entry of the tensor descriptor table: name, shape
+// (dims[0] is the fastest-varying dimension, ggml's ne[0]), storage kind,
+// and byte offset from the start of the (alignment-padded) data section.
+type ggufTensorInfo struct {
+	name   string
+	dims   []uint64
+	kind   ggufTensorType
+	offset uint64
+}
+
+// LoadGGUF memory-maps the GGUF checkpoint at path and builds a LlamaModel
+// from its metadata and tensors, leaving every weight in its on-disk
+// quantized form: matmul dequantizes rows as needed (see Weight/quantTensor)
+// rather than expanding gigabytes of Q4_K_M data to float64 up front.
+//
+// It expects the standard llama.cpp GGUF metadata keys
+// (llama.embedding_length, llama.block_count, llama.attention.head_count,
+// llama.attention.head_count_kv, llama.rope.freq_base, llama.context_length,
+// tokenizer.ggml.tokens, tokenizer.ggml.merges, and the tokenizer special
+// token ids) and the standard blk.N.{attn_q,attn_k,attn_v,attn_output,
+// attn_norm,ffn_up,ffn_down,ffn_gate,ffn_norm}.weight tensor names plus
+// token_embd.weight, output_norm.weight, and output.weight (falling back to
+// token_embd.weight for checkpoints that tie input/output embeddings).
+func LoadGGUF(path string) (*LlamaModel, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("llama: open gguf %q: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("llama: stat gguf %q: %w", path, err)
+	}
+	size := int(info.Size())
+	if size == 0 {
+		return nil, fmt.Errorf("llama: gguf %q is empty", path)
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("llama: mmap gguf %q: %w", path, err)
+	}
+	// On success the returned model keeps data alive via every tensor's
+	// quantTensor; only the error paths below need to unmap explicitly.
+	cleanup := func() { syscall.Munmap(data) }
+
+	r := &ggufReader{data: data}
+	if r.u32() != ggufMagic {
+		cleanup()
+		return nil, fmt.Errorf("llama: %q is not a GGUF file (bad magic)", path)
+	}
+	_ = r.u32() // version; every layout this parses is version-independent so far
+	tensorCount := r.u64()
+	kvCount := r.u64()
+
+	meta := make(map[string]interface{}, kvCount)
+	for i := uint64(0); i < kvCount && r.err == nil; i++ {
+		key := r.str()
+		t := ggufValueType(r.u32())
+		meta[key] = r.value(t)
+	}
+
+	infos := make([]ggufTensorInfo, tensorCount)
+	for i := range infos {
+		if r.err != nil {
+			break
+		}
+		name := r.str()
+		nDims := r.u32()
+		dims := make([]uint64, nDims)
+		for d := range dims {
+			dims[d] = r.u64()
+		}
+		kind := ggufTensorType(r.u32())
+		offset := r.u64()
+		infos[i] = ggufTensorInfo{name: name, dims: dims, kind: kind, offset: offset}
+	}
+
+	if r.err != nil {
+		cleanup()
+		return nil, fmt.Errorf("llama: parsing gguf %q: %w", path, r.err)
+	}
+
+	alignment := uint64(32)
+	if a, ok := metaUint(meta, "general.alignment"); ok && a > 0 {
+		alignment = a
+	}
+	dataStart := uint64(r.off)
+	if rem := dataStart % alignment; rem != 0 {
+		dataStart += alignment - rem
+	}
+
+	byName := make(map[string]ggufTensorInfo, len(infos))
+	for _, t := range infos {
+		byName[t.name] = t
+	}
+
+	weight := func(name string) (*Weight, error) {
+		qt, err := loadQuantTensor(path, byName, data, dataStart, name)
+		if err != nil {
+			return nil, err
+		}
+		return newQuantWeight(qt), nil
+	}
+	vector := func(name string) ([]float64, error) {
+		t, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("llama: gguf %q missing tensor %q", path, name)
+		}
+		n := 1
+		for _, d := range t.dims {
+			n *= int(d)
+		}
+		qt, err := newTensorAt(path, data, dataStart, t, n, 1)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]float64, n)
+		qt.dequantRow(0, out)
+		return out, nil
+	}
+
+	embedLen, _ := metaUint(meta, "llama.embedding_length")
+	blockCount, _ := metaUint(meta, "llama.block_count")
+	headCount, _ := metaUint(meta, "llama.attention.head_count")
+	if embedLen == 0 || blockCount == 0 || headCount == 0 {
+		cleanup()
+		return nil, fmt.Errorf("llama: gguf %q is missing required llama.* metadata", path)
+	}
+	headCountKV, ok := metaUint(meta, "llama.attention.head_count_kv")
+	if !ok || headCountKV == 0 {
+		headCountKV = headCount
+	}
+	ropeFreqBase, ok := metaFloat(meta, "llama.rope.freq_base")
+	if !ok {
+		ropeFreqBase = 10000.0
+	}
+	ctxLen, _ := metaUint(meta, "llama.context_length")
+	rmsEps, ok := metaFloat(meta, "llama.attention.layer_norm_rms_epsilon")
+	if !ok {
+		rmsEps = 1e-5
+	}
+
+	tokens, _ := metaStringArray(meta, "tokenizer.ggml.tokens")
+
+	config := ModelConfig{
+		VocabSize:  len(tokens),
+		HiddenSize: int(embedLen),
+		NumLayers:  int(blockCount),
+		NumHeads:   int(headCount),
+		NumKVHeads: int(headCountKV),
+		HeadDim:    int(embedLen) / int(headCount),
+		MaxSeqLen:  int(ctxLen),
+		RopeTheta:  ropeFreqBase,
+	}
+
+	tokConfig := TokenizerConfig{
+		VocabSize:     config.VocabSize,
+		BOS:           int(metaUintDefault(meta, "tokenizer.ggml.bos_token_id", 1)),
+		EOS:           int(metaUintDefault(meta, "tokenizer.ggml.eos_token_id", 2)),
+		PAD:           int(metaUintDefault(meta, "tokenizer.ggml.padding_token_id", 0)),
+		UNK:           int(metaUintDefault(meta, "tokenizer.ggml.unknown_token_id", 3)),
+		Vocab:         make(map[string]int, len(tokens)),
+		SpecialTokens: make(map[string]int),
+	}
+	for i, tok := range tokens {
+		tokConfig.Vocab[tok] = i
+	}
+	if merges, ok := metaStringArray(meta, "tokenizer.ggml.merges"); ok {
+		tokConfig.Merges = make([][]string, 0, len(merges))
+		for _, m := range merges {
+			parts := strings.SplitN(m, " ", 2)
+			if len(parts) == 2 {
+				tokConfig.Merges = append(tokConfig.Merges, []string{parts[0], parts[1]})
+			}
+		}
+	}
+
+	embed, err := weight("token_embd.weight")
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	lmHead, err := weight("output.weight")
+	if err != nil {
+		lmHead = embed // tied input/output embeddings
+	}
+	outputNormGamma, err := vector("output_norm.weight")
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	blocks := make([]*TransformerBlock, config.NumLayers)
+	for i := 0; i < config.NumLayers; i++ {
+		prefix := fmt.Sprintf("blk.%d.", i)
+
+		wq, err := weight(prefix + "attn_q.weight")
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		wk, err := weight(prefix + "attn_k.weight")
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		wv, err := weight(prefix + "attn_v.weight")
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		wo, err := weight(prefix + "attn_output.weight")
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		w1, err := weight(prefix + "ffn_up.weight")
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		w2, err := weight(prefix + "ffn_down.weight")
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		w3, err := weight(prefix + "ffn_gate.weight")
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		attnGamma, err := vector(prefix + "attn_norm.weight")
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		ffnGamma, err := vector(prefix + "ffn_norm.weight")
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+
+		blocks[i] = &TransformerBlock{
+			Attention: &Attention{
+				Config: AttentionConfig{
+					NumHeads:   config.NumHeads,
+					NumKVHeads: config.NumKVHeads,
+					HeadDim:    config.HeadDim,
+					HiddenSize: config.HiddenSize,
+				},
+				WQ: wq, WK: wk, WV: wv, WO: wo,
+			},
+			FFN: &FFN{
+				Config: FFNConfig{
+					HiddenSize:       config.HiddenSize,
+					IntermediateSize: w1.OutDim(),
+					Activation:       "silu",
+				},
+				W1: w1, W2: w2, W3: w3,
+			},
+			LNAttn: &LayerNorm{Gamma: attnGamma, Beta: make([]float64, len(attnGamma)), Eps: rmsEps, RMS: true},
+			LNFFN:  &LayerNorm{Gamma: ffnGamma, Beta: make([]float64, len(ffnGamma)), Eps: rmsEps, RMS: true},
+		}
+	}
+	if len(blocks) > 0 {
+		config.IntermediateSize = blocks[0].FFN.Config.IntermediateSize
+	}
+
+	return &LlamaModel{
+		Tokenizer: NewTokenizer(tokConfig),
+		Blocks:    blocks,
+		Embed:     embed,
+		LNFinal:   &LayerNorm{Gamma: outputNormGamma, Beta: make([]float64, len(outputNormGamma)), Eps: rmsEps, RMS: true},
+		LMHead:    lmHead,
+		Config:    config,
+		Rope:      NewRopeTable(config.MaxSeqLen, config.HeadDim, config.RopeTheta),
+	}, nil
+}
+
+// loadQuantTensor looks up name in byName and wraps it as a quantTensor
+// whose logical shape is [inDim=dims[0]][outDim=product of the rest].
+func loadQuantTensor(path string, byName map[string]ggufTensorInfo, data []byte, dataStart uint64, name string) (*quantTensor, error) {
+	t, ok := byName[name]
+	if !ok {
+		return nil, fmt.Errorf("llama: gguf %q missing tensor %q", path, name)
+	}
+	if len(t.dims) == 0 {
+		return nil, fmt.Errorf("llama: gguf %q tensor %q has no dimensions", path, name)
+	}
+	inDim := int(t.dims[0])
+	outDim := 1
+	for _, d := range t.dims[1:] {
+		outDim *= int(d)
+	}
+	return newTensorAt(path, data, dataStart, t, inDim, outDim)
+}
+
+func newTensorAt(path string, data []byte, dataStart uint64, t ggufTensorInfo, inDim, outDim int) (*quantTensor, error) {
+	start := dataStart + t.offset
+	if start > uint64(len(data)) {
+		return nil, fmt.Errorf("llama: gguf %q tensor %q offset out of range", path, t.name)
+	}
+	return newQuantTensor(t.kind, data[start:], inDim, outDim), nil
+}
+
+func metaUint(meta map[string]interface{}, key string) (uint64, bool) {
+	v, ok := meta[key]
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case uint8:
+		return uint64(n), true
+	case uint16:
+		return uint64(n), true
+	case uint32:
+		return uint64(n), true
+	case uint64:
+		return n, true
+	case int8:
+		return uint64(n), true
+	case int16:
+		return uint64(n), true
+	case int32:
+		return uint64(n), true
+	case int64:
+		return uint64(n), true
+	default:
+		return 0, false
+	}
+}
+
+func metaUintDefault(meta map[string]interface{}, key string, def uint64) uint64 {
+	if v, ok := metaUint(meta, key); ok {
+		return v
+	}
+	return def
+}
+
+func metaFloat(meta map[string]interface{}, key string) (float64, bool) {
+	v, ok := meta[key]
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func metaStringArray(meta map[string]interface{}, key string) ([]string, bool) {
+	v, ok := meta[key]
+	if !ok {
+		return nil, false
+	}
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, 0, len(arr))
+	for _, e := range arr {
+		if s, ok := e.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out, true
+}
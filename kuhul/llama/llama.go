@@ -11,8 +11,13 @@
 package llama
 
 import (
+	"container/heap"
+	"fmt"
 	"math"
+	"math/rand"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/ollama/ollama/kuhul/runtime"
 	"github.com/ollama/ollama/kuhul/scxq2"
@@ -20,21 +25,28 @@ import (
 
 // TokenizerConfig holds tokenizer configuration
 type TokenizerConfig struct {
-	VocabSize    int                `json:"vocab_size"`
-	BOS          int                `json:"bos_token"`
-	EOS          int                `json:"eos_token"`
-	PAD          int                `json:"pad_token"`
-	UNK          int                `json:"unk_token"`
-	Vocab        map[string]int     `json:"vocab"`
-	Merges       [][]string         `json:"merges"`
-	SpecialTokens map[string]int    `json:"special_tokens"`
+	VocabSize     int            `json:"vocab_size"`
+	BOS           int            `json:"bos_token"`
+	EOS           int            `json:"eos_token"`
+	PAD           int            `json:"pad_token"`
+	UNK           int            `json:"unk_token"`
+	Vocab         map[string]int `json:"vocab"`
+	Merges        [][]string     `json:"merges"`
+	SpecialTokens map[string]int `json:"special_tokens"`
 }
 
-// Tokenizer implements BPE tokenization
+// Tokenizer implements byte-level BPE tokenization (the GPT-2/Llama
+// scheme): text is encoded to UTF-8 bytes, each byte is mapped to a
+// printable rune via byteToUnicode so arbitrary bytes survive as an
+// ordinary Go string, and adjacent runes are merged in MergeRank priority
+// order until no eligible pair remains.
 type Tokenizer struct {
 	Config    TokenizerConfig
 	VocabRev  map[int]string
 	MergeRank map[string]int
+
+	cacheMu   sync.RWMutex
+	wordCache map[string][]int
 }
 
 // NewTokenizer creates a new tokenizer
@@ -43,6 +55,7 @@ func NewTokenizer(config TokenizerConfig) *Tokenizer {
 		Config:    config,
 		VocabRev:  make(map[int]string),
 		MergeRank: make(map[string]int),
+		wordCache: make(map[string][]int),
 	}
 
 	// Build reverse vocab
@@ -77,43 +90,269 @@ func (t *Tokenizer) Encode(text string) []int {
 	return tokens
 }
 
-// encodeWord applies BPE to a single word
+// encodeWord applies byte-level BPE to a single word: it starts from the
+// GPT-2 byte-to-unicode mapping of word's UTF-8 bytes, then repeatedly
+// merges the adjacent pair with the lowest MergeRank (a min-heap keyed by
+// rank plus left-hand position avoids rescanning every pair from scratch
+// after each merge) until none of the remaining pairs appears in
+// MergeRank. Results are cached per input word.
 func (t *Tokenizer) encodeWord(word string) []int {
-	// Check if word is in vocab
 	if id, ok := t.Config.Vocab[word]; ok {
 		return []int{id}
 	}
-
-	// Check special tokens
 	if id, ok := t.Config.SpecialTokens[word]; ok {
 		return []int{id}
 	}
 
-	// Apply character-level fallback
-	tokens := make([]int, 0)
-	for _, ch := range word {
-		charStr := string(ch)
-		if id, ok := t.Config.Vocab[charStr]; ok {
+	t.cacheMu.RLock()
+	cached, ok := t.wordCache[word]
+	t.cacheMu.RUnlock()
+	if ok {
+		return cached
+	}
+
+	tokens := t.bpeEncode(word)
+
+	t.cacheMu.Lock()
+	t.wordCache[word] = tokens
+	t.cacheMu.Unlock()
+
+	return tokens
+}
+
+// bpeEncode is encodeWord's uncached core: it merges word's byte symbols
+// and maps the final symbols to vocab ids (falling back to UNK for a
+// symbol the vocab doesn't have, which shouldn't happen with a complete
+// byte-level vocab but keeps Encode total).
+func (t *Tokenizer) bpeEncode(word string) []int {
+	symbols := byteSymbols(word)
+	if len(symbols) == 0 {
+		return []int{}
+	}
+
+	next := make([]int, len(symbols))
+	prev := make([]int, len(symbols))
+	alive := make([]bool, len(symbols))
+	for i := range symbols {
+		next[i] = i + 1
+		prev[i] = i - 1
+		alive[i] = true
+	}
+	next[len(symbols)-1] = -1
+
+	pending := &bpePairHeap{}
+	pushPair := func(i int) {
+		j := next[i]
+		if j < 0 {
+			return
+		}
+		if rank, ok := t.MergeRank[symbols[i]+" "+symbols[j]]; ok {
+			heap.Push(pending, bpePair{left: i, rank: rank, leftText: symbols[i], rightText: symbols[j]})
+		}
+	}
+	for i := 0; i < len(symbols)-1; i++ {
+		pushPair(i)
+	}
+
+	for pending.Len() > 0 {
+		top := heap.Pop(pending).(bpePair)
+		i := top.left
+		j := next[i]
+		// A pop can be stale if either symbol already merged with a
+		// different neighbour since this pair was pushed; the left/right
+		// text captured at push time lets us tell without a rescan.
+		if !alive[i] || j < 0 || symbols[i] != top.leftText || symbols[j] != top.rightText {
+			continue
+		}
+
+		symbols[i] += symbols[j]
+		alive[j] = false
+		next[i] = next[j]
+		if next[i] >= 0 {
+			prev[next[i]] = i
+		}
+
+		if p := prev[i]; p >= 0 {
+			pushPair(p)
+		}
+		pushPair(i)
+	}
+
+	tokens := make([]int, 0, len(symbols))
+	for i := 0; i >= 0; i = next[i] {
+		if id, ok := t.Config.Vocab[symbols[i]]; ok {
 			tokens = append(tokens, id)
 		} else {
 			tokens = append(tokens, t.Config.UNK)
 		}
 	}
+	return tokens
+}
+
+// EncodeWithSpecial tokenizes text like Encode, except any substring that
+// exactly matches a SpecialTokens entry is emitted as that single token id
+// instead of being run through byte-level BPE — so "<|begin_of_text|>"
+// appearing inside a prompt isn't split into its bytes — and optionally
+// wraps the result in BOS/EOS.
+func (t *Tokenizer) EncodeWithSpecial(text string, addBOS, addEOS bool) []int {
+	specials := make([]string, 0, len(t.Config.SpecialTokens))
+	for s := range t.Config.SpecialTokens {
+		specials = append(specials, s)
+	}
+	// Longest-first so one special token can't shadow a longer one that
+	// contains it as a prefix.
+	sort.Slice(specials, func(i, j int) bool { return len(specials[i]) > len(specials[j]) })
+
+	tokens := make([]int, 0)
+	if addBOS {
+		tokens = append(tokens, t.Config.BOS)
+	}
 
+	remaining := text
+	for remaining != "" {
+		if id, s, ok := matchSpecialPrefix(remaining, specials, t.Config.SpecialTokens); ok {
+			tokens = append(tokens, id)
+			remaining = remaining[len(s):]
+			continue
+		}
+
+		cut := len(remaining)
+		for _, s := range specials {
+			if idx := strings.Index(remaining, s); idx >= 0 && idx < cut {
+				cut = idx
+			}
+		}
+		if cut == 0 {
+			cut = len(remaining)
+		}
+		tokens = append(tokens, t.Encode(remaining[:cut])...)
+		remaining = remaining[cut:]
+	}
+
+	if addEOS {
+		tokens = append(tokens, t.Config.EOS)
+	}
 	return tokens
 }
 
-// Decode converts token IDs back to text
+// matchSpecialPrefix returns the id and text of the first entry of
+// specials (already sorted longest-first) that remaining starts with.
+func matchSpecialPrefix(remaining string, specials []string, ids map[string]int) (int, string, bool) {
+	for _, s := range specials {
+		if strings.HasPrefix(remaining, s) {
+			return ids[s], s, true
+		}
+	}
+	return 0, "", false
+}
+
+// Decode converts token IDs back to text, reversing the GPT-2
+// byte-to-unicode mapping encodeWord applied so merged BPE tokens decode
+// back to the original UTF-8 bytes instead of being joined as opaque,
+// space-separated symbol strings.
 func (t *Tokenizer) Decode(tokens []int) string {
-	parts := make([]string, 0, len(tokens))
+	raw := make([]byte, 0, len(tokens)*2)
 
 	for _, id := range tokens {
-		if token, ok := t.VocabRev[id]; ok {
-			parts = append(parts, token)
+		token, ok := t.VocabRev[id]
+		if !ok {
+			continue
 		}
+		for _, r := range token {
+			if b, ok := unicodeToByte[r]; ok {
+				raw = append(raw, b)
+			} else {
+				raw = append(raw, string(r)...)
+			}
+		}
+	}
+
+	return string(raw)
+}
+
+// byteToUnicode is GPT-2's byte-to-printable-rune table: every one of the
+// 256 possible byte values maps to a rune that's printable and unambiguous
+// on its own, so a word's raw UTF-8 bytes can be carried through as an
+// ordinary Go string (and through MergeRank's map keys) without any byte
+// value colliding with whitespace or a merge-rule separator.
+var byteToUnicode = buildByteToUnicode()
+var unicodeToByte = buildUnicodeToByte()
+
+func buildByteToUnicode() map[byte]rune {
+	printable := map[int]bool{}
+	for b := '!'; b <= '~'; b++ {
+		printable[int(b)] = true
+	}
+	for b := 0xA1; b <= 0xAC; b++ {
+		printable[b] = true
+	}
+	for b := 0xAE; b <= 0xFF; b++ {
+		printable[b] = true
 	}
 
-	return strings.Join(parts, " ")
+	m := make(map[byte]rune, 256)
+	next := 0x100
+	for b := 0; b < 256; b++ {
+		if printable[b] {
+			m[byte(b)] = rune(b)
+		} else {
+			m[byte(b)] = rune(next)
+			next++
+		}
+	}
+	return m
+}
+
+func buildUnicodeToByte() map[rune]byte {
+	m := make(map[rune]byte, 256)
+	for b, r := range byteToUnicode {
+		m[r] = b
+	}
+	return m
+}
+
+// byteSymbols maps word's UTF-8 bytes to one-rune strings via
+// byteToUnicode, the starting point for bpeEncode's merge loop.
+func byteSymbols(word string) []string {
+	b := []byte(word)
+	symbols := make([]string, len(b))
+	for i, by := range b {
+		symbols[i] = string(byteToUnicode[by])
+	}
+	return symbols
+}
+
+// bpePair is one candidate adjacent-symbol merge in bpeEncode's heap:
+// left is the index (into bpeEncode's symbols slice) of the pair's first
+// symbol, and leftText/rightText are what that symbol and its successor
+// held when this pair was pushed — if either has since changed, the pair
+// popped off the heap is stale and must be skipped rather than re-merged.
+type bpePair struct {
+	left      int
+	rank      int
+	leftText  string
+	rightText string
+}
+
+type bpePairHeap []bpePair
+
+func (h bpePairHeap) Len() int { return len(h) }
+func (h bpePairHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	return h[i].left < h[j].left
+}
+func (h bpePairHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *bpePairHeap) Push(x interface{}) {
+	*h = append(*h, x.(bpePair))
+}
+func (h *bpePairHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
 }
 
 // Fingerprint generates an SCXQ2 fingerprint for tokens
@@ -123,35 +362,117 @@ func (t *Tokenizer) Fingerprint(tokens []int) string {
 
 // AttentionConfig holds attention layer configuration
 type AttentionConfig struct {
-	NumHeads   int     `json:"num_heads"`
+	NumHeads int `json:"num_heads"`
+	// NumKVHeads is the number of distinct key/value heads. Real Llama
+	// checkpoints often set this below NumHeads (grouped-query attention):
+	// each group of NumHeads/NumKVHeads query heads shares one K/V head.
+	// Zero means "same as NumHeads" (plain multi-head attention, the mock
+	// model's case).
+	NumKVHeads int     `json:"num_kv_heads"`
 	HeadDim    int     `json:"head_dim"`
 	HiddenSize int     `json:"hidden_size"`
 	Dropout    float64 `json:"dropout"`
 }
 
+// numKVHeads returns NumKVHeads, defaulting to NumHeads when unset.
+func (c AttentionConfig) numKVHeads() int {
+	if c.NumKVHeads > 0 {
+		return c.NumKVHeads
+	}
+	return c.NumHeads
+}
+
 // Attention implements multi-head attention
 type Attention struct {
 	Config AttentionConfig
-	WQ     [][]float64 // Query weights
-	WK     [][]float64 // Key weights
-	WV     [][]float64 // Value weights
-	WO     [][]float64 // Output projection
+	WQ     *Weight // Query weights
+	WK     *Weight // Key weights
+	WV     *Weight // Value weights
+	WO     *Weight // Output projection
 }
 
 // NewAttention creates a new attention layer
 func NewAttention(config AttentionConfig) *Attention {
 	dim := config.HiddenSize
+	kvDim := config.numKVHeads() * config.HeadDim
 	return &Attention{
 		Config: config,
-		WQ:     makeMatrix(dim, dim),
-		WK:     makeMatrix(dim, dim),
-		WV:     makeMatrix(dim, dim),
-		WO:     makeMatrix(dim, dim),
+		WQ:     newDenseWeight(makeMatrix(dim, dim)),
+		WK:     newDenseWeight(makeMatrix(dim, kvDim)),
+		WV:     newDenseWeight(makeMatrix(dim, kvDim)),
+		WO:     newDenseWeight(makeMatrix(dim, dim)),
+	}
+}
+
+// RopeTable holds precomputed rotary position embedding angles for every
+// position up to MaxSeqLen, one cos/sin pair per dimension-pair within a
+// head: freq_i = 1 / RopeTheta^(2i/HeadDim), angle = pos * freq_i.
+type RopeTable struct {
+	Cos [][]float64 // [MaxSeqLen][HeadDim/2]
+	Sin [][]float64
+}
+
+// NewRopeTable precomputes a RopeTable for the given sequence length, head
+// dimension, and base (ModelConfig.RopeTheta).
+func NewRopeTable(maxSeqLen, headDim int, theta float64) *RopeTable {
+	half := headDim / 2
+	cos := make([][]float64, maxSeqLen)
+	sin := make([][]float64, maxSeqLen)
+	for pos := 0; pos < maxSeqLen; pos++ {
+		cos[pos] = make([]float64, half)
+		sin[pos] = make([]float64, half)
+		for i := 0; i < half; i++ {
+			freq := 1.0 / math.Pow(theta, float64(2*i)/float64(headDim))
+			angle := float64(pos) * freq
+			cos[pos][i] = math.Cos(angle)
+			sin[pos][i] = math.Sin(angle)
+		}
 	}
+	return &RopeTable{Cos: cos, Sin: sin}
 }
 
-// Forward computes attention
-func (a *Attention) Forward(x [][]float64, mask [][]float64) [][]float64 {
+// applyRope rotates vec (one head's Q or K row, length HeadDim) in place
+// for position pos, pairing dimension i with i+half the standard
+// "rotate-half" way, via the active Backend.
+func applyRope(vec []float64, pos int, table *RopeTable) {
+	activeBackend.RoPE(vec, pos, table)
+}
+
+// KVCache holds one transformer block's cached key/value rows (already
+// rotated by RoPE) across a multi-step Generate call, so token N+1 only
+// computes its own Q/K/V row instead of reprocessing every earlier
+// position.
+type KVCache struct {
+	K [][]float64
+	V [][]float64
+}
+
+// GenerationState threads the per-block KVCaches through an autoregressive
+// Generate call, alongside Pos, the sequence position the next Forward
+// call should rotate its rows from.
+type GenerationState struct {
+	Pos    int
+	Caches []*KVCache // one per LlamaModel.Blocks, same order
+}
+
+// NewGenerationState allocates an empty KVCache per transformer block.
+func NewGenerationState(numLayers int) *GenerationState {
+	caches := make([]*KVCache, numLayers)
+	for i := range caches {
+		caches[i] = &KVCache{}
+	}
+	return &GenerationState{Caches: caches}
+}
+
+// Forward computes multi-head scaled dot-product attention over x (seqLen
+// new rows starting at sequence position pos): it rotates each head's Q/K
+// row with rope, appends the rotated K/V to cache (if given — a nil cache
+// means x is the whole sequence and nothing needs to persist afterward),
+// and attends each row causally over everything cached so far plus its own
+// preceding rows in this call. When Config.NumKVHeads is below NumHeads
+// (grouped-query attention), each group of NumHeads/NumKVHeads query heads
+// reads from the same K/V head.
+func (a *Attention) Forward(x [][]float64, mask [][]float64, rope *RopeTable, pos int, cache *KVCache) [][]float64 {
 	seqLen := len(x)
 	if seqLen == 0 {
 		return x
@@ -160,96 +481,121 @@ func (a *Attention) Forward(x [][]float64, mask [][]float64) [][]float64 {
 	dim := a.Config.HiddenSize
 	headDim := a.Config.HeadDim
 	numHeads := a.Config.NumHeads
-
-	// Project Q, K, V
-	Q := matmul(x, a.WQ)
-	K := matmul(x, a.WK)
-	V := matmul(x, a.WV)
-
-	// Scaled dot-product attention
-	scale := 1.0 / math.Sqrt(float64(headDim))
-
-	// Compute attention scores
-	scores := make([][]float64, seqLen)
-	for i := 0; i < seqLen; i++ {
-		scores[i] = make([]float64, seqLen)
-		for j := 0; j < seqLen; j++ {
-			dot := 0.0
-			for k := 0; k < dim; k++ {
-				dot += Q[i][k] * K[j][k]
-			}
-			scores[i][j] = dot * scale
-
-			// Apply mask if provided
-			if mask != nil && mask[i][j] < 0 {
-				scores[i][j] = math.Inf(-1)
-			}
+	kvHeads := a.Config.numKVHeads()
+	groupSize := numHeads / kvHeads
+
+	Q := a.WQ.MatMul(x)
+	K := a.WK.MatMul(x)
+	V := a.WV.MatMul(x)
+
+	for row := 0; row < seqLen; row++ {
+		position := pos + row
+		for h := 0; h < numHeads; h++ {
+			start := h * headDim
+			applyRope(Q[row][start:start+headDim], position, rope)
+		}
+		for h := 0; h < kvHeads; h++ {
+			start := h * headDim
+			applyRope(K[row][start:start+headDim], position, rope)
 		}
 	}
 
-	// Apply softmax
-	for i := 0; i < seqLen; i++ {
-		scores[i] = softmax(scores[i])
+	prevLen := 0
+	allK, allV := K, V
+	if cache != nil {
+		prevLen = len(cache.K)
+		cache.K = append(cache.K, K...)
+		cache.V = append(cache.V, V...)
+		allK, allV = cache.K, cache.V
 	}
 
-	// Compute weighted sum of values
-	output := make([][]float64, seqLen)
-	for i := 0; i < seqLen; i++ {
-		output[i] = make([]float64, dim)
-		for j := 0; j < seqLen; j++ {
-			for k := 0; k < dim; k++ {
-				output[i][k] += scores[i][j] * V[j][k]
+	scale := 1.0 / math.Sqrt(float64(headDim))
+	// output never escapes this call (a.WO.MatMul below copies it into a
+	// fresh result), so it's borrowed from attnArena instead of allocated
+	// fresh on every autoregressive step.
+	output := attnArena.get(seqLen, dim)
+	defer attnArena.put(output)
+
+	for h := 0; h < numHeads; h++ {
+		qStart := h * headDim
+		kvStart := (h / groupSize) * headDim
+		for row := 0; row < seqLen; row++ {
+			q := Q[row][qStart : qStart+headDim]
+			limit := prevLen + row + 1 // causal: attend to cache plus own preceding rows
+
+			scores := scoresArena.get(limit)
+			for j := 0; j < limit; j++ {
+				k := allK[j][kvStart : kvStart+headDim]
+				dot := 0.0
+				for d := 0; d < headDim; d++ {
+					dot += q[d] * k[d]
+				}
+				scores[j] = dot * scale
+				if mask != nil && row < len(mask) && j < len(mask[row]) && mask[row][j] < 0 {
+					scores[j] = math.Inf(-1)
+				}
+			}
+			scores = activeBackend.Softmax(scores)
+
+			out := output[row][qStart : qStart+headDim]
+			for j := 0; j < limit; j++ {
+				v := allV[j][kvStart : kvStart+headDim]
+				w := scores[j]
+				for d := 0; d < headDim; d++ {
+					out[d] += w * v[d]
+				}
 			}
+			scoresArena.put(scores)
 		}
 	}
 
-	// Output projection
-	output = matmul(output, a.WO)
-
-	_ = headDim
-	_ = numHeads
-
-	return output
+	return a.WO.MatMul(output)
 }
 
 // FFNConfig holds feed-forward network configuration
 type FFNConfig struct {
-	HiddenSize      int     `json:"hidden_size"`
+	HiddenSize       int    `json:"hidden_size"`
 	IntermediateSize int    `json:"intermediate_size"`
-	Activation      string  `json:"activation"`
+	Activation       string `json:"activation"`
 }
 
 // FFN implements the feed-forward network
 type FFN struct {
 	Config FFNConfig
-	W1     [][]float64 // First projection
-	W2     [][]float64 // Second projection
-	W3     [][]float64 // Gate projection (for SwiGLU)
+	W1     *Weight // First projection (ffn_up)
+	W2     *Weight // Second projection (ffn_down)
+	W3     *Weight // Gate projection (ffn_gate, for SwiGLU)
 }
 
 // NewFFN creates a new FFN layer
 func NewFFN(config FFNConfig) *FFN {
 	return &FFN{
 		Config: config,
-		W1:     makeMatrix(config.HiddenSize, config.IntermediateSize),
-		W2:     makeMatrix(config.IntermediateSize, config.HiddenSize),
-		W3:     makeMatrix(config.HiddenSize, config.IntermediateSize),
+		W1:     newDenseWeight(makeMatrix(config.HiddenSize, config.IntermediateSize)),
+		W2:     newDenseWeight(makeMatrix(config.IntermediateSize, config.HiddenSize)),
+		W3:     newDenseWeight(makeMatrix(config.HiddenSize, config.IntermediateSize)),
 	}
 }
 
-// Forward computes FFN output
+// Forward computes FFN output. h (and, for SwiGLU, gate) are seqLen x
+// IntermediateSize — borrowed from ffnArena rather than allocated fresh,
+// since autoregressive generation calls Forward once per token and neither
+// buffer is needed past this call (the final W2 projection copies out of
+// h into a freshly allocated, much smaller seqLen x HiddenSize result).
 func (f *FFN) Forward(x [][]float64) [][]float64 {
 	seqLen := len(x)
 	if seqLen == 0 {
 		return x
 	}
 
-	// First projection with activation
-	h := matmul(x, f.W1)
+	h := ffnArena.get(seqLen, f.Config.IntermediateSize)
+	defer ffnArena.put(h)
+	f.W1.MatMulInto(x, h)
 
-	// Apply SwiGLU activation
 	if f.Config.Activation == "silu" || f.Config.Activation == "swiglu" {
-		gate := matmul(x, f.W3)
+		gate := ffnArena.get(seqLen, f.Config.IntermediateSize)
+		defer ffnArena.put(gate)
+		f.W3.MatMulInto(x, gate)
 		for i := range h {
 			for j := range h[i] {
 				h[i][j] = silu(h[i][j]) * gate[i][j]
@@ -264,8 +610,7 @@ func (f *FFN) Forward(x [][]float64) [][]float64 {
 		}
 	}
 
-	// Second projection
-	return matmul(h, f.W2)
+	return f.W2.MatMul(h)
 }
 
 // TransformerBlock represents a single transformer block
@@ -276,11 +621,46 @@ type TransformerBlock struct {
 	LNFFN     *LayerNorm
 }
 
-// LayerNorm implements layer normalization
+// Forward runs one pre-norm transformer block over x (seqLen rows starting
+// at sequence position pos): LNAttn -> Attention with its residual, then
+// LNFFN -> FFN with its residual. cache is this block's KVCache (nil for a
+// one-shot forward pass with nothing to persist afterward).
+func (b *TransformerBlock) Forward(x [][]float64, rope *RopeTable, pos int, cache *KVCache) [][]float64 {
+	normed := make([][]float64, len(x))
+	for i, row := range x {
+		normed[i] = b.LNAttn.Forward(row)
+	}
+
+	attnOut := b.Attention.Forward(normed, nil, rope, pos, cache)
+	for i := range attnOut {
+		for j := range attnOut[i] {
+			attnOut[i][j] += x[i][j]
+		}
+	}
+
+	normed2 := make([][]float64, len(attnOut))
+	for i, row := range attnOut {
+		normed2[i] = b.LNFFN.Forward(row)
+	}
+
+	ffnOut := b.FFN.Forward(normed2)
+	for i := range ffnOut {
+		for j := range ffnOut[i] {
+			ffnOut[i][j] += attnOut[i][j]
+		}
+	}
+
+	return ffnOut
+}
+
+// LayerNorm implements layer normalization, or, when RMS is set, RMSNorm
+// (the norm real Llama checkpoints use: magnitude-only, no mean-centering
+// or Beta shift).
 type LayerNorm struct {
 	Gamma []float64
 	Beta  []float64
 	Eps   float64
+	RMS   bool
 }
 
 // NewLayerNorm creates a new layer norm
@@ -297,36 +677,13 @@ func NewLayerNorm(dim int) *LayerNorm {
 	}
 }
 
-// Forward applies layer normalization
+// Forward applies layer normalization (or RMSNorm, if ln.RMS) via the
+// active Backend.
 func (ln *LayerNorm) Forward(x []float64) []float64 {
-	n := len(x)
-	if n == 0 {
-		return x
-	}
-
-	// Compute mean
-	mean := 0.0
-	for _, v := range x {
-		mean += v
-	}
-	mean /= float64(n)
-
-	// Compute variance
-	variance := 0.0
-	for _, v := range x {
-		diff := v - mean
-		variance += diff * diff
-	}
-	variance /= float64(n)
-
-	// Normalize
-	result := make([]float64, n)
-	std := math.Sqrt(variance + ln.Eps)
-	for i, v := range x {
-		result[i] = (v-mean)/std*ln.Gamma[i] + ln.Beta[i]
+	if ln.RMS {
+		return activeBackend.RMSNorm(x, ln.Gamma, ln.Eps)
 	}
-
-	return result
+	return activeBackend.LayerNorm(x, ln.Gamma, ln.Beta, ln.Eps)
 }
 
 // InferenceConfig holds inference configuration
@@ -351,22 +708,26 @@ func DefaultInferenceConfig() InferenceConfig {
 type LlamaModel struct {
 	Tokenizer *Tokenizer
 	Blocks    []*TransformerBlock
-	Embed     [][]float64  // Token embeddings
+	Embed     *Weight // Token embeddings, one row per vocab entry
 	LNFinal   *LayerNorm
-	LMHead    [][]float64  // Output projection
+	LMHead    *Weight // Output projection
 	Config    ModelConfig
+	Rope      *RopeTable
 }
 
 // ModelConfig holds model configuration
 type ModelConfig struct {
-	VocabSize        int    `json:"vocab_size"`
-	HiddenSize       int    `json:"hidden_size"`
-	IntermediateSize int    `json:"intermediate_size"`
-	NumLayers        int    `json:"num_layers"`
-	NumHeads         int    `json:"num_heads"`
-	HeadDim          int    `json:"head_dim"`
-	MaxSeqLen        int    `json:"max_seq_len"`
-	RopeTheta        float64 `json:"rope_theta"`
+	VocabSize        int `json:"vocab_size"`
+	HiddenSize       int `json:"hidden_size"`
+	IntermediateSize int `json:"intermediate_size"`
+	NumLayers        int `json:"num_layers"`
+	NumHeads         int `json:"num_heads"`
+	// NumKVHeads is the number of key/value heads; zero means "same as
+	// NumHeads". See AttentionConfig.NumKVHeads.
+	NumKVHeads int     `json:"num_kv_heads"`
+	HeadDim    int     `json:"head_dim"`
+	MaxSeqLen  int     `json:"max_seq_len"`
+	RopeTheta  float64 `json:"rope_theta"`
 }
 
 // NewLlamaModel creates a mock Llama model
@@ -386,18 +747,21 @@ func NewLlamaModel(config ModelConfig) *LlamaModel {
 		},
 	}
 
-	// Add basic vocab
-	for i := 0; i < 256; i++ {
-		tokConfig.Vocab[string(rune(i))] = i + 4
+	// Add the 256 single-byte tokens every byte-level BPE vocab needs,
+	// keyed by their GPT-2 byte-to-unicode rune so encodeWord/Decode can
+	// round-trip through them like a real checkpoint's vocab.
+	for b := 0; b < 256; b++ {
+		tokConfig.Vocab[string(byteToUnicode[byte(b)])] = b + 4
 	}
 
 	model := &LlamaModel{
 		Tokenizer: NewTokenizer(tokConfig),
 		Blocks:    make([]*TransformerBlock, config.NumLayers),
-		Embed:     makeMatrix(config.VocabSize, config.HiddenSize),
+		Embed:     newDenseWeight(makeMatrix(config.VocabSize, config.HiddenSize)),
 		LNFinal:   NewLayerNorm(config.HiddenSize),
-		LMHead:    makeMatrix(config.HiddenSize, config.VocabSize),
+		LMHead:    newDenseWeight(makeMatrix(config.HiddenSize, config.VocabSize)),
 		Config:    config,
+		Rope:      NewRopeTable(config.MaxSeqLen, config.HeadDim, config.RopeTheta),
 	}
 
 	// Create transformer blocks
@@ -405,6 +769,7 @@ func NewLlamaModel(config ModelConfig) *LlamaModel {
 		model.Blocks[i] = &TransformerBlock{
 			Attention: NewAttention(AttentionConfig{
 				NumHeads:   config.NumHeads,
+				NumKVHeads: config.NumKVHeads,
 				HeadDim:    config.HeadDim,
 				HiddenSize: config.HiddenSize,
 			}),
@@ -421,30 +786,138 @@ func NewLlamaModel(config ModelConfig) *LlamaModel {
 	return model
 }
 
-// Generate generates tokens given input
-func (m *LlamaModel) Generate(input string, config InferenceConfig) (string, error) {
-	// Tokenize input
-	tokens := m.Tokenizer.Encode(input)
+// forward embeds tokenIDs, runs them through every TransformerBlock (each
+// reading/writing its slot of state.Caches) starting at sequence position
+// pos, applies LNFinal, and projects through LMHead — one row of logits per
+// input token.
+func (m *LlamaModel) forward(tokenIDs []int, pos int, state *GenerationState) [][]float64 {
+	x := make([][]float64, len(tokenIDs))
+	for i, id := range tokenIDs {
+		if id < 0 {
+			id = 0
+		} else if id >= m.Config.VocabSize {
+			id = m.Config.VocabSize - 1
+		}
+		x[i] = m.Embed.Row(id)
+	}
 
-	// Add BOS token
-	tokens = append([]int{m.Config.VocabSize + 1}, tokens...)
+	for li, block := range m.Blocks {
+		x = block.Forward(x, m.Rope, pos, state.Caches[li])
+	}
 
-	// Generate tokens (mock)
-	generated := make([]int, 0)
-	for i := 0; i < config.MaxTokens; i++ {
-		// Get next token (mock - just repeat pattern)
-		nextToken := (tokens[len(tokens)-1] + i) % m.Tokenizer.Config.VocabSize
-		generated = append(generated, nextToken)
+	normed := make([][]float64, len(x))
+	for i, row := range x {
+		normed[i] = m.LNFinal.Forward(row)
+	}
+
+	return m.LMHead.MatMul(normed)
+}
+
+// Generate runs real autoregressive decoding: the prompt is embedded and
+// run through every TransformerBlock in one prefill pass (populating each
+// block's KVCache), then one token at a time — sampled per config's
+// Temperature/TopK/TopP — is fed back in as the next single-row forward
+// call, so the prefix is never reprocessed.
+func (m *LlamaModel) Generate(input string, config InferenceConfig) (string, error) {
+	tokens := m.Tokenizer.EncodeWithSpecial(input, true, false)
+	if len(tokens) == 0 {
+		return "", nil
+	}
+	if len(tokens) > m.Config.MaxSeqLen {
+		return "", fmt.Errorf("llama: prompt of %d tokens exceeds max sequence length %d", len(tokens), m.Config.MaxSeqLen)
+	}
 
-		// Check for EOS
-		if nextToken == m.Tokenizer.Config.EOS {
+	state := NewGenerationState(len(m.Blocks))
+	logits := m.forward(tokens, 0, state)
+	pos := len(tokens)
+	state.Pos = pos
+	next := sampleToken(logits[len(logits)-1], config)
+
+	// pos is also the RopeTable index every forward call below rotates its
+	// new row from (see Attention.Forward), and that table only has
+	// MaxSeqLen entries, so generation must stop at the context boundary
+	// even if MaxTokens asks for more.
+	generated := make([]int, 0, config.MaxTokens)
+	for i := 0; i < config.MaxTokens && pos < m.Config.MaxSeqLen; i++ {
+		generated = append(generated, next)
+		if next == m.Tokenizer.Config.EOS || i == config.MaxTokens-1 {
 			break
 		}
+
+		logits = m.forward([]int{next}, pos, state)
+		pos++
+		state.Pos = pos
+		next = sampleToken(logits[0], config)
+	}
+
+	return m.Tokenizer.Decode(generated), nil
+}
+
+// llamaCandidate is one vocabulary entry under consideration in
+// sampleToken's top-k/top-p narrowing.
+type llamaCandidate struct {
+	id    int
+	logit float64
+}
+
+// sampleToken draws one token id from a row of logits: scale by
+// 1/Temperature, keep only the TopK highest, soften to probabilities, then
+// keep the smallest nucleus whose cumulative probability reaches TopP
+// (renormalizing over just that nucleus) before drawing.
+func sampleToken(logits []float64, config InferenceConfig) int {
+	if len(logits) == 0 {
+		return 0
+	}
+
+	temp := config.Temperature
+	if temp <= 0 {
+		temp = 1.0
+	}
+
+	candidates := make([]llamaCandidate, len(logits))
+	for i, v := range logits {
+		candidates[i] = llamaCandidate{id: i, logit: v / temp}
 	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].logit > candidates[j].logit })
 
-	// Decode output
-	output := m.Tokenizer.Decode(generated)
-	return output, nil
+	if config.TopK > 0 && config.TopK < len(candidates) {
+		candidates = candidates[:config.TopK]
+	}
+
+	probs := activeBackend.Softmax(candidateLogits(candidates))
+	if config.TopP > 0 && config.TopP < 1.0 {
+		cum := 0.0
+		cutoff := len(probs)
+		for i, p := range probs {
+			cum += p
+			if cum >= config.TopP {
+				cutoff = i + 1
+				break
+			}
+		}
+		candidates = candidates[:cutoff]
+		probs = activeBackend.Softmax(candidateLogits(candidates))
+	}
+
+	r := rand.Float64()
+	cum := 0.0
+	for i, p := range probs {
+		cum += p
+		if r <= cum {
+			return candidates[i].id
+		}
+	}
+	return candidates[len(candidates)-1].id
+}
+
+// candidateLogits extracts the scaled logits sampleToken's softmax needs
+// from a (possibly already top-k/top-p narrowed) candidate slice.
+func candidateLogits(candidates []llamaCandidate) []float64 {
+	logits := make([]float64, len(candidates))
+	for i, c := range candidates {
+		logits[i] = c.logit
+	}
+	return logits
 }
 
 // RegisterLlamaHandlers registers Llama handlers with the runtime
@@ -454,7 +927,7 @@ func RegisterLlamaHandlers(state *runtime.RuntimeState) {
 		VocabSize:        32000,
 		HiddenSize:       4096,
 		IntermediateSize: 11008,
-		NumLayers:        2,    // Use fewer layers for mock
+		NumLayers:        2, // Use fewer layers for mock
 		NumHeads:         32,
 		HeadDim:          128,
 		MaxSeqLen:        4096,
@@ -509,6 +982,12 @@ func RegisterLlamaHandlers(state *runtime.RuntimeState) {
 			if maxTok, ok := ctx.Body["max_tokens"].(float64); ok {
 				inferConfig.MaxTokens = int(maxTok)
 			}
+			if topK, ok := ctx.Body["top_k"].(float64); ok {
+				inferConfig.TopK = int(topK)
+			}
+			if topP, ok := ctx.Body["top_p"].(float64); ok {
+				inferConfig.TopP = topP
+			}
 
 			output, err := model.Generate(prompt, inferConfig)
 			if err != nil {
@@ -553,56 +1032,6 @@ func makeMatrix(rows, cols int) [][]float64 {
 	return mat
 }
 
-func matmul(a, b [][]float64) [][]float64 {
-	if len(a) == 0 || len(b) == 0 {
-		return nil
-	}
-
-	m, k := len(a), len(a[0])
-	n := len(b[0])
-
-	result := make([][]float64, m)
-	for i := range result {
-		result[i] = make([]float64, n)
-		for j := 0; j < n; j++ {
-			for l := 0; l < k && l < len(b); l++ {
-				result[i][j] += a[i][l] * b[l][j]
-			}
-		}
-	}
-
-	return result
-}
-
-func softmax(x []float64) []float64 {
-	if len(x) == 0 {
-		return x
-	}
-
-	// Find max for numerical stability
-	max := x[0]
-	for _, v := range x[1:] {
-		if v > max {
-			max = v
-		}
-	}
-
-	// Compute exp and sum
-	result := make([]float64, len(x))
-	sum := 0.0
-	for i, v := range x {
-		result[i] = math.Exp(v - max)
-		sum += result[i]
-	}
-
-	// Normalize
-	for i := range result {
-		result[i] /= sum
-	}
-
-	return result
-}
-
 func silu(x float64) float64 {
 	return x / (1.0 + math.Exp(-x))
 }
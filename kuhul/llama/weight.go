@@ -0,0 +1,289 @@
+package llama
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+)
+
+// Weight is one matrix used in a matmul: either already-expanded float64
+// rows (every weight NewLlamaModel's mock model builds) or a still-quantized
+// GGUF tensor, whose
+// rows are dequantized into a reused scratch buffer only when MatMul
+// actually needs them rather than being expanded up front — the point of
+// LoadGGUF's on-demand dequantization for Q4_K_M-sized checkpoints, where
+// eagerly expanding every tensor to float64 would multiply memory use by
+// roughly 16x.
+type Weight struct {
+	dense [][]float64 // non-nil for an already-expanded weight; shape [in][out]
+	rows  int         // == len(dense), kept so OutDim/InDim don't need dense[0]
+	cols  int         // == len(dense[0])
+
+	denseTOnce sync.Once
+	denseT     [][]float64 // dense transposed ([out][in]); built once, lazily, by MatMul
+
+	quant *quantTensor // non-nil for a still-quantized GGUF tensor
+}
+
+// newDenseWeight wraps an already-expanded [in][out] matrix, the shape
+// makeMatrix has always used in this package.
+func newDenseWeight(m [][]float64) *Weight {
+	cols := 0
+	if len(m) > 0 {
+		cols = len(m[0])
+	}
+	return &Weight{dense: m, rows: len(m), cols: cols}
+}
+
+// newQuantWeight wraps a still-quantized GGUF tensor.
+func newQuantWeight(q *quantTensor) *Weight {
+	return &Weight{quant: q}
+}
+
+// MatMul computes a x w. When w wraps a quantized tensor, each output
+// column is dequantized once into a scratch row and reused across every
+// row of a, rather than expanding the whole tensor to float64 first. A
+// dense w transposes itself once, the first time it's ever used, and
+// keeps that transposed copy so every later call pays only MatMulT's
+// cost instead of re-transposing on every forward pass.
+func (w *Weight) MatMul(a [][]float64) [][]float64 {
+	if len(a) == 0 {
+		return nil
+	}
+	out := make([][]float64, len(a))
+	for i := range out {
+		out[i] = make([]float64, w.OutDim())
+	}
+	w.MatMulInto(a, out)
+	return out
+}
+
+// MatMulInto computes a x w into out (out must already be shaped
+// [len(a)][w.OutDim()]), for callers — FFN.Forward's hidden/gate buffers —
+// that supply their own (typically arena-borrowed) output matrix instead
+// of asking MatMul to allocate one.
+func (w *Weight) MatMulInto(a, out [][]float64) {
+	if w.quant != nil {
+		res := w.quant.matmul(a)
+		for i := range out {
+			copy(out[i], res[i])
+		}
+		return
+	}
+	if len(a) == 0 || w.rows == 0 {
+		return
+	}
+	w.denseTOnce.Do(func() { w.denseT = transposeOnce(w.dense) })
+	activeBackend.MatMulT(a, w.denseT, out)
+}
+
+// Row returns the hidden-dim vector for entry id of a table laid out one
+// row per entry (an embedding table used with Embed.Row, or — since
+// embedding and output-projection tensors share the same natural GGUF
+// layout — the equivalent row out of a tied LMHead). A quantized table
+// dequantizes just that one row; a dense table copies it.
+func (w *Weight) Row(id int) []float64 {
+	if w.quant != nil {
+		row := make([]float64, w.quant.inDim)
+		w.quant.dequantRow(id, row)
+		return row
+	}
+	return append([]float64(nil), w.dense[id]...)
+}
+
+// OutDim reports how many columns a MatMul against this weight produces.
+func (w *Weight) OutDim() int {
+	if w.quant != nil {
+		return w.quant.outDim
+	}
+	return w.cols
+}
+
+// ggufTensorType identifies a GGUF tensor's on-disk storage format (the
+// subset of ggml's type enum LoadGGUF understands).
+type ggufTensorType uint32
+
+const (
+	ggufTensorF32  ggufTensorType = 0
+	ggufTensorF16  ggufTensorType = 1
+	ggufTensorQ8_0 ggufTensorType = 8
+	ggufTensorQ4_K ggufTensorType = 12
+)
+
+const (
+	q8_0BlockElems = 32
+	q8_0BlockBytes = 2 + q8_0BlockElems // f16 scale + 32 int8 quants
+
+	q4_KSuperBlockElems = 256
+	q4_KSuperBlockBytes = 2 + 2 + 12 + 128 // d, dmin (f16 each) + packed scales/mins + 4-bit quants
+)
+
+// quantTensor is one still-quantized GGUF tensor in its natural [outDim]
+// rows of [inDim] storage (ggml's row-major layout: the fastest-varying
+// dimension, ne[0], is inDim). dequantRow expands exactly one output row at
+// a time, which is also exactly the output column quantTensor.matmul needs
+// out of a [in][out] weight — no transpose is needed to use it that way.
+type quantTensor struct {
+	kind     ggufTensorType
+	data     []byte // mapped bytes starting at row 0 and running to the end of the file; only the first rowBytes*outDim are read
+	inDim    int
+	outDim   int
+	rowBytes int
+}
+
+func newQuantTensor(kind ggufTensorType, data []byte, inDim, outDim int) *quantTensor {
+	return &quantTensor{
+		kind:     kind,
+		data:     data,
+		inDim:    inDim,
+		outDim:   outDim,
+		rowBytes: rowByteSize(kind, inDim),
+	}
+}
+
+func rowByteSize(kind ggufTensorType, inDim int) int {
+	switch kind {
+	case ggufTensorF32:
+		return inDim * 4
+	case ggufTensorF16:
+		return inDim * 2
+	case ggufTensorQ8_0:
+		return (inDim / q8_0BlockElems) * q8_0BlockBytes
+	case ggufTensorQ4_K:
+		return (inDim / q4_KSuperBlockElems) * q4_KSuperBlockBytes
+	default:
+		return 0
+	}
+}
+
+// dequantRow expands output row i into scratch (which must have length
+// q.inDim), converting from q.kind's on-disk representation to float64.
+func (q *quantTensor) dequantRow(i int, scratch []float64) {
+	base := i * q.rowBytes
+	row := q.data[base : base+q.rowBytes]
+
+	switch q.kind {
+	case ggufTensorF32:
+		for j := 0; j < q.inDim; j++ {
+			scratch[j] = float64(math.Float32frombits(binary.LittleEndian.Uint32(row[j*4:])))
+		}
+	case ggufTensorF16:
+		for j := 0; j < q.inDim; j++ {
+			scratch[j] = float64(f16ToF32(binary.LittleEndian.Uint16(row[j*2:])))
+		}
+	case ggufTensorQ8_0:
+		blocks := q.inDim / q8_0BlockElems
+		for b := 0; b < blocks; b++ {
+			blockOff := b * q8_0BlockBytes
+			scale := float64(f16ToF32(binary.LittleEndian.Uint16(row[blockOff:])))
+			quants := row[blockOff+2 : blockOff+2+q8_0BlockElems]
+			for l := 0; l < q8_0BlockElems; l++ {
+				scratch[b*q8_0BlockElems+l] = scale * float64(int8(quants[l]))
+			}
+		}
+	case ggufTensorQ4_K:
+		superBlocks := q.inDim / q4_KSuperBlockElems
+		for sb := 0; sb < superBlocks; sb++ {
+			off := sb * q4_KSuperBlockBytes
+			d := float64(f16ToF32(binary.LittleEndian.Uint16(row[off:])))
+			dmin := float64(f16ToF32(binary.LittleEndian.Uint16(row[off+2:])))
+			scales := row[off+4 : off+16]
+			qs := row[off+16 : off+16+128]
+
+			out := scratch[sb*q4_KSuperBlockElems : sb*q4_KSuperBlockElems+q4_KSuperBlockElems]
+			is := 0
+			qOff := 0
+			for j := 0; j < q4_KSuperBlockElems; j += 64 {
+				sc1, m1 := q4KScaleMin(is+0, scales)
+				sc2, m2 := q4KScaleMin(is+1, scales)
+				d1, min1 := d*float64(sc1), dmin*float64(m1)
+				d2, min2 := d*float64(sc2), dmin*float64(m2)
+
+				nibbles := qs[qOff : qOff+32]
+				for l := 0; l < 32; l++ {
+					out[j+l] = d1*float64(nibbles[l]&0x0F) - min1
+				}
+				for l := 0; l < 32; l++ {
+					out[j+32+l] = d2*float64(nibbles[l]>>4) - min2
+				}
+				qOff += 32
+				is += 2
+			}
+		}
+	}
+}
+
+// q4KScaleMin unpacks the j-th 6-bit scale and 6-bit min out of a Q4_K
+// super-block's 12-byte packed scales array — the same bit layout ggml's
+// get_scale_min_k4 uses, since that packing is part of the GGUF wire
+// format, not an implementation detail.
+func q4KScaleMin(j int, scales []byte) (scale, min byte) {
+	if j < 4 {
+		scale = scales[j] & 0x3F
+		min = scales[j+4] & 0x3F
+		return
+	}
+	scale = (scales[j+4] & 0x0F) | ((scales[j-4] >> 6) << 4)
+	min = (scales[j+4] >> 4) | ((scales[j] >> 6) << 4)
+	return
+}
+
+// matmul computes a x q (a conventional [in][out] matmul) by dequantizing
+// each of q's outDim rows exactly once and reusing it across every row of
+// a, instead of expanding q to a dense [in][out] float64 matrix first.
+func (q *quantTensor) matmul(a [][]float64) [][]float64 {
+	m := len(a)
+	if m == 0 {
+		return nil
+	}
+
+	result := make([][]float64, m)
+	for i := range result {
+		result[i] = make([]float64, q.outDim)
+	}
+
+	scratch := make([]float64, q.inDim)
+	for j := 0; j < q.outDim; j++ {
+		q.dequantRow(j, scratch)
+		for i := 0; i < m; i++ {
+			row := a[i]
+			limit := q.inDim
+			if len(row) < limit {
+				limit = len(row)
+			}
+			dot := 0.0
+			for l := 0; l < limit; l++ {
+				dot += row[l] * scratch[l]
+			}
+			result[i][j] = dot
+		}
+	}
+	return result
+}
+
+// f16ToF32 converts an IEEE 754 half-precision float (as its raw bits) to
+// float32, handling subnormals, infinities, and NaN.
+func f16ToF32(h uint16) float32 {
+	sign := uint32(h>>15) & 0x1
+	exp := uint32(h>>10) & 0x1F
+	frac := uint32(h) & 0x3FF
+
+	var bits uint32
+	switch {
+	case exp == 0 && frac == 0: // zero
+		bits = sign << 31
+	case exp == 0: // subnormal: normalize by shifting frac until it has an implicit leading 1
+		e := 0
+		for frac&0x400 == 0 {
+			frac <<= 1
+			e--
+		}
+		frac &= 0x3FF
+		bits = (sign << 31) | uint32(int32(e+1+127-15))<<23 | (frac << 13)
+	case exp == 0x1F: // inf/NaN
+		bits = (sign << 31) | (0xFF << 23) | (frac << 13)
+	default:
+		bits = (sign << 31) | ((exp - 15 + 127) << 23) | (frac << 13)
+	}
+	return math.Float32frombits(bits)
+}
@@ -0,0 +1,91 @@
+// Package manifest loads a K'UHUL program manifest (name, version,
+// atomic_law, handler/accel/kv-binding fields) from an external .toml,
+// .yaml, or .json file, as an alternative to declaring it inline with a
+// ⟁Pop⟁ manifest_ast block.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ollama/ollama/kuhul/ast"
+	"github.com/ollama/ollama/kuhul/kerror"
+	"github.com/ollama/ollama/kuhul/parser"
+)
+
+// Load reads path and builds an *ast.Manifest from it. The format is chosen
+// by extension (.toml, .yaml/.yml, or .json); all three describe the same
+// fields a ⟁Pop⟁ manifest_ast block would ("n", "v", "atomic_law", "packs",
+// and any handler-route/accel/kv-namespace keys a pack or the runtime reads
+// off Manifest.Raw).
+func Load(path string) (*ast.Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+
+	raw := make(map[string]interface{})
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".toml":
+		if _, err := toml.Decode(string(content), &raw); err != nil {
+			return nil, fmt.Errorf("manifest: parsing %s as toml: %w", path, err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(content, &raw); err != nil {
+			return nil, fmt.Errorf("manifest: parsing %s as yaml: %w", path, err)
+		}
+	case ".json":
+		if err := json.Unmarshal(content, &raw); err != nil {
+			return nil, fmt.Errorf("manifest: parsing %s as json: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("manifest: unsupported extension %q (want .toml, .yaml, .yml, or .json)", ext)
+	}
+
+	return parser.ManifestFromMap(raw), nil
+}
+
+// Merge combines a file-loaded manifest (base) with an inline ⟁Pop⟁
+// manifest_ast (override), with override's keys winning key-by-key. A key
+// present in both with different values is still resolved in override's
+// favor, but is reported back as a *kerror.Error so the conflict isn't
+// silent; callers that don't care can discard the error and keep the
+// returned manifest.
+func Merge(base, override *ast.Manifest) (*ast.Manifest, error) {
+	if base == nil {
+		return override, nil
+	}
+	if override == nil {
+		return base, nil
+	}
+
+	merged := make(map[string]interface{}, len(base.Raw)+len(override.Raw))
+	for k, v := range base.Raw {
+		merged[k] = v
+	}
+
+	var conflicts []string
+	for k, v := range override.Raw {
+		if baseVal, ok := base.Raw[k]; ok && !reflect.DeepEqual(baseVal, v) {
+			conflicts = append(conflicts, k)
+		}
+		merged[k] = v
+	}
+
+	result := parser.ManifestFromMap(merged)
+	if len(conflicts) == 0 {
+		return result, nil
+	}
+
+	sort.Strings(conflicts)
+	return result, kerror.New(kerror.ManifestConflict, "", 0, 0, 0,
+		"inline ⟁Pop⟁ manifest_ast overrides file manifest for: %s", strings.Join(conflicts, ", "))
+}
@@ -0,0 +1,271 @@
+package parser
+
+import (
+	"github.com/ollama/ollama/kuhul/ast"
+	"github.com/ollama/ollama/kuhul/lexer"
+)
+
+// Binding powers for the Pratt expression parser, lowest to highest.
+const (
+	bpOr      = 1
+	bpAnd     = 2
+	bpEq      = 3
+	bpCmp     = 4
+	bpAdd     = 5
+	bpMul     = 6
+	bpUnary   = 7
+	bpPostfix = 8
+)
+
+type prefixRule struct {
+	fn func(p *Parser) ast.Node
+}
+
+type infixRule struct {
+	lbp int
+	rbp int
+	fn  func(p *Parser, left ast.Node) ast.Node
+}
+
+// prefixRules and infixRules are populated in init rather than by their var
+// declarations: several of the parse methods they reference (parseLambdaExpr,
+// parseIndexExpr, ...) call parseExpression, which reads these same tables,
+// so a composite-literal initializer here would be an initialization cycle.
+var prefixRules map[lexer.TokenType]prefixRule
+var infixRules map[lexer.TokenType]infixRule
+
+func init() {
+	prefixRules = map[lexer.TokenType]prefixRule{
+		lexer.NUMBER:          {fn: (*Parser).parseLiteralExpr},
+		lexer.STRING:          {fn: (*Parser).parseLiteralExpr},
+		lexer.JSON:            {fn: (*Parser).parseLiteralExpr},
+		lexer.TRUE:            {fn: (*Parser).parseBoolExpr},
+		lexer.FALSE:           {fn: (*Parser).parseBoolExpr},
+		lexer.NULL:            {fn: (*Parser).parseNullExpr},
+		lexer.IDENT:           {fn: (*Parser).parseIdentExpr},
+		lexer.MAP:             {fn: (*Parser).parseIdentExpr}, // "map" is a keyword token, but map(arr, fn) is an ordinary call
+		lexer.AT:              {fn: (*Parser).parseAtomExpr},
+		lexer.LBRACKET:        {fn: (*Parser).parseArrayExpr},
+		lexer.MINUS:           {fn: (*Parser).parseUnaryExpr},
+		lexer.NOT:             {fn: (*Parser).parseUnaryExpr},
+		lexer.DEFINE_FUNCTION: {fn: (*Parser).parseLambdaExpr},
+	}
+
+	infixRules = map[lexer.TokenType]infixRule{
+		lexer.OR:       {lbp: bpOr, rbp: bpOr, fn: (*Parser).parseBinaryExpr},
+		lexer.AND:      {lbp: bpAnd, rbp: bpAnd, fn: (*Parser).parseBinaryExpr},
+		lexer.EQ:       {lbp: bpEq, rbp: bpEq, fn: (*Parser).parseBinaryExpr},
+		lexer.NEQ:      {lbp: bpEq, rbp: bpEq, fn: (*Parser).parseBinaryExpr},
+		lexer.LT:       {lbp: bpCmp, rbp: bpCmp, fn: (*Parser).parseBinaryExpr},
+		lexer.LTE:      {lbp: bpCmp, rbp: bpCmp, fn: (*Parser).parseBinaryExpr},
+		lexer.GT:       {lbp: bpCmp, rbp: bpCmp, fn: (*Parser).parseBinaryExpr},
+		lexer.GTE:      {lbp: bpCmp, rbp: bpCmp, fn: (*Parser).parseBinaryExpr},
+		lexer.PLUS:     {lbp: bpAdd, rbp: bpAdd, fn: (*Parser).parseBinaryExpr},
+		lexer.MINUS:    {lbp: bpAdd, rbp: bpAdd, fn: (*Parser).parseBinaryExpr},
+		lexer.STAR:     {lbp: bpMul, rbp: bpMul, fn: (*Parser).parseBinaryExpr},
+		lexer.SLASH:    {lbp: bpMul, rbp: bpMul, fn: (*Parser).parseBinaryExpr},
+		lexer.PERCENT:  {lbp: bpMul, rbp: bpMul, fn: (*Parser).parseBinaryExpr},
+		lexer.DOT:      {lbp: bpPostfix, rbp: bpPostfix, fn: (*Parser).parseMemberExpr},
+		lexer.LPAREN:   {lbp: bpPostfix, rbp: bpPostfix, fn: (*Parser).parseCallExpr},
+		lexer.LBRACKET: {lbp: bpPostfix, rbp: bpPostfix, fn: (*Parser).parseIndexExpr},
+	}
+}
+
+// parseExpression is a Pratt (top-down operator precedence) parser: it reads
+// a prefix (a literal, identifier, or unary operator), then repeatedly
+// consumes infix operators whose left binding power is greater than minBP,
+// recursing with the operator's right binding power for the right operand.
+// Left-associative operators recurse with rbp == lbp; a right-associative
+// operator would recurse with rbp == lbp-1 (none of the currently registered
+// operators need that).
+func (p *Parser) parseExpression(minBP int) ast.Node {
+	rule, ok := prefixRules[p.peek().Type]
+	if !ok {
+		return nil
+	}
+	left := rule.fn(p)
+	if left == nil {
+		return nil
+	}
+
+	for {
+		irule, ok := infixRules[p.peek().Type]
+		if !ok || irule.lbp <= minBP {
+			break
+		}
+		left = irule.fn(p, left)
+	}
+
+	return left
+}
+
+func (p *Parser) parseLiteralExpr() ast.Node {
+	tok := p.advance()
+	return &ast.Literal{
+		BaseNode: ast.BaseNode{NodeType: ast.NodeLiteral, Position: ast.Position{Line: tok.Line, Column: tok.Column}},
+		Value:    tok.Value,
+		Raw:      tok.Literal,
+	}
+}
+
+func (p *Parser) parseBoolExpr() ast.Node {
+	tok := p.advance()
+	return &ast.Literal{
+		BaseNode: ast.BaseNode{NodeType: ast.NodeLiteral, Position: ast.Position{Line: tok.Line, Column: tok.Column}},
+		Value:    tok.Type == lexer.TRUE,
+		Raw:      tok.Literal,
+	}
+}
+
+func (p *Parser) parseNullExpr() ast.Node {
+	tok := p.advance()
+	return &ast.Literal{
+		BaseNode: ast.BaseNode{NodeType: ast.NodeLiteral, Position: ast.Position{Line: tok.Line, Column: tok.Column}},
+		Raw:      tok.Literal,
+	}
+}
+
+func (p *Parser) parseIdentExpr() ast.Node {
+	tok := p.advance()
+	return &ast.Identifier{
+		BaseNode: ast.BaseNode{NodeType: ast.NodeIdentifier, Position: ast.Position{Line: tok.Line, Column: tok.Column}},
+		Name:     tok.Literal,
+	}
+}
+
+// parseAtomExpr parses an @-prefixed atom reference (e.g. "@x") as an
+// identifier, keeping the "@" so evaluateValue's existing variable-lookup
+// convention keeps working unchanged.
+func (p *Parser) parseAtomExpr() ast.Node {
+	tok := p.advance()
+	return &ast.Identifier{
+		BaseNode: ast.BaseNode{NodeType: ast.NodeIdentifier, Position: ast.Position{Line: tok.Line, Column: tok.Column}},
+		Name:     tok.Literal,
+	}
+}
+
+func (p *Parser) parseArrayExpr() ast.Node {
+	tok := p.peek()
+	elements := p.parseArrayLiteral()
+	return &ast.Literal{
+		BaseNode: ast.BaseNode{NodeType: ast.NodeLiteral, Position: ast.Position{Line: tok.Line, Column: tok.Column}},
+		Value:    elements,
+	}
+}
+
+// parseUnaryExpr parses a prefix "-" or "!". Its operand binds at bpUnary,
+// tighter than any binary operator so it doesn't swallow a trailing "* b",
+// but looser than postfix so "-a.b" parses as "-(a.b)".
+func (p *Parser) parseUnaryExpr() ast.Node {
+	tok := p.advance()
+	operand := p.parseExpression(bpUnary)
+	return &ast.UnaryExpr{
+		BaseNode: ast.BaseNode{NodeType: ast.NodeUnaryExpr, Position: ast.Position{Line: tok.Line, Column: tok.Column}},
+		Operator: tok.Literal,
+		Operand:  operand,
+	}
+}
+
+func (p *Parser) parseBinaryExpr(left ast.Node) ast.Node {
+	tok := p.advance()
+	rule := infixRules[tok.Type]
+	right := p.parseExpression(rule.rbp)
+	return &ast.BinaryExpr{
+		BaseNode: ast.BaseNode{NodeType: ast.NodeBinaryExpr, Position: ast.Position{Line: tok.Line, Column: tok.Column}},
+		Operator: tok.Literal,
+		Left:     left,
+		Right:    right,
+	}
+}
+
+func (p *Parser) parseMemberExpr(left ast.Node) ast.Node {
+	dotTok := p.advance()
+	name := ""
+	if nameTok := p.expect(lexer.IDENT, "Expected property name after '.'"); nameTok != nil {
+		name = nameTok.Literal
+	}
+	return &ast.MemberExpr{
+		BaseNode: ast.BaseNode{NodeType: ast.NodeMemberExpr, Position: ast.Position{Line: dotTok.Line, Column: dotTok.Column}},
+		Object:   left,
+		Property: name,
+	}
+}
+
+func (p *Parser) parseCallExpr(left ast.Node) ast.Node {
+	lparenTok := p.advance()
+	args := make([]ast.Node, 0)
+	for !p.isAtEnd() && !p.check(lexer.RPAREN) {
+		if arg := p.parseExpression(0); arg != nil {
+			args = append(args, arg)
+		}
+		if p.check(lexer.COMMA) {
+			p.advance()
+		}
+	}
+	p.expect(lexer.RPAREN, "Expected ')' after call arguments")
+	return &ast.CallExpr{
+		BaseNode:  ast.BaseNode{NodeType: ast.NodeCallExpr, Position: ast.Position{Line: lparenTok.Line, Column: lparenTok.Column}},
+		Callee:    left,
+		Arguments: args,
+	}
+}
+
+// parseLambdaExpr parses an inline function literal:
+// define_function(@x, @y) { @x + @y }. Its body is a single expression (the
+// same thing a ⟁Ch'en⟁ inside a C@@L BLOCK could return), not a full
+// statement list — a multi-statement callback belongs in a registered
+// handler dispatched by name, not an expression-position lambda. Parameters
+// are "@"-prefixed atoms, the same as a C@@L BLOCK's own @-keyed params, so
+// the body can refer to them with the usual "@x" variable-reference syntax.
+func (p *Parser) parseLambdaExpr() ast.Node {
+	tok := p.advance()
+	p.expect(lexer.LPAREN, "Expected '(' after define_function")
+
+	params := make([]string, 0)
+	for !p.isAtEnd() && !p.check(lexer.RPAREN) {
+		if paramTok := p.expect(lexer.AT, "Expected '@param' in lambda parameter list"); paramTok != nil {
+			params = append(params, paramTok.Literal)
+		}
+		if p.check(lexer.COMMA) {
+			p.advance()
+		}
+	}
+	p.expect(lexer.RPAREN, "Expected ')' after lambda parameters")
+	p.expect(lexer.LBRACE, "Expected '{' before lambda body")
+	body := p.parseExpression(0)
+	p.expect(lexer.RBRACE, "Expected '}' after lambda body")
+
+	return &ast.Lambda{
+		BaseNode: ast.BaseNode{NodeType: ast.NodeLambda, Position: ast.Position{Line: tok.Line, Column: tok.Column}},
+		Params:   params,
+		Body:     body,
+	}
+}
+
+func (p *Parser) parseIndexExpr(left ast.Node) ast.Node {
+	lbracketTok := p.advance()
+	index := p.parseExpression(0)
+	p.expect(lexer.RBRACKET, "Expected ']' after index expression")
+	return &ast.IndexExpr{
+		BaseNode: ast.BaseNode{NodeType: ast.NodeIndexExpr, Position: ast.Position{Line: lbracketTok.Line, Column: lbracketTok.Column}},
+		Object:   left,
+		Index:    index,
+	}
+}
+
+// unwrapExprValue collapses a parsed expression back to the raw value shape
+// parseValue used to return whenever no operator was actually applied, so
+// existing callers (and the interpreter) see unchanged plain values for the
+// common case and only have to deal with an ast.Node for real expressions.
+func unwrapExprValue(node ast.Node) interface{} {
+	switch n := node.(type) {
+	case nil:
+		return nil
+	case *ast.Literal:
+		return n.Value
+	case *ast.Identifier:
+		return n.Name
+	default:
+		return node
+	}
+}
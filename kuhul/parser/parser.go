@@ -7,15 +7,18 @@ import (
 	"strings"
 
 	"github.com/ollama/ollama/kuhul/ast"
+	"github.com/ollama/ollama/kuhul/diag"
 	"github.com/ollama/ollama/kuhul/lexer"
 )
 
 // Parser parses K'UHUL tokens into an AST
 type Parser struct {
-	tokens  []lexer.Token
-	pos     int
-	errors  []string
-	program *ast.Program
+	tokens      []lexer.Token
+	pos         int
+	errors      []string
+	diagnostics []diag.Diagnostic
+	handler     diag.ErrorHandler
+	program     *ast.Program
 }
 
 // New creates a new Parser for the given tokens
@@ -28,6 +31,18 @@ func New(tokens []lexer.Token) *Parser {
 	}
 }
 
+// SetErrorHandler installs a pluggable diagnostics sink; every diagnostic is
+// forwarded to it as it is produced, in addition to being collected for
+// Diagnostics()/Errors().
+func (p *Parser) SetErrorHandler(h diag.ErrorHandler) {
+	p.handler = h
+}
+
+// Diagnostics returns all diagnostics collected during parsing, in order.
+func (p *Parser) Diagnostics() []diag.Diagnostic {
+	return p.diagnostics
+}
+
 // Parse parses the tokens into a complete AST
 func Parse(source string) (*ast.Program, []string) {
 	l := lexer.New(source)
@@ -45,6 +60,25 @@ func Parse(source string) (*ast.Program, []string) {
 	return p.Parse(), p.errors
 }
 
+// ParseWithDiagnostics parses source the same way Parse does, but returns
+// structured diagnostics (with spans and error codes) instead of bare
+// strings.
+func ParseWithDiagnostics(source string) (*ast.Program, []diag.Diagnostic) {
+	l := lexer.New(source)
+	tokens := l.Tokenize()
+
+	filtered := make([]lexer.Token, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Type != lexer.COMMENT && t.Type != lexer.NEWLINE && t.Type != lexer.BLOCK_COMMENT {
+			filtered = append(filtered, t)
+		}
+	}
+
+	p := New(filtered)
+	program := p.Parse()
+	return program, p.Diagnostics()
+}
+
 // Parse parses all tokens and returns the program AST
 func (p *Parser) Parse() *ast.Program {
 	for !p.isAtEnd() {
@@ -87,7 +121,8 @@ func (p *Parser) parseTopLevel() interface{} {
 	case lexer.EOF:
 		return nil
 	default:
-		p.advance()
+		p.error(tok, "KHL0001", fmt.Sprintf("unexpected token %s", tok.Type))
+		p.synchronize()
 		return nil
 	}
 }
@@ -140,8 +175,9 @@ func (p *Parser) parseAssignment() *ast.Assignment {
 		p.advance()
 	}
 
-	// Parse value
-	assign.Value = p.parseValue()
+	// Parse value as a full expression (arithmetic, comparisons, calls,
+	// member/index access) instead of just a primary literal.
+	assign.Value = unwrapExprValue(p.parseExpression(0))
 
 	return assign
 }
@@ -296,8 +332,13 @@ func (p *Parser) parseCoolBlock() *ast.CoolBlock {
 		Body:   make([]interface{}, 0),
 	}
 
-	// Parse block content
-	for !p.isAtEnd() && !p.check(lexer.COOL_BLOCK) && !p.check(lexer.XUL) && !p.check(lexer.CHEN) {
+	// Parse block content. Must also stop on POP/WO/SEK — a following
+	// ⟁Pop⟁/⟁Wo⟁ declaration or ⟁Sek⟁ control vector is a sibling statement,
+	// not part of this block's body, the same way COOL_BLOCK/XUL/CHEN are;
+	// otherwise its tokens fall into the warn-and-skip else branch below and
+	// it's silently dropped instead of parsed as its own top-level node.
+	for !p.isAtEnd() && !p.check(lexer.COOL_BLOCK) && !p.check(lexer.XUL) && !p.check(lexer.CHEN) &&
+		!p.check(lexer.POP) && !p.check(lexer.WO) && !p.check(lexer.SEK) {
 		if p.check(lexer.AT) {
 			name, value := p.parseAtomParam()
 			block.Params[name] = value
@@ -306,10 +347,16 @@ func (p *Parser) parseCoolBlock() *ast.CoolBlock {
 					block.Handler = s
 				}
 			}
+			if name == "on" {
+				if s, ok := value.(string); ok {
+					block.OnEvent = s
+				}
+			}
 		} else if p.check(lexer.JSON) {
 			jsonTok := p.advance()
 			block.Body = append(block.Body, jsonTok.Value)
 		} else {
+			p.warn(p.peek(), "KHL0003", fmt.Sprintf("ignoring unexpected token %s in C@@L BLOCK %s", p.peek().Type, blockName))
 			p.advance()
 		}
 	}
@@ -511,11 +558,23 @@ func (p *Parser) addNodeToProgram(node interface{}) {
 		p.program.CoolVariables[n.Name] = n
 	case *ast.BlockDefinition:
 		p.program.Blocks = append(p.program.Blocks, n)
+	case *ast.ControlVector:
+		p.program.ControlVectors = append(p.program.ControlVectors, n)
 	}
 }
 
 // parseManifest parses a manifest from a map
 func (p *Parser) parseManifest(m map[string]interface{}) *ast.Manifest {
+	return ManifestFromMap(m)
+}
+
+// ManifestFromMap builds an *ast.Manifest from a decoded map of manifest
+// fields ("n", "v", "atomic_law", "packs", "tapes", "kuhul_folds",
+// "rest_mesh", "site_content", "environments"), the same shape whether m came from an
+// inline ⟁Pop⟁ manifest_ast block or an external TOML/YAML/JSON file (see
+// kuhul/manifest). Unrecognized keys are preserved on Raw but don't get
+// their own field.
+func ManifestFromMap(m map[string]interface{}) *ast.Manifest {
 	manifest := &ast.Manifest{
 		BaseNode: ast.BaseNode{NodeType: ast.NodeManifest},
 		Raw:      m,
@@ -550,6 +609,9 @@ func (p *Parser) parseManifest(m map[string]interface{}) *ast.Manifest {
 	if v, ok := m["site_content"].(map[string]interface{}); ok {
 		manifest.SiteContent = v
 	}
+	if v, ok := m["environments"].(map[string]interface{}); ok {
+		manifest.Environments = v
+	}
 
 	return manifest
 }
@@ -580,7 +642,7 @@ func (p *Parser) expect(t lexer.TokenType, msg string) *lexer.Token {
 		tok := p.advance()
 		return &tok
 	}
-	p.error(fmt.Sprintf("%s at line %d", msg, p.peek().Line))
+	p.error(p.peek(), "KHL0004", msg)
 	return nil
 }
 
@@ -594,6 +656,50 @@ func (p *Parser) isMarker() bool {
 		t == lexer.XUL || t == lexer.CHEN || t == lexer.ATOMIC_BLOCK
 }
 
-func (p *Parser) error(msg string) {
-	p.errors = append(p.errors, msg)
+// synchronize implements panic-mode recovery: after an error, skip tokens
+// until the next top-level marker (POP/WO/SEK/XUL/CHEN/ATOMIC_BLOCK) so a single bad
+// construct doesn't cascade into a wall of spurious follow-on errors.
+func (p *Parser) synchronize() {
+	for !p.isAtEnd() && !p.isMarker() {
+		p.advance()
+	}
+}
+
+// error records an error-severity diagnostic anchored at tok.
+func (p *Parser) error(tok lexer.Token, code, msg string) {
+	p.report(diag.SeverityError, tok, code, msg)
+}
+
+// warn records a warning-severity diagnostic anchored at tok.
+func (p *Parser) warn(tok lexer.Token, code, msg string) {
+	p.report(diag.SeverityWarning, tok, code, msg)
+}
+
+func (p *Parser) report(sev diag.Severity, tok lexer.Token, code, msg string) {
+	d := diag.Diagnostic{
+		Severity: sev,
+		Span:     spanOfToken(tok),
+		Message:  msg,
+		Code:     code,
+	}
+	p.diagnostics = append(p.diagnostics, d)
+	if sev == diag.SeverityError {
+		p.errors = append(p.errors, fmt.Sprintf("%s at line %d", msg, tok.Line))
+	}
+	if p.handler != nil {
+		p.handler.HandleDiagnostic(d)
+	}
+}
+
+// spanOfToken converts a lexer.Token's position into a diag.Span.
+func spanOfToken(tok lexer.Token) diag.Span {
+	endCol := tok.Column + len([]rune(tok.Literal))
+	return diag.Span{
+		StartLine:   tok.Line,
+		StartCol:    tok.Column,
+		EndLine:     tok.Line,
+		EndCol:      endCol,
+		StartOffset: tok.StartOffset,
+		EndOffset:   tok.EndOffset,
+	}
 }
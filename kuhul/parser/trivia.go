@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"github.com/ollama/ollama/kuhul/ast"
+	"github.com/ollama/ollama/kuhul/lexer"
+)
+
+// ParseWithTrivia parses source the same way Parse does, but first walks the
+// unfiltered token stream to collect comments and attaches them to the
+// top-level AST node that owns their line: a run of COMMENT/BLOCK_COMMENT
+// tokens immediately preceding a construct becomes its LeadingComments, and
+// a comment on the same line as (and after) a construct's start line becomes
+// its TrailingComment. This exists so kuhul/printer can round-trip comments
+// that Parse silently drops.
+func ParseWithTrivia(source string) (*ast.Program, []string) {
+	l := lexer.New(source)
+	tokens := l.Tokenize()
+
+	leading, trailing := collectTrivia(tokens)
+
+	filtered := make([]lexer.Token, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Type != lexer.COMMENT && t.Type != lexer.NEWLINE && t.Type != lexer.BLOCK_COMMENT {
+			filtered = append(filtered, t)
+		}
+	}
+
+	p := New(filtered)
+	program := p.Parse()
+	attachTrivia(program, leading, trailing)
+	return program, p.errors
+}
+
+// collectTrivia scans the unfiltered token stream and returns two maps keyed
+// by source line: leading comments that sit on their own line(s) directly
+// above a line, and a trailing comment that shares a line with a preceding
+// non-trivia token.
+func collectTrivia(tokens []lexer.Token) (leading map[int][]string, trailing map[int]string) {
+	leading = make(map[int][]string)
+	trailing = make(map[int]string)
+
+	var pending []string
+	pendingEndLine := -1
+	sawCodeOnLine := false
+	lastCodeLine := -1
+
+	for _, t := range tokens {
+		switch t.Type {
+		case lexer.NEWLINE:
+			sawCodeOnLine = false
+		case lexer.COMMENT, lexer.BLOCK_COMMENT:
+			if sawCodeOnLine && t.Line == lastCodeLine {
+				trailing[lastCodeLine] = t.Literal
+				continue
+			}
+			// A comment block only stays "pending" for the very next line;
+			// a blank line in between detaches it from what follows.
+			if pendingEndLine >= 0 && t.Line != pendingEndLine+1 {
+				pending = nil
+			}
+			pending = append(pending, t.Literal)
+			pendingEndLine = t.Line
+		case lexer.EOF:
+			// no-op
+		default:
+			sawCodeOnLine = true
+			lastCodeLine = t.Line
+			if len(pending) > 0 {
+				if pendingEndLine != t.Line-1 {
+					// Not immediately adjacent; drop the stale run.
+					pending = nil
+				} else {
+					leading[t.Line] = append(leading[t.Line], pending...)
+					pending = nil
+				}
+			}
+		}
+	}
+
+	return leading, trailing
+}
+
+// attachTrivia sets LeadingComments/TrailingComment on every top-level node
+// in program based on the node's start line.
+func attachTrivia(program *ast.Program, leading map[int][]string, trailing map[int]string) {
+	apply := func(n ast.Node) {
+		line := n.Pos().Line
+		base := baseNodeOf(n)
+		if base == nil {
+			return
+		}
+		base.LeadingComments = leading[line]
+		base.TrailingComment = trailing[line]
+	}
+
+	for _, d := range program.Declarations {
+		apply(d)
+	}
+	for _, a := range program.Assignments {
+		apply(a)
+	}
+	for _, b := range program.Blocks {
+		apply(b)
+	}
+	for _, cv := range program.ControlVectors {
+		apply(cv)
+	}
+	for _, ab := range program.AtomicBlocks {
+		apply(ab)
+	}
+	for _, cb := range program.CoolBlocks {
+		apply(cb)
+	}
+	for _, cv := range program.CoolVectors {
+		apply(cv)
+	}
+	for _, cvar := range program.CoolVariables {
+		apply(cvar)
+	}
+}
+
+// baseNodeOf extracts the *ast.BaseNode embedded in a top-level node so
+// attachTrivia can write to it through the common interface.
+func baseNodeOf(n ast.Node) *ast.BaseNode {
+	switch v := n.(type) {
+	case *ast.Declaration:
+		return &v.BaseNode
+	case *ast.Assignment:
+		return &v.BaseNode
+	case *ast.BlockDefinition:
+		return &v.BaseNode
+	case *ast.ControlVector:
+		return &v.BaseNode
+	case *ast.AtomicBlock:
+		return &v.BaseNode
+	case *ast.CoolBlock:
+		return &v.BaseNode
+	case *ast.CoolVector:
+		return &v.BaseNode
+	case *ast.CoolVariable:
+		return &v.BaseNode
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,245 @@
+package printer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// editOp is one step of a Myers diff over lines.
+type editOp struct {
+	kind byte // ' ' (equal), '-' (delete from a), '+' (insert from b)
+	line string
+}
+
+// diffLines computes a minimal edit script turning a's lines into b's lines
+// using the classic Myers O(ND) algorithm. It's small on purpose: kuhulfmt
+// only ever diffs a formatted file against its unformatted original, so
+// there is no need for a general-purpose diff library.
+func diffLines(a, b []string) []editOp {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	// trace[d] is the V array (offset by max) after step d, kept so the
+	// script can be reconstructed by walking back through it.
+	trace := make([][]int, 0, max+1)
+	v := make([]int, 2*max+1)
+	offset := max
+
+	find := func() int {
+		for d := 0; d <= max; d++ {
+			snapshot := make([]int, len(v))
+			copy(snapshot, v)
+			trace = append(trace, snapshot)
+
+			for k := -d; k <= d; k += 2 {
+				var x int
+				if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+					x = v[offset+k+1]
+				} else {
+					x = v[offset+k-1] + 1
+				}
+				y := x - k
+				for x < n && y < m && a[x] == b[y] {
+					x++
+					y++
+				}
+				v[offset+k] = x
+				if x >= n && y >= m {
+					return d
+				}
+			}
+		}
+		return max
+	}
+
+	d := find()
+
+	var ops []editOp
+	x, y := n, m
+	for depth := d; depth > 0; depth-- {
+		v := trace[depth]
+		k := x - y
+		var prevK int
+		if k == -depth || (k != depth && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, editOp{kind: ' ', line: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, editOp{kind: '+', line: b[y-1]})
+			y--
+		} else {
+			ops = append(ops, editOp{kind: '-', line: a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, editOp{kind: ' ', line: a[x-1]})
+		x--
+		y--
+	}
+
+	// Built back-to-front; reverse to read forward.
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// unifiedContext is the number of unchanged lines kept around each change,
+// matching `diff -u`'s default.
+const unifiedContext = 3
+
+// UnifiedDiff renders a unified diff between before and after, in the
+// standard `--- a\n+++ b\n@@ -l,s +l,s @@` format that `diff -u` and
+// `git apply` produce, for use by `kuhulfmt -d`. It returns "" if
+// before == after.
+func UnifiedDiff(filename, before, after string) string {
+	if before == after {
+		return ""
+	}
+
+	ops := diffLines(splitLines(before), splitLines(after))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n", filename)
+	fmt.Fprintf(&b, "+++ %s\n", filename)
+
+	for _, h := range hunks(ops) {
+		writeHunk(&b, h)
+	}
+
+	return b.String()
+}
+
+// hunk is one @@ region: a contiguous run of ops (padded with up to
+// unifiedContext lines of surrounding context) plus the 1-based starting
+// line number of that run in each file.
+type hunk struct {
+	aStart, bStart int
+	ops            []editOp
+}
+
+// hunks groups a full edit script into unified-diff hunks, merging change
+// regions that are within 2*unifiedContext lines of each other so their
+// context overlaps.
+func hunks(ops []editOp) []hunk {
+	type change struct{ start, end int } // half-open range into ops of non-' ' runs
+	var changes []change
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == ' ' {
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].kind != ' ' {
+			j++
+		}
+		changes = append(changes, change{i, j})
+		i = j
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var result []hunk
+	aLine, bLine := 1, 1 // 1-based line numbers at ops[0]
+	i := 0
+
+	for ci := 0; ci < len(changes); ci++ {
+		start := changes[ci].start - unifiedContext
+		if start < 0 {
+			start = 0
+		}
+		end := changes[ci].end + unifiedContext
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		// Merge with the previous hunk if their context windows overlap.
+		for ci+1 < len(changes) && changes[ci+1].start-unifiedContext <= end {
+			ci++
+			end = changes[ci].end + unifiedContext
+			if end > len(ops) {
+				end = len(ops)
+			}
+		}
+
+		// Advance the running line counters up to start, then record where
+		// this hunk begins in each file.
+		for ; i < start; i++ {
+			aLine, bLine = advance(ops[i], aLine, bLine)
+		}
+		aStart, bStart := aLine, bLine
+		hunkOps := append([]editOp(nil), ops[start:end]...)
+		for ; i < end; i++ {
+			aLine, bLine = advance(ops[i], aLine, bLine)
+		}
+
+		result = append(result, hunk{aStart: aStart, bStart: bStart, ops: hunkOps})
+	}
+
+	return result
+}
+
+func advance(op editOp, aLine, bLine int) (int, int) {
+	switch op.kind {
+	case ' ':
+		return aLine + 1, bLine + 1
+	case '-':
+		return aLine + 1, bLine
+	case '+':
+		return aLine, bLine + 1
+	}
+	return aLine, bLine
+}
+
+func writeHunk(b *strings.Builder, h hunk) {
+	aCount, bCount := 0, 0
+	for _, op := range h.ops {
+		switch op.kind {
+		case ' ':
+			aCount++
+			bCount++
+		case '-':
+			aCount++
+		case '+':
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", h.aStart, aCount, h.bStart, bCount)
+	for _, op := range h.ops {
+		switch op.kind {
+		case ' ':
+			fmt.Fprintf(b, " %s\n", op.line)
+		case '-':
+			fmt.Fprintf(b, "-%s\n", op.line)
+		case '+':
+			fmt.Fprintf(b, "+%s\n", op.line)
+		}
+	}
+}
+
+// splitLines splits on "\n" the way UnifiedDiff needs: a trailing newline
+// doesn't produce a spurious empty final line.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,408 @@
+// Package printer implements a canonical, deterministic formatter for
+// K'UHUL (.khl) source, mirroring how go/printer relates to go/ast: it walks
+// an *ast.Program and re-emits source text rather than round-tripping raw
+// tokens.
+//
+// Because ast.Program flattens a parse into per-kind slices/maps
+// (Declarations, Assignments, CoolBlocks, ...), the original interleaving of
+// unrelated construct kinds is not recoverable from the AST alone. Fprint
+// resolves this by emitting a fixed canonical order — manifest, then
+// declarations, assignments, control vectors, blocks, atomic blocks, cool
+// variables, cool vectors, cool blocks — and sorting map-backed sections by
+// name. That is exactly the "canonical" a formatter is for: two files that
+// differ only in construct order format to the same output.
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ollama/ollama/kuhul/ast"
+	"github.com/ollama/ollama/kuhul/parser"
+)
+
+// Config controls formatting output. Fprint treats a nil *Config as
+// DefaultConfig and an empty Indent as two spaces.
+type Config struct {
+	// Indent is the string used for one level of indentation (inside
+	// ⟁Xul⟁ ... ⟁Ch'en⟁ bodies and nested JSON).
+	Indent string
+}
+
+// DefaultConfig returns the formatting configuration kuhulfmt uses when no
+// override is given: two-space indentation.
+func DefaultConfig() *Config {
+	return &Config{Indent: "  "}
+}
+
+// Fprint writes the canonical K'UHUL source form of program to w.
+func Fprint(w io.Writer, program *ast.Program, cfg *Config) error {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	if cfg.Indent == "" {
+		cfg.Indent = "  "
+	}
+	p := &printer{w: w, cfg: cfg}
+	p.printProgram(program)
+	return p.err
+}
+
+// Format parses source with full comment trivia and returns its canonical
+// formatting. Callers that already hold a *ast.Program (e.g. because they
+// need diagnostics too) should call Fprint directly instead.
+func Format(source string, cfg *Config) (string, error) {
+	program, errs := parser.ParseWithTrivia(source)
+	if len(errs) > 0 {
+		return "", fmt.Errorf("kuhulfmt: %s", strings.Join(errs, "; "))
+	}
+	var buf bytes.Buffer
+	if err := Fprint(&buf, program, cfg); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// printer accumulates formatting errors instead of threading them through
+// every write call; the first error wins and subsequent writes are no-ops.
+type printer struct {
+	w   io.Writer
+	cfg *Config
+	err error
+}
+
+func (p *printer) writeString(s string) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = io.WriteString(p.w, s)
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	p.writeString(fmt.Sprintf(format, args...))
+}
+
+// blank emits a single blank line separating top-level declarations, unless
+// nothing has been printed yet.
+func (p *printer) blank(wrote *bool) {
+	if *wrote {
+		p.writeString("\n")
+	}
+	*wrote = true
+}
+
+func (p *printer) printProgram(program *ast.Program) {
+	wrote := false
+
+	if program.Manifest != nil {
+		p.blank(&wrote)
+		// Manifest is synthesized from the "manifest_ast" Declaration by the
+		// parser and doesn't carry its own trivia, so pull it from the
+		// Declaration that produced it.
+		leading, trailing := manifestTrivia(program)
+		p.printComments(leading, "")
+		p.printf("⟁Pop⟁ manifest_ast %s", p.renderJSON(program.Manifest.Raw, ""))
+		p.printTrailing(trailing)
+	}
+
+	for _, d := range program.Declarations {
+		if d.Name == "manifest_ast" {
+			continue // already emitted above from program.Manifest
+		}
+		p.blank(&wrote)
+		p.printDeclaration(d)
+	}
+
+	for _, a := range program.Assignments {
+		p.blank(&wrote)
+		p.printAssignment(a)
+	}
+
+	for _, cv := range program.ControlVectors {
+		p.blank(&wrote)
+		p.printControlVector(cv)
+	}
+
+	for _, b := range program.Blocks {
+		p.blank(&wrote)
+		p.printBlockDefinition(b)
+	}
+
+	atomicBlockNames := make([]string, 0, len(program.AtomicBlocks))
+	for name := range program.AtomicBlocks {
+		atomicBlockNames = append(atomicBlockNames, name)
+	}
+	sort.Strings(atomicBlockNames)
+	for _, name := range atomicBlockNames {
+		p.blank(&wrote)
+		p.printAtomicBlock(program.AtomicBlocks[name])
+	}
+
+	coolVariableNames := make([]string, 0, len(program.CoolVariables))
+	for name := range program.CoolVariables {
+		coolVariableNames = append(coolVariableNames, name)
+	}
+	sort.Strings(coolVariableNames)
+	for _, name := range coolVariableNames {
+		p.blank(&wrote)
+		p.printCoolVariable(program.CoolVariables[name])
+	}
+
+	coolVectorNames := make([]string, 0, len(program.CoolVectors))
+	for name := range program.CoolVectors {
+		coolVectorNames = append(coolVectorNames, name)
+	}
+	sort.Strings(coolVectorNames)
+	for _, name := range coolVectorNames {
+		p.blank(&wrote)
+		p.printCoolVector(program.CoolVectors[name])
+	}
+
+	coolBlockNames := make([]string, 0, len(program.CoolBlocks))
+	for name := range program.CoolBlocks {
+		coolBlockNames = append(coolBlockNames, name)
+	}
+	sort.Strings(coolBlockNames)
+	for _, name := range coolBlockNames {
+		p.blank(&wrote)
+		p.printCoolBlock(program.CoolBlocks[name])
+	}
+}
+
+func manifestTrivia(program *ast.Program) (leading []string, trailing string) {
+	for _, d := range program.Declarations {
+		if d.Name == "manifest_ast" {
+			return d.LeadingComments, d.TrailingComment
+		}
+	}
+	return nil, ""
+}
+
+func (p *printer) printDeclaration(d *ast.Declaration) {
+	p.printComments(d.LeadingComments, "")
+	p.printf("⟁Pop⟁ %s", d.Name)
+	if d.Value != nil {
+		p.printf(" %s", p.renderJSON(d.Value, ""))
+	}
+	p.printTrailing(d.TrailingComment)
+}
+
+func (p *printer) printAssignment(a *ast.Assignment) {
+	p.printComments(a.LeadingComments, "")
+	p.printf("⟁Wo⟁ %s = %s", a.Name, p.renderExprValue(a.Value, ""))
+	p.printTrailing(a.TrailingComment)
+}
+
+func (p *printer) printControlVector(cv *ast.ControlVector) {
+	p.printComments(cv.LeadingComments, "")
+	p.printf("⟁Sek⟁ %s", cv.VectorType)
+	p.printTrailing(cv.TrailingComment)
+	p.printParams(cv.Params, p.cfg.Indent)
+}
+
+func (p *printer) printBlockDefinition(b *ast.BlockDefinition) {
+	p.printComments(b.LeadingComments, "")
+	p.printf("⟁Xul⟁ %s", b.Name)
+	p.printTrailing(b.TrailingComment)
+	p.printParams(b.Params, p.cfg.Indent)
+	for _, n := range b.Body {
+		if cb, ok := n.(*ast.CoolBlock); ok {
+			p.printIndentedCoolBlock(cb, p.cfg.Indent)
+		}
+	}
+	p.writeString("⟁Ch'en⟁\n")
+}
+
+func (p *printer) printAtomicBlock(ab *ast.AtomicBlock) {
+	p.printComments(ab.LeadingComments, "")
+	p.printf("ATOMIC_BLOCK_%s %s", ab.Name, p.renderJSON(ab.Content, ""))
+	p.printTrailing(ab.TrailingComment)
+}
+
+func (p *printer) printCoolVariable(cvar *ast.CoolVariable) {
+	p.printComments(cvar.LeadingComments, "")
+	p.printf("C@@L ATOMIC_VARIABLE %s", cvar.Name)
+	p.printTrailing(cvar.TrailingComment)
+	params := map[string]interface{}{"scope": cvar.Scope}
+	if cvar.DefaultValue != nil {
+		params["default"] = cvar.DefaultValue
+	}
+	p.printParams(params, p.cfg.Indent)
+}
+
+func (p *printer) printCoolVector(cv *ast.CoolVector) {
+	p.printComments(cv.LeadingComments, "")
+	p.printf("C@@L ATOMIC_VECTOR %s", cv.Name)
+	p.printTrailing(cv.TrailingComment)
+	p.printParams(cv.Params, p.cfg.Indent)
+}
+
+func (p *printer) printCoolBlock(cb *ast.CoolBlock) {
+	p.printComments(cb.LeadingComments, "")
+	p.printf("C@@L BLOCK %s", cb.Name)
+	p.printTrailing(cb.TrailingComment)
+	p.printParams(cb.Params, p.cfg.Indent)
+	for _, v := range cb.Body {
+		p.printf("%s%s\n", p.cfg.Indent, p.renderJSON(v, p.cfg.Indent))
+	}
+}
+
+// printIndentedCoolBlock renders a CoolBlock nested inside a ⟁Xul⟁ body,
+// indenting every line it produces by prefix.
+func (p *printer) printIndentedCoolBlock(cb *ast.CoolBlock, prefix string) {
+	var buf bytes.Buffer
+	inner := &printer{w: &buf, cfg: p.cfg}
+	inner.printCoolBlock(cb)
+	if inner.err != nil && p.err == nil {
+		p.err = inner.err
+	}
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		if line == "" {
+			p.writeString("\n")
+			continue
+		}
+		p.printf("%s%s\n", prefix, line)
+	}
+}
+
+// printParams renders @param: value lines with @-columns aligned to the
+// widest key, sorted by key for determinism.
+func (p *printer) printParams(params map[string]interface{}, prefix string) {
+	keys := sortedValueKeys(params)
+	if len(keys) == 0 {
+		return
+	}
+
+	width := 0
+	for _, k := range keys {
+		if len(k) > width {
+			width = len(k)
+		}
+	}
+
+	for _, k := range keys {
+		at := "@" + k + ":"
+		pad := strings.Repeat(" ", width-len(k))
+		p.printf("%s%s%s %s\n", prefix, at, pad, p.renderValue(params[k], prefix))
+	}
+}
+
+func (p *printer) printComments(comments []string, prefix string) {
+	for _, c := range comments {
+		p.printf("%s// %s\n", prefix, c)
+	}
+}
+
+func (p *printer) printTrailing(comment string) {
+	if comment != "" {
+		p.printf(" // %s", comment)
+	}
+	p.writeString("\n")
+}
+
+// renderExprValue renders an ⟁Wo⟁ assignment value, which is either a plain
+// Go value (unwrapExprValue's common case) or an ast.Node for a real
+// expression (binary/call/member/...).
+func (p *printer) renderExprValue(v interface{}, indent string) string {
+	if n, ok := v.(ast.Node); ok {
+		return p.renderExprNode(n)
+	}
+	return p.renderValue(v, indent)
+}
+
+func (p *printer) renderExprNode(n ast.Node) string {
+	switch e := n.(type) {
+	case nil:
+		return "null"
+	case *ast.Literal:
+		return p.renderValue(e.Value, "")
+	case *ast.Identifier:
+		return e.Name
+	case *ast.BinaryExpr:
+		return p.renderExprNode(e.Left) + " " + e.Operator + " " + p.renderExprNode(e.Right)
+	case *ast.UnaryExpr:
+		return e.Operator + p.renderExprNode(e.Operand)
+	case *ast.CallExpr:
+		args := make([]string, len(e.Arguments))
+		for i, a := range e.Arguments {
+			args[i] = p.renderExprNode(a)
+		}
+		return p.renderExprNode(e.Callee) + "(" + strings.Join(args, ", ") + ")"
+	case *ast.MemberExpr:
+		return p.renderExprNode(e.Object) + "." + e.Property
+	case *ast.IndexExpr:
+		return p.renderExprNode(e.Object) + "[" + p.renderExprNode(e.Index) + "]"
+	case *ast.ArrayExpr:
+		elems := make([]string, len(e.Elements))
+		for i, el := range e.Elements {
+			elems[i] = p.renderExprNode(el)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case *ast.ObjectExpr:
+		keys := make([]string, 0, len(e.Properties))
+		for k := range e.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = strconv.Quote(k) + ": " + p.renderExprNode(e.Properties[k])
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	default:
+		return n.String()
+	}
+}
+
+// renderValue renders a plain (non-AST-node) value: JSON payloads are
+// reformatted with json.MarshalIndent at indent, everything else uses a
+// compact literal form.
+func (p *printer) renderValue(v interface{}, indent string) string {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return p.renderJSON(v, indent)
+	}
+
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(t)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprintf("%v", t)
+		}
+		return string(b)
+	}
+}
+
+func (p *printer) renderJSON(v interface{}, indent string) string {
+	b, err := json.MarshalIndent(v, indent, p.cfg.Indent)
+	if err != nil {
+		if p.err == nil {
+			p.err = err
+		}
+		return "{}"
+	}
+	return string(b)
+}
+
+// sortedValueKeys returns the keys of a params/content map in sorted order,
+// giving the formatter deterministic output over Go's randomized map
+// iteration.
+func sortedValueKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,84 @@
+package printer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatCanonicalizesSpacingAndOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "assignment gets canonical spacing and one trailing newline",
+			source: `⟁Wo⟁ x=10`,
+			want:   "⟁Wo⟁ x = 10\n",
+		},
+		{
+			name:   "param columns align to the widest key",
+			source: "C@@L ATOMIC_VECTOR @v\n@a: 1\n@longer: 2\n",
+			want:   "C@@L ATOMIC_VECTOR @v\n  @a:      1\n  @longer: 2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format(tt.source, nil)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatIsIdempotent(t *testing.T) {
+	source := "⟁Wo⟁ x = 10\n\n⟁Wo⟁ y = 20\n"
+	once, err := Format(source, nil)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	twice, err := Format(once, nil)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if once != twice {
+		t.Errorf("formatting is not idempotent: first pass %q, second pass %q", once, twice)
+	}
+}
+
+func TestFormatPreservesLeadingComment(t *testing.T) {
+	source := "// keep me\n⟁Wo⟁ x = 10\n"
+	got, err := Format(source, nil)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if !strings.Contains(got, "// keep me") {
+		t.Errorf("Format() = %q, want it to preserve the leading comment", got)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "a\nb\nc\n"
+	after := "a\nx\nc\n"
+
+	diff := UnifiedDiff("f.khl", before, after)
+	if diff == "" {
+		t.Fatal("UnifiedDiff() = \"\", want a non-empty diff")
+	}
+	for _, want := range []string{"--- f.khl", "+++ f.khl", "-b", "+x"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("UnifiedDiff() = %q, want it to contain %q", diff, want)
+		}
+	}
+}
+
+func TestUnifiedDiffEmptyWhenEqual(t *testing.T) {
+	if diff := UnifiedDiff("f.khl", "same\n", "same\n"); diff != "" {
+		t.Errorf("UnifiedDiff() = %q, want \"\" for identical input", diff)
+	}
+}
@@ -0,0 +1,132 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+)
+
+// BuiltinBackend executes the numeric kernels behind the matrix builtins
+// matrix_multiply, transpose and softmax (see evaluateBackendCall); dot is
+// computed by builtinDot without it, and no builtin calls Gemv. cpuBackend is
+// always available;
+// newOpenCLBackend/newCUDABackend are compiled in only under the matching
+// build tag ("opencl" / "cuda") and return nil otherwise, so a manifest that
+// asks for an accelerator this binary wasn't built with falls back to CPU.
+type BuiltinBackend interface {
+	Name() string
+	MatMul(a, b Tensor) (Tensor, error)
+	Transpose(a Tensor) (Tensor, error)
+	Softmax(a Tensor) (Tensor, error)
+	Gemv(a, x Tensor) (Tensor, error)
+}
+
+// selectBackend picks a BuiltinBackend per the manifest's "accel" key
+// ("opencl", "cuda", or anything else/absent for "cpu"). The chosen backend
+// (OpenCL compiles its kernel and CUDA creates its cuBLAS handle once, at
+// construction) is cached on rs.KernelCache so reloading the same manifest
+// doesn't redo that setup.
+func selectBackend(rs *RuntimeState, manifest map[string]interface{}) BuiltinBackend {
+	accel, _ := manifest["accel"].(string)
+	if cached, ok := rs.KernelCache["backend:"+accel]; ok {
+		return cached.(BuiltinBackend)
+	}
+
+	backend := cpuBackend{}
+	var result BuiltinBackend = backend
+	switch accel {
+	case "opencl":
+		if b := newOpenCLBackend(); b != nil {
+			result = b
+		} else {
+			rs.AddError(`accel "opencl" requested but this binary was not built with the opencl tag; falling back to cpu`)
+		}
+	case "cuda":
+		if b := newCUDABackend(); b != nil {
+			result = b
+		} else {
+			rs.AddError(`accel "cuda" requested but this binary was not built with the cuda tag; falling back to cpu`)
+		}
+	}
+
+	rs.KernelCache["backend:"+accel] = result
+	return result
+}
+
+// cpuBackend is the default BuiltinBackend: plain Go loops over Tensor.Data.
+type cpuBackend struct{}
+
+func (cpuBackend) Name() string { return "cpu" }
+
+func (cpuBackend) MatMul(a, b Tensor) (Tensor, error) {
+	if len(a.Shape) != 2 || len(b.Shape) != 2 || a.Shape[1] != b.Shape[0] {
+		return Tensor{}, fmt.Errorf("matmul: dimension mismatch %v x %v", a.Shape, b.Shape)
+	}
+	rows, inner, cols := a.Shape[0], a.Shape[1], b.Shape[1]
+	out := NewTensor(rows, cols)
+	for i := 0; i < rows; i++ {
+		for k := 0; k < inner; k++ {
+			aik := a.At(i, k)
+			if aik == 0 {
+				continue
+			}
+			for j := 0; j < cols; j++ {
+				out.Data[i*cols+j] += aik * b.At(k, j)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (cpuBackend) Transpose(a Tensor) (Tensor, error) {
+	if len(a.Shape) != 2 {
+		return Tensor{}, fmt.Errorf("transpose: expected a 2D tensor, got shape %v", a.Shape)
+	}
+	rows, cols := a.Shape[0], a.Shape[1]
+	out := NewTensor(cols, rows)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			out.Data[j*rows+i] = a.At(i, j)
+		}
+	}
+	return out, nil
+}
+
+func (cpuBackend) Softmax(a Tensor) (Tensor, error) {
+	if len(a.Shape) != 1 {
+		return Tensor{}, fmt.Errorf("softmax: expected a 1D tensor, got shape %v", a.Shape)
+	}
+	out := NewTensor(a.Shape[0])
+
+	maxVal := a.Data[0]
+	for _, v := range a.Data[1:] {
+		if v > maxVal {
+			maxVal = v
+		}
+	}
+
+	sum := float32(0)
+	for i, v := range a.Data {
+		out.Data[i] = float32(math.Exp(float64(v - maxVal)))
+		sum += out.Data[i]
+	}
+	for i := range out.Data {
+		out.Data[i] /= sum
+	}
+	return out, nil
+}
+
+func (cpuBackend) Gemv(a, x Tensor) (Tensor, error) {
+	if len(a.Shape) != 2 || len(x.Shape) != 1 || a.Shape[1] != x.Shape[0] {
+		return Tensor{}, fmt.Errorf("gemv: dimension mismatch %v x %v", a.Shape, x.Shape)
+	}
+	rows, cols := a.Shape[0], a.Shape[1]
+	out := NewTensor(rows)
+	for i := 0; i < rows; i++ {
+		sum := float32(0)
+		for j := 0; j < cols; j++ {
+			sum += a.At(i, j) * x.Data[j]
+		}
+		out.Data[i] = sum
+	}
+	return out, nil
+}
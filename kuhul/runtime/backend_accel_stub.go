@@ -0,0 +1,8 @@
+//go:build !opencl
+
+package runtime
+
+// newOpenCLBackend is only implemented when this binary is built with
+// `-tags opencl` (requires cgo and the OpenCL ICD loader on the host);
+// selectBackend falls back to cpuBackend when it returns nil.
+func newOpenCLBackend() BuiltinBackend { return nil }
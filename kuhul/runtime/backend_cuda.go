@@ -0,0 +1,78 @@
+//go:build cuda
+
+package runtime
+
+/*
+#cgo LDFLAGS: -lcudart -lcublas
+#include <cuda_runtime.h>
+#include <cublas_v2.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// cudaBackend runs matrix_multiply via cuBLAS's Sgemm. Like openCLBackend, it
+// delegates Transpose/Softmax/Gemv to cpu since those are bandwidth-bound and
+// don't earn back the device round-trip at the sizes K'UHUL programs use.
+type cudaBackend struct {
+	cpu    cpuBackend
+	handle C.cublasHandle_t
+}
+
+// newCUDABackend returns nil (never an error) if cuBLAS can't be initialized,
+// so callers fall back to cpuBackend the same way as a missing OpenCL device.
+func newCUDABackend() BuiltinBackend {
+	var handle C.cublasHandle_t
+	if C.cublasCreate(&handle) != C.CUBLAS_STATUS_SUCCESS {
+		return nil
+	}
+	return &cudaBackend{handle: handle}
+}
+
+func (b *cudaBackend) Name() string { return "cuda" }
+
+func (b *cudaBackend) MatMul(a, x Tensor) (Tensor, error) {
+	if len(a.Shape) != 2 || len(x.Shape) != 2 || a.Shape[1] != x.Shape[0] {
+		return Tensor{}, fmt.Errorf("matmul: dimension mismatch %v x %v", a.Shape, x.Shape)
+	}
+	rows, inner, cols := a.Shape[0], a.Shape[1], x.Shape[1]
+	out := NewTensor(rows, cols)
+	elemSize := C.size_t(unsafe.Sizeof(float32(0)))
+
+	var dA, dX, dOut unsafe.Pointer
+	C.cudaMalloc(&dA, elemSize*C.size_t(len(a.Data)))
+	C.cudaMalloc(&dX, elemSize*C.size_t(len(x.Data)))
+	C.cudaMalloc(&dOut, elemSize*C.size_t(len(out.Data)))
+	defer C.cudaFree(dA)
+	defer C.cudaFree(dX)
+	defer C.cudaFree(dOut)
+
+	C.cudaMemcpy(dA, unsafe.Pointer(&a.Data[0]), elemSize*C.size_t(len(a.Data)), C.cudaMemcpyHostToDevice)
+	C.cudaMemcpy(dX, unsafe.Pointer(&x.Data[0]), elemSize*C.size_t(len(x.Data)), C.cudaMemcpyHostToDevice)
+
+	one := C.float(1.0)
+	zero := C.float(0.0)
+	// cuBLAS is column-major; compute out^T = x^T * a^T (i.e. swap operands
+	// and dimensions) so the row-major result comes out right without an
+	// extra transpose kernel.
+	status := C.cublasSgemm(b.handle, C.CUBLAS_OP_N, C.CUBLAS_OP_N,
+		C.int(cols), C.int(rows), C.int(inner),
+		&one,
+		(*C.float)(dX), C.int(cols),
+		(*C.float)(dA), C.int(inner),
+		&zero,
+		(*C.float)(dOut), C.int(cols))
+	if status != C.CUBLAS_STATUS_SUCCESS {
+		return Tensor{}, fmt.Errorf("matmul: cublasSgemm failed with status %d", status)
+	}
+
+	C.cudaMemcpy(unsafe.Pointer(&out.Data[0]), dOut, elemSize*C.size_t(len(out.Data)), C.cudaMemcpyDeviceToHost)
+	return out, nil
+}
+
+func (b *cudaBackend) Transpose(a Tensor) (Tensor, error) { return b.cpu.Transpose(a) }
+func (b *cudaBackend) Softmax(a Tensor) (Tensor, error)   { return b.cpu.Softmax(a) }
+func (b *cudaBackend) Gemv(a, x Tensor) (Tensor, error)   { return b.cpu.Gemv(a, x) }
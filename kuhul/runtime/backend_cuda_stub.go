@@ -0,0 +1,8 @@
+//go:build !cuda
+
+package runtime
+
+// newCUDABackend is only implemented when this binary is built with
+// `-tags cuda` (requires cgo and the CUDA toolkit on the host); selectBackend
+// falls back to cpuBackend when it returns nil.
+func newCUDABackend() BuiltinBackend { return nil }
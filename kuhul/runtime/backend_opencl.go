@@ -0,0 +1,128 @@
+//go:build opencl
+
+package runtime
+
+/*
+#cgo LDFLAGS: -lOpenCL
+#include <CL/cl.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+const matMulKernelSource = `
+__kernel void matmul(__global const float *a, __global const float *b,
+                      __global float *out, int aCols, int bCols) {
+	int row = get_global_id(0);
+	int col = get_global_id(1);
+	float sum = 0.0f;
+	for (int k = 0; k < aCols; k++) {
+		sum += a[row * aCols + k] * b[k * bCols + col];
+	}
+	out[row * bCols + col] = sum;
+}
+`
+
+// openCLBackend runs matrix_multiply on the first available OpenCL device.
+// Transpose/Softmax/Gemv are memory-bandwidth bound rather than compute
+// bound, so they're cheap enough on the CPU that shipping kernels for them
+// isn't worth the launch overhead; openCLBackend delegates those to cpu.
+type openCLBackend struct {
+	cpu     cpuBackend
+	context C.cl_context
+	queue   C.cl_command_queue
+	program C.cl_program
+	kernel  C.cl_kernel
+}
+
+// newOpenCLBackend initializes the first OpenCL platform/device it finds and
+// compiles the matmul kernel. It returns nil (never an error) so callers can
+// treat "no device" the same as "not built with the opencl tag".
+func newOpenCLBackend() BuiltinBackend {
+	var platform C.cl_platform_id
+	if C.clGetPlatformIDs(1, &platform, nil) != C.CL_SUCCESS {
+		return nil
+	}
+
+	var device C.cl_device_id
+	if C.clGetDeviceIDs(platform, C.CL_DEVICE_TYPE_GPU, 1, &device, nil) != C.CL_SUCCESS {
+		if C.clGetDeviceIDs(platform, C.CL_DEVICE_TYPE_CPU, 1, &device, nil) != C.CL_SUCCESS {
+			return nil
+		}
+	}
+
+	var err C.cl_int
+	context := C.clCreateContext(nil, 1, &device, nil, nil, &err)
+	if err != C.CL_SUCCESS {
+		return nil
+	}
+	queue := C.clCreateCommandQueue(context, device, 0, &err)
+	if err != C.CL_SUCCESS {
+		return nil
+	}
+
+	src := C.CString(matMulKernelSource)
+	defer C.free(unsafe.Pointer(src))
+	program := C.clCreateProgramWithSource(context, 1, &src, nil, &err)
+	if err != C.CL_SUCCESS {
+		return nil
+	}
+	if C.clBuildProgram(program, 1, &device, nil, nil, nil) != C.CL_SUCCESS {
+		return nil
+	}
+
+	kernelName := C.CString("matmul")
+	defer C.free(unsafe.Pointer(kernelName))
+	kernel := C.clCreateKernel(program, kernelName, &err)
+	if err != C.CL_SUCCESS {
+		return nil
+	}
+
+	return &openCLBackend{context: context, queue: queue, program: program, kernel: kernel}
+}
+
+func (b *openCLBackend) Name() string { return "opencl" }
+
+func (b *openCLBackend) MatMul(a, x Tensor) (Tensor, error) {
+	if len(a.Shape) != 2 || len(x.Shape) != 2 || a.Shape[1] != x.Shape[0] {
+		return Tensor{}, fmt.Errorf("matmul: dimension mismatch %v x %v", a.Shape, x.Shape)
+	}
+	rows, inner, cols := a.Shape[0], a.Shape[1], x.Shape[1]
+	out := NewTensor(rows, cols)
+
+	elemSize := C.size_t(unsafe.Sizeof(float32(0)))
+	var err C.cl_int
+	aBuf := C.clCreateBuffer(b.context, C.CL_MEM_READ_ONLY|C.CL_MEM_COPY_HOST_PTR,
+		elemSize*C.size_t(len(a.Data)), unsafe.Pointer(&a.Data[0]), &err)
+	xBuf := C.clCreateBuffer(b.context, C.CL_MEM_READ_ONLY|C.CL_MEM_COPY_HOST_PTR,
+		elemSize*C.size_t(len(x.Data)), unsafe.Pointer(&x.Data[0]), &err)
+	outBuf := C.clCreateBuffer(b.context, C.CL_MEM_WRITE_ONLY, elemSize*C.size_t(len(out.Data)), nil, &err)
+	defer C.clReleaseMemObject(aBuf)
+	defer C.clReleaseMemObject(xBuf)
+	defer C.clReleaseMemObject(outBuf)
+
+	cInner := C.int(inner)
+	cCols := C.int(cols)
+	C.clSetKernelArg(b.kernel, 0, C.size_t(unsafe.Sizeof(aBuf)), unsafe.Pointer(&aBuf))
+	C.clSetKernelArg(b.kernel, 1, C.size_t(unsafe.Sizeof(xBuf)), unsafe.Pointer(&xBuf))
+	C.clSetKernelArg(b.kernel, 2, C.size_t(unsafe.Sizeof(outBuf)), unsafe.Pointer(&outBuf))
+	C.clSetKernelArg(b.kernel, 3, C.size_t(unsafe.Sizeof(cInner)), unsafe.Pointer(&cInner))
+	C.clSetKernelArg(b.kernel, 4, C.size_t(unsafe.Sizeof(cCols)), unsafe.Pointer(&cCols))
+
+	globalSize := [2]C.size_t{C.size_t(rows), C.size_t(cols)}
+	if C.clEnqueueNDRangeKernel(b.queue, b.kernel, 2, nil, &globalSize[0], nil, 0, nil, nil) != C.CL_SUCCESS {
+		return Tensor{}, fmt.Errorf("matmul: kernel launch failed")
+	}
+	C.clEnqueueReadBuffer(b.queue, outBuf, C.CL_TRUE, 0, elemSize*C.size_t(len(out.Data)),
+		unsafe.Pointer(&out.Data[0]), 0, nil, nil)
+
+	return out, nil
+}
+
+func (b *openCLBackend) Transpose(a Tensor) (Tensor, error) { return b.cpu.Transpose(a) }
+func (b *openCLBackend) Softmax(a Tensor) (Tensor, error)   { return b.cpu.Softmax(a) }
+func (b *openCLBackend) Gemv(a, x Tensor) (Tensor, error)   { return b.cpu.Gemv(a, x) }
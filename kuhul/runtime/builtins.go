@@ -3,202 +3,485 @@ package runtime
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 )
 
-// BuiltinFunc represents a built-in function
-type BuiltinFunc func(args ...interface{}) interface{}
+// BuiltinFuncCtx is a built-in function that needs the calling Interpreter
+// rather than just its evaluated args — today, that's map/filter/reduce/sort
+// (see BuiltinsCtx), which call back into a *Callable lambda argument via
+// Interpreter.evaluateValue.
+type BuiltinFuncCtx func(interp *Interpreter, args ...interface{}) interface{}
+
+// BuiltinsCtx holds the built-ins evaluateCallExpr checks before Builtins,
+// since they need the interpreter to invoke a Callable argument. Populated in
+// init rather than here: several of these funcs invoke a Callable via
+// Interpreter.evaluateValue, which (through evaluateCallExpr) reads
+// BuiltinsCtx itself, so a composite-literal initializer would be an
+// initialization cycle.
+var BuiltinsCtx map[string]BuiltinFuncCtx
+
+func init() {
+	BuiltinsCtx = map[string]BuiltinFuncCtx{
+		"map":    builtinMapCtx,
+		"filter": builtinFilterCtx,
+		"reduce": builtinReduceCtx,
+		"sort":   builtinSortCtx,
+		"select": builtinSelectCtx,
+		"walk":   builtinWalkCtx,
+	}
+}
+
+// Kind categorizes what type of value a Builtin's parameter expects, for
+// Call's type checking. KindAny accepts anything, for a parameter a builtin
+// handles dynamically itself (object()'s alternating key/value pairs, or a
+// type-conversion builtin like str() whose whole job is accepting any type).
+type Kind string
+
+const (
+	KindAny    Kind = "any"
+	KindNumber Kind = "number"
+	KindString Kind = "string"
+	KindArray  Kind = "array"
+	KindObject Kind = "object"
+	KindBool   Kind = "bool"
+	// KindInt is KindNumber narrowed to integer-kinded values (isIntegral) —
+	// for mod/divmod/gcd/lcm and the bitwise builtins, which operate on
+	// int64 and reject a float64 argument (even an integral one like 2.0)
+	// rather than silently truncating it.
+	KindInt Kind = "int"
+)
+
+// matchesKind reports whether v is an acceptable argument for kind.
+func matchesKind(kind Kind, v interface{}) bool {
+	switch kind {
+	case KindAny:
+		return true
+	case KindNumber:
+		switch v.(type) {
+		case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return true
+		default:
+			return false
+		}
+	case KindInt:
+		return isIntegral(v)
+	case KindString:
+		_, ok := v.(string)
+		return ok
+	case KindArray:
+		if _, ok := v.([]interface{}); ok {
+			return true
+		}
+		_, ok := v.(*Tensor)
+		return ok
+	case KindObject:
+		_, ok := v.(map[string]interface{})
+		return ok
+	case KindBool:
+		_, ok := v.(bool)
+		return ok
+	default:
+		return true
+	}
+}
+
+// ArityError reports a Builtin call with the wrong number of arguments.
+type ArityError struct {
+	Name string
+	Got  int
+	Want string // e.g. "2", "1-3", "at least 1"
+}
+
+func (e *ArityError) Error() string {
+	return fmt.Sprintf("%s: expected %s argument(s), got %d", e.Name, e.Want, e.Got)
+}
+
+// TypeError reports a Builtin call whose ArgIndex'th argument (0-based)
+// didn't match its declared Kind.
+type TypeError struct {
+	Name     string
+	ArgIndex int
+	Got      interface{}
+	Want     Kind
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%s: argument %d: expected %s, got %T", e.Name, e.ArgIndex, e.Want, e.Got)
+}
+
+// Builtin declares one entry in Builtins: its name (for error messages),
+// arity bounds, parameter kinds, and implementation. Call validates a call
+// against MinArity/MaxArity/Variadic/ParamKinds before Fn ever runs, so Fn
+// itself can assume its args are present and well-typed instead of
+// re-checking len(args) and re-coercing types the way every builtin used to.
+type Builtin struct {
+	Name     string
+	MinArity int
+	MaxArity int  // ignored (no upper bound) when Variadic is true
+	Variadic bool // true if args beyond MaxArity (or MinArity, if Variadic) are accepted
+	// ParamKinds is indexed by argument position; the last entry repeats for
+	// any variadic argument past len(ParamKinds). A nil/short ParamKinds
+	// treats the missing positions as KindAny.
+	ParamKinds []Kind
+	Fn         func(args ...interface{}) (interface{}, error)
+}
+
+// kindFor returns the Kind Call should check argument idx against.
+func (b *Builtin) kindFor(idx int) Kind {
+	if len(b.ParamKinds) == 0 {
+		return KindAny
+	}
+	if idx < len(b.ParamKinds) {
+		return b.ParamKinds[idx]
+	}
+	return b.ParamKinds[len(b.ParamKinds)-1]
+}
+
+func (b *Builtin) arityWant() string {
+	switch {
+	case b.Variadic && b.MinArity == 0:
+		return "any number of"
+	case b.Variadic:
+		return fmt.Sprintf("at least %d", b.MinArity)
+	case b.MinArity == b.MaxArity:
+		return fmt.Sprintf("%d", b.MinArity)
+	default:
+		return fmt.Sprintf("%d-%d", b.MinArity, b.MaxArity)
+	}
+}
+
+// Call looks up name in Builtins, validates args against its arity and
+// ParamKinds, and invokes it — returning an *ArityError/*TypeError instead of
+// the silent nil/best-effort results the old per-function "if len(args) < N"
+// guards produced on a bad call.
+func Call(name string, args ...interface{}) (interface{}, error) {
+	b, ok := Builtins[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function: %s", name)
+	}
+
+	if len(args) < b.MinArity || (!b.Variadic && len(args) > b.MaxArity) {
+		return nil, &ArityError{Name: name, Got: len(args), Want: b.arityWant()}
+	}
 
-// Builtins contains all built-in functions
-var Builtins = map[string]BuiltinFunc{
+	for idx, arg := range args {
+		if kind := b.kindFor(idx); !matchesKind(kind, arg) {
+			return nil, &TypeError{Name: name, ArgIndex: idx, Got: arg, Want: kind}
+		}
+	}
+
+	return b.Fn(args...)
+}
+
+// Builtins contains all built-in functions, keyed by the name K'UHUL source
+// calls them by.
+var Builtins = map[string]*Builtin{
 	// Math functions
-	"abs":   builtinAbs,
-	"min":   builtinMin,
-	"max":   builtinMax,
-	"floor": builtinFloor,
-	"ceil":  builtinCeil,
-	"round": builtinRound,
-	"sqrt":  builtinSqrt,
-	"pow":   builtinPow,
-	"exp":   builtinExp,
-	"log":   builtinLog,
+	"abs":   {Name: "abs", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindNumber}, Fn: builtinAbs},
+	"min":   {Name: "min", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindNumber, KindNumber}, Fn: builtinMin},
+	"max":   {Name: "max", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindNumber, KindNumber}, Fn: builtinMax},
+	"floor": {Name: "floor", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindNumber}, Fn: builtinFloor},
+	"ceil":  {Name: "ceil", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindNumber}, Fn: builtinCeil},
+	"round": {Name: "round", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindNumber}, Fn: builtinRound},
+	"sqrt":  {Name: "sqrt", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindNumber}, Fn: builtinSqrt},
+	"pow":   {Name: "pow", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindNumber, KindNumber}, Fn: builtinPow},
+	"exp":   {Name: "exp", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindNumber}, Fn: builtinExp},
+	"log":   {Name: "log", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindNumber}, Fn: builtinLog},
+
+	// Integer-tower functions — these reject a float argument outright
+	// (KindInt) rather than truncating it, since mod/gcd/lcm and bitwise
+	// ops are only well-defined over integers.
+	"mod":    {Name: "mod", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindInt, KindInt}, Fn: builtinMod},
+	"divmod": {Name: "divmod", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindInt, KindInt}, Fn: builtinDivmod},
+	"gcd":    {Name: "gcd", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindInt, KindInt}, Fn: builtinGcd},
+	"lcm":    {Name: "lcm", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindInt, KindInt}, Fn: builtinLcm},
+	"band":   {Name: "band", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindInt, KindInt}, Fn: builtinBand},
+	"bor":    {Name: "bor", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindInt, KindInt}, Fn: builtinBor},
+	"bxor":   {Name: "bxor", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindInt, KindInt}, Fn: builtinBxor},
+	"bshl":   {Name: "bshl", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindInt, KindInt}, Fn: builtinBshl},
+	"bshr":   {Name: "bshr", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindInt, KindInt}, Fn: builtinBshr},
 
 	// Array functions
-	"len":     builtinLen,
-	"push":    builtinPush,
-	"pop":     builtinPop,
-	"slice":   builtinSlice,
-	"concat":  builtinConcat,
-	"map":     builtinMap,
-	"filter":  builtinFilter,
-	"reduce":  builtinReduce,
-	"reverse": builtinReverse,
-	"sort":    builtinSort,
+	"len":     {Name: "len", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindAny}, Fn: builtinLen},
+	"push":    {Name: "push", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindArray, KindAny}, Fn: builtinPush},
+	"pop":     {Name: "pop", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindArray}, Fn: builtinPop},
+	"slice":   {Name: "slice", MinArity: 3, MaxArity: 3, ParamKinds: []Kind{KindArray, KindNumber, KindNumber}, Fn: builtinSlice},
+	"concat":  {Name: "concat", MinArity: 0, Variadic: true, ParamKinds: []Kind{KindAny}, Fn: builtinConcat},
+	"reverse": {Name: "reverse", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindArray}, Fn: builtinReverse},
 
 	// String functions
-	"upper":    builtinUpper,
-	"lower":    builtinLower,
-	"trim":     builtinTrim,
-	"split":    builtinSplit,
-	"join":     builtinJoin,
-	"replace":  builtinReplace,
-	"contains": builtinContains,
-	"starts":   builtinStartsWith,
-	"ends":     builtinEndsWith,
-
-	// Type functions
-	"type":   builtinType,
-	"str":    builtinStr,
-	"int":    builtinInt,
-	"float":  builtinFloat,
-	"bool":   builtinBool,
-	"array":  builtinArray,
-	"object": builtinObject,
+	"upper":    {Name: "upper", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindString}, Fn: builtinUpper},
+	"lower":    {Name: "lower", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindString}, Fn: builtinLower},
+	"trim":     {Name: "trim", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindString}, Fn: builtinTrim},
+	"split":    {Name: "split", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindString, KindString}, Fn: builtinSplit},
+	"join":     {Name: "join", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindArray, KindString}, Fn: builtinJoin},
+	"replace":  {Name: "replace", MinArity: 3, MaxArity: 3, ParamKinds: []Kind{KindString, KindString, KindString}, Fn: builtinReplace},
+	"contains": {Name: "contains", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindString, KindString}, Fn: builtinContains},
+	"starts":   {Name: "starts", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindString, KindString}, Fn: builtinStartsWith},
+	"ends":     {Name: "ends", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindString, KindString}, Fn: builtinEndsWith},
+
+	// Type functions — these exist specifically to coerce an arbitrary value,
+	// so their one argument stays KindAny rather than any narrower Kind.
+	"type":   {Name: "type", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindAny}, Fn: builtinType},
+	"str":    {Name: "str", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindAny}, Fn: builtinStr},
+	"int":    {Name: "int", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindAny}, Fn: builtinInt},
+	"float":  {Name: "float", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindAny}, Fn: builtinFloat},
+	"bool":   {Name: "bool", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindAny}, Fn: builtinBool},
+	"array":  {Name: "array", MinArity: 0, Variadic: true, ParamKinds: []Kind{KindAny}, Fn: builtinArray},
+	"object": {Name: "object", MinArity: 0, Variadic: true, ParamKinds: []Kind{KindAny}, Fn: builtinObject},
 
 	// Matrix functions (for Llama K'UHUL)
-	"matrix_multiply": builtinMatrixMultiply,
-	"transpose":       builtinTranspose,
-	"softmax":         builtinSoftmax,
-	"dot":             builtinDot,
-	"zeros":           builtinZeros,
-	"ones":            builtinOnes,
+	"matrix_multiply": {Name: "matrix_multiply", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindArray, KindArray}, Fn: builtinMatrixMultiply},
+	"transpose":       {Name: "transpose", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindArray}, Fn: builtinTranspose},
+	"softmax":         {Name: "softmax", MinArity: 1, MaxArity: 2, ParamKinds: []Kind{KindArray, KindNumber}, Fn: builtinSoftmaxAxis},
+	"dot":             {Name: "dot", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindArray, KindArray}, Fn: builtinDot},
+	"zeros":           {Name: "zeros", MinArity: 1, MaxArity: 2, ParamKinds: []Kind{KindNumber, KindNumber}, Fn: builtinZeros},
+	"ones":            {Name: "ones", MinArity: 1, MaxArity: 2, ParamKinds: []Kind{KindNumber, KindNumber}, Fn: builtinOnes},
+	"matmul":          {Name: "matmul", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindArray, KindArray}, Fn: builtinMatmul},
+	"reshape":         {Name: "reshape", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindArray, KindArray}, Fn: builtinReshape},
+	"tensor":          {Name: "tensor", MinArity: 1, MaxArity: 2, ParamKinds: []Kind{KindArray, KindAny}, Fn: builtinTensor},
+	"to_array":        {Name: "to_array", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindArray}, Fn: builtinToArray},
+	"add":             {Name: "add", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindArray, KindArray}, Fn: builtinTensorAdd},
+	"mul":             {Name: "mul", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindArray, KindArray}, Fn: builtinTensorMul},
+	"layer_norm":      {Name: "layer_norm", MinArity: 3, MaxArity: 4, ParamKinds: []Kind{KindArray, KindArray, KindArray, KindNumber}, Fn: builtinLayerNorm},
+	"rms_norm":        {Name: "rms_norm", MinArity: 2, MaxArity: 3, ParamKinds: []Kind{KindArray, KindArray, KindNumber}, Fn: builtinRMSNorm},
+	"gelu":            {Name: "gelu", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindArray}, Fn: builtinGelu},
+	"silu":            {Name: "silu", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindArray}, Fn: builtinSilu},
 
 	// Utility functions
-	"print":  builtinPrint,
-	"range":  builtinRange,
-	"keys":   builtinKeys,
-	"values": builtinValues,
+	"print":  {Name: "print", MinArity: 0, Variadic: true, ParamKinds: []Kind{KindAny}, Fn: builtinPrint},
+	"range":  {Name: "range", MinArity: 1, MaxArity: 3, ParamKinds: []Kind{KindNumber, KindNumber, KindNumber}, Fn: builtinRange},
+	"keys":   {Name: "keys", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindObject}, Fn: builtinKeys},
+	"values": {Name: "values", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindObject}, Fn: builtinValues},
+
+	// jq-style path/query functions — path is a dotted string ("a.b[0].c",
+	// with ".field" / "[index]" / "[*]" splat / "[start:end]" slice) or a
+	// mixed array (["a", "b", 0, "c"]). See runtime/pathexpr.go.
+	"get":   {Name: "get", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindAny, KindAny}, Fn: builtinGet},
+	"set":   {Name: "set", MinArity: 3, MaxArity: 3, ParamKinds: []Kind{KindAny, KindAny, KindAny}, Fn: builtinSet},
+	"has":   {Name: "has", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindAny, KindAny}, Fn: builtinHas},
+	"del":   {Name: "del", MinArity: 2, MaxArity: 2, ParamKinds: []Kind{KindAny, KindAny}, Fn: builtinDel},
+	"paths": {Name: "paths", MinArity: 1, MaxArity: 1, ParamKinds: []Kind{KindAny}, Fn: builtinPaths},
+}
+
+// Math builtins. abs/min/max/floor/ceil/round funnel through
+// numericBinop/numericUnop (see numeric.go), and pow has its own int64 lane
+// (see builtinPow), so an int64 argument stays int64 instead of coercing
+// through float64 and losing precision above 2^53. sqrt/exp/log always produce an irrational-capable result, so they
+// stay float64-only.
+
+func builtinAbs(args ...interface{}) (interface{}, error) {
+	return numericUnop(args[0],
+		func(x int64) int64 {
+			if x < 0 {
+				return -x
+			}
+			return x
+		},
+		math.Abs), nil
 }
 
-// Math builtins
+func builtinMin(args ...interface{}) (interface{}, error) {
+	return numericBinop(args[0], args[1],
+		func(a, b int64) int64 {
+			if a < b {
+				return a
+			}
+			return b
+		},
+		math.Min), nil
+}
 
-func builtinAbs(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return nil
-	}
-	return math.Abs(toFloat(args[0]))
+func builtinMax(args ...interface{}) (interface{}, error) {
+	return numericBinop(args[0], args[1],
+		func(a, b int64) int64 {
+			if a > b {
+				return a
+			}
+			return b
+		},
+		math.Max), nil
 }
 
-func builtinMin(args ...interface{}) interface{} {
-	if len(args) < 2 {
-		return nil
-	}
-	return math.Min(toFloat(args[0]), toFloat(args[1]))
+func builtinFloor(args ...interface{}) (interface{}, error) {
+	return numericUnop(args[0], func(x int64) int64 { return x }, math.Floor), nil
 }
 
-func builtinMax(args ...interface{}) interface{} {
-	if len(args) < 2 {
-		return nil
-	}
-	return math.Max(toFloat(args[0]), toFloat(args[1]))
+func builtinCeil(args ...interface{}) (interface{}, error) {
+	return numericUnop(args[0], func(x int64) int64 { return x }, math.Ceil), nil
 }
 
-func builtinFloor(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return nil
+func builtinRound(args ...interface{}) (interface{}, error) {
+	return numericUnop(args[0], func(x int64) int64 { return x }, math.Round), nil
+}
+
+func builtinSqrt(args ...interface{}) (interface{}, error) {
+	return math.Sqrt(toFloat(args[0])), nil
+}
+
+// builtinPow stays in the int64 lane for a non-negative integer exponent
+// (matching math.Pow's semantics with an int64 result), and falls back to
+// math.Pow — promoting both operands to float64 — for a negative exponent
+// or a non-integral operand, since neither fits in int64.
+func builtinPow(args ...interface{}) (interface{}, error) {
+	if isIntegral(args[0]) && isIntegral(args[1]) {
+		base, exp := toInt64(args[0]), toInt64(args[1])
+		if exp >= 0 {
+			result := int64(1)
+			for ; exp > 0; exp-- {
+				result *= base
+			}
+			return result, nil
+		}
 	}
-	return math.Floor(toFloat(args[0]))
+	return math.Pow(toFloat(args[0]), toFloat(args[1])), nil
 }
 
-func builtinCeil(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return nil
+func builtinExp(args ...interface{}) (interface{}, error) {
+	return math.Exp(toFloat(args[0])), nil
+}
+
+func builtinLog(args ...interface{}) (interface{}, error) {
+	return math.Log(toFloat(args[0])), nil
+}
+
+// Integer-tower builtins. mod/divmod use floored-division semantics (the
+// remainder's sign matches the divisor's, like Python's % and divmod)
+// rather than the "%" operator's truncated-division semantics, so a
+// negative dividend still gets a continuation-friendly non-negative
+// remainder against a positive modulus.
+
+func builtinMod(args ...interface{}) (interface{}, error) {
+	a, b := toInt64(args[0]), toInt64(args[1])
+	if b == 0 {
+		return nil, fmt.Errorf("mod: division by zero")
+	}
+	m := a % b
+	if m != 0 && (m < 0) != (b < 0) {
+		m += b
 	}
-	return math.Ceil(toFloat(args[0]))
+	return m, nil
 }
 
-func builtinRound(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return nil
+func builtinDivmod(args ...interface{}) (interface{}, error) {
+	a, b := toInt64(args[0]), toInt64(args[1])
+	if b == 0 {
+		return nil, fmt.Errorf("divmod: division by zero")
 	}
-	return math.Round(toFloat(args[0]))
+	q, m := a/b, a%b
+	if m != 0 && (m < 0) != (b < 0) {
+		q--
+		m += b
+	}
+	return []interface{}{q, m}, nil
 }
 
-func builtinSqrt(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return nil
+func builtinGcd(args ...interface{}) (interface{}, error) {
+	a, b := toInt64(args[0]), toInt64(args[1])
+	if a < 0 {
+		a = -a
+	}
+	if b < 0 {
+		b = -b
 	}
-	return math.Sqrt(toFloat(args[0]))
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a, nil
 }
 
-func builtinPow(args ...interface{}) interface{} {
-	if len(args) < 2 {
-		return nil
+func builtinLcm(args ...interface{}) (interface{}, error) {
+	a, b := toInt64(args[0]), toInt64(args[1])
+	if a == 0 || b == 0 {
+		return int64(0), nil
+	}
+	g, err := builtinGcd(a, b)
+	if err != nil {
+		return nil, err
 	}
-	return math.Pow(toFloat(args[0]), toFloat(args[1]))
+	result := a / g.(int64) * b
+	if result < 0 {
+		result = -result
+	}
+	return result, nil
 }
 
-func builtinExp(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return nil
+func builtinBand(args ...interface{}) (interface{}, error) {
+	return toInt64(args[0]) & toInt64(args[1]), nil
+}
+
+func builtinBor(args ...interface{}) (interface{}, error) {
+	return toInt64(args[0]) | toInt64(args[1]), nil
+}
+
+func builtinBxor(args ...interface{}) (interface{}, error) {
+	return toInt64(args[0]) ^ toInt64(args[1]), nil
+}
+
+func builtinBshl(args ...interface{}) (interface{}, error) {
+	shift := toInt64(args[1])
+	if shift < 0 {
+		return nil, fmt.Errorf("bshl: negative shift %d", shift)
 	}
-	return math.Exp(toFloat(args[0]))
+	return toInt64(args[0]) << uint(shift), nil
 }
 
-func builtinLog(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return nil
+func builtinBshr(args ...interface{}) (interface{}, error) {
+	shift := toInt64(args[1])
+	if shift < 0 {
+		return nil, fmt.Errorf("bshr: negative shift %d", shift)
 	}
-	return math.Log(toFloat(args[0]))
+	return toInt64(args[0]) >> uint(shift), nil
 }
 
 // Array builtins
 
-func builtinLen(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return 0
-	}
+func builtinLen(args ...interface{}) (interface{}, error) {
 	switch v := args[0].(type) {
 	case []interface{}:
-		return len(v)
+		return int64(len(v)), nil
 	case string:
-		return len(v)
+		return int64(len(v)), nil
 	case map[string]interface{}:
-		return len(v)
+		return int64(len(v)), nil
+	case *Tensor:
+		return int64(v.Shape[0]), nil
 	default:
-		return 0
+		return int64(0), nil
 	}
 }
 
-func builtinPush(args ...interface{}) interface{} {
-	if len(args) < 2 {
-		return args[0]
-	}
-	if arr, ok := args[0].([]interface{}); ok {
-		return append(arr, args[1])
-	}
-	return args[0]
+func builtinPush(args ...interface{}) (interface{}, error) {
+	arr := args[0].([]interface{})
+	return append(arr, args[1]), nil
 }
 
-func builtinPop(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return nil
+func builtinPop(args ...interface{}) (interface{}, error) {
+	arr := args[0].([]interface{})
+	if len(arr) == 0 {
+		return arr, nil
 	}
-	if arr, ok := args[0].([]interface{}); ok && len(arr) > 0 {
-		return arr[:len(arr)-1]
-	}
-	return args[0]
+	return arr[:len(arr)-1], nil
 }
 
-func builtinSlice(args ...interface{}) interface{} {
-	if len(args) < 3 {
-		return nil
+func builtinSlice(args ...interface{}) (interface{}, error) {
+	arr := args[0].([]interface{})
+	start := int(toFloat(args[1]))
+	end := int(toFloat(args[2]))
+	if start < 0 {
+		start = 0
 	}
-	if arr, ok := args[0].([]interface{}); ok {
-		start := int(toFloat(args[1]))
-		end := int(toFloat(args[2]))
-		if start < 0 {
-			start = 0
-		}
-		if end > len(arr) {
-			end = len(arr)
-		}
-		return arr[start:end]
+	if end > len(arr) {
+		end = len(arr)
 	}
-	return nil
+	if start > end {
+		return []interface{}{}, nil
+	}
+	return arr[start:end], nil
 }
 
-func builtinConcat(args ...interface{}) interface{} {
+func builtinConcat(args ...interface{}) (interface{}, error) {
 	result := make([]interface{}, 0)
 	for _, arg := range args {
 		if arr, ok := arg.([]interface{}); ok {
@@ -207,229 +490,242 @@ func builtinConcat(args ...interface{}) interface{} {
 			result = append(result, arg)
 		}
 	}
-	return result
+	return result, nil
 }
 
-func builtinMap(args ...interface{}) interface{} {
-	// Simple map - in a real implementation this would support lambdas
-	if len(args) < 1 {
+// builtinMapCtx implements map(arr, fn) -> [fn(x) for x in arr]. fn is a
+// Callable produced by a define_function(...) lambda expression; anything
+// else leaves arr unchanged. Unlike the Builtins table, which Call checks,
+// the BuiltinsCtx entries record no error for a bad argument.
+func builtinMapCtx(interp *Interpreter, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		if len(args) == 1 {
+			return args[0]
+		}
 		return nil
 	}
-	if arr, ok := args[0].([]interface{}); ok {
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return args[0]
+	}
+	fn, ok := args[1].(*Callable)
+	if !ok {
 		return arr
 	}
-	return args[0]
+
+	result := make([]interface{}, len(arr))
+	for idx, v := range arr {
+		result[idx] = fn.Call(v)
+	}
+	return result
 }
 
-func builtinFilter(args ...interface{}) interface{} {
-	// Simple filter - in a real implementation this would support lambdas
-	if len(args) < 1 {
+// builtinFilterCtx implements filter(arr, pred) -> [x for x in arr if
+// pred(x)], pred a Callable.
+func builtinFilterCtx(interp *Interpreter, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		if len(args) == 1 {
+			return args[0]
+		}
 		return nil
 	}
-	if arr, ok := args[0].([]interface{}); ok {
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return args[0]
+	}
+	fn, ok := args[1].(*Callable)
+	if !ok {
 		return arr
 	}
-	return args[0]
-}
 
-func builtinReduce(args ...interface{}) interface{} {
-	// Simple reduce - in a real implementation this would support lambdas
-	if len(args) < 1 {
-		return nil
+	result := make([]interface{}, 0, len(arr))
+	for _, v := range arr {
+		if toBool(fn.Call(v)) {
+			result = append(result, v)
+		}
 	}
-	return args[0]
+	return result
 }
 
-func builtinReverse(args ...interface{}) interface{} {
-	if len(args) < 1 {
+// builtinReduceCtx implements reduce(arr, fn, init) -> fn(...fn(fn(init,
+// arr[0]), arr[1])..., arr[n-1]). init may be omitted, in which case arr[0]
+// seeds the accumulator and folding starts from arr[1] (an empty arr then
+// returns nil).
+func builtinReduceCtx(interp *Interpreter, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		if len(args) == 1 {
+			return args[0]
+		}
 		return nil
 	}
-	if arr, ok := args[0].([]interface{}); ok {
-		result := make([]interface{}, len(arr))
-		for i, v := range arr {
-			result[len(arr)-1-i] = v
-		}
-		return result
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return args[0]
+	}
+	fn, ok := args[1].(*Callable)
+	if !ok {
+		return arr
+	}
+
+	var acc interface{}
+	start := 0
+	if len(args) >= 3 {
+		acc = args[2]
+	} else if len(arr) > 0 {
+		acc = arr[0]
+		start = 1
+	}
+	for idx := start; idx < len(arr); idx++ {
+		acc = fn.Call(acc, arr[idx])
+	}
+	return acc
+}
+
+func builtinReverse(args ...interface{}) (interface{}, error) {
+	arr := args[0].([]interface{})
+	result := make([]interface{}, len(arr))
+	for i, v := range arr {
+		result[len(arr)-1-i] = v
 	}
-	return args[0]
+	return result, nil
 }
 
-func builtinSort(args ...interface{}) interface{} {
+// builtinSortCtx implements sort(arr) with the default ascending numeric
+// sort, or sort(arr, cmp) with an explicit comparator: cmp(a, b) should
+// return a negative number if a sorts before b, positive if after, and 0 if
+// equal, the same convention as JavaScript's Array.prototype.sort.
+func builtinSortCtx(interp *Interpreter, args ...interface{}) interface{} {
 	if len(args) < 1 {
 		return nil
 	}
-	if arr, ok := args[0].([]interface{}); ok {
-		// Simple numeric sort
-		result := make([]interface{}, len(arr))
-		copy(result, arr)
-		// Basic bubble sort for simplicity
-		for i := 0; i < len(result)-1; i++ {
-			for j := 0; j < len(result)-1-i; j++ {
-				if toFloat(result[j]) > toFloat(result[j+1]) {
-					result[j], result[j+1] = result[j+1], result[j]
-				}
-			}
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return args[0]
+	}
+
+	result := make([]interface{}, len(arr))
+	copy(result, arr)
+
+	less := func(a, b interface{}) bool { return toFloat(a) < toFloat(b) }
+	if len(args) >= 2 {
+		if fn, ok := args[1].(*Callable); ok {
+			less = func(a, b interface{}) bool { return toFloat(fn.Call(a, b)) < 0 }
 		}
-		return result
 	}
-	return args[0]
+
+	sort.Slice(result, func(i, j int) bool { return less(result[i], result[j]) })
+	return result
 }
 
 // String builtins
 
-func builtinUpper(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return ""
-	}
-	return strings.ToUpper(toString(args[0]))
+func builtinUpper(args ...interface{}) (interface{}, error) {
+	return strings.ToUpper(args[0].(string)), nil
 }
 
-func builtinLower(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return ""
-	}
-	return strings.ToLower(toString(args[0]))
+func builtinLower(args ...interface{}) (interface{}, error) {
+	return strings.ToLower(args[0].(string)), nil
 }
 
-func builtinTrim(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return ""
-	}
-	return strings.TrimSpace(toString(args[0]))
+func builtinTrim(args ...interface{}) (interface{}, error) {
+	return strings.TrimSpace(args[0].(string)), nil
 }
 
-func builtinSplit(args ...interface{}) interface{} {
-	if len(args) < 2 {
-		return []interface{}{}
-	}
-	parts := strings.Split(toString(args[0]), toString(args[1]))
+func builtinSplit(args ...interface{}) (interface{}, error) {
+	parts := strings.Split(args[0].(string), args[1].(string))
 	result := make([]interface{}, len(parts))
 	for i, p := range parts {
 		result[i] = p
 	}
-	return result
+	return result, nil
 }
 
-func builtinJoin(args ...interface{}) interface{} {
-	if len(args) < 2 {
-		return ""
-	}
-	if arr, ok := args[0].([]interface{}); ok {
-		strs := make([]string, len(arr))
-		for i, v := range arr {
-			strs[i] = toString(v)
-		}
-		return strings.Join(strs, toString(args[1]))
+func builtinJoin(args ...interface{}) (interface{}, error) {
+	arr := args[0].([]interface{})
+	strs := make([]string, len(arr))
+	for i, v := range arr {
+		strs[i] = toString(v)
 	}
-	return ""
+	return strings.Join(strs, args[1].(string)), nil
 }
 
-func builtinReplace(args ...interface{}) interface{} {
-	if len(args) < 3 {
-		return args[0]
-	}
-	return strings.ReplaceAll(toString(args[0]), toString(args[1]), toString(args[2]))
+func builtinReplace(args ...interface{}) (interface{}, error) {
+	return strings.ReplaceAll(args[0].(string), args[1].(string), args[2].(string)), nil
 }
 
-func builtinContains(args ...interface{}) interface{} {
-	if len(args) < 2 {
-		return false
-	}
-	return strings.Contains(toString(args[0]), toString(args[1]))
+func builtinContains(args ...interface{}) (interface{}, error) {
+	return strings.Contains(args[0].(string), args[1].(string)), nil
 }
 
-func builtinStartsWith(args ...interface{}) interface{} {
-	if len(args) < 2 {
-		return false
-	}
-	return strings.HasPrefix(toString(args[0]), toString(args[1]))
+func builtinStartsWith(args ...interface{}) (interface{}, error) {
+	return strings.HasPrefix(args[0].(string), args[1].(string)), nil
 }
 
-func builtinEndsWith(args ...interface{}) interface{} {
-	if len(args) < 2 {
-		return false
-	}
-	return strings.HasSuffix(toString(args[0]), toString(args[1]))
+func builtinEndsWith(args ...interface{}) (interface{}, error) {
+	return strings.HasSuffix(args[0].(string), args[1].(string)), nil
 }
 
 // Type builtins
 
-func builtinType(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return "null"
-	}
+func builtinType(args ...interface{}) (interface{}, error) {
 	switch args[0].(type) {
 	case nil:
-		return "null"
+		return "null", nil
 	case bool:
-		return "bool"
+		return "bool", nil
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-		return "int"
+		return "int", nil
 	case float32, float64:
-		return "float"
+		return "float", nil
 	case string:
-		return "string"
+		return "string", nil
 	case []interface{}:
-		return "array"
+		return "array", nil
 	case map[string]interface{}:
-		return "object"
+		return "object", nil
+	case *Tensor:
+		return "tensor", nil
 	default:
-		return "unknown"
+		return "unknown", nil
 	}
 }
 
-func builtinStr(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return ""
-	}
-	return toString(args[0])
+func builtinStr(args ...interface{}) (interface{}, error) {
+	return toString(args[0]), nil
 }
 
-func builtinInt(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return 0
-	}
-	return int(toFloat(args[0]))
+func builtinInt(args ...interface{}) (interface{}, error) {
+	return toInt64(args[0]), nil
 }
 
-func builtinFloat(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return 0.0
-	}
-	return toFloat(args[0])
+func builtinFloat(args ...interface{}) (interface{}, error) {
+	return toFloat(args[0]), nil
 }
 
-func builtinBool(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return false
-	}
-	return toBool(args[0])
+func builtinBool(args ...interface{}) (interface{}, error) {
+	return toBool(args[0]), nil
 }
 
-func builtinArray(args ...interface{}) interface{} {
-	return args
+func builtinArray(args ...interface{}) (interface{}, error) {
+	return args, nil
 }
 
-func builtinObject(args ...interface{}) interface{} {
+func builtinObject(args ...interface{}) (interface{}, error) {
 	result := make(map[string]interface{})
 	for i := 0; i+1 < len(args); i += 2 {
 		key := toString(args[i])
 		result[key] = args[i+1]
 	}
-	return result
+	return result, nil
 }
 
 // Matrix builtins (for Llama K'UHUL support)
 
-func builtinMatrixMultiply(args ...interface{}) interface{} {
-	if len(args) < 2 {
-		return nil
-	}
-
-	a, aOk := args[0].([]interface{})
-	b, bOk := args[1].([]interface{})
-	if !aOk || !bOk || len(a) == 0 || len(b) == 0 {
-		return nil
+func builtinMatrixMultiply(args ...interface{}) (interface{}, error) {
+	a := args[0].([]interface{})
+	b := args[1].([]interface{})
+	if len(a) == 0 || len(b) == 0 {
+		return nil, nil
 	}
 
 	// Get dimensions
@@ -445,7 +741,7 @@ func builtinMatrixMultiply(args ...interface{}) interface{} {
 	}
 
 	if aCols != bRows {
-		return nil // Dimension mismatch
+		return nil, fmt.Errorf("matrix_multiply: dimension mismatch (%dx%d vs %dx%d)", aRows, aCols, bRows, bCols)
 	}
 
 	// Perform multiplication
@@ -470,17 +766,13 @@ func builtinMatrixMultiply(args ...interface{}) interface{} {
 		result[i] = row
 	}
 
-	return result
+	return result, nil
 }
 
-func builtinTranspose(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return nil
-	}
-
-	mat, ok := args[0].([]interface{})
-	if !ok || len(mat) == 0 {
-		return nil
+func builtinTranspose(args ...interface{}) (interface{}, error) {
+	mat := args[0].([]interface{})
+	if len(mat) == 0 {
+		return []interface{}{}, nil
 	}
 
 	rows := len(mat)
@@ -502,67 +794,24 @@ func builtinTranspose(args ...interface{}) interface{} {
 		result[j] = row
 	}
 
-	return result
-}
-
-func builtinSoftmax(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return nil
-	}
-
-	arr, ok := args[0].([]interface{})
-	if !ok || len(arr) == 0 {
-		return nil
-	}
-
-	// Find max for numerical stability
-	maxVal := toFloat(arr[0])
-	for _, v := range arr[1:] {
-		if f := toFloat(v); f > maxVal {
-			maxVal = f
-		}
-	}
-
-	// Compute exp(x - max) and sum
-	exps := make([]float64, len(arr))
-	sum := 0.0
-	for i, v := range arr {
-		exps[i] = math.Exp(toFloat(v) - maxVal)
-		sum += exps[i]
-	}
-
-	// Normalize
-	result := make([]interface{}, len(arr))
-	for i, exp := range exps {
-		result[i] = exp / sum
-	}
-
-	return result
+	return result, nil
 }
 
-func builtinDot(args ...interface{}) interface{} {
-	if len(args) < 2 {
-		return 0.0
-	}
-
-	a, aOk := args[0].([]interface{})
-	b, bOk := args[1].([]interface{})
-	if !aOk || !bOk || len(a) != len(b) {
-		return 0.0
+func builtinDot(args ...interface{}) (interface{}, error) {
+	a := args[0].([]interface{})
+	b := args[1].([]interface{})
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("dot: length mismatch (%d vs %d)", len(a), len(b))
 	}
 
 	sum := 0.0
 	for i := range a {
 		sum += toFloat(a[i]) * toFloat(b[i])
 	}
-	return sum
+	return sum, nil
 }
 
-func builtinZeros(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return []interface{}{}
-	}
-
+func builtinZeros(args ...interface{}) (interface{}, error) {
 	n := int(toFloat(args[0]))
 	if len(args) >= 2 {
 		// 2D zeros
@@ -575,7 +824,7 @@ func builtinZeros(args ...interface{}) interface{} {
 			}
 			result[i] = row
 		}
-		return result
+		return result, nil
 	}
 
 	// 1D zeros
@@ -583,14 +832,10 @@ func builtinZeros(args ...interface{}) interface{} {
 	for i := 0; i < n; i++ {
 		result[i] = 0.0
 	}
-	return result
+	return result, nil
 }
 
-func builtinOnes(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return []interface{}{}
-	}
-
+func builtinOnes(args ...interface{}) (interface{}, error) {
 	n := int(toFloat(args[0]))
 	if len(args) >= 2 {
 		// 2D ones
@@ -603,7 +848,7 @@ func builtinOnes(args ...interface{}) interface{} {
 			}
 			result[i] = row
 		}
-		return result
+		return result, nil
 	}
 
 	// 1D ones
@@ -611,12 +856,12 @@ func builtinOnes(args ...interface{}) interface{} {
 	for i := 0; i < n; i++ {
 		result[i] = 1.0
 	}
-	return result
+	return result, nil
 }
 
 // Utility builtins
 
-func builtinPrint(args ...interface{}) interface{} {
+func builtinPrint(args ...interface{}) (interface{}, error) {
 	for i, arg := range args {
 		if i > 0 {
 			fmt.Print(" ")
@@ -624,28 +869,26 @@ func builtinPrint(args ...interface{}) interface{} {
 		fmt.Print(toString(arg))
 	}
 	fmt.Println()
-	return nil
+	return nil, nil
 }
 
-func builtinRange(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return []interface{}{}
-	}
-
-	start := 0
-	end := int(toFloat(args[0]))
-	step := 1
+// builtinRange returns int64 elements, like every other integer-tower
+// builtin, rather than the float64 a toFloat-based range used to.
+func builtinRange(args ...interface{}) (interface{}, error) {
+	var start int64
+	end := toInt64(args[0])
+	step := int64(1)
 
 	if len(args) >= 2 {
-		start = int(toFloat(args[0]))
-		end = int(toFloat(args[1]))
+		start = toInt64(args[0])
+		end = toInt64(args[1])
 	}
 	if len(args) >= 3 {
-		step = int(toFloat(args[2]))
+		step = toInt64(args[2])
 	}
 
 	if step == 0 {
-		return []interface{}{}
+		return nil, fmt.Errorf("range: step must not be 0")
 	}
 
 	result := make([]interface{}, 0)
@@ -658,35 +901,25 @@ func builtinRange(args ...interface{}) interface{} {
 			result = append(result, i)
 		}
 	}
-	return result
+	return result, nil
 }
 
-func builtinKeys(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return []interface{}{}
-	}
-	if m, ok := args[0].(map[string]interface{}); ok {
-		keys := make([]interface{}, 0, len(m))
-		for k := range m {
-			keys = append(keys, k)
-		}
-		return keys
+func builtinKeys(args ...interface{}) (interface{}, error) {
+	m := args[0].(map[string]interface{})
+	keys := make([]interface{}, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
-	return []interface{}{}
+	return keys, nil
 }
 
-func builtinValues(args ...interface{}) interface{} {
-	if len(args) < 1 {
-		return []interface{}{}
-	}
-	if m, ok := args[0].(map[string]interface{}); ok {
-		values := make([]interface{}, 0, len(m))
-		for _, v := range m {
-			values = append(values, v)
-		}
-		return values
+func builtinValues(args ...interface{}) (interface{}, error) {
+	m := args[0].(map[string]interface{})
+	values := make([]interface{}, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
 	}
-	return []interface{}{}
+	return values, nil
 }
 
 // Helper functions
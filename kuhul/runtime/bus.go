@@ -0,0 +1,117 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// HandlerFn is a handler's executable body, the same shape as
+// Handler.Execute, kept separate so Bus doesn't need to import *Handler.
+type HandlerFn func(ctx *Context) (interface{}, error)
+
+// Bus is the pluggable job queue behind Interpreter.DispatchAsync:
+// Publish enqueues a handler invocation and returns a job id immediately
+// instead of blocking for the result, and Subscribe registers the function
+// a given handler name runs when a job for it comes off the queue.
+// memoryBus (the default) is an in-process channel; the AMQP driver
+// (build tag "amqp") publishes the same work onto a broker so it can be
+// picked up by RunConsumer in another process.
+type Bus interface {
+	Publish(handler string, ctx *Context) (jobID string, err error)
+	Subscribe(handler string, fn HandlerFn)
+}
+
+// JobResult is what Store.GetJob/PutJob persist for a DispatchAsync job,
+// and what the "job.get" basher command returns.
+type JobResult struct {
+	Status string      `json:"status"` // "pending", "done", or "error"
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// selectBus picks a Bus per the manifest's "broker" key ("amqp", or
+// anything else/absent for the in-process default). Mirrors selectStore: a
+// driver holding a live connection (amqpBus) is cached on rs.KernelCache so
+// reloading the same manifest doesn't reconnect.
+func selectBus(rs *RuntimeState, manifest map[string]interface{}) Bus {
+	broker, _ := manifest["broker"].(string)
+	if cached, ok := rs.KernelCache["bus:"+broker]; ok {
+		return cached.(Bus)
+	}
+
+	result := Bus(newMemoryBus(rs))
+	if broker == "amqp" {
+		url, _ := manifest["broker_url"].(string)
+		exchange, _ := manifest["broker_exchange"].(string)
+		if b := newAMQPBus(rs, url, exchange); b != nil {
+			result = b
+		} else {
+			rs.AddError(`broker "amqp" requested but this binary was not built with the amqp tag; falling back to in-process`)
+		}
+	}
+
+	rs.KernelCache["bus:"+broker] = result
+	return result
+}
+
+// memoryBus is the default Bus: Publish hands the job to a single
+// background goroutine over an unbounded-enough buffered channel, which
+// runs the subscribed HandlerFn and records the JobResult on rs.Store
+// under the returned job id.
+type memoryBus struct {
+	rs       *RuntimeState
+	mu       sync.Mutex
+	handlers map[string]HandlerFn
+	jobs     chan memoryJob
+	nextID   uint64
+}
+
+type memoryJob struct {
+	id      string
+	handler string
+	ctx     *Context
+}
+
+func newMemoryBus(rs *RuntimeState) *memoryBus {
+	b := &memoryBus{
+		rs:       rs,
+		handlers: make(map[string]HandlerFn),
+		jobs:     make(chan memoryJob, 256),
+	}
+	go b.run()
+	return b
+}
+
+func (b *memoryBus) run() {
+	for job := range b.jobs {
+		b.mu.Lock()
+		fn, ok := b.handlers[job.handler]
+		b.mu.Unlock()
+
+		if !ok {
+			b.rs.Store.PutJob(job.id, JobResult{Status: "error", Error: fmt.Sprintf("no subscriber for handler: %s", job.handler)})
+			continue
+		}
+
+		result, err := fn(job.ctx)
+		if err != nil {
+			b.rs.Store.PutJob(job.id, JobResult{Status: "error", Error: err.Error()})
+			continue
+		}
+		b.rs.Store.PutJob(job.id, JobResult{Status: "done", Result: result})
+	}
+}
+
+func (b *memoryBus) Publish(handler string, ctx *Context) (string, error) {
+	id := fmt.Sprintf("job_%d", atomic.AddUint64(&b.nextID, 1))
+	b.rs.Store.PutJob(id, JobResult{Status: "pending"})
+	b.jobs <- memoryJob{id: id, handler: handler, ctx: ctx}
+	return id, nil
+}
+
+func (b *memoryBus) Subscribe(handler string, fn HandlerFn) {
+	b.mu.Lock()
+	b.handlers[handler] = fn
+	b.mu.Unlock()
+}
@@ -0,0 +1,179 @@
+//go:build amqp
+
+package runtime
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// amqpJob is the JSON envelope amqpBus.Publish puts on the exchange and
+// RunConsumer decodes on the other side: Context.Runtime/Env don't cross a
+// process boundary, so only the handler name and the three request-shaped
+// maps travel.
+type amqpJob struct {
+	JobID   string                 `json:"job_id"`
+	Handler string                 `json:"handler"`
+	Params  map[string]interface{} `json:"params"`
+	Body    map[string]interface{} `json:"body"`
+	Query   map[string]interface{} `json:"query"`
+}
+
+// amqpBus publishes DispatchAsync jobs onto a RabbitMQ topic exchange
+// (routing key = handler name) instead of running them in-process: unlike
+// memoryBus, the subscriber that actually executes a job is usually a
+// RunConsumer worker in another process. Subscribe here only records fn in
+// b.handlers; nothing reads that table, since RunConsumer runs each job
+// through interp.Dispatch instead.
+type amqpBus struct {
+	rs       *RuntimeState
+	conn     *amqp.Connection
+	ch       *amqp.Channel
+	exchange string
+	nextID   uint64
+
+	mu       sync.Mutex
+	handlers map[string]HandlerFn
+}
+
+// newAMQPBus dials url, declares exchange as a durable topic exchange, and
+// returns nil (after recording the failure on rs.Errors) if any of that
+// fails, so selectBus can fall back to memoryBus the same way newRedisStore
+// lets selectStore fall back to inMemoryStore.
+func newAMQPBus(rs *RuntimeState, url, exchange string) Bus {
+	if url == "" {
+		rs.AddError(`broker "amqp" requires a "broker_url" in the manifest`)
+		return nil
+	}
+	if exchange == "" {
+		exchange = "kuhul.jobs"
+	}
+
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		rs.AddError(fmt.Sprintf("amqp: dial %s: %v", url, err))
+		return nil
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		rs.AddError(fmt.Sprintf("amqp: open channel: %v", err))
+		return nil
+	}
+
+	if err := ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil); err != nil {
+		ch.Close()
+		conn.Close()
+		rs.AddError(fmt.Sprintf("amqp: declare exchange %s: %v", exchange, err))
+		return nil
+	}
+
+	return &amqpBus{rs: rs, conn: conn, ch: ch, exchange: exchange, handlers: make(map[string]HandlerFn)}
+}
+
+// Publish marshals handler/ctx into an amqpJob envelope and routes it to
+// b.exchange under the handler name, so a RunConsumer bound to that routing
+// key (or a wildcard) picks it up. The job is recorded "pending" on
+// b.rs.Store before the publish so a "job.get" issued immediately after
+// Publish returns never sees a missing key.
+func (b *amqpBus) Publish(handler string, ctx *Context) (string, error) {
+	id := fmt.Sprintf("job_%d", atomic.AddUint64(&b.nextID, 1))
+	b.rs.Store.PutJob(id, JobResult{Status: "pending"})
+
+	payload, err := json.Marshal(amqpJob{
+		JobID:   id,
+		Handler: handler,
+		Params:  ctx.Params,
+		Body:    ctx.Body,
+		Query:   ctx.Query,
+	})
+	if err != nil {
+		b.rs.Store.PutJob(id, JobResult{Status: "error", Error: err.Error()})
+		return "", err
+	}
+
+	err = b.ch.PublishWithContext(gocontext.Background(), b.exchange, handler, false, false, amqp.Publishing{
+		ContentType: "application/json",
+		Body:        payload,
+	})
+	if err != nil {
+		b.rs.Store.PutJob(id, JobResult{Status: "error", Error: err.Error()})
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (b *amqpBus) Subscribe(handler string, fn HandlerFn) {
+	b.mu.Lock()
+	b.handlers[handler] = fn
+	b.mu.Unlock()
+}
+
+// RunConsumer declares queue, binds it to interp's AMQP bus under the "#"
+// wildcard routing key so it receives every handler's jobs, and runs the
+// handler named in each delivery through interp's already-registered
+// handlers until the delivery channel closes. The JobResult is recorded on
+// interp's Store, and also published back to the delivery's ReplyTo queue
+// (if set) so a caller in yet another process can wait on it directly
+// instead of polling "job.get".
+func RunConsumer(interp *Interpreter, queue string) error {
+	bus, ok := interp.state.Bus.(*amqpBus)
+	if !ok {
+		return fmt.Errorf("amqp: RunConsumer requires an interpreter whose broker is \"amqp\", got %T", interp.state.Bus)
+	}
+
+	q, err := bus.ch.QueueDeclare(queue, true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("amqp: declare queue %s: %w", queue, err)
+	}
+	if err := bus.ch.QueueBind(q.Name, "#", bus.exchange, false, nil); err != nil {
+		return fmt.Errorf("amqp: bind queue %s to %s: %w", queue, bus.exchange, err)
+	}
+
+	deliveries, err := bus.ch.Consume(q.Name, "", true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("amqp: consume %s: %w", queue, err)
+	}
+
+	for d := range deliveries {
+		var job amqpJob
+		if err := json.Unmarshal(d.Body, &job); err != nil {
+			continue
+		}
+
+		result, execErr := interp.Dispatch(job.Handler, &Context{
+			Handler: job.Handler,
+			Params:  job.Params,
+			Body:    job.Body,
+			Query:   job.Query,
+			Runtime: interp.state,
+			Env:     interp.state.Variables,
+		})
+
+		jobResult := JobResult{Status: "done", Result: result}
+		if execErr != nil {
+			jobResult = JobResult{Status: "error", Error: execErr.Error()}
+		}
+		interp.state.Store.PutJob(job.JobID, jobResult)
+
+		if d.ReplyTo != "" {
+			reply, err := json.Marshal(jobResult)
+			if err != nil {
+				continue
+			}
+			bus.ch.PublishWithContext(gocontext.Background(), "", d.ReplyTo, false, false, amqp.Publishing{
+				ContentType: "application/json",
+				Body:        reply,
+			})
+		}
+	}
+
+	return nil
+}
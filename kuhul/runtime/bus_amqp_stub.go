@@ -0,0 +1,16 @@
+//go:build !amqp
+
+package runtime
+
+import "fmt"
+
+// newAMQPBus is only implemented when this binary is built with `-tags
+// amqp` (requires github.com/rabbitmq/amqp091-go); selectBus falls back to
+// memoryBus when it returns nil.
+func newAMQPBus(rs *RuntimeState, url, exchange string) Bus { return nil }
+
+// RunConsumer is only implemented when this binary is built with `-tags
+// amqp`; a plain build has no AMQP worker process to run.
+func RunConsumer(interp *Interpreter, queue string) error {
+	return fmt.Errorf("amqp: RunConsumer requires building with -tags amqp")
+}
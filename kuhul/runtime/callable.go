@@ -0,0 +1,43 @@
+package runtime
+
+import "github.com/ollama/ollama/kuhul/ast"
+
+// Callable is a first-class function value produced by evaluating a
+// define_function(...) lambda expression (see ast.Lambda), passed as the
+// fn/pred/cmp argument to the map/filter/reduce/sort entries in BuiltinsCtx.
+// Call binds Params to args positionally (a missing trailing argument binds
+// to nil) in a scope enclosing the interpreter's current variables, then
+// evaluates Body as a single expression against it.
+//
+// Errors encountered while evaluating Body (an out-of-range index, a bad
+// push() argument) are recorded onto the interpreter's state the same way
+// any other expression error is, via AddError/AddKuhulError, rather than
+// returned from Call — evaluateValue itself has no error return to
+// propagate through. They surface on the caller's Diagnostics/Errors exactly
+// as if the callback's expression had been written inline, which is what
+// "errors from the callback propagate" means for this interpreter.
+type Callable struct {
+	params []string
+	body   ast.Node
+	interp *Interpreter
+}
+
+// Call evaluates the lambda against args, temporarily swapping the
+// interpreter's variable scope to one enclosing its current scope with
+// params bound, and restoring the prior scope before returning.
+func (c *Callable) Call(args ...interface{}) interface{} {
+	scope := NewEnclosedEnvironment(c.interp.state.Variables)
+	for idx, name := range c.params {
+		var v interface{}
+		if idx < len(args) {
+			v = args[idx]
+		}
+		scope.Set(name, v)
+	}
+
+	prev := c.interp.state.Variables
+	c.interp.state.Variables = scope
+	defer func() { c.interp.state.Variables = prev }()
+
+	return c.interp.evaluateValue(c.body)
+}
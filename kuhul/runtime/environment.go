@@ -2,7 +2,11 @@
 package runtime
 
 import (
+	gocontext "context"
 	"sync"
+
+	"github.com/ollama/ollama/kuhul/events"
+	"github.com/ollama/ollama/kuhul/kerror"
 )
 
 // Environment represents a variable scope
@@ -137,12 +141,51 @@ type RuntimeState struct {
 	BootSteps []string
 	Errors    []string
 
+	// Diagnostics holds structured errors (out-of-range index, invalid
+	// push target, and similar) raised during evaluation.
+	Diagnostics []*kerror.Error
+
 	// MX2DB runtime storage
 	MX2DB *MX2DB
 
+	// Backend executes the matrix builtins (matrix_multiply, transpose,
+	// softmax); it's cpuBackend unless the manifest's "accel" key selects
+	// an OpenCL/CUDA build. KernelCache lets a backend memoize anything
+	// expensive to prepare per shape (e.g. a compiled OpenCL kernel) across
+	// calls within one RuntimeState.
+	Backend     BuiltinBackend
+	KernelCache map[string]interface{}
+
+	// Store is the pluggable persistence layer behind ASXRAM, MX2DB.Tapes,
+	// MX2DB.NGrams, and MX2DB.RLHFTraces; it's inMemoryStore (reading/
+	// writing this RuntimeState directly) unless the manifest's "store" key
+	// selects a Redis driver. GetASXRAM/SetASXRAM below delegate to it.
+	Store Store
+
+	// Bus is the pluggable job queue behind DispatchAsync; it's memoryBus
+	// (an in-process channel) unless the manifest's "broker" key selects
+	// the AMQP driver.
+	Bus Bus
+
 	// ASX-RAM
 	ASXRAM map[string]interface{}
 
+	// Events is the pump CoolBlocks with an @on param observe and
+	// ⟁Sek⟁ emit vectors post to.
+	Events *events.EventPump
+
+	// ChunkStore is the content-addressed store behind scxq2's
+	// content-defined chunking (see scxq2.Chunk): chunk hash -> chunk
+	// bytes. ChunkManifests remembers, for each fingerprint scxq2.fingerprint
+	// has produced, the ordered chunk hashes that made it up, so
+	// scxq2.diff can compare two fingerprints' chunk lists without
+	// re-chunking either payload. PutChunk/GetChunk and
+	// PutChunkManifest/GetChunkManifest below own chunkMu's locking;
+	// nothing else touches these maps directly.
+	ChunkStore     map[string][]byte
+	ChunkManifests map[string][]string
+	chunkMu        sync.RWMutex
+
 	mu sync.RWMutex
 }
 
@@ -152,6 +195,12 @@ type Handler struct {
 	Block   interface{}
 	Params  map[string]interface{}
 	Execute func(ctx *Context) (interface{}, error)
+
+	// Stream is the optional StreamingHandler DispatchStream prefers over
+	// Execute, set by packs.StreamingPack handlers (or left nil for a
+	// handler that only supports the synchronous Dispatch/DispatchAsync
+	// path).
+	Stream StreamingHandler
 }
 
 // Vector represents a registered C@@L ATOMIC_VECTOR
@@ -169,19 +218,39 @@ type MX2DB struct {
 	TrainingHistory map[string]interface{}
 	Tapes           map[string]interface{}
 	FeedEntries     map[string]interface{}
-	mu              sync.RWMutex
+
+	// PrefixTotals, PrefixContinuations, and TokenContexts are Kneser-Ney
+	// smoothing's auxiliary indexes over NGrams, kept up to date alongside it
+	// by inMemoryStore.IncrNGram: PrefixTotals is c(prefix,*) (NGrams summed
+	// by context), PrefixContinuations is the distinct-continuation set
+	// N1+(prefix,*) needs, and TokenContexts is the distinct-context set
+	// N1+(*,w) needs.
+	PrefixTotals        map[string]int
+	PrefixContinuations map[string]map[string]struct{}
+	TokenContexts       map[string]map[string]struct{}
+
+	// Jobs holds DispatchAsync results (JobResult, as interface{} so it
+	// shares Restore/Snapshot's existing map[string]interface{} handling),
+	// keyed by the job id Bus.Publish returned.
+	Jobs map[string]interface{}
+
+	mu sync.RWMutex
 }
 
 // NewMX2DB creates a new MX2DB storage
 func NewMX2DB() *MX2DB {
 	return &MX2DB{
-		NGrams:          make(map[string]int),
-		Supagrams:       make(map[string]interface{}),
-		RLHFTraces:      make(map[string]interface{}),
-		AgentState:      make(map[string]interface{}),
-		TrainingHistory: make(map[string]interface{}),
-		Tapes:           make(map[string]interface{}),
-		FeedEntries:     make(map[string]interface{}),
+		NGrams:              make(map[string]int),
+		Supagrams:           make(map[string]interface{}),
+		RLHFTraces:          make(map[string]interface{}),
+		AgentState:          make(map[string]interface{}),
+		TrainingHistory:     make(map[string]interface{}),
+		Tapes:               make(map[string]interface{}),
+		FeedEntries:         make(map[string]interface{}),
+		PrefixTotals:        make(map[string]int),
+		PrefixContinuations: make(map[string]map[string]struct{}),
+		TokenContexts:       make(map[string]map[string]struct{}),
+		Jobs:                make(map[string]interface{}),
 	}
 }
 
@@ -193,21 +262,36 @@ type Context struct {
 	Query   map[string]interface{}
 	Runtime *RuntimeState
 	Env     *Environment
+
+	// Ctx is the request's cancellation/deadline context, checked by
+	// Dispatch before running the handler (see RunContext for the
+	// equivalent check between Run's top-level statements). Left nil for
+	// a Context built without one, in which case Dispatch runs
+	// unconditionally as before.
+	Ctx gocontext.Context
 }
 
 // NewRuntimeState creates a new RuntimeState
 func NewRuntimeState() *RuntimeState {
-	return &RuntimeState{
-		Variables: NewEnvironment(),
-		Handlers:  make(map[string]*Handler),
-		Vectors:   make(map[string]*Vector),
-		Manifest:  make(map[string]interface{}),
-		Booted:    false,
-		BootSteps: make([]string, 0),
-		Errors:    make([]string, 0),
-		MX2DB:     NewMX2DB(),
-		ASXRAM:    make(map[string]interface{}),
+	rs := &RuntimeState{
+		Variables:      NewEnvironment(),
+		Handlers:       make(map[string]*Handler),
+		Vectors:        make(map[string]*Vector),
+		Manifest:       make(map[string]interface{}),
+		Booted:         false,
+		BootSteps:      make([]string, 0),
+		Errors:         make([]string, 0),
+		MX2DB:          NewMX2DB(),
+		ASXRAM:         make(map[string]interface{}),
+		Events:         events.NewEventPump(),
+		Backend:        cpuBackend{},
+		KernelCache:    make(map[string]interface{}),
+		ChunkStore:     make(map[string][]byte),
+		ChunkManifests: make(map[string][]string),
 	}
+	rs.Store = newInMemoryStore(rs)
+	rs.Bus = newMemoryBus(rs)
+	return rs
 }
 
 // RegisterHandler registers a handler
@@ -254,19 +338,61 @@ func (rs *RuntimeState) AddError(err string) {
 	rs.mu.Unlock()
 }
 
-// SetASXRAM sets a value in ASX-RAM
-func (rs *RuntimeState) SetASXRAM(key string, value interface{}) {
+// AddKuhulError records a structured diagnostic alongside the plain-string
+// Errors log, so callers that want a code/location can use Diagnostics
+// instead of parsing Errors.
+func (rs *RuntimeState) AddKuhulError(err *kerror.Error) {
 	rs.mu.Lock()
-	rs.ASXRAM[key] = value
+	rs.Errors = append(rs.Errors, err.Error())
+	rs.Diagnostics = append(rs.Diagnostics, err)
 	rs.mu.Unlock()
 }
 
+// SetASXRAM sets a value in ASX-RAM
+func (rs *RuntimeState) SetASXRAM(key string, value interface{}) {
+	rs.Store.SetASXRAM(key, value)
+}
+
 // GetASXRAM gets a value from ASX-RAM
 func (rs *RuntimeState) GetASXRAM(key string) (interface{}, bool) {
-	rs.mu.RLock()
-	v, ok := rs.ASXRAM[key]
-	rs.mu.RUnlock()
-	return v, ok
+	return rs.Store.GetASXRAM(key)
+}
+
+// PutChunk stores data under its content hash, returning true if this was a
+// new chunk and false if hash was already present (a dedup hit).
+func (rs *RuntimeState) PutChunk(hash string, data []byte) bool {
+	rs.chunkMu.Lock()
+	defer rs.chunkMu.Unlock()
+	if _, exists := rs.ChunkStore[hash]; exists {
+		return false
+	}
+	rs.ChunkStore[hash] = data
+	return true
+}
+
+// GetChunk retrieves the chunk bytes stored under hash.
+func (rs *RuntimeState) GetChunk(hash string) ([]byte, bool) {
+	rs.chunkMu.RLock()
+	defer rs.chunkMu.RUnlock()
+	data, ok := rs.ChunkStore[hash]
+	return data, ok
+}
+
+// PutChunkManifest records the ordered chunk hashes that made up fingerprint,
+// so a later scxq2.diff can look them up without re-chunking the payload.
+func (rs *RuntimeState) PutChunkManifest(fingerprint string, hashes []string) {
+	rs.chunkMu.Lock()
+	defer rs.chunkMu.Unlock()
+	rs.ChunkManifests[fingerprint] = hashes
+}
+
+// GetChunkManifest retrieves the chunk hash list previously recorded for
+// fingerprint by PutChunkManifest.
+func (rs *RuntimeState) GetChunkManifest(fingerprint string) ([]string, bool) {
+	rs.chunkMu.RLock()
+	defer rs.chunkMu.RUnlock()
+	hashes, ok := rs.ChunkManifests[fingerprint]
+	return hashes, ok
 }
 
 // GetState returns a snapshot of the runtime state
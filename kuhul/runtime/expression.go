@@ -0,0 +1,252 @@
+package runtime
+
+import (
+	"reflect"
+
+	"github.com/ollama/ollama/kuhul/ast"
+	"github.com/ollama/ollama/kuhul/kerror"
+)
+
+// evaluateBinaryExpr evaluates the arithmetic, comparison, and logical
+// operators produced by the parser's Pratt expression parser.
+func (i *Interpreter) evaluateBinaryExpr(n *ast.BinaryExpr) interface{} {
+	left := i.evaluateValue(n.Left)
+	right := i.evaluateValue(n.Right)
+
+	switch n.Operator {
+	case "+":
+		if ls, ok := left.(string); ok {
+			return ls + toString(right)
+		}
+		if rs, ok := right.(string); ok {
+			return toString(left) + rs
+		}
+		return i.checkedArith(n, "+", left, right, checkedAddInt64, func(a, b float64) float64 { return a + b })
+	case "-":
+		return i.checkedArith(n, "-", left, right, checkedSubInt64, func(a, b float64) float64 { return a - b })
+	case "*":
+		return i.checkedArith(n, "*", left, right, checkedMulInt64, func(a, b float64) float64 { return a * b })
+	case "/":
+		divisor := toFloat(right)
+		if divisor == 0 {
+			return nil
+		}
+		return toFloat(left) / divisor
+	case "%":
+		divisor := toFloat(right)
+		if divisor == 0 {
+			return nil
+		}
+		return float64(int64(toFloat(left)) % int64(divisor))
+	case "==":
+		return valuesEqual(left, right)
+	case "!=":
+		return !valuesEqual(left, right)
+	case "<":
+		return toFloat(left) < toFloat(right)
+	case "<=":
+		return toFloat(left) <= toFloat(right)
+	case ">":
+		return toFloat(left) > toFloat(right)
+	case ">=":
+		return toFloat(left) >= toFloat(right)
+	case "&&":
+		return toBool(left) && toBool(right)
+	case "||":
+		return toBool(left) || toBool(right)
+	default:
+		return nil
+	}
+}
+
+// checkedArith evaluates a "+"/"-"/"*" BinaryExpr through the numeric
+// tower: if left and right are both integer-kinded it stays in int64 via
+// intOp, otherwise it promotes to float64 via floatOp. intOp's bool return
+// is only consulted when i.checkOverflow is on (see SetOverflowCheck); with
+// overflow checking off, an overflowing int64 result wraps the same way it
+// always has, since intOp is built on plain Go +/-/* under the hood.
+func (i *Interpreter) checkedArith(n *ast.BinaryExpr, op string, left, right interface{}, intOp func(a, b int64) (int64, bool), floatOp func(a, b float64) float64) interface{} {
+	if !isIntegral(left) || !isIntegral(right) {
+		return floatOp(toFloat(left), toFloat(right))
+	}
+	a, b := toInt64(left), toInt64(right)
+	result, ok := intOp(a, b)
+	if !ok {
+		if i.checkOverflow {
+			pos := n.Pos()
+			i.state.AddKuhulError(newOverflowError(op, pos.Line, pos.Column, pos.Offset).Error)
+			return nil
+		}
+		switch op {
+		case "+":
+			return a + b
+		case "-":
+			return a - b
+		default:
+			return a * b
+		}
+	}
+	return result
+}
+
+func (i *Interpreter) evaluateUnaryExpr(n *ast.UnaryExpr) interface{} {
+	operand := i.evaluateValue(n.Operand)
+	switch n.Operator {
+	case "-":
+		return numericUnop(operand, func(x int64) int64 { return -x }, func(x float64) float64 { return -x })
+	case "!":
+		return !toBool(operand)
+	default:
+		return nil
+	}
+}
+
+// evaluateCallExpr calls a registered built-in by name. Calls to anything
+// else (a Handler, a user block) go through Dispatch, not expression
+// evaluation. BuiltinsCtx (map/filter/reduce/sort, which need to call back
+// into a Callable lambda argument) is checked before the plain Builtins
+// table.
+func (i *Interpreter) evaluateCallExpr(n *ast.CallExpr) interface{} {
+	name, ok := n.Callee.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+
+	args := make([]interface{}, len(n.Arguments))
+	for idx, arg := range n.Arguments {
+		args[idx] = i.evaluateValue(arg)
+	}
+
+	if ctxFn, ok := BuiltinsCtx[name.Name]; ok {
+		return ctxFn(i, args...)
+	}
+
+	if _, ok := Builtins[name.Name]; !ok {
+		i.state.AddError("unknown function: " + name.Name)
+		return nil
+	}
+
+	if name.Name == "push" && len(args) >= 1 {
+		if _, ok := args[0].([]interface{}); !ok {
+			pos := n.Pos()
+			i.state.AddKuhulError(kerror.New(kerror.PushingInvalidType, name.Name, pos.Line, pos.Column, pos.Offset,
+				"push() requires an array, got %T", args[0]))
+		}
+	}
+
+	if result, handled := i.evaluateBackendCall(name.Name, args); handled {
+		return result
+	}
+
+	result, err := Call(name.Name, args...)
+	if err != nil {
+		i.state.AddError(err.Error())
+		return nil
+	}
+	return result
+}
+
+// evaluateBackendCall routes matrix_multiply/transpose/softmax through
+// i.state.Backend instead of the boxed []interface{} implementation in
+// Builtins, so a manifest-selected OpenCL/CUDA backend actually gets used.
+// handled is false (falling through to the Builtins entry) whenever the
+// arguments aren't a clean Tensor, matching those builtins' existing
+// "bad input -> nil" behavior.
+func (i *Interpreter) evaluateBackendCall(name string, args []interface{}) (result interface{}, handled bool) {
+	switch name {
+	case "matrix_multiply":
+		if len(args) < 2 {
+			return nil, false
+		}
+		a, aOk := tensorFromNested(args[0])
+		b, bOk := tensorFromNested(args[1])
+		if !aOk || !bOk {
+			return nil, false
+		}
+		out, err := i.state.Backend.MatMul(a, b)
+		if err != nil {
+			return nil, true
+		}
+		return out.ToNested(), true
+	case "transpose":
+		if len(args) < 1 {
+			return nil, false
+		}
+		a, ok := tensorFromNested(args[0])
+		if !ok {
+			return nil, false
+		}
+		out, err := i.state.Backend.Transpose(a)
+		if err != nil {
+			return nil, true
+		}
+		return out.ToNested(), true
+	case "softmax":
+		if len(args) != 1 {
+			// An explicit axis argument needs the N-D-aware builtin, not the
+			// 1D-only backend kernel.
+			return nil, false
+		}
+		a, ok := tensorFromNested(args[0])
+		if !ok {
+			return nil, false
+		}
+		out, err := i.state.Backend.Softmax(a)
+		if err != nil {
+			return nil, true
+		}
+		return out.ToNested(), true
+	default:
+		return nil, false
+	}
+}
+
+func (i *Interpreter) evaluateMemberExpr(n *ast.MemberExpr) interface{} {
+	object := i.evaluateValue(n.Object)
+	if m, ok := object.(map[string]interface{}); ok {
+		return m[n.Property]
+	}
+	return nil
+}
+
+func (i *Interpreter) evaluateIndexExpr(n *ast.IndexExpr) interface{} {
+	object := i.evaluateValue(n.Object)
+	index := i.evaluateValue(n.Index)
+
+	switch obj := object.(type) {
+	case []interface{}:
+		idx := int(toFloat(index))
+		if idx < 0 || idx >= len(obj) {
+			pos := n.Pos()
+			i.state.AddKuhulError(kerror.New(kerror.IndexOutOfRange, n.String(), pos.Line, pos.Column, pos.Offset,
+				"index %d out of range for array of length %d", idx, len(obj)))
+			return nil
+		}
+		return obj[idx]
+	case map[string]interface{}:
+		return obj[toString(index)]
+	default:
+		return nil
+	}
+}
+
+// valuesEqual compares two evaluated values for "==" / "!=". Numbers compare
+// by value so `1 == 1.0` holds regardless of how each side was typed (two
+// integer-kinded values compare as int64, any other pair of numbers as
+// float64); everything else falls back to reflect.DeepEqual since either side
+// may hold an uncomparable JSON map or slice.
+func valuesEqual(a, b interface{}) bool {
+	if isIntegral(a) && isIntegral(b) {
+		return toInt64(a) == toInt64(b)
+	}
+	if matchesKind(KindNumber, a) && matchesKind(KindNumber, b) {
+		asFloat := func(v interface{}) float64 {
+			if isIntegral(v) {
+				return float64(toInt64(v))
+			}
+			return toFloat(v)
+		}
+		return asFloat(a) == asFloat(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
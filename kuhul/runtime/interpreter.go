@@ -1,10 +1,15 @@
 package runtime
 
 import (
+	gocontext "context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/ollama/ollama/kuhul/ast"
+	"github.com/ollama/ollama/kuhul/events"
+	"github.com/ollama/ollama/kuhul/kerror"
+	"github.com/ollama/ollama/kuhul/manifest"
 	"github.com/ollama/ollama/kuhul/parser"
 )
 
@@ -14,6 +19,35 @@ type Interpreter struct {
 	program      *ast.Program
 	callDepth    int
 	maxCallDepth int
+
+	// env is the manifest environment (dev/staging/prod, ...) whose
+	// vars/handlers/tapes block applyEnvironment overlays over the base
+	// state on every Load. baseVars is the environment.Variables from
+	// before any overlay, kept so re-selecting the environment doesn't
+	// stack enclosures.
+	env      string
+	baseVars *Environment
+
+	// limits caps instructions/heap/output/handlers for a sandboxed
+	// Interpreter (see NewInterpreterWithLimits); nil leaves every cap
+	// unenforced. counters tracks what's actually been consumed against
+	// limits so Counters can report it back to the caller.
+	limits   *Limits
+	counters Counters
+
+	// checkOverflow, when true, makes evaluateBinaryExpr's "+"/"-"/"*"
+	// raise an OverflowError instead of silently wrapping once both
+	// operands are int64 and the result no longer fits. Off by default,
+	// matching every numeric builtin's existing wrapping behavior.
+	checkOverflow bool
+}
+
+// SetOverflowCheck turns i's int64 overflow-check mode on or off (see
+// checkOverflow). It's a runtime toggle rather than a constructor option so
+// a caller can flip it per-request the way SetLimits already does for
+// Limits.
+func (i *Interpreter) SetOverflowCheck(enabled bool) {
+	i.checkOverflow = enabled
 }
 
 // NewInterpreter creates a new interpreter
@@ -24,6 +58,50 @@ func NewInterpreter() *Interpreter {
 	}
 }
 
+// NewInterpreterWithEnv creates a new interpreter that overlays the named
+// manifest environment over the base vars/handlers/tapes on every Load, the
+// way UseEnvironment does for an interpreter that's already running.
+func NewInterpreterWithEnv(name string) *Interpreter {
+	i := NewInterpreter()
+	i.env = name
+	return i
+}
+
+// NewInterpreterWithLimits creates a new interpreter that enforces limits
+// (see Limits) while it runs — intended for an isolated, per-request
+// Interpreter executing untrusted source, rather than the long-lived
+// globalKuhulState interpreter server/kuhul_handlers.go dispatches against.
+func NewInterpreterWithLimits(limits *Limits) *Interpreter {
+	i := NewInterpreter()
+	i.limits = limits
+	return i
+}
+
+// Counters returns what this interpreter has consumed against its Limits so
+// far (zero-valued if it has none).
+func (i *Interpreter) Counters() Counters {
+	return i.counters
+}
+
+// SetLimits installs limits on an already-constructed Interpreter, enforced
+// from the next checkBudget/Dispatch/executeAssignment call onward. Unlike
+// NewInterpreterWithLimits, this lets a caller Load a program first and
+// compute Limits.AllowedHandlers from the handler names Load just
+// registered (see GetState().Handlers) before turning enforcement on.
+func (i *Interpreter) SetLimits(limits *Limits) {
+	i.limits = limits
+}
+
+// UseEnvironment selects name as the manifest environment to overlay over
+// the base vars/handlers/tapes. If a program is already loaded, the overlay
+// is re-applied immediately; otherwise it takes effect on the next Load.
+func (i *Interpreter) UseEnvironment(name string) {
+	i.env = name
+	if i.program != nil {
+		i.applyEnvironment()
+	}
+}
+
 // Load parses and loads a K'UHUL source file
 func (i *Interpreter) Load(source string) (*ast.Program, []string) {
 	program, errors := parser.Parse(source)
@@ -31,33 +109,121 @@ func (i *Interpreter) Load(source string) (*ast.Program, []string) {
 		return program, errors
 	}
 
+	i.finishLoad(program)
+	return program, nil
+}
+
+// LoadWithManifest parses source like Load, but first merges m (typically
+// loaded from an external .toml/.yaml/.json file via kuhul/manifest) as the
+// base layer underneath any inline ⟁Pop⟁ manifest_ast, which wins on
+// conflicting keys. A conflict is recorded on state.Diagnostics rather than
+// failing the load, since the inline value takes effect either way.
+func (i *Interpreter) LoadWithManifest(source string, m *ast.Manifest) (*ast.Program, []string) {
+	program, errors := parser.Parse(source)
+	if len(errors) > 0 {
+		return program, errors
+	}
+
+	merged, err := manifest.Merge(m, program.Manifest)
+	if kerr, ok := err.(*kerror.Error); ok {
+		i.state.AddKuhulError(kerr)
+	}
+	program.Manifest = merged
+
+	i.finishLoad(program)
+	return program, nil
+}
+
+// finishLoad wires up everything Load/LoadWithManifest do once program has
+// its final Manifest: select the accel backend, then register handlers,
+// variables, and vectors.
+func (i *Interpreter) finishLoad(program *ast.Program) {
 	i.program = program
 
-	// Load manifest
 	if program.Manifest != nil {
 		i.state.Manifest = program.Manifest.Raw
+		i.state.Backend = selectBackend(i.state, i.state.Manifest)
+		i.state.Store = selectStore(i.state, i.state.Manifest)
+		i.state.Bus = selectBus(i.state, i.state.Manifest)
 	}
 
-	// Register handlers from C@@L BLOCKs
 	i.registerHandlers()
-
-	// Register variables
 	i.registerVariables()
-
-	// Register vectors
 	i.registerVectors()
+	i.baseVars = i.state.Variables
+	i.applyEnvironment()
+}
 
-	return program, nil
+// applyEnvironment deep-merges program.Manifest.Environments[i.env]'s
+// "vars", "handlers", and "tapes" over the base state registerHandlers/
+// registerVariables/registerVectors set up. Vars are layered as a child
+// Environment enclosing baseVars, so a "@var" lookup that misses in the
+// env overlay falls back to the base value instead of vanishing. Also
+// records the selected environment in ASXRAM as "os.env".
+func (i *Interpreter) applyEnvironment() {
+	i.state.Variables = i.baseVars
+	i.state.SetASXRAM("os.env", i.env)
+
+	if i.env == "" || i.program.Manifest == nil {
+		return
+	}
+	envBlock, ok := i.program.Manifest.Environments[i.env].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if vars, ok := envBlock["vars"].(map[string]interface{}); ok {
+		overlay := NewEnclosedEnvironment(i.baseVars)
+		for name, val := range vars {
+			overlay.Set(name, val)
+		}
+		i.state.Variables = overlay
+	}
+
+	if handlers, ok := envBlock["handlers"].(map[string]interface{}); ok {
+		for name, override := range handlers {
+			params, ok := override.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if handler, ok := i.state.GetHandler(name); ok {
+				for k, v := range params {
+					handler.Params[k] = v
+				}
+			}
+		}
+	}
+
+	if tapes, ok := envBlock["tapes"].(map[string]interface{}); ok {
+		for id, tape := range tapes {
+			i.state.MX2DB.Tapes[id] = tape
+		}
+	}
 }
 
-// Run executes the loaded program
+// Run executes the loaded program. It's RunContext against
+// context.Background(), for callers that don't need a deadline.
 func (i *Interpreter) Run() (interface{}, error) {
+	return i.RunContext(gocontext.Background())
+}
+
+// RunContext executes the loaded program the same way Run does, but checks
+// ctx before every top-level statement (each declaration, assignment,
+// control vector, and block), so a caller's timeout or cancellation stops
+// the program between statements instead of running it to completion
+// regardless. A check mid-statement isn't possible for the statement kinds
+// this interpreter has today (none of them loop internally), but executeBlock
+// performs the same check again before each node in a ⟁Xul⟁ block's body.
+func (i *Interpreter) RunContext(ctx gocontext.Context) (interface{}, error) {
 	if i.program == nil {
 		return nil, fmt.Errorf("no program loaded")
 	}
 
 	// Execute declarations
 	for _, decl := range i.program.Declarations {
+		if err := i.checkBudget(ctx); err != nil {
+			return nil, err
+		}
 		if err := i.executeDeclaration(decl); err != nil {
 			return nil, err
 		}
@@ -65,14 +231,30 @@ func (i *Interpreter) Run() (interface{}, error) {
 
 	// Execute assignments
 	for _, assign := range i.program.Assignments {
+		if err := i.checkBudget(ctx); err != nil {
+			return nil, err
+		}
 		if err := i.executeAssignment(assign); err != nil {
 			return nil, err
 		}
 	}
 
+	// Execute control vectors
+	for _, cv := range i.program.ControlVectors {
+		if err := i.checkBudget(ctx); err != nil {
+			return nil, err
+		}
+		if err := i.executeControlVector(cv); err != nil {
+			return nil, err
+		}
+	}
+
 	// Execute blocks
 	for _, block := range i.program.Blocks {
-		if _, err := i.executeBlock(block); err != nil {
+		if err := i.checkBudget(ctx); err != nil {
+			return nil, err
+		}
+		if _, err := i.executeBlock(ctx, block); err != nil {
 			return nil, err
 		}
 	}
@@ -80,8 +262,15 @@ func (i *Interpreter) Run() (interface{}, error) {
 	return i.state.GetState(), nil
 }
 
-// Eval evaluates a single expression or statement
+// Eval evaluates a single expression or statement. It's EvalContext against
+// context.Background(), for callers that don't need a deadline.
 func (i *Interpreter) Eval(source string) (interface{}, error) {
+	return i.EvalContext(gocontext.Background(), source)
+}
+
+// EvalContext evaluates a single expression or statement the same way Eval
+// does, but checks ctx before every top-level statement like RunContext.
+func (i *Interpreter) EvalContext(ctx gocontext.Context, source string) (interface{}, error) {
 	program, errors := parser.Parse(source)
 	if len(errors) > 0 {
 		return nil, fmt.Errorf("parse errors: %v", errors)
@@ -92,6 +281,9 @@ func (i *Interpreter) Eval(source string) (interface{}, error) {
 
 	// Execute declarations
 	for _, decl := range program.Declarations {
+		if err := i.checkBudget(ctx); err != nil {
+			return nil, err
+		}
 		if err = i.executeDeclaration(decl); err != nil {
 			return nil, err
 		}
@@ -99,15 +291,31 @@ func (i *Interpreter) Eval(source string) (interface{}, error) {
 
 	// Execute assignments
 	for _, assign := range program.Assignments {
+		if err := i.checkBudget(ctx); err != nil {
+			return nil, err
+		}
 		if err = i.executeAssignment(assign); err != nil {
 			return nil, err
 		}
 		result = i.state.Variables.store[assign.Name]
 	}
 
+	// Execute control vectors
+	for _, cv := range program.ControlVectors {
+		if err := i.checkBudget(ctx); err != nil {
+			return nil, err
+		}
+		if err = i.executeControlVector(cv); err != nil {
+			return nil, err
+		}
+	}
+
 	// Execute blocks
 	for _, block := range program.Blocks {
-		result, err = i.executeBlock(block)
+		if err := i.checkBudget(ctx); err != nil {
+			return nil, err
+		}
+		result, err = i.executeBlock(ctx, block)
 		if err != nil {
 			return nil, err
 		}
@@ -116,11 +324,36 @@ func (i *Interpreter) Eval(source string) (interface{}, error) {
 	return result, nil
 }
 
+// deadlineError wraps a context.Context.Err() (context.DeadlineExceeded or
+// context.Canceled) as a structured KuhulError, so a canceled RunContext/
+// EvalContext/Dispatch looks like any other interpreter failure to callers
+// that branch on kerror.Code.
+func deadlineError(err error) error {
+	return kerror.New(kerror.DeadlineExceeded, "", 0, 0, 0, "%s", err.Error())
+}
+
+// checkBudget checks ctx the same way deadlineError's callers always have,
+// then — for a sandboxed Interpreter built with NewInterpreterWithLimits —
+// counts one more step against limits.MaxInstructions. It's called once per
+// top-level statement in RunContext/EvalContext and once per node in
+// executeBlock's body, so a runaway ⟁K'ayab⟁ loop aborts on instruction
+// count well before a deadline would otherwise catch it.
+func (i *Interpreter) checkBudget(ctx gocontext.Context) error {
+	if err := ctx.Err(); err != nil {
+		return deadlineError(err)
+	}
+	return i.counters.step(i.limits)
+}
+
 // Dispatch calls a handler by name
 func (i *Interpreter) Dispatch(handlerName string, ctx *Context) (interface{}, error) {
+	if err := checkHandlerAllowed(i.limits, handlerName); err != nil {
+		return nil, err
+	}
+
 	handler, ok := i.state.GetHandler(handlerName)
 	if !ok {
-		return nil, fmt.Errorf("handler not found: %s", handlerName)
+		return nil, kerror.New(kerror.HandlerNotFound, handlerName, 0, 0, 0, "handler not found: %s", handlerName)
 	}
 
 	if ctx == nil {
@@ -134,9 +367,43 @@ func (i *Interpreter) Dispatch(handlerName string, ctx *Context) (interface{}, e
 		}
 	}
 
+	if ctx.Ctx != nil {
+		if err := ctx.Ctx.Err(); err != nil {
+			return nil, deadlineError(err)
+		}
+	}
+
+	if handler.Execute == nil {
+		return nil, kerror.New(kerror.HandlerNotFound, handlerName, 0, 0, 0, "handler not found: %s", handlerName)
+	}
+
 	return handler.Execute(ctx)
 }
 
+// DispatchAsync enqueues handlerName onto i.state.Bus instead of running it
+// in-process: it returns a job id immediately, and the JobResult can be
+// fetched later via i.state.Store.GetJob or the "job.get" basher command,
+// once the bus's worker (memoryBus's background goroutine, or an external
+// RunConsumer process for the AMQP driver) has run it.
+func (i *Interpreter) DispatchAsync(handlerName string, ctx *Context) (string, error) {
+	if _, ok := i.state.GetHandler(handlerName); !ok {
+		return "", kerror.New(kerror.HandlerNotFound, handlerName, 0, 0, 0, "handler not found: %s", handlerName)
+	}
+
+	if ctx == nil {
+		ctx = &Context{
+			Handler: handlerName,
+			Params:  make(map[string]interface{}),
+			Body:    make(map[string]interface{}),
+			Query:   make(map[string]interface{}),
+			Runtime: i.state,
+			Env:     i.state.Variables,
+		}
+	}
+
+	return i.state.Bus.Publish(handlerName, ctx)
+}
+
 // registerHandlers registers C@@L BLOCK handlers
 func (i *Interpreter) registerHandlers() {
 	for name, block := range i.program.CoolBlocks {
@@ -160,6 +427,28 @@ func (i *Interpreter) registerHandlers() {
 		handler.Execute = i.createExecutor(handlerName, block)
 
 		i.state.RegisterHandler(handlerName, handler)
+		i.state.Bus.Subscribe(handlerName, handler.Execute)
+
+		if block.OnEvent != "" {
+			i.state.Events.AddObserver(block.OnEvent, handlerName, i.createEventObserver(handlerName, handler))
+		}
+	}
+}
+
+// createEventObserver adapts a handler into an events.EventCallback so a
+// CoolBlock with an @on param can be invoked by PostEvent instead of Dispatch.
+func (i *Interpreter) createEventObserver(handlerName string, handler *Handler) events.EventCallback {
+	return func(event string, source interface{}) {
+		ctx := &Context{
+			Handler: handlerName,
+			Params:  handler.Params,
+			Body:    map[string]interface{}{"event": event, "source": source},
+			Runtime: i.state,
+			Env:     i.state.Variables,
+		}
+		if _, err := handler.Execute(ctx); err != nil {
+			i.state.AddError(fmt.Sprintf("event %q handler %q: %v", event, handlerName, err))
+		}
 	}
 }
 
@@ -190,7 +479,7 @@ func (i *Interpreter) createExecutor(handlerName string, block *ast.CoolBlock) f
 		i.callDepth++
 		if i.callDepth > i.maxCallDepth {
 			i.callDepth--
-			return nil, fmt.Errorf("maximum call depth exceeded")
+			return nil, kerror.New(kerror.MaxCallDepthExceeded, handlerName, 0, 0, 0, "maximum call depth exceeded")
 		}
 		defer func() { i.callDepth-- }()
 
@@ -241,15 +530,42 @@ func (i *Interpreter) executeDeclaration(decl *ast.Declaration) error {
 	return nil
 }
 
-// executeAssignment executes a Wo assignment
+// executeAssignment executes a Wo assignment. For a sandboxed Interpreter
+// (see NewInterpreterWithLimits) this is also where heap accounting happens:
+// every ⟁Wo⟁ binding adds its approximate size to counters.HeapBytes, so a
+// loop that keeps binding larger and larger values aborts once
+// limits.MaxHeapBytes is hit instead of growing unbounded.
 func (i *Interpreter) executeAssignment(assign *ast.Assignment) error {
 	value := i.evaluateValue(assign.Value)
+	if err := i.counters.addHeap(i.limits, approxSize(value)); err != nil {
+		return err
+	}
 	i.state.Variables.Set(assign.Name, value)
 	return nil
 }
 
-// executeBlock executes a Xul block
-func (i *Interpreter) executeBlock(block *ast.BlockDefinition) (interface{}, error) {
+// executeControlVector executes a Sek control vector. Only "emit" is
+// interpreted today; other vector types are no-ops until their own handlers
+// land.
+func (i *Interpreter) executeControlVector(cv *ast.ControlVector) error {
+	if cv.VectorType != "emit" {
+		return nil
+	}
+
+	event, ok := cv.Params["event"].(string)
+	if !ok || event == "" {
+		return fmt.Errorf("⟁Sek⟁ emit requires an @event param")
+	}
+
+	i.state.Events.PostEvent(event, cv.Params)
+	return nil
+}
+
+// executeBlock executes a Xul block. goCtx is checked before every node in
+// block.Body, same as RunContext/EvalContext check it between top-level
+// statements, and threaded onto each CoolBlock's Context so Dispatch-style
+// cancellation checks see it too.
+func (i *Interpreter) executeBlock(goCtx gocontext.Context, block *ast.BlockDefinition) (interface{}, error) {
 	// Create new scope
 	env := NewEnclosedEnvironment(i.state.Variables)
 
@@ -261,14 +577,21 @@ func (i *Interpreter) executeBlock(block *ast.BlockDefinition) (interface{}, err
 	// Execute body
 	var result interface{}
 	for _, node := range block.Body {
+		if err := i.checkBudget(goCtx); err != nil {
+			return nil, err
+		}
 		switch n := node.(type) {
 		case *ast.CoolBlock:
 			if handler, ok := i.state.GetHandler(n.Handler); ok {
+				if err := checkHandlerAllowed(i.limits, n.Handler); err != nil {
+					return nil, err
+				}
 				ctx := &Context{
 					Handler: n.Handler,
 					Params:  n.Params,
 					Runtime: i.state,
 					Env:     env,
+					Ctx:     goCtx,
 				}
 				r, err := handler.Execute(ctx)
 				if err != nil {
@@ -293,6 +616,22 @@ func (i *Interpreter) evaluateValue(value interface{}) interface{} {
 			}
 		}
 		return v
+	case *ast.Literal:
+		return i.evaluateValue(v.Value)
+	case *ast.Identifier:
+		return i.evaluateValue(v.Name)
+	case *ast.BinaryExpr:
+		return i.evaluateBinaryExpr(v)
+	case *ast.UnaryExpr:
+		return i.evaluateUnaryExpr(v)
+	case *ast.CallExpr:
+		return i.evaluateCallExpr(v)
+	case *ast.MemberExpr:
+		return i.evaluateMemberExpr(v)
+	case *ast.IndexExpr:
+		return i.evaluateIndexExpr(v)
+	case *ast.Lambda:
+		return &Callable{params: v.Params, body: v.Body, interp: i}
 	default:
 		return v
 	}
@@ -320,11 +659,24 @@ func (i *Interpreter) handleKernelBoot(ctx *Context) (interface{}, error) {
 	// Initialize tapes
 	if tapes, ok := i.state.Manifest["tapes"].(map[string]interface{}); ok {
 		for id, tape := range tapes {
-			i.state.MX2DB.Tapes[id] = tape
+			i.state.Store.PutTape(id, tape)
 		}
 		i.state.AddBootStep("tapes_registered")
 	}
 
+	// Re-apply the selected environment's tapes last, so they win over the
+	// base manifest's on conflicting ids.
+	if i.env != "" && i.program != nil && i.program.Manifest != nil {
+		if envBlock, ok := i.program.Manifest.Environments[i.env].(map[string]interface{}); ok {
+			if tapes, ok := envBlock["tapes"].(map[string]interface{}); ok {
+				for id, tape := range tapes {
+					i.state.Store.PutTape(id, tape)
+				}
+				i.state.AddBootStep("env_tapes_registered")
+			}
+		}
+	}
+
 	i.state.Booted = true
 	i.state.SetASXRAM("os.state", "active")
 	i.state.AddBootStep("kernel_boot_complete")
@@ -352,7 +704,7 @@ func (i *Interpreter) handleTapeBoot(ctx *Context) (interface{}, error) {
 		return map[string]interface{}{"ok": false, "error": "No tape_id provided"}, nil
 	}
 
-	tape, ok := i.state.MX2DB.Tapes[tapeID]
+	tape, ok := i.state.Store.GetTape(tapeID)
 	if !ok {
 		return map[string]interface{}{"ok": false, "error": "Tape not found", "tape_id": tapeID}, nil
 	}
@@ -428,6 +780,15 @@ func (i *Interpreter) handleBasherRun(ctx *Context) (interface{}, error) {
 		}
 		return map[string]interface{}{"ok": true, "keys": keys, "count": len(keys)}, nil
 
+	case "job.get":
+		if len(args) > 0 {
+			if result, ok := i.state.Store.GetJob(args[0]); ok {
+				return map[string]interface{}{"ok": true, "job_id": args[0], "job": result}, nil
+			}
+			return map[string]interface{}{"ok": false, "error": "Job not found", "job_id": args[0]}, nil
+		}
+		return map[string]interface{}{"ok": false, "error": "No job_id provided"}, nil
+
 	case "health":
 		return map[string]interface{}{
 			"ok":       true,
@@ -443,10 +804,7 @@ func (i *Interpreter) handleBasherRun(ctx *Context) (interface{}, error) {
 }
 
 func (i *Interpreter) handleCmsRlhfList(ctx *Context) (interface{}, error) {
-	items := make([]interface{}, 0)
-	for _, v := range i.state.MX2DB.RLHFTraces {
-		items = append(items, v)
-	}
+	items := i.state.Store.ListRLHF()
 	return map[string]interface{}{
 		"ok":    true,
 		"mode":  "list",
@@ -464,14 +822,14 @@ func (i *Interpreter) handleCmsRlhfGet(ctx *Context) (interface{}, error) {
 		caseID = v
 	}
 
-	if item, ok := i.state.MX2DB.RLHFTraces[caseID]; ok {
+	if item, ok := i.state.Store.GetRLHF(caseID); ok {
 		return map[string]interface{}{"ok": true, "mode": "get", "item": item}, nil
 	}
 	return map[string]interface{}{"ok": false, "error": "Case not found", "case_id": caseID}, nil
 }
 
 func (i *Interpreter) handleCmsRlhfPost(ctx *Context) (interface{}, error) {
-	caseID := fmt.Sprintf("rlhf_%d", len(i.state.MX2DB.RLHFTraces)+1)
+	caseID := fmt.Sprintf("rlhf_%d", len(i.state.Store.ListRLHF())+1)
 
 	newCase := map[string]interface{}{
 		"case_id": caseID,
@@ -480,7 +838,7 @@ func (i *Interpreter) handleCmsRlhfPost(ctx *Context) (interface{}, error) {
 		"body":    ctx.Body["body"],
 	}
 
-	i.state.MX2DB.RLHFTraces[caseID] = newCase
+	i.state.Store.PutRLHF(caseID, newCase)
 
 	return map[string]interface{}{
 		"ok":      true,
@@ -490,6 +848,12 @@ func (i *Interpreter) handleCmsRlhfPost(ctx *Context) (interface{}, error) {
 	}, nil
 }
 
+// handleGramObserve counts windowSize-token n-grams over ctx.Body's
+// "sequence" and merges them into MX2DB.NGrams. Long sequences are observed
+// in parallel via observeNGramsParallel: "chunk_size" and "workers" in
+// ctx.Body tune the demux/worker/mux pipeline (workers defaults to one per
+// CPU); the merge is order-independent, so the result doesn't depend on how
+// the work was split.
 func (i *Interpreter) handleGramObserve(ctx *Context) (interface{}, error) {
 	sequence, ok := ctx.Body["sequence"].([]interface{})
 	if !ok {
@@ -500,31 +864,31 @@ func (i *Interpreter) handleGramObserve(ctx *Context) (interface{}, error) {
 	if ws, ok := ctx.Body["window_size"].(float64); ok {
 		windowSize = int(ws)
 	}
+	chunkSize := defaultGramChunkSize
+	if cs, ok := ctx.Body["chunk_size"].(float64); ok && cs > 0 {
+		chunkSize = int(cs)
+	}
 
-	// Generate n-grams
-	for j := 0; j <= len(sequence)-windowSize; j++ {
-		gram := ""
-		for k := 0; k < windowSize; k++ {
-			if k > 0 {
-				gram += "|"
-			}
-			gram += fmt.Sprintf("%v", sequence[j+k])
-		}
-		i.state.MX2DB.NGrams[gram]++
+	counts := observeNGramsParallel(sequence, windowSize, chunkSize, gramWorkerCount(ctx))
+
+	for gram, count := range counts {
+		i.state.Store.IncrNGram(gram, count)
 	}
+	total := i.state.Store.NGramCount()
 
 	return map[string]interface{}{
 		"ok":            true,
 		"observed":      len(sequence),
-		"n_grams_count": len(i.state.MX2DB.NGrams),
+		"n_grams_count": total,
 	}, nil
 }
 
 func (i *Interpreter) handleGramAnalyze(ctx *Context) (interface{}, error) {
 	patterns := make([]map[string]interface{}, 0)
-	total := len(i.state.MX2DB.NGrams)
+	all := i.state.Store.ScanNGramsWithPrefix("")
+	total := i.state.Store.NGramCount()
 
-	for gram, count := range i.state.MX2DB.NGrams {
+	for gram, count := range all {
 		patterns = append(patterns, map[string]interface{}{
 			"gram":      gram,
 			"count":     count,
@@ -540,12 +904,22 @@ func (i *Interpreter) handleGramAnalyze(ctx *Context) (interface{}, error) {
 	}, nil
 }
 
+// handleGramSuggest ranks ctx.Body's "prefix" continuations by interpolated
+// Kneser-Ney smoothed probability (see kneserNey) rather than raw frequency,
+// so a rare-but-real continuation can outrank a common gram shared with
+// unrelated contexts, and an unseen exact prefix still backs off to a
+// sensible shorter-context estimate instead of returning nothing.
 func (i *Interpreter) handleGramSuggest(ctx *Context) (interface{}, error) {
 	prefix, ok := ctx.Body["prefix"].([]interface{})
 	if !ok {
 		return map[string]interface{}{"ok": false, "error": "No prefix provided"}, nil
 	}
 
+	discount := defaultKNDiscount
+	if d, ok := ctx.Body["discount"].(float64); ok {
+		discount = d
+	}
+
 	prefixStr := ""
 	for j, p := range prefix {
 		if j > 0 {
@@ -554,21 +928,20 @@ func (i *Interpreter) handleGramSuggest(ctx *Context) (interface{}, error) {
 		prefixStr += fmt.Sprintf("%v", p)
 	}
 
-	suggestions := make([]map[string]interface{}, 0)
-
-	for gram, count := range i.state.MX2DB.NGrams {
-		if strings.HasPrefix(gram, prefixStr) {
-			parts := strings.Split(gram, "|")
-			if len(parts) > len(prefix) {
-				suggestions = append(suggestions, map[string]interface{}{
-					"next":       parts[len(prefix)],
-					"count":      count,
-					"confidence": float64(count) / float64(len(i.state.MX2DB.NGrams)),
-				})
-			}
-		}
+	words := i.state.Store.PrefixContinuations(prefixStr)
+	suggestions := make([]map[string]interface{}, 0, len(words))
+	for _, w := range words {
+		suggestions = append(suggestions, map[string]interface{}{
+			"next":       w,
+			"count":      i.state.Store.GramCount(prefixStr, w),
+			"confidence": i.kneserNey(prefixStr, w, discount),
+		})
 	}
 
+	sort.Slice(suggestions, func(a, b int) bool {
+		return suggestions[a]["confidence"].(float64) > suggestions[b]["confidence"].(float64)
+	})
+
 	return map[string]interface{}{
 		"ok":          true,
 		"mode":        "suggest",
@@ -582,6 +955,22 @@ func (i *Interpreter) GetState() *RuntimeState {
 	return i.state
 }
 
+// RestoreState replaces the interpreter's runtime state with rs, typically
+// one built by runtime.Restore from a prior Snapshot. The loaded program,
+// env overlay, and call depth are untouched, so Run/Eval against the
+// program already loaded here still work the same; handlers rs restored
+// with no Execute func (see handlerSnapshot) won't Dispatch until the
+// program is Load-ed again to re-register them.
+func (i *Interpreter) RestoreState(rs *RuntimeState) {
+	i.state = rs
+}
+
+// GetProgram returns the most recently Load-ed AST, or nil if nothing has
+// been loaded yet.
+func (i *Interpreter) GetProgram() *ast.Program {
+	return i.program
+}
+
 // GetVariable gets a variable value
 func (i *Interpreter) GetVariable(name string) (interface{}, bool) {
 	return i.state.Variables.Get(name)
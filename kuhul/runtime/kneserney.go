@@ -0,0 +1,50 @@
+package runtime
+
+import "strings"
+
+// defaultKNDiscount is the discount D used by kneserNey unless ctx.Body
+// overrides it for a handleGramSuggest call.
+const defaultKNDiscount = 0.75
+
+// kneserNey computes interpolated Kneser-Ney smoothing:
+//
+//	P_KN(w|prefix) = max(c(prefix,w)-D, 0)/c(prefix,*)
+//	               + D*N1+(prefix,*)/c(prefix,*) * P_KN(w|backoff(prefix))
+//
+// where backoff(prefix) drops prefix's leftmost token, and the recursion
+// terminates at the unigram level (prefix == ""), where the continuation
+// probability is N1+(*,w)/N1+(*,*): how many distinct contexts w follows in,
+// normalised by the total number of distinct (context, word) pairs observed.
+func (i *Interpreter) kneserNey(prefix, word string, discount float64) float64 {
+	if prefix == "" {
+		total := i.state.Store.DistinctGramTypes()
+		if total == 0 {
+			return 0
+		}
+		return float64(i.state.Store.TokenContextCount(word)) / float64(total)
+	}
+
+	total := i.state.Store.PrefixTotal(prefix)
+	if total == 0 {
+		return i.kneserNey(backoffPrefix(prefix), word, discount)
+	}
+
+	discounted := float64(i.state.Store.GramCount(prefix, word)) - discount
+	if discounted < 0 {
+		discounted = 0
+	}
+
+	lambda := discount * float64(i.state.Store.PrefixContinuationCount(prefix)) / float64(total)
+
+	return discounted/float64(total) + lambda*i.kneserNey(backoffPrefix(prefix), word, discount)
+}
+
+// backoffPrefix drops prefix's leftmost "|"-joined token, the way a
+// trigram context "a|b" backs off to the bigram context "b". A
+// single-token (or empty) prefix backs off to "", the unigram base case.
+func backoffPrefix(prefix string) string {
+	if idx := strings.Index(prefix, "|"); idx >= 0 {
+		return prefix[idx+1:]
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package runtime
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ollama/ollama/kuhul/kerror"
+)
+
+// Limits bounds a sandboxed Interpreter's resource usage. MaxInstructions
+// caps how many declaration/assignment/control-vector/block steps Run/Eval
+// may take before aborting with a LimitExceededError — catching a runaway
+// ⟁K'ayab⟁ loop faster than a deadline alone would, since a deadline only
+// cuts in wall-clock time. MaxHeapBytes caps the approximate size of
+// ⟁Wo⟁-bound values. MaxOutputBytes caps the size of the JSON-encoded result
+// Run/Eval/Dispatch returns. AllowedHandlers, when non-nil, denies Dispatch
+// for any C@@L BLOCK whose name isn't in the set — e.g. a "strict" sandbox
+// omitting "lam_o.infer" and any FS/net pack handler. A nil Limits (or a
+// zero field within one) leaves that particular cap unenforced.
+type Limits struct {
+	MaxInstructions int64
+	MaxHeapBytes    int64
+	MaxOutputBytes  int
+	AllowedHandlers map[string]bool
+}
+
+// Counters reports what an Interpreter actually consumed against its
+// Limits, so a caller that hit a cap (or didn't) can size the next
+// request's Limits. Interpreter.Counters returns a copy.
+type Counters struct {
+	Instructions int64
+	HeapBytes    int64
+}
+
+// LimitExceededError marks a Limits cap an Interpreter hit. Kind names which
+// one: "instructions", "heap_bytes", "output_bytes", or "handler".
+type LimitExceededError struct {
+	Err  *kerror.Error
+	Kind string
+}
+
+// Error satisfies the error interface. It isn't promoted from Err because
+// embedding *kerror.Error anonymously under the field name "Error" would
+// shadow *kerror.Error's own Error() method instead of exposing it.
+func (e *LimitExceededError) Error() string {
+	return e.Err.Error()
+}
+
+func newLimitExceededError(kind, format string, args ...interface{}) *LimitExceededError {
+	return &LimitExceededError{
+		Err:  kerror.New(kerror.LimitExceeded, "", 0, 0, 0, format, args...),
+		Kind: kind,
+	}
+}
+
+// step records one more executed instruction and returns a
+// LimitExceededError once limits caps it.
+func (c *Counters) step(limits *Limits) error {
+	n := atomic.AddInt64(&c.Instructions, 1)
+	if limits != nil && limits.MaxInstructions > 0 && n > limits.MaxInstructions {
+		return newLimitExceededError("instructions", "exceeded max instructions (%d)", limits.MaxInstructions)
+	}
+	return nil
+}
+
+// addHeap records size more heap bytes bound and returns a
+// LimitExceededError once limits caps it.
+func (c *Counters) addHeap(limits *Limits, size int64) error {
+	n := atomic.AddInt64(&c.HeapBytes, size)
+	if limits != nil && limits.MaxHeapBytes > 0 && n > limits.MaxHeapBytes {
+		return newLimitExceededError("heap_bytes", "exceeded max heap bytes (%d)", limits.MaxHeapBytes)
+	}
+	return nil
+}
+
+// CheckOutput returns a LimitExceededError if size exceeds limits'
+// MaxOutputBytes. Exported for callers like server.KuhulExecuteHandler that
+// marshal a sandboxed Run/EvalContext result before returning it.
+func CheckOutput(limits *Limits, size int) error {
+	if limits != nil && limits.MaxOutputBytes > 0 && size > limits.MaxOutputBytes {
+		return newLimitExceededError("output_bytes", "result of %d bytes exceeds max output bytes (%d)", size, limits.MaxOutputBytes)
+	}
+	return nil
+}
+
+// checkHandlerAllowed returns a LimitExceededError if limits has an
+// AllowedHandlers set that doesn't include name.
+func checkHandlerAllowed(limits *Limits, name string) error {
+	if limits != nil && limits.AllowedHandlers != nil && !limits.AllowedHandlers[name] {
+		return newLimitExceededError("handler", "handler %q is not in this sandbox's allowed set", name)
+	}
+	return nil
+}
+
+// approxSize estimates the in-memory footprint of a ⟁Wo⟁-bound value well
+// enough to bound runaway growth; it doesn't need to be exact, just
+// monotonic in the value's actual size.
+func approxSize(v interface{}) int64 {
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(t))
+	case []interface{}:
+		var n int64
+		for _, e := range t {
+			n += approxSize(e)
+		}
+		return n
+	case map[string]interface{}:
+		var n int64
+		for k, e := range t {
+			n += int64(len(k)) + approxSize(e)
+		}
+		return n
+	default:
+		return int64(len(fmt.Sprint(t)))
+	}
+}
@@ -0,0 +1,98 @@
+package runtime
+
+import (
+	"fmt"
+	goruntime "runtime"
+	"strings"
+	"sync"
+)
+
+// defaultGramChunkSize is how many tokens each demuxed job covers when
+// ctx.Body doesn't name a "chunk_size" for handleGramObserve.
+const defaultGramChunkSize = 50_000
+
+// observeNGramsParallel counts windowSize-token n-grams over sequence with a
+// demux/worker/mux pipeline: a demuxer goroutine slices sequence into
+// chunkSize-token jobs (each extended windowSize-1 tokens past its nominal
+// end so a gram straddling a chunk boundary still gets counted, and exactly
+// once — the next job's nominal start picks up right after the unextended
+// boundary), workers goroutines each accumulate their own local
+// map[string]int off the jobs channel, and the results are merged by plain
+// integer addition, which is commutative, so the total is independent of
+// job completion order.
+func observeNGramsParallel(sequence []interface{}, windowSize, chunkSize, workers int) map[string]int {
+	if workers < 1 {
+		workers = 1
+	}
+	if chunkSize < windowSize {
+		chunkSize = windowSize
+	}
+
+	type job struct{ start, end int }
+	jobs := make(chan job)
+
+	go func() {
+		defer close(jobs)
+		for start := 0; start < len(sequence); start += chunkSize {
+			nominalEnd := start + chunkSize
+			if nominalEnd > len(sequence) {
+				nominalEnd = len(sequence)
+			}
+			end := nominalEnd + windowSize - 1
+			if end > len(sequence) {
+				end = len(sequence)
+			}
+			jobs <- job{start, end}
+		}
+	}()
+
+	results := make(chan map[string]int, workers)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			local := make(map[string]int)
+			for j := range jobs {
+				countGramsInto(sequence[j.start:j.end], windowSize, local)
+			}
+			results <- local
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	merged := make(map[string]int)
+	for local := range results {
+		for gram, count := range local {
+			merged[gram] += count
+		}
+	}
+	return merged
+}
+
+// countGramsInto slides a windowSize window over seq, adding one count per
+// "|"-joined gram to into.
+func countGramsInto(seq []interface{}, windowSize int, into map[string]int) {
+	for j := 0; j <= len(seq)-windowSize; j++ {
+		var b strings.Builder
+		for k := 0; k < windowSize; k++ {
+			if k > 0 {
+				b.WriteByte('|')
+			}
+			fmt.Fprintf(&b, "%v", seq[j+k])
+		}
+		into[b.String()]++
+	}
+}
+
+// gramWorkerCount returns the worker count to use for handleGramObserve:
+// ctx.Body's "workers" if present and positive, else one per CPU.
+func gramWorkerCount(ctx *Context) int {
+	if w, ok := ctx.Body["workers"].(float64); ok && w > 0 {
+		return int(w)
+	}
+	return goruntime.NumCPU()
+}
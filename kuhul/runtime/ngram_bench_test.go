@@ -0,0 +1,24 @@
+package runtime
+
+import (
+	"fmt"
+	"testing"
+)
+
+// BenchmarkObserveNGramsParallel observes a 1M-token sequence at several
+// worker counts to show the demux/worker/mux pipeline scales past the
+// single-worker (effectively sequential) baseline.
+func BenchmarkObserveNGramsParallel(b *testing.B) {
+	sequence := make([]interface{}, 1_000_000)
+	for i := range sequence {
+		sequence[i] = i % 5000
+	}
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				observeNGramsParallel(sequence, 3, defaultGramChunkSize, workers)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ollama/ollama/kuhul/kerror"
+)
+
+// isIntegral reports whether v holds an integer-kinded Go value — not a
+// float64/float32, even one with no fractional part, since that distinction
+// is exactly what numericBinop/numericUnop use to decide whether to stay in
+// the int64 lane of the numeric tower or promote to float64.
+func isIntegral(v interface{}) bool {
+	switch v.(type) {
+	case int64, int, int8, int16, int32, uint, uint8, uint16, uint32, uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// toInt64 coerces v to int64, truncating a float or parsing a numeric
+// string the same permissive way toFloat does. It's the int64 counterpart
+// toFloat has always been for float64.
+func toInt64(v interface{}) int64 {
+	switch val := v.(type) {
+	case int64:
+		return val
+	case int:
+		return int64(val)
+	case int8:
+		return int64(val)
+	case int16:
+		return int64(val)
+	case int32:
+		return int64(val)
+	case uint:
+		return int64(val)
+	case uint8:
+		return int64(val)
+	case uint16:
+		return int64(val)
+	case uint32:
+		return int64(val)
+	case uint64:
+		return int64(val)
+	case float32:
+		return int64(val)
+	case float64:
+		return int64(val)
+	case string:
+		var n int64
+		fmt.Sscanf(val, "%d", &n)
+		return n
+	default:
+		return 0
+	}
+}
+
+// numericBinop applies intOp when both a and b are integer-kinded (staying
+// in int64 and preserving precision past 2^53), otherwise promotes both to
+// float64 and applies floatOp. This is the numeric tower math/min/max/etc.
+// funnel through so they no longer coerce every integer argument to float64
+// the way a blanket toFloat call used to.
+func numericBinop(a, b interface{}, intOp func(x, y int64) int64, floatOp func(x, y float64) float64) interface{} {
+	if isIntegral(a) && isIntegral(b) {
+		return intOp(toInt64(a), toInt64(b))
+	}
+	return floatOp(toFloat(a), toFloat(b))
+}
+
+// numericUnop is numericBinop's one-argument counterpart, for abs/floor/
+// ceil/round.
+func numericUnop(a interface{}, intOp func(x int64) int64, floatOp func(x float64) float64) interface{} {
+	if isIntegral(a) {
+		return intOp(toInt64(a))
+	}
+	return floatOp(toFloat(a))
+}
+
+// OverflowError marks an int64 arithmetic operation (+, -, *) that
+// overflowed, raised by evaluateBinaryExpr instead of wrapping when the
+// Interpreter's overflow-check mode is on (see Interpreter.SetOverflowCheck).
+type OverflowError struct {
+	*kerror.Error
+	Op string
+}
+
+func newOverflowError(op string, line, column, offset int) *OverflowError {
+	return &OverflowError{
+		Error: kerror.New(kerror.IntegerOverflow, op, line, column, offset, "integer overflow in %q", op),
+		Op:    op,
+	}
+}
+
+// checkedAddInt64 returns x+y and true, or (0, false) if that sum overflows
+// int64.
+func checkedAddInt64(x, y int64) (int64, bool) {
+	sum := x + y
+	if (y > 0 && sum < x) || (y < 0 && sum > x) {
+		return 0, false
+	}
+	return sum, true
+}
+
+// checkedSubInt64 returns x-y and true, or (0, false) if that difference
+// overflows int64.
+func checkedSubInt64(x, y int64) (int64, bool) {
+	diff := x - y
+	if (y < 0 && diff < x) || (y > 0 && diff > x) {
+		return 0, false
+	}
+	return diff, true
+}
+
+// checkedMulInt64 returns x*y and true, or (0, false) if that product
+// overflows int64.
+func checkedMulInt64(x, y int64) (int64, bool) {
+	if x == 0 || y == 0 {
+		return 0, true
+	}
+	product := x * y
+	if product/y != x || (x == math.MinInt64 && y == -1) {
+		return 0, false
+	}
+	return product, true
+}
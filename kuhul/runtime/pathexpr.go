@@ -0,0 +1,477 @@
+package runtime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathError reports a get/set/has/del/paths call that couldn't resolve Path
+// against the value it was given — jq's "object has no key" /
+// "array out of bounds" failures, but structured instead of a formatted
+// string so a caller (or a trailing "?" on the path, see parsePath) can tell
+// "missing" apart from a genuine type error.
+type PathError struct {
+	Path    string
+	Missing bool
+}
+
+func (e *PathError) Error() string {
+	if e.Missing {
+		return fmt.Sprintf("path %q: not found", e.Path)
+	}
+	return fmt.Sprintf("path %q: cannot traverse", e.Path)
+}
+
+type segKind int
+
+const (
+	segField segKind = iota
+	segIndex
+	segSplat
+	segSlice
+)
+
+type pathSeg struct {
+	kind       segKind
+	field      string
+	index      int
+	start, end int // segSlice only; end == -1 means "to the end"
+}
+
+// parsePath parses a jq-flavored path expression: ".field", "[index]", "[*]"
+// (splat — apply the remainder of the path to every element and flatten one
+// level into the result), and "[start:end]" slicing, chained as in
+// "a.b[0].c". A trailing "?" (e.g. "a.b?") marks the whole query optional:
+// a missing key anywhere along it returns nil instead of a *PathError.
+func parsePath(path string) (segs []pathSeg, optional bool, err error) {
+	if strings.HasSuffix(path, "?") {
+		optional = true
+		path = path[:len(path)-1]
+	}
+
+	i := 0
+	n := len(path)
+	field := func(start int) (string, int) {
+		j := start
+		for j < n && path[j] != '.' && path[j] != '[' {
+			j++
+		}
+		return path[start:j], j
+	}
+
+	// A path may start with a bare field name with no leading ".".
+	if i < n && path[i] != '.' && path[i] != '[' {
+		f, j := field(i)
+		segs = append(segs, pathSeg{kind: segField, field: f})
+		i = j
+	}
+
+	for i < n {
+		switch path[i] {
+		case '.':
+			i++
+			f, j := field(i)
+			if f == "" {
+				return nil, false, fmt.Errorf("path %q: expected a field name after '.' at position %d", path, i)
+			}
+			segs = append(segs, pathSeg{kind: segField, field: f})
+			i = j
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, false, fmt.Errorf("path %q: unterminated '[' at position %d", path, i)
+			}
+			inner := path[i+1 : i+end]
+			i += end + 1
+			switch {
+			case inner == "*":
+				segs = append(segs, pathSeg{kind: segSplat})
+			case strings.Contains(inner, ":"):
+				parts := strings.SplitN(inner, ":", 2)
+				start, end := 0, -1
+				if parts[0] != "" {
+					start, err = strconv.Atoi(parts[0])
+					if err != nil {
+						return nil, false, fmt.Errorf("path %q: invalid slice start %q", path, parts[0])
+					}
+				}
+				if parts[1] != "" {
+					end, err = strconv.Atoi(parts[1])
+					if err != nil {
+						return nil, false, fmt.Errorf("path %q: invalid slice end %q", path, parts[1])
+					}
+				}
+				segs = append(segs, pathSeg{kind: segSlice, start: start, end: end})
+			default:
+				idx, convErr := strconv.Atoi(inner)
+				if convErr != nil {
+					return nil, false, fmt.Errorf("path %q: invalid index %q", path, inner)
+				}
+				segs = append(segs, pathSeg{kind: segIndex, index: idx})
+			}
+		default:
+			return nil, false, fmt.Errorf("path %q: unexpected character %q at position %d", path, path[i], i)
+		}
+	}
+
+	return segs, optional, nil
+}
+
+// pathFromValue builds the pathSeg list directly from a mixed array path
+// (["a", "b", 0, "c"]) instead of parsing a dotted string — get/set/has/del
+// accept either form.
+func pathFromValue(v interface{}) ([]pathSeg, bool, error) {
+	if s, ok := v.(string); ok {
+		return parsePathValue(s)
+	}
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("path must be a dotted string or an array of field/index segments")
+	}
+	segs := make([]pathSeg, 0, len(arr))
+	for _, e := range arr {
+		switch ev := e.(type) {
+		case string:
+			if ev == "*" {
+				segs = append(segs, pathSeg{kind: segSplat})
+			} else {
+				segs = append(segs, pathSeg{kind: segField, field: ev})
+			}
+		default:
+			segs = append(segs, pathSeg{kind: segIndex, index: int(toFloat(e))})
+		}
+	}
+	return segs, false, nil
+}
+
+func parsePathValue(s string) ([]pathSeg, bool, error) {
+	return parsePath(s)
+}
+
+// segString renders seg the way it would appear in the dotted-path syntax,
+// for building up PathError.Path as evalPath descends.
+func (seg pathSeg) segString() string {
+	switch seg.kind {
+	case segField:
+		return "." + seg.field
+	case segIndex:
+		return fmt.Sprintf("[%d]", seg.index)
+	case segSplat:
+		return "[*]"
+	case segSlice:
+		return fmt.Sprintf("[%d:%d]", seg.start, seg.end)
+	default:
+		return ""
+	}
+}
+
+// evalPath walks value through segs, returning *PathError (never a plain
+// error) on a missing key/out-of-range index so callers can honor a
+// trailing "?"'s "return nil instead" contract.
+func evalPath(value interface{}, segs []pathSeg, soFar string) (interface{}, *PathError) {
+	if len(segs) == 0 {
+		return value, nil
+	}
+	seg := segs[0]
+	rest := segs[1:]
+	here := soFar + seg.segString()
+
+	switch seg.kind {
+	case segField:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, &PathError{Path: here, Missing: true}
+		}
+		v, exists := m[seg.field]
+		if !exists {
+			return nil, &PathError{Path: here, Missing: true}
+		}
+		return evalPath(v, rest, here)
+
+	case segIndex:
+		arr, ok := value.([]interface{})
+		if !ok || seg.index < 0 || seg.index >= len(arr) {
+			return nil, &PathError{Path: here, Missing: true}
+		}
+		return evalPath(arr[seg.index], rest, here)
+
+	case segSlice:
+		arr, ok := value.([]interface{})
+		if !ok {
+			return nil, &PathError{Path: here, Missing: true}
+		}
+		start, end := seg.start, seg.end
+		if end < 0 || end > len(arr) {
+			end = len(arr)
+		}
+		if start < 0 {
+			start = 0
+		}
+		if start > end {
+			start = end
+		}
+		return evalPath(append([]interface{}(nil), arr[start:end]...), rest, here)
+
+	case segSplat:
+		var elems []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			elems = v
+		case map[string]interface{}:
+			for _, e := range v {
+				elems = append(elems, e)
+			}
+		default:
+			return nil, &PathError{Path: here, Missing: true}
+		}
+
+		result := make([]interface{}, 0, len(elems))
+		for _, e := range elems {
+			v, perr := evalPath(e, rest, here)
+			if perr != nil {
+				return nil, perr
+			}
+			if nested, ok := v.([]interface{}); ok {
+				result = append(result, nested...)
+			} else {
+				result = append(result, v)
+			}
+		}
+		return result, nil
+
+	default:
+		return nil, &PathError{Path: here, Missing: true}
+	}
+}
+
+// assignPath returns a copy of value with segs set to newValue, copy-on-write
+// along the traversed path only (everything else is shared with value).
+// Splat and slice segments aren't assignable, matching jq's restriction that
+// only concrete field/index paths can appear on the left of an update.
+func assignPath(value interface{}, segs []pathSeg, newValue interface{}) (interface{}, error) {
+	if len(segs) == 0 {
+		return newValue, nil
+	}
+	seg := segs[0]
+	rest := segs[1:]
+
+	switch seg.kind {
+	case segField:
+		m, _ := value.(map[string]interface{})
+		copyM := make(map[string]interface{}, len(m)+1)
+		for k, v := range m {
+			copyM[k] = v
+		}
+		child, err := assignPath(copyM[seg.field], rest, newValue)
+		if err != nil {
+			return nil, err
+		}
+		copyM[seg.field] = child
+		return copyM, nil
+
+	case segIndex:
+		arr, _ := value.([]interface{})
+		copyArr := append([]interface{}(nil), arr...)
+		for len(copyArr) <= seg.index {
+			copyArr = append(copyArr, nil)
+		}
+		child, err := assignPath(copyArr[seg.index], rest, newValue)
+		if err != nil {
+			return nil, err
+		}
+		copyArr[seg.index] = child
+		return copyArr, nil
+
+	default:
+		return nil, fmt.Errorf("set: splat/slice segments are not assignable")
+	}
+}
+
+// deletePath returns a copy of value with the final segment of segs removed.
+func deletePath(value interface{}, segs []pathSeg) (interface{}, error) {
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("del: path must not be empty")
+	}
+	if len(segs) == 1 {
+		seg := segs[0]
+		switch seg.kind {
+		case segField:
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("del: cannot delete a field from %T", value)
+			}
+			copyM := make(map[string]interface{}, len(m))
+			for k, v := range m {
+				if k != seg.field {
+					copyM[k] = v
+				}
+			}
+			return copyM, nil
+		case segIndex:
+			arr, ok := value.([]interface{})
+			if !ok || seg.index < 0 || seg.index >= len(arr) {
+				return nil, fmt.Errorf("del: index %d out of range", seg.index)
+			}
+			copyArr := make([]interface{}, 0, len(arr)-1)
+			copyArr = append(copyArr, arr[:seg.index]...)
+			copyArr = append(copyArr, arr[seg.index+1:]...)
+			return copyArr, nil
+		default:
+			return nil, fmt.Errorf("del: splat/slice segments are not deletable")
+		}
+	}
+
+	seg := segs[0]
+	rest := segs[1:]
+	switch seg.kind {
+	case segField:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("del: cannot traverse field %q on %T", seg.field, value)
+		}
+		child, exists := m[seg.field]
+		if !exists {
+			return nil, &PathError{Path: seg.segString(), Missing: true}
+		}
+		newChild, err := deletePath(child, rest)
+		if err != nil {
+			return nil, err
+		}
+		copyM := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			copyM[k] = v
+		}
+		copyM[seg.field] = newChild
+		return copyM, nil
+	case segIndex:
+		arr, ok := value.([]interface{})
+		if !ok || seg.index < 0 || seg.index >= len(arr) {
+			return nil, &PathError{Path: seg.segString(), Missing: true}
+		}
+		newChild, err := deletePath(arr[seg.index], rest)
+		if err != nil {
+			return nil, err
+		}
+		copyArr := append([]interface{}(nil), arr...)
+		copyArr[seg.index] = newChild
+		return copyArr, nil
+	default:
+		return nil, fmt.Errorf("del: splat/slice segments are not traversable for delete")
+	}
+}
+
+// collectPaths appends the path (as a []interface{} of string/int segments)
+// of every leaf (a non-array, non-object value) reachable from value to out.
+func collectPaths(value interface{}, prefix []interface{}, out *[]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			*out = append(*out, append([]interface{}(nil), prefix...))
+			return
+		}
+		for k, child := range v {
+			collectPaths(child, append(prefix, k), out)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			*out = append(*out, append([]interface{}(nil), prefix...))
+			return
+		}
+		for idx, child := range v {
+			collectPaths(child, append(prefix, float64(idx)), out)
+		}
+	default:
+		*out = append(*out, append([]interface{}(nil), prefix...))
+	}
+}
+
+func builtinGet(args ...interface{}) (interface{}, error) {
+	segs, optional, err := pathFromValue(args[1])
+	if err != nil {
+		return nil, err
+	}
+	v, perr := evalPath(args[0], segs, "")
+	if perr != nil {
+		if optional {
+			return nil, nil
+		}
+		return nil, perr
+	}
+	return v, nil
+}
+
+func builtinSet(args ...interface{}) (interface{}, error) {
+	segs, _, err := pathFromValue(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return assignPath(args[0], segs, args[2])
+}
+
+func builtinHas(args ...interface{}) (interface{}, error) {
+	segs, _, err := pathFromValue(args[1])
+	if err != nil {
+		return nil, err
+	}
+	_, perr := evalPath(args[0], segs, "")
+	return perr == nil, nil
+}
+
+func builtinDel(args ...interface{}) (interface{}, error) {
+	segs, _, err := pathFromValue(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return deletePath(args[0], segs)
+}
+
+func builtinPaths(args ...interface{}) (interface{}, error) {
+	var out []interface{}
+	collectPaths(args[0], nil, &out)
+	return out, nil
+}
+
+// builtinSelectCtx implements select(arr, predicate) -> [x for x in arr if
+// predicate(x)] — jq's select() under the array-filter name this codebase
+// already uses map/filter/reduce/sort for (see BuiltinsCtx).
+func builtinSelectCtx(interp *Interpreter, args ...interface{}) interface{} {
+	return builtinFilterCtx(interp, args...)
+}
+
+// builtinWalkCtx implements walk(value, fn): recursively rebuilds value
+// bottom-up (every map/array's children are walked first), calling fn on
+// each result — maps and arrays as their rebuilt selves, everything else as
+// the leaf value itself.
+func builtinWalkCtx(interp *Interpreter, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		if len(args) == 1 {
+			return args[0]
+		}
+		return nil
+	}
+	fn, ok := args[1].(*Callable)
+	if !ok {
+		return args[0]
+	}
+	return walkValue(args[0], fn)
+}
+
+func walkValue(value interface{}, fn *Callable) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, child := range v {
+			out[k] = walkValue(child, fn)
+		}
+		return fn.Call(out)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = walkValue(child, fn)
+		}
+		return fn.Call(out)
+	default:
+		return fn.Call(value)
+	}
+}
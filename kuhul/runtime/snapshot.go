@@ -0,0 +1,292 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ollama/ollama/kuhul/scxq2"
+)
+
+// snapshotVersion is bumped whenever runtimeSnapshot's shape changes, so
+// Restore can reject (or one day migrate) a snapshot from an older build
+// instead of silently misreading it.
+const snapshotVersion = "RTS-v1"
+
+// runtimeSnapshot is the canonical, serializable form of a RuntimeState.
+// Backend/KernelCache (compiled kernels, device handles) and Events (a live
+// channel-backed pump) aren't data and are intentionally left out; a
+// restored RuntimeState gets fresh zero values for those.
+type runtimeSnapshot struct {
+	Version   string                     `json:"version"`
+	Variables *environmentSnapshot       `json:"variables"`
+	Handlers  map[string]handlerSnapshot `json:"handlers"`
+	Vectors   map[string]vectorSnapshot  `json:"vectors"`
+	Manifest  map[string]interface{}     `json:"manifest"`
+	Booted    bool                       `json:"booted"`
+	BootSteps []string                   `json:"boot_steps"`
+	Errors    []string                   `json:"errors"`
+	MX2DB     mx2dbSnapshot              `json:"mx2db"`
+	ASXRAM    map[string]interface{}     `json:"asx_ram"`
+
+	// ChunkStore/ChunkManifests preserve scxq2's content-defined-chunking
+	// state (see RuntimeState.ChunkStore) so a restored RuntimeState can
+	// still dedup against and diff chunks fingerprinted before the
+	// snapshot was taken.
+	ChunkStore     map[string][]byte   `json:"chunk_store,omitempty"`
+	ChunkManifests map[string][]string `json:"chunk_manifests,omitempty"`
+}
+
+// environmentSnapshot preserves one Environment.store plus, recursively, the
+// parent chain it was enclosed by.
+type environmentSnapshot struct {
+	Vars   map[string]interface{} `json:"vars"`
+	Parent *environmentSnapshot   `json:"parent,omitempty"`
+}
+
+// handlerSnapshot drops Handler.Execute: a closure over the Interpreter that
+// registered it, which has no serializable form. Restoring a handler's Name
+// and Params is enough to reconstruct the registration once the owning
+// program is reloaded; Dispatch on a bare-restored handler will fail until
+// then, same as any handler that was never registered.
+type handlerSnapshot struct {
+	Name   string                 `json:"name"`
+	Params map[string]interface{} `json:"params"`
+}
+
+type vectorSnapshot struct {
+	Name   string                 `json:"name"`
+	Params map[string]interface{} `json:"params"`
+}
+
+type mx2dbSnapshot struct {
+	NGrams          map[string]int         `json:"n_grams"`
+	Supagrams       map[string]interface{} `json:"supagrams"`
+	RLHFTraces      map[string]interface{} `json:"rlhf_traces"`
+	AgentState      map[string]interface{} `json:"agent_state"`
+	TrainingHistory map[string]interface{} `json:"training_history"`
+	Tapes           map[string]interface{} `json:"tapes"`
+	FeedEntries     map[string]interface{} `json:"feed_entries"`
+	Jobs            map[string]interface{} `json:"jobs"`
+
+	// Kneser-Ney's auxiliary indexes over NGrams (see MX2DB). The
+	// continuation/context sets are sorted slices rather than sets so
+	// Snapshot's byte-identical-for-equivalent-data property holds: map
+	// iteration order isn't deterministic, but a sorted slice is.
+	PrefixTotals        map[string]int      `json:"prefix_totals"`
+	PrefixContinuations map[string][]string `json:"prefix_continuations"`
+	TokenContexts       map[string][]string `json:"token_contexts"`
+}
+
+// Snapshot serializes rs to a canonical byte form and returns an SCXQ2-v1
+// fingerprint over it. Map keys are sorted (encoding/json already does this
+// for map[string]T) and the Environment.parent chain is walked explicitly,
+// so two RuntimeStates with equivalent data produce byte-identical output
+// and identical fingerprints — check either with VerifyFingerprint.
+func (rs *RuntimeState) Snapshot() ([]byte, string, error) {
+	snap := rs.toSnapshot()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return nil, "", fmt.Errorf("snapshot: %w", err)
+	}
+
+	return data, scxq2.Fingerprint(snap), nil
+}
+
+// Restore rebuilds a RuntimeState from data produced by Snapshot. Backend is
+// reset to cpuBackend and Events to a fresh EventPump, since neither was
+// part of the snapshot.
+func Restore(data []byte) (*RuntimeState, error) {
+	var snap runtimeSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("restore: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("restore: unsupported snapshot version %q (want %q)", snap.Version, snapshotVersion)
+	}
+
+	rs := NewRuntimeState()
+	if env := buildEnvironment(snap.Variables); env != nil {
+		rs.Variables = env
+	}
+	rs.Manifest = copyInterfaceMap(snap.Manifest)
+	rs.Booted = snap.Booted
+	rs.BootSteps = append([]string(nil), snap.BootSteps...)
+	rs.Errors = append([]string(nil), snap.Errors...)
+	rs.ASXRAM = copyInterfaceMap(snap.ASXRAM)
+	rs.ChunkStore = copyByteSliceMap(snap.ChunkStore)
+	rs.ChunkManifests = copyStringSliceMap(snap.ChunkManifests)
+
+	for name, h := range snap.Handlers {
+		rs.Handlers[name] = &Handler{Name: h.Name, Params: copyInterfaceMap(h.Params)}
+	}
+	for name, v := range snap.Vectors {
+		rs.Vectors[name] = &Vector{Name: v.Name, Params: copyInterfaceMap(v.Params)}
+	}
+
+	rs.MX2DB = &MX2DB{
+		NGrams:              copyIntMap(snap.MX2DB.NGrams),
+		Supagrams:           copyInterfaceMap(snap.MX2DB.Supagrams),
+		RLHFTraces:          copyInterfaceMap(snap.MX2DB.RLHFTraces),
+		AgentState:          copyInterfaceMap(snap.MX2DB.AgentState),
+		TrainingHistory:     copyInterfaceMap(snap.MX2DB.TrainingHistory),
+		Tapes:               copyInterfaceMap(snap.MX2DB.Tapes),
+		FeedEntries:         copyInterfaceMap(snap.MX2DB.FeedEntries),
+		Jobs:                copyInterfaceMap(snap.MX2DB.Jobs),
+		PrefixTotals:        copyIntMap(snap.MX2DB.PrefixTotals),
+		PrefixContinuations: fromSortedSetMap(snap.MX2DB.PrefixContinuations),
+		TokenContexts:       fromSortedSetMap(snap.MX2DB.TokenContexts),
+	}
+
+	return rs, nil
+}
+
+// toSnapshot copies rs's data fields under their respective locks.
+func (rs *RuntimeState) toSnapshot() runtimeSnapshot {
+	rs.mu.RLock()
+	handlers := make(map[string]handlerSnapshot, len(rs.Handlers))
+	for name, h := range rs.Handlers {
+		handlers[name] = handlerSnapshot{Name: h.Name, Params: copyInterfaceMap(h.Params)}
+	}
+	vectors := make(map[string]vectorSnapshot, len(rs.Vectors))
+	for name, v := range rs.Vectors {
+		vectors[name] = vectorSnapshot{Name: v.Name, Params: copyInterfaceMap(v.Params)}
+	}
+	manifest := copyInterfaceMap(rs.Manifest)
+	bootSteps := append([]string(nil), rs.BootSteps...)
+	errs := append([]string(nil), rs.Errors...)
+	asxram := copyInterfaceMap(rs.ASXRAM)
+	booted := rs.Booted
+	rs.mu.RUnlock()
+
+	rs.chunkMu.RLock()
+	chunkStore := copyByteSliceMap(rs.ChunkStore)
+	chunkManifests := copyStringSliceMap(rs.ChunkManifests)
+	rs.chunkMu.RUnlock()
+
+	return runtimeSnapshot{
+		Version:        snapshotVersion,
+		Variables:      snapshotEnvironment(rs.Variables),
+		Handlers:       handlers,
+		Vectors:        vectors,
+		Manifest:       manifest,
+		Booted:         booted,
+		BootSteps:      bootSteps,
+		Errors:         errs,
+		MX2DB:          rs.MX2DB.toSnapshot(),
+		ASXRAM:         asxram,
+		ChunkStore:     chunkStore,
+		ChunkManifests: chunkManifests,
+	}
+}
+
+// toSnapshot copies db's maps under its lock.
+func (db *MX2DB) toSnapshot() mx2dbSnapshot {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return mx2dbSnapshot{
+		NGrams:              copyIntMap(db.NGrams),
+		Supagrams:           copyInterfaceMap(db.Supagrams),
+		RLHFTraces:          copyInterfaceMap(db.RLHFTraces),
+		AgentState:          copyInterfaceMap(db.AgentState),
+		TrainingHistory:     copyInterfaceMap(db.TrainingHistory),
+		Tapes:               copyInterfaceMap(db.Tapes),
+		FeedEntries:         copyInterfaceMap(db.FeedEntries),
+		Jobs:                copyInterfaceMap(db.Jobs),
+		PrefixTotals:        copyIntMap(db.PrefixTotals),
+		PrefixContinuations: toSortedSetMap(db.PrefixContinuations),
+		TokenContexts:       toSortedSetMap(db.TokenContexts),
+	}
+}
+
+// snapshotEnvironment walks e's parent chain, capturing each scope's store.
+func snapshotEnvironment(e *Environment) *environmentSnapshot {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.RLock()
+	vars := copyInterfaceMap(e.store)
+	parent := e.parent
+	e.mu.RUnlock()
+
+	return &environmentSnapshot{Vars: vars, Parent: snapshotEnvironment(parent)}
+}
+
+// buildEnvironment reconstructs an Environment chain from a snapshot,
+// recursing through Parent first so each Environment's parent already
+// exists by the time it's referenced. A nil snap means "no further parent",
+// matching how snapshotEnvironment terminates the chain.
+func buildEnvironment(snap *environmentSnapshot) *Environment {
+	if snap == nil {
+		return nil
+	}
+	return &Environment{store: copyInterfaceMap(snap.Vars), parent: buildEnvironment(snap.Parent)}
+}
+
+func copyInterfaceMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func copyIntMap(m map[string]int) map[string]int {
+	out := make(map[string]int, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// copyByteSliceMap deep-copies a map[string][]byte, same intent as
+// copyInterfaceMap but for ChunkStore's raw chunk bytes.
+func copyByteSliceMap(m map[string][]byte) map[string][]byte {
+	out := make(map[string][]byte, len(m))
+	for k, v := range m {
+		cp := make([]byte, len(v))
+		copy(cp, v)
+		out[k] = cp
+	}
+	return out
+}
+
+// copyStringSliceMap deep-copies a map[string][]string, same intent as
+// copyInterfaceMap but for ChunkManifests' ordered hash lists.
+func copyStringSliceMap(m map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(m))
+	for k, v := range m {
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+// toSortedSetMap flattens a map of sets into a map of sorted slices, so its
+// JSON encoding doesn't depend on map iteration order.
+func toSortedSetMap(m map[string]map[string]struct{}) map[string][]string {
+	out := make(map[string][]string, len(m))
+	for k, set := range m {
+		words := make([]string, 0, len(set))
+		for w := range set {
+			words = append(words, w)
+		}
+		sort.Strings(words)
+		out[k] = words
+	}
+	return out
+}
+
+// fromSortedSetMap is toSortedSetMap's inverse, used when restoring.
+func fromSortedSetMap(m map[string][]string) map[string]map[string]struct{} {
+	out := make(map[string]map[string]struct{}, len(m))
+	for k, words := range m {
+		set := make(map[string]struct{}, len(words))
+		for _, w := range words {
+			set[w] = struct{}{}
+		}
+		out[k] = set
+	}
+	return out
+}
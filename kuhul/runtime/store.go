@@ -0,0 +1,245 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Store is the pluggable MX2DB persistence layer behind ASX-RAM, tapes,
+// n-grams, and RLHF cases. inMemoryStore (the default) keeps everything in
+// RuntimeState's own maps, same as before this became pluggable; a Redis
+// driver (build tag "redis") persists the same data in Redis so it survives
+// a restart. selectStore chooses between them from the manifest.
+type Store interface {
+	GetASXRAM(key string) (interface{}, bool)
+	SetASXRAM(key string, value interface{})
+
+	GetTape(id string) (interface{}, bool)
+	PutTape(id string, tape interface{})
+
+	// IncrNGram adds delta to gram's count and returns the new total.
+	IncrNGram(gram string, delta int) int
+	NGramCount() int
+	// ScanNGramsWithPrefix returns every gram (and its count) starting with
+	// prefix; an empty prefix matches everything.
+	ScanNGramsWithPrefix(prefix string) map[string]int
+
+	// The remaining methods back handleGramSuggest's interpolated
+	// Kneser-Ney smoothing: GramCount is c(prefix,w), PrefixTotal is
+	// c(prefix,*), PrefixContinuationCount is N1+(prefix,*),
+	// PrefixContinuations lists that same continuation set so a caller can
+	// rank it, TokenContextCount is N1+(*,w), and DistinctGramTypes is
+	// N1+(*,*), the unigram-backoff normaliser.
+	GramCount(prefix, word string) int
+	PrefixTotal(prefix string) int
+	PrefixContinuationCount(prefix string) int
+	PrefixContinuations(prefix string) []string
+	TokenContextCount(word string) int
+	DistinctGramTypes() int
+
+	ListRLHF() []interface{}
+	GetRLHF(caseID string) (interface{}, bool)
+	PutRLHF(caseID string, item interface{})
+
+	// PutJob/GetJob back DispatchAsync and the "job.get" basher command:
+	// PutJob records a JobResult (as interface{}, same convention as
+	// PutRLHF) under the job id Bus.Publish returned, GetJob retrieves it.
+	PutJob(jobID string, result interface{})
+	GetJob(jobID string) (interface{}, bool)
+}
+
+// selectStore picks a Store per the manifest's "store" key ("redis", "wal",
+// or anything else/absent for the in-memory default). Mirrors
+// selectBackend: a driver that holds a live connection or open file
+// (redisStore, walStore) is cached on rs.KernelCache so reloading the same
+// manifest doesn't reconnect/reopen.
+func selectStore(rs *RuntimeState, manifest map[string]interface{}) Store {
+	driver, _ := manifest["store"].(string)
+	if cached, ok := rs.KernelCache["store:"+driver]; ok {
+		return cached.(Store)
+	}
+
+	result := Store(newInMemoryStore(rs))
+	switch driver {
+	case "redis":
+		addr, _ := manifest["store_addr"].(string)
+		if s := newRedisStore(addr); s != nil {
+			result = s
+		} else {
+			rs.AddError(`store "redis" requested but this binary was not built with the redis tag; falling back to memory`)
+		}
+	case "wal":
+		path, _ := manifest["store_path"].(string)
+		syncMode, _ := manifest["store_sync"].(string)
+		if path == "" {
+			path = "asxram.wal"
+		}
+		if syncMode == "" {
+			syncMode = "always"
+		}
+		if s, err := newWalStore(rs, path, syncMode); err == nil {
+			result = s
+		} else {
+			rs.AddError(fmt.Sprintf(`store "wal" requested but could not open %q: %v; falling back to memory`, path, err))
+		}
+	}
+
+	rs.KernelCache["store:"+driver] = result
+	return result
+}
+
+// inMemoryStore is the default Store: it reads/writes the RuntimeState it
+// wraps directly, so its behaviour is exactly what RuntimeState did before
+// Store existed.
+type inMemoryStore struct {
+	rs *RuntimeState
+}
+
+func newInMemoryStore(rs *RuntimeState) *inMemoryStore {
+	return &inMemoryStore{rs: rs}
+}
+
+func (s *inMemoryStore) GetASXRAM(key string) (interface{}, bool) {
+	s.rs.mu.RLock()
+	defer s.rs.mu.RUnlock()
+	v, ok := s.rs.ASXRAM[key]
+	return v, ok
+}
+
+func (s *inMemoryStore) SetASXRAM(key string, value interface{}) {
+	s.rs.mu.Lock()
+	s.rs.ASXRAM[key] = value
+	s.rs.mu.Unlock()
+}
+
+func (s *inMemoryStore) GetTape(id string) (interface{}, bool) {
+	s.rs.MX2DB.mu.RLock()
+	defer s.rs.MX2DB.mu.RUnlock()
+	tape, ok := s.rs.MX2DB.Tapes[id]
+	return tape, ok
+}
+
+func (s *inMemoryStore) PutTape(id string, tape interface{}) {
+	s.rs.MX2DB.mu.Lock()
+	s.rs.MX2DB.Tapes[id] = tape
+	s.rs.MX2DB.mu.Unlock()
+}
+
+func (s *inMemoryStore) IncrNGram(gram string, delta int) int {
+	s.rs.MX2DB.mu.Lock()
+	defer s.rs.MX2DB.mu.Unlock()
+	s.rs.MX2DB.NGrams[gram] += delta
+
+	context, word := "", gram
+	if idx := strings.LastIndex(gram, "|"); idx >= 0 {
+		context, word = gram[:idx], gram[idx+1:]
+	}
+	s.rs.MX2DB.PrefixTotals[context] += delta
+	if s.rs.MX2DB.PrefixContinuations[context] == nil {
+		s.rs.MX2DB.PrefixContinuations[context] = make(map[string]struct{})
+	}
+	s.rs.MX2DB.PrefixContinuations[context][word] = struct{}{}
+	if s.rs.MX2DB.TokenContexts[word] == nil {
+		s.rs.MX2DB.TokenContexts[word] = make(map[string]struct{})
+	}
+	s.rs.MX2DB.TokenContexts[word][context] = struct{}{}
+
+	return s.rs.MX2DB.NGrams[gram]
+}
+
+func (s *inMemoryStore) NGramCount() int {
+	s.rs.MX2DB.mu.RLock()
+	defer s.rs.MX2DB.mu.RUnlock()
+	return len(s.rs.MX2DB.NGrams)
+}
+
+func (s *inMemoryStore) ScanNGramsWithPrefix(prefix string) map[string]int {
+	s.rs.MX2DB.mu.RLock()
+	defer s.rs.MX2DB.mu.RUnlock()
+	matches := make(map[string]int)
+	for gram, count := range s.rs.MX2DB.NGrams {
+		if strings.HasPrefix(gram, prefix) {
+			matches[gram] = count
+		}
+	}
+	return matches
+}
+
+func (s *inMemoryStore) GramCount(prefix, word string) int {
+	key := word
+	if prefix != "" {
+		key = prefix + "|" + word
+	}
+	s.rs.MX2DB.mu.RLock()
+	defer s.rs.MX2DB.mu.RUnlock()
+	return s.rs.MX2DB.NGrams[key]
+}
+
+func (s *inMemoryStore) PrefixTotal(prefix string) int {
+	s.rs.MX2DB.mu.RLock()
+	defer s.rs.MX2DB.mu.RUnlock()
+	return s.rs.MX2DB.PrefixTotals[prefix]
+}
+
+func (s *inMemoryStore) PrefixContinuationCount(prefix string) int {
+	s.rs.MX2DB.mu.RLock()
+	defer s.rs.MX2DB.mu.RUnlock()
+	return len(s.rs.MX2DB.PrefixContinuations[prefix])
+}
+
+func (s *inMemoryStore) PrefixContinuations(prefix string) []string {
+	s.rs.MX2DB.mu.RLock()
+	defer s.rs.MX2DB.mu.RUnlock()
+	set := s.rs.MX2DB.PrefixContinuations[prefix]
+	words := make([]string, 0, len(set))
+	for w := range set {
+		words = append(words, w)
+	}
+	return words
+}
+
+func (s *inMemoryStore) TokenContextCount(word string) int {
+	s.rs.MX2DB.mu.RLock()
+	defer s.rs.MX2DB.mu.RUnlock()
+	return len(s.rs.MX2DB.TokenContexts[word])
+}
+
+func (s *inMemoryStore) DistinctGramTypes() int {
+	return s.NGramCount()
+}
+
+func (s *inMemoryStore) ListRLHF() []interface{} {
+	s.rs.MX2DB.mu.RLock()
+	defer s.rs.MX2DB.mu.RUnlock()
+	items := make([]interface{}, 0, len(s.rs.MX2DB.RLHFTraces))
+	for _, v := range s.rs.MX2DB.RLHFTraces {
+		items = append(items, v)
+	}
+	return items
+}
+
+func (s *inMemoryStore) GetRLHF(caseID string) (interface{}, bool) {
+	s.rs.MX2DB.mu.RLock()
+	defer s.rs.MX2DB.mu.RUnlock()
+	item, ok := s.rs.MX2DB.RLHFTraces[caseID]
+	return item, ok
+}
+
+func (s *inMemoryStore) PutRLHF(caseID string, item interface{}) {
+	s.rs.MX2DB.mu.Lock()
+	s.rs.MX2DB.RLHFTraces[caseID] = item
+	s.rs.MX2DB.mu.Unlock()
+}
+
+func (s *inMemoryStore) PutJob(jobID string, result interface{}) {
+	s.rs.MX2DB.mu.Lock()
+	s.rs.MX2DB.Jobs[jobID] = result
+	s.rs.MX2DB.mu.Unlock()
+}
+
+func (s *inMemoryStore) GetJob(jobID string) (interface{}, bool) {
+	s.rs.MX2DB.mu.RLock()
+	defer s.rs.MX2DB.mu.RUnlock()
+	result, ok := s.rs.MX2DB.Jobs[jobID]
+	return result, ok
+}
@@ -0,0 +1,205 @@
+//go:build redis
+
+package runtime
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis key layout: one hash per concern, so a single kuhul RuntimeState
+// shares one Redis instance cleanly alongside other tenants' keys.
+// prefixContinuationsKey/tokenContextsKey are per-prefix/per-token sets
+// (SADD/SMEMBERS/SCARD), since each needs its own membership, not a single
+// shared hash.
+const (
+	redisASXRAMKey       = "kuhul:asxram"
+	redisTapesKey        = "kuhul:tapes"
+	redisNGramsKey       = "kuhul:ngrams"
+	redisRLHFKey         = "kuhul:rlhf"
+	redisPrefixTotalsKey = "kuhul:prefix_totals"
+	redisJobsKey         = "kuhul:jobs"
+)
+
+func prefixContinuationsKey(prefix string) string { return "kuhul:prefix_continuations:" + prefix }
+func tokenContextsKey(word string) string         { return "kuhul:token_contexts:" + word }
+
+// redisStore is a Store backed by a Redis instance: ASXRAM, tapes, and RLHF
+// cases are JSON blobs in their own hash, n-gram counts live in a hash of
+// plain integers so HINCRBY can update them atomically, and
+// ScanNGramsWithPrefix walks that hash with HSCAN MATCH instead of the
+// in-memory driver's O(N) walk.
+type redisStore struct {
+	client *redis.Client
+}
+
+func newRedisStore(addr string) Store {
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	return &redisStore{client: redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+func (s *redisStore) GetASXRAM(key string) (interface{}, bool) {
+	return s.hgetJSON(redisASXRAMKey, key)
+}
+
+func (s *redisStore) SetASXRAM(key string, value interface{}) {
+	s.hsetJSON(redisASXRAMKey, key, value)
+}
+
+func (s *redisStore) GetTape(id string) (interface{}, bool) {
+	return s.hgetJSON(redisTapesKey, id)
+}
+
+func (s *redisStore) PutTape(id string, tape interface{}) {
+	s.hsetJSON(redisTapesKey, id, tape)
+}
+
+func (s *redisStore) IncrNGram(gram string, delta int) int {
+	ctx := gocontext.Background()
+	total, err := s.client.HIncrBy(ctx, redisNGramsKey, gram, int64(delta)).Result()
+	if err != nil {
+		return 0
+	}
+
+	context, word := "", gram
+	if idx := strings.LastIndex(gram, "|"); idx >= 0 {
+		context, word = gram[:idx], gram[idx+1:]
+	}
+	s.client.HIncrBy(ctx, redisPrefixTotalsKey, context, int64(delta))
+	s.client.SAdd(ctx, prefixContinuationsKey(context), word)
+	s.client.SAdd(ctx, tokenContextsKey(word), context)
+
+	return int(total)
+}
+
+func (s *redisStore) NGramCount() int {
+	n, err := s.client.HLen(gocontext.Background(), redisNGramsKey).Result()
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+func (s *redisStore) GramCount(prefix, word string) int {
+	key := word
+	if prefix != "" {
+		key = prefix + "|" + word
+	}
+	v, err := s.client.HGet(gocontext.Background(), redisNGramsKey, key).Result()
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func (s *redisStore) PrefixTotal(prefix string) int {
+	v, err := s.client.HGet(gocontext.Background(), redisPrefixTotalsKey, prefix).Result()
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func (s *redisStore) PrefixContinuationCount(prefix string) int {
+	n, err := s.client.SCard(gocontext.Background(), prefixContinuationsKey(prefix)).Result()
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+func (s *redisStore) PrefixContinuations(prefix string) []string {
+	words, err := s.client.SMembers(gocontext.Background(), prefixContinuationsKey(prefix)).Result()
+	if err != nil {
+		return nil
+	}
+	return words
+}
+
+func (s *redisStore) TokenContextCount(word string) int {
+	n, err := s.client.SCard(gocontext.Background(), tokenContextsKey(word)).Result()
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+func (s *redisStore) DistinctGramTypes() int {
+	return s.NGramCount()
+}
+
+func (s *redisStore) ScanNGramsWithPrefix(prefix string) map[string]int {
+	ctx := gocontext.Background()
+	matches := make(map[string]int)
+
+	iter := s.client.HScan(ctx, redisNGramsKey, 0, prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		gram := iter.Val()
+		if !iter.Next(ctx) {
+			break
+		}
+		count, _ := strconv.Atoi(iter.Val())
+		matches[gram] = count
+	}
+	return matches
+}
+
+func (s *redisStore) ListRLHF() []interface{} {
+	ctx := gocontext.Background()
+	all, err := s.client.HGetAll(ctx, redisRLHFKey).Result()
+	if err != nil {
+		return nil
+	}
+	items := make([]interface{}, 0, len(all))
+	for _, blob := range all {
+		var item interface{}
+		if json.Unmarshal([]byte(blob), &item) == nil {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (s *redisStore) GetRLHF(caseID string) (interface{}, bool) {
+	return s.hgetJSON(redisRLHFKey, caseID)
+}
+
+func (s *redisStore) PutRLHF(caseID string, item interface{}) {
+	s.hsetJSON(redisRLHFKey, caseID, item)
+}
+
+func (s *redisStore) PutJob(jobID string, result interface{}) {
+	s.hsetJSON(redisJobsKey, jobID, result)
+}
+
+func (s *redisStore) GetJob(jobID string) (interface{}, bool) {
+	return s.hgetJSON(redisJobsKey, jobID)
+}
+
+func (s *redisStore) hgetJSON(hash, field string) (interface{}, bool) {
+	blob, err := s.client.HGet(gocontext.Background(), hash, field).Result()
+	if err != nil {
+		return nil, false
+	}
+	var value interface{}
+	if json.Unmarshal([]byte(blob), &value) != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func (s *redisStore) hsetJSON(hash, field string, value interface{}) {
+	blob, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	s.client.HSet(gocontext.Background(), hash, field, blob)
+}
@@ -0,0 +1,8 @@
+//go:build !redis
+
+package runtime
+
+// newRedisStore is only implemented when this binary is built with `-tags
+// redis` (requires github.com/redis/go-redis/v9); selectStore falls back to
+// inMemoryStore when it returns nil.
+func newRedisStore(addr string) Store { return nil }
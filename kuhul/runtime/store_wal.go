@@ -0,0 +1,312 @@
+package runtime
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+)
+
+// walSnapshotSuffix names the snapshot sidecar file next to a walStore's log
+// (e.g. "/var/lib/kuhul/asxram.log" snapshots to
+// "/var/lib/kuhul/asxram.log.snapshot"). A snapshot is the whole ASXRAM map
+// as JSON; the log holds only the records written since the last one.
+const walSnapshotSuffix = ".snapshot"
+
+// walSnapshotThreshold is how many bytes of log walStore.Append tolerates
+// before triggering a Snapshot on its own, so a long-running process
+// doesn't replay an ever-growing log on every restart.
+const walSnapshotThreshold = 4 << 20 // 4MiB
+
+// walRecordKind distinguishes what a walRecord does when replayed.
+type walRecordKind string
+
+const (
+	walRecordSet walRecordKind = "set" // one key set (or deleted, Value nil)
+	walRecordTxn walRecordKind = "txn" // multiple keys set/deleted atomically
+)
+
+// walEntry is one key/value pair inside a walRecord; a walRecordSet record
+// holds exactly one, a walRecordTxn record holds every key in the
+// transaction.
+type walEntry struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// walRecord is one entry in the write-ahead log, framed on disk as a
+// 4-byte big-endian length, a 4-byte big-endian CRC32C checksum of the
+// payload, and the JSON-encoded payload itself.
+type walRecord struct {
+	Kind    walRecordKind `json:"kind"`
+	Entries []walEntry    `json:"entries"`
+}
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// walStore wraps inMemoryStore to make ASXRAM durable: every SetASXRAM
+// appends a length-prefixed, checksummed record to a write-ahead log before
+// applying it to the in-memory map, and the log (preceded by the most
+// recent snapshot, if any) is replayed to rebuild the map on startup.
+// Everything else (tapes, n-grams, RLHF, jobs) is unaffected and delegates
+// to the embedded inMemoryStore exactly as before.
+type walStore struct {
+	*inMemoryStore
+
+	mu       sync.Mutex
+	path     string
+	log      *os.File
+	logSize  int64
+	syncMode string // "always", "interval", or "never"; append fsyncs each record unless "never"
+}
+
+// newWalStore opens (creating if necessary) the WAL at path, replays its
+// snapshot and log into rs.ASXRAM, and returns a Store ready to accept
+// further writes. syncMode is read once here and doesn't change for the
+// life of the store.
+func newWalStore(rs *RuntimeState, path string, syncMode string) (*walStore, error) {
+	ws := &walStore{
+		inMemoryStore: newInMemoryStore(rs),
+		path:          path,
+		syncMode:      syncMode,
+	}
+
+	if err := ws.loadSnapshot(); err != nil {
+		return nil, fmt.Errorf("wal: load snapshot: %w", err)
+	}
+	if err := ws.replayLog(); err != nil {
+		return nil, fmt.Errorf("wal: replay log: %w", err)
+	}
+
+	log, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("wal: open log: %w", err)
+	}
+	info, err := log.Stat()
+	if err != nil {
+		log.Close()
+		return nil, fmt.Errorf("wal: stat log: %w", err)
+	}
+	ws.log = log
+	ws.logSize = info.Size()
+
+	return ws, nil
+}
+
+// loadSnapshot applies path+walSnapshotSuffix's JSON map into rs.ASXRAM, if
+// that file exists. A missing snapshot (the common case on first boot)
+// isn't an error.
+func (s *walStore) loadSnapshot() error {
+	data, err := os.ReadFile(s.path + walSnapshotSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	for k, v := range snapshot {
+		s.inMemoryStore.SetASXRAM(k, v)
+	}
+	return nil
+}
+
+// replayLog applies every record in path onto rs.ASXRAM. A record
+// truncated by a crash mid-write (a short length/checksum header, or a
+// payload shorter than its header promised), or one whose checksum or JSON
+// doesn't match, stops the replay at that point rather than erroring out,
+// the same bounded-EOF-recovery convention the lexer uses for a source file
+// cut off mid-token. Everything after the last complete record is then
+// truncated away, so the log's own os.O_APPEND writer starts appending
+// complete records again from there.
+func (s *walStore) replayLog() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var good int64
+	for {
+		rec, ok := readWalRecord(r)
+		if !ok {
+			break
+		}
+		s.apply(rec)
+		pos, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		good = pos - int64(r.Buffered())
+	}
+	if info, err := f.Stat(); err == nil && info.Size() > good {
+		return os.Truncate(s.path, good)
+	}
+	return nil
+}
+
+// readWalRecord reads one framed record from r. ok is false both at a
+// clean EOF and at a truncated trailing record; the caller can't tell
+// those apart and doesn't need to, since both mean "nothing more to
+// replay".
+func readWalRecord(r *bufio.Reader) (walRecord, bool) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return walRecord{}, false
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	wantCRC := binary.BigEndian.Uint32(header[4:8])
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return walRecord{}, false
+	}
+	if crc32.Checksum(payload, crc32cTable) != wantCRC {
+		return walRecord{}, false
+	}
+
+	var rec walRecord
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return walRecord{}, false
+	}
+	return rec, true
+}
+
+// apply replays rec's entries onto the in-memory map directly, bypassing
+// the log (used during startup replay, and internally by append once the
+// record is durable).
+func (s *walStore) apply(rec walRecord) {
+	for _, e := range rec.Entries {
+		s.inMemoryStore.SetASXRAM(e.Key, e.Value)
+	}
+}
+
+// append frames rec, writes it to the log, fsyncs per syncMode, and
+// applies it to the in-memory map. Snapshots (and truncates) the log first
+// if it has grown past walSnapshotThreshold.
+func (s *walStore) append(rec walRecord) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.Checksum(payload, crc32cTable))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.log.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := s.log.Write(payload); err != nil {
+		return err
+	}
+	s.logSize += int64(len(header) + len(payload))
+
+	if s.syncMode != "never" {
+		if err := s.log.Sync(); err != nil {
+			return err
+		}
+	}
+
+	s.apply(rec)
+
+	if s.logSize >= walSnapshotThreshold {
+		if err := s.snapshotLocked(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetASXRAM implements Store: it durably logs the write before applying it,
+// overriding inMemoryStore's in-place-only version. A write whose log
+// append fails is dropped rather than applied in memory only, so ASXRAM
+// never silently drifts ahead of what a restart would recover.
+func (s *walStore) SetASXRAM(key string, value interface{}) {
+	s.append(walRecord{Kind: walRecordSet, Entries: []walEntry{{Key: key, Value: value}}})
+}
+
+// Txn applies updates atomically: every key lands in a single WAL record,
+// so a crash between two keys of what the caller considers one update
+// can't replay only half of it. Not part of the Store interface — callers
+// reach it via a type assertion (see asx_ram.txn in packs.ASXRAMPack),
+// mirroring how RunConsumer type-asserts *amqpBus for AMQP-only behaviour.
+func (s *walStore) Txn(updates map[string]interface{}) error {
+	entries := make([]walEntry, 0, len(updates))
+	for k, v := range updates {
+		entries = append(entries, walEntry{Key: k, Value: v})
+	}
+	return s.append(walRecord{Kind: walRecordTxn, Entries: entries})
+}
+
+// Snapshot serializes the current ASXRAM map to path+walSnapshotSuffix and
+// truncates the log, so the next restart replays a snapshot plus a short
+// tail instead of the log from the beginning of time. Safe to call
+// concurrently with writes.
+func (s *walStore) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked()
+}
+
+// snapshotLocked is Snapshot's body; callers must hold s.mu.
+func (s *walStore) snapshotLocked() error {
+	s.rs.mu.RLock()
+	snapshot := make(map[string]interface{}, len(s.rs.ASXRAM))
+	for k, v := range s.rs.ASXRAM {
+		snapshot[k] = v
+	}
+	s.rs.mu.RUnlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + walSnapshotSuffix + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if f, err := os.Open(tmp); err == nil {
+		f.Sync()
+		f.Close()
+	}
+	if err := os.Rename(tmp, s.path+walSnapshotSuffix); err != nil {
+		return err
+	}
+
+	if err := s.log.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.log.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.logSize = 0
+	return nil
+}
+
+// Close syncs and closes the underlying log file.
+func (s *walStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return nil
+	}
+	s.log.Sync()
+	return s.log.Close()
+}
@@ -0,0 +1,60 @@
+package runtime
+
+import "github.com/ollama/ollama/kuhul/kerror"
+
+// StreamChunk is one piece of a DispatchStream result: a partial Result
+// (for a handler streaming incremental output), or the final one with Done
+// set. Err set and Done set together means the stream failed and should be
+// drained as the terminal frame, not retried.
+type StreamChunk struct {
+	Result interface{}
+	Err    error
+	Done   bool
+}
+
+// StreamingHandler is the streaming counterpart of Handler.Execute: a
+// handler that can hand back partial results incrementally (e.g. lam_o.infer
+// emitting tokens as they're generated) instead of blocking until the whole
+// result is ready. A Handler that has one is registered the normal way via
+// RegisterHandler and then upgraded by setting its Stream field (see
+// packs.StreamingPack and Interpreter.registerHandlers); DispatchStream uses
+// it when present and falls back to running Execute as a single-chunk
+// stream otherwise, so a caller never needs to know which handlers support
+// native streaming.
+type StreamingHandler func(ctx *Context) (<-chan StreamChunk, error)
+
+// DispatchStream is DispatchAsync's synchronous-delivery sibling: instead of
+// running in-process and blocking for one result (Dispatch) or enqueueing
+// onto the Bus (DispatchAsync), it returns a channel the caller drains for
+// partial results as the handler produces them. A handler registered
+// without a Stream runs through Execute on a goroutine and delivers its one
+// result as the channel's only (Done) chunk.
+func (i *Interpreter) DispatchStream(handlerName string, ctx *Context) (<-chan StreamChunk, error) {
+	handler, ok := i.state.GetHandler(handlerName)
+	if !ok {
+		return nil, kerror.New(kerror.HandlerNotFound, handlerName, 0, 0, 0, "handler not found: %s", handlerName)
+	}
+
+	if ctx == nil {
+		ctx = &Context{
+			Handler: handlerName,
+			Params:  make(map[string]interface{}),
+			Body:    make(map[string]interface{}),
+			Query:   make(map[string]interface{}),
+			Runtime: i.state,
+			Env:     i.state.Variables,
+		}
+	}
+
+	if handler.Stream != nil {
+		return handler.Stream(ctx)
+	}
+
+	ch := make(chan StreamChunk, 1)
+	go func() {
+		defer close(ch)
+		result, err := handler.Execute(ctx)
+		ch <- StreamChunk{Result: result, Err: err, Done: true}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,14 @@
+package runtime
+
+import "github.com/ollama/ollama/kuhul/runtime/tensor"
+
+// Tensor, NewTensor, and tensorFromNested are thin aliases over
+// kuhul/runtime/tensor so the matrix builtins and BuiltinBackend kernels
+// elsewhere in this package can keep referring to "Tensor" without importing
+// it at every call site. The type itself — Shape/Strides/Data []float32 —
+// lives in that package; see its doc comment for why.
+type Tensor = tensor.Tensor
+
+func NewTensor(shape ...int) Tensor { return tensor.New(shape...) }
+
+func tensorFromNested(v interface{}) (Tensor, bool) { return tensor.FromNested(v) }
@@ -0,0 +1,153 @@
+// Package tensor implements the flat, contiguous numeric buffer the runtime
+// package's matrix builtins (matrix_multiply, transpose, softmax, dot,
+// reshape, layer_norm, ...) convert to and from at their boundary, so a
+// BuiltinBackend kernel can run over a []float32 slice directly instead of
+// walking boxed []interface{} rows. Data is float32 rather than float64 so a
+// Llama-scale matmul doesn't double its memory footprint for precision the
+// checkpoints driving it don't carry in the first place.
+package tensor
+
+import "fmt"
+
+// Tensor is a flat, contiguous numeric buffer with an explicit shape and
+// row-major Strides. A tensor()-constructed *Tensor passed to a builtin is
+// used as is (see FromNested's passthrough case). reshape always returns a
+// *Tensor, and the axis form of softmax, add, mul, gelu and silu return one
+// when their first argument was one; matmul, matrix_multiply, transpose,
+// one-argument softmax, layer_norm, rms_norm and to_array return nested
+// arrays.
+type Tensor struct {
+	Shape   []int
+	Strides []int
+	Data    []float32
+}
+
+// New allocates a zeroed Tensor of the given shape (1D or 2D) with row-major
+// strides.
+func New(shape ...int) Tensor {
+	size := 1
+	for _, d := range shape {
+		size *= d
+	}
+	return Tensor{Shape: append([]int(nil), shape...), Strides: rowMajorStrides(shape), Data: make([]float32, size)}
+}
+
+// rowMajorStrides computes the stride (in elements) for each dimension of
+// shape, so index (i0, i1, ..., in) maps to offset sum(ik * Strides[k]).
+func rowMajorStrides(shape []int) []int {
+	strides := make([]int, len(shape))
+	acc := 1
+	for k := len(shape) - 1; k >= 0; k-- {
+		strides[k] = acc
+		acc *= shape[k]
+	}
+	return strides
+}
+
+// Reshape returns a Tensor viewing the same Data under a new shape, erroring
+// if the element count doesn't match — the same restriction NumPy's
+// reshape() applies.
+func (t Tensor) Reshape(shape ...int) (Tensor, error) {
+	size := 1
+	for _, d := range shape {
+		size *= d
+	}
+	if size != len(t.Data) {
+		return Tensor{}, fmt.Errorf("reshape: cannot reshape tensor of %d elements into shape %v", len(t.Data), shape)
+	}
+	return Tensor{Shape: append([]int(nil), shape...), Strides: rowMajorStrides(shape), Data: t.Data}, nil
+}
+
+// At returns the element at (row, col) of a 2D Tensor.
+func (t Tensor) At(row, col int) float32 {
+	return t.Data[row*t.Shape[1]+col]
+}
+
+// FromNested converts a K'UHUL array or matrix ([]interface{} or
+// [][]interface{}, boxed as nested []interface{}) into a Tensor, or passes a
+// *Tensor argument through unchanged. ok is false if v isn't a numeric array,
+// a rectangular matrix, or a *Tensor.
+func FromNested(v interface{}) (t Tensor, ok bool) {
+	if tp, isTensor := v.(*Tensor); isTensor {
+		return *tp, true
+	}
+
+	rows, isSlice := v.([]interface{})
+	if !isSlice || len(rows) == 0 {
+		return Tensor{}, false
+	}
+
+	firstRow, isMatrix := rows[0].([]interface{})
+	if !isMatrix {
+		t = New(len(rows))
+		for i, e := range rows {
+			t.Data[i] = toFloat32(e)
+		}
+		return t, true
+	}
+
+	cols := len(firstRow)
+	t = New(len(rows), cols)
+	for i, r := range rows {
+		row, ok := r.([]interface{})
+		if !ok || len(row) != cols {
+			return Tensor{}, false
+		}
+		for j, e := range row {
+			t.Data[i*cols+j] = toFloat32(e)
+		}
+	}
+	return t, true
+}
+
+// ToNested converts a 1D or 2D Tensor back into the []interface{} / nested
+// []interface{} representation the rest of the interpreter expects.
+func (t Tensor) ToNested() interface{} {
+	switch len(t.Shape) {
+	case 1:
+		out := make([]interface{}, t.Shape[0])
+		for i, v := range t.Data {
+			out[i] = float64(v)
+		}
+		return out
+	case 2:
+		rows, cols := t.Shape[0], t.Shape[1]
+		out := make([]interface{}, rows)
+		for i := 0; i < rows; i++ {
+			row := make([]interface{}, cols)
+			for j := 0; j < cols; j++ {
+				row[j] = float64(t.Data[i*cols+j])
+			}
+			out[i] = row
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// toFloat32 coerces a boxed K'UHUL scalar to float32, the same conversion
+// runtime's own toFloat applies for float64 — duplicated here (rather than
+// calling into package runtime, which imports this package) since it's a
+// handful of lines and this package has no other reason to depend on
+// runtime's internals.
+func toFloat32(v interface{}) float32 {
+	switch val := v.(type) {
+	case float64:
+		return float32(val)
+	case float32:
+		return val
+	case int:
+		return float32(val)
+	case int64:
+		return float32(val)
+	case int32:
+		return float32(val)
+	case string:
+		var f float32
+		fmt.Sscanf(val, "%f", &f)
+		return f
+	default:
+		return 0
+	}
+}
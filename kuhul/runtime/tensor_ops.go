@@ -0,0 +1,409 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+)
+
+// broadcastShapes computes the NumPy-style broadcast result shape for a and
+// b: shapes are right-aligned, and any dimension of size 1 (or missing,
+// treated as 1) may stretch to match the other operand's size at that
+// position. Mismatched dims that are both >1 are an error.
+func broadcastShapes(a, b []int) ([]int, error) {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	out := make([]int, n)
+	for i := 0; i < n; i++ {
+		da, db := 1, 1
+		if i < len(a) {
+			da = a[len(a)-1-i]
+		}
+		if i < len(b) {
+			db = b[len(b)-1-i]
+		}
+		switch {
+		case da == db:
+			out[n-1-i] = da
+		case da == 1:
+			out[n-1-i] = db
+		case db == 1:
+			out[n-1-i] = da
+		default:
+			return nil, fmt.Errorf("shape mismatch: %v vs %v", a, b)
+		}
+	}
+	return out, nil
+}
+
+// broadcastStrides returns the strides t should be read through to be viewed
+// at shape outShape (t's own shape right-aligned against it, broadcast dims
+// reading stride 0 so every output index along that axis hits the same
+// element).
+func broadcastStrides(t Tensor, outShape []int) []int {
+	strides := make([]int, len(outShape))
+	offset := len(outShape) - len(t.Shape)
+	for i := range outShape {
+		srcDim := i - offset
+		if srcDim < 0 || t.Shape[srcDim] == 1 {
+			strides[i] = 0
+		} else {
+			strides[i] = t.Strides[srcDim]
+		}
+	}
+	return strides
+}
+
+// elementwiseBroadcast applies op(a[i], b[i]) over every index of the
+// broadcast shape of a and b, following NumPy's broadcasting rule: shapes
+// are right-aligned and a size-1 dimension stretches to match the other
+// operand.
+func elementwiseBroadcast(a, b Tensor, op func(x, y float64) float64) (Tensor, error) {
+	shape, err := broadcastShapes(a.Shape, b.Shape)
+	if err != nil {
+		return Tensor{}, err
+	}
+	out := NewTensor(shape...)
+	aStrides := broadcastStrides(a, shape)
+	bStrides := broadcastStrides(b, shape)
+
+	idx := make([]int, len(shape))
+	for i := range out.Data {
+		aOff, bOff := 0, 0
+		for d, v := range idx {
+			aOff += v * aStrides[d]
+			bOff += v * bStrides[d]
+		}
+		out.Data[i] = float32(op(float64(a.Data[aOff]), float64(b.Data[bOff])))
+
+		for d := len(idx) - 1; d >= 0; d-- {
+			idx[d]++
+			if idx[d] < shape[d] {
+				break
+			}
+			idx[d] = 0
+		}
+	}
+	return out, nil
+}
+
+// softmaxAxis applies softmax along axis, subtracting the per-slice max
+// first for numerical stability, matching cpuBackend.Softmax's 1D behavior
+// generalized to an arbitrary axis of an N-D tensor.
+func softmaxAxis(t Tensor, axis int) (Tensor, error) {
+	if axis < 0 || axis >= len(t.Shape) {
+		return Tensor{}, fmt.Errorf("softmax: axis %d out of range for shape %v", axis, t.Shape)
+	}
+	out := NewTensor(t.Shape...)
+	axisLen := t.Shape[axis]
+	axisStride := t.Strides[axis]
+
+	outer := len(t.Data) / axisLen
+	idx := make([]int, len(t.Shape))
+	for n := 0; n < outer; n++ {
+		base := 0
+		for d, v := range idx {
+			if d == axis {
+				continue
+			}
+			base += v * t.Strides[d]
+		}
+
+		maxVal := t.Data[base]
+		for k := 1; k < axisLen; k++ {
+			if v := t.Data[base+k*axisStride]; v > maxVal {
+				maxVal = v
+			}
+		}
+		sum := 0.0
+		for k := 0; k < axisLen; k++ {
+			e := math.Exp(float64(t.Data[base+k*axisStride] - maxVal))
+			out.Data[base+k*axisStride] = float32(e)
+			sum += e
+		}
+		for k := 0; k < axisLen; k++ {
+			out.Data[base+k*axisStride] /= float32(sum)
+		}
+
+		for d := len(idx) - 1; d >= 0; d-- {
+			if d == axis {
+				continue
+			}
+			idx[d]++
+			if idx[d] < t.Shape[d] {
+				break
+			}
+			idx[d] = 0
+		}
+	}
+	return out, nil
+}
+
+// layerNorm normalizes the last axis of t to zero mean and unit variance,
+// then scales/shifts by gamma/beta (each length t.Shape[last]).
+func layerNorm(t, gamma, beta Tensor, eps float64) (Tensor, error) {
+	axis := len(t.Shape) - 1
+	if axis < 0 || gamma.Shape[0] != t.Shape[axis] || beta.Shape[0] != t.Shape[axis] {
+		return Tensor{}, fmt.Errorf("layer_norm: gamma/beta length must match last dim of shape %v", t.Shape)
+	}
+	out := NewTensor(t.Shape...)
+	n := t.Shape[axis]
+	rows := len(t.Data) / n
+	for r := 0; r < rows; r++ {
+		base := r * n
+		mean := 0.0
+		for k := 0; k < n; k++ {
+			mean += float64(t.Data[base+k])
+		}
+		mean /= float64(n)
+
+		variance := 0.0
+		for k := 0; k < n; k++ {
+			d := float64(t.Data[base+k]) - mean
+			variance += d * d
+		}
+		variance /= float64(n)
+
+		invStd := 1.0 / math.Sqrt(variance+eps)
+		for k := 0; k < n; k++ {
+			out.Data[base+k] = float32((float64(t.Data[base+k])-mean)*invStd*float64(gamma.Data[k]) + float64(beta.Data[k]))
+		}
+	}
+	return out, nil
+}
+
+// rmsNorm scales the last axis of t by 1/rms(t) * gamma, the normalization
+// LLaMA-family models use in place of layer_norm (no mean-centering, no
+// beta).
+func rmsNorm(t, gamma Tensor, eps float64) (Tensor, error) {
+	axis := len(t.Shape) - 1
+	if axis < 0 || gamma.Shape[0] != t.Shape[axis] {
+		return Tensor{}, fmt.Errorf("rms_norm: gamma length must match last dim of shape %v", t.Shape)
+	}
+	out := NewTensor(t.Shape...)
+	n := t.Shape[axis]
+	rows := len(t.Data) / n
+	for r := 0; r < rows; r++ {
+		base := r * n
+		sumSq := 0.0
+		for k := 0; k < n; k++ {
+			sumSq += float64(t.Data[base+k]) * float64(t.Data[base+k])
+		}
+		invRMS := 1.0 / math.Sqrt(sumSq/float64(n)+eps)
+		for k := 0; k < n; k++ {
+			out.Data[base+k] = float32(float64(t.Data[base+k]) * invRMS * float64(gamma.Data[k]))
+		}
+	}
+	return out, nil
+}
+
+// gelu is the tanh approximation of the Gaussian Error Linear Unit
+// activation, the same formula GPT-2-family models use.
+func gelu(x float64) float64 {
+	const c = 0.7978845608028654 // sqrt(2/pi)
+	return 0.5 * x * (1 + math.Tanh(c*(x+0.044715*x*x*x)))
+}
+
+// silu (a.k.a. swish) is x * sigmoid(x), the activation LLaMA-family models
+// use in their feed-forward blocks.
+func silu(x float64) float64 {
+	return x / (1 + math.Exp(-x))
+}
+
+func builtinReshape(args ...interface{}) (interface{}, error) {
+	t, ok := tensorFromNested(args[0])
+	if !ok {
+		return nil, fmt.Errorf("reshape: argument 1 is not a tensor or numeric array")
+	}
+	dims, ok := args[1].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("reshape: argument 2 must be a shape array")
+	}
+	shape := make([]int, len(dims))
+	for i, d := range dims {
+		shape[i] = int(toFloat(d))
+	}
+	out, err := t.Reshape(shape...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func builtinTensor(args ...interface{}) (interface{}, error) {
+	dims, ok := args[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("tensor: argument 1 must be a shape array")
+	}
+	shape := make([]int, len(dims))
+	for i, d := range dims {
+		shape[i] = int(toFloat(d))
+	}
+	out := NewTensor(shape...)
+
+	if len(args) < 2 {
+		return &out, nil
+	}
+	if data, ok := args[1].([]interface{}); ok {
+		flat := flattenNested(data)
+		for i := 0; i < len(out.Data) && i < len(flat); i++ {
+			out.Data[i] = flat[i]
+		}
+		return &out, nil
+	}
+
+	fill := float32(toFloat(args[1]))
+	for i := range out.Data {
+		out.Data[i] = fill
+	}
+	return &out, nil
+}
+
+// flattenNested walks a (possibly nested) []interface{} of numbers and
+// returns every scalar it contains in traversal order, for tensor()'s
+// data-literal form.
+func flattenNested(v []interface{}) []float32 {
+	out := make([]float32, 0, len(v))
+	for _, e := range v {
+		if nested, ok := e.([]interface{}); ok {
+			out = append(out, flattenNested(nested)...)
+		} else {
+			out = append(out, float32(toFloat(e)))
+		}
+	}
+	return out
+}
+
+func builtinToArray(args ...interface{}) (interface{}, error) {
+	t, ok := tensorFromNested(args[0])
+	if !ok {
+		return nil, fmt.Errorf("to_array: argument 1 is not a tensor or numeric array")
+	}
+	return t.ToNested(), nil
+}
+
+func builtinMatmul(args ...interface{}) (interface{}, error) {
+	a, aOk := tensorFromNested(args[0])
+	b, bOk := tensorFromNested(args[1])
+	if !aOk || !bOk {
+		return nil, fmt.Errorf("matmul: arguments must be tensors or numeric matrices")
+	}
+	out, err := cpuBackend{}.MatMul(a, b)
+	if err != nil {
+		return nil, err
+	}
+	return out.ToNested(), nil
+}
+
+func builtinSoftmaxAxis(args ...interface{}) (interface{}, error) {
+	t, ok := tensorFromNested(args[0])
+	if !ok {
+		return nil, fmt.Errorf("softmax: argument 1 is not a tensor or numeric array")
+	}
+	axis := len(t.Shape) - 1
+	if len(args) >= 2 {
+		axis = int(toFloat(args[1]))
+	}
+	out, err := softmaxAxis(t, axis)
+	if err != nil {
+		return nil, err
+	}
+	if _, wasTensor := args[0].(*Tensor); wasTensor {
+		return &out, nil
+	}
+	return out.ToNested(), nil
+}
+
+func builtinLayerNorm(args ...interface{}) (interface{}, error) {
+	t, ok := tensorFromNested(args[0])
+	if !ok {
+		return nil, fmt.Errorf("layer_norm: argument 1 is not a tensor or numeric array")
+	}
+	gamma, ok := tensorFromNested(args[1])
+	if !ok {
+		return nil, fmt.Errorf("layer_norm: argument 2 (gamma) is not a tensor or numeric array")
+	}
+	beta, ok := tensorFromNested(args[2])
+	if !ok {
+		return nil, fmt.Errorf("layer_norm: argument 3 (beta) is not a tensor or numeric array")
+	}
+	eps := 1e-5
+	if len(args) >= 4 {
+		eps = toFloat(args[3])
+	}
+	out, err := layerNorm(t, gamma, beta, eps)
+	if err != nil {
+		return nil, err
+	}
+	return out.ToNested(), nil
+}
+
+func builtinRMSNorm(args ...interface{}) (interface{}, error) {
+	t, ok := tensorFromNested(args[0])
+	if !ok {
+		return nil, fmt.Errorf("rms_norm: argument 1 is not a tensor or numeric array")
+	}
+	gamma, ok := tensorFromNested(args[1])
+	if !ok {
+		return nil, fmt.Errorf("rms_norm: argument 2 (gamma) is not a tensor or numeric array")
+	}
+	eps := 1e-5
+	if len(args) >= 3 {
+		eps = toFloat(args[2])
+	}
+	out, err := rmsNorm(t, gamma, eps)
+	if err != nil {
+		return nil, err
+	}
+	return out.ToNested(), nil
+}
+
+func builtinGelu(args ...interface{}) (interface{}, error) {
+	return mapElementwise(args[0], gelu)
+}
+
+func builtinSilu(args ...interface{}) (interface{}, error) {
+	return mapElementwise(args[0], silu)
+}
+
+// mapElementwise applies fn to every element of a tensor or numeric array,
+// returning the same shape/kind it was given.
+func mapElementwise(v interface{}, fn func(float64) float64) (interface{}, error) {
+	t, ok := tensorFromNested(v)
+	if !ok {
+		return nil, fmt.Errorf("expected a tensor or numeric array, got %T", v)
+	}
+	out := NewTensor(t.Shape...)
+	for i, x := range t.Data {
+		out.Data[i] = float32(fn(float64(x)))
+	}
+	if _, wasTensor := v.(*Tensor); wasTensor {
+		return &out, nil
+	}
+	return out.ToNested(), nil
+}
+
+func builtinTensorAdd(args ...interface{}) (interface{}, error) {
+	return tensorElementwiseBuiltin("add", args[0], args[1], func(x, y float64) float64 { return x + y })
+}
+
+func builtinTensorMul(args ...interface{}) (interface{}, error) {
+	return tensorElementwiseBuiltin("mul", args[0], args[1], func(x, y float64) float64 { return x * y })
+}
+
+func tensorElementwiseBuiltin(name string, av, bv interface{}, op func(x, y float64) float64) (interface{}, error) {
+	a, aOk := tensorFromNested(av)
+	b, bOk := tensorFromNested(bv)
+	if !aOk || !bOk {
+		return nil, fmt.Errorf("%s: arguments must be tensors or numeric arrays", name)
+	}
+	out, err := elementwiseBroadcast(a, b, op)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if _, wasTensor := av.(*Tensor); wasTensor {
+		return &out, nil
+	}
+	return out.ToNested(), nil
+}
@@ -0,0 +1,150 @@
+package scxq2
+
+// Content-defined chunking (CDC) splits a byte stream into variable-sized
+// chunks at boundaries determined by the content itself (a rolling Gear
+// hash), rather than at fixed offsets. Inserting or deleting a few bytes
+// only perturbs the chunks touching the edit; every other chunk's hash is
+// unchanged, which is what lets PutChunk dedup across two payloads that are
+// mostly the same and ChunkDiff report just the chunks that moved.
+
+const (
+	// cdcWindowSize is the nominal window of the Gear hash; Chunk does not
+	// read it. A byte's contribution shifts out of the 64-bit rolling value
+	// after 64 more bytes, and the low bits cdcMask tests are reached only
+	// by the last 13 bytes.
+	cdcWindowSize = 48
+	// cdcMinChunk and cdcMaxChunk bound every chunk's length regardless of
+	// what the rolling hash says, so a pathological input (e.g. all zero
+	// bytes) can't produce a degenerate 1-byte or unbounded chunk.
+	cdcMinChunk = 2 * 1024
+	cdcMaxChunk = 64 * 1024
+	// cdcMask is tested against the rolling hash's low bits each byte past
+	// cdcMinChunk; it's sized so a boundary is found on average every
+	// cdcAvgChunk bytes for well-mixed input.
+	cdcAvgChunk = 8 * 1024
+	cdcMask     = 1<<13 - 1
+)
+
+// gearTable maps each byte value to a fixed pseudo-random 64-bit constant,
+// the standard Gear/FastCDC construction: rolling = rolling<<1 + gearTable[b]
+// mixes in the last 64 bytes of history with O(1) work per byte. It's
+// generated once in init() with a fixed seed (splitmix64) rather than
+// listed literally, so the boundary distribution is reproducible across
+// builds without hand-maintaining 256 constants.
+var gearTable [256]uint64
+
+func init() {
+	seed := uint64(0x9e3779b97f4a7c15)
+	for i := range gearTable {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gearTable[i] = z ^ (z >> 31)
+	}
+}
+
+// ChunkInfo is one content-addressed piece of a payload split by Chunk:
+// Offset is its byte offset in the original data, Data is its raw bytes, and
+// Hash is their SCXQ2-formatted hash (hashBytes/formatHash, same as
+// Fingerprint's hash half), used as the key into RuntimeState.ChunkStore.
+type ChunkInfo struct {
+	Hash   string
+	Data   []byte
+	Offset int
+}
+
+// Chunk splits data into content-defined chunks using a Gear-hash rolling
+// boundary detector (the FastCDC approach): each chunk is at least
+// cdcMinChunk and at most cdcMaxChunk bytes, with the boundary between
+// chosen wherever the rolling hash's low cdcMask bits are all zero, which
+// happens on average every cdcAvgChunk bytes. Because the boundary test
+// depends only on the last 13 bytes before it, inserting or deleting bytes
+// elsewhere in data shifts at most the chunks adjacent to the edit.
+func Chunk(data []byte) []ChunkInfo {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var chunks []ChunkInfo
+	start := 0
+	var rolling uint64
+
+	for i := 0; i < len(data); i++ {
+		rolling = rolling<<1 + gearTable[data[i]]
+
+		size := i - start + 1
+		if size < cdcMinChunk {
+			continue
+		}
+		if size >= cdcMaxChunk || rolling&cdcMask == 0 {
+			chunks = append(chunks, newChunk(data[start:i+1], start))
+			start = i + 1
+			rolling = 0
+		}
+	}
+	if start < len(data) {
+		chunks = append(chunks, newChunk(data[start:], start))
+	}
+	return chunks
+}
+
+func newChunk(b []byte, offset int) ChunkInfo {
+	data := make([]byte, len(b))
+	copy(data, b)
+	return ChunkInfo{
+		Hash:   formatHash(hashBytes(data)),
+		Data:   data,
+		Offset: offset,
+	}
+}
+
+// ChunkMerkleRoot folds a payload's chunk hashes left-to-right into a single
+// SCXQ2 hash, the same pairwise concatenate-then-hash step Prove uses for
+// its sibling folding, so two payloads that share all
+// but a few chunks can be compared chunk-hash-list to chunk-hash-list
+// instead of re-hashing the whole payload.
+func ChunkMerkleRoot(chunks []ChunkInfo) string {
+	if len(chunks) == 0 {
+		return formatHash(hashBytes(nil))
+	}
+	current := parseHash(chunks[0].Hash)
+	for _, c := range chunks[1:] {
+		combined := make([]byte, 0, len(current)+len(parseHash(c.Hash)))
+		combined = append(combined, current...)
+		combined = append(combined, parseHash(c.Hash)...)
+		current = hashBytes(combined)
+	}
+	return formatHash(current)
+}
+
+// ChunkDiff returns the symmetric difference of two chunk hash lists a and
+// b — the hashes present in exactly one of them, a's in order first, then
+// b's — which is scxq2.diff's answer for "what changed between these two
+// fingerprinted payloads" once their chunk manifests are in hand.
+func ChunkDiff(a, b []string) []string {
+	inA := make(map[string]bool, len(a))
+	for _, h := range a {
+		inA[h] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, h := range b {
+		inB[h] = true
+	}
+
+	var diff []string
+	seen := make(map[string]bool)
+	for _, h := range a {
+		if !inB[h] && !seen[h] {
+			diff = append(diff, h)
+			seen[h] = true
+		}
+	}
+	for _, h := range b {
+		if !inA[h] && !seen[h] {
+			diff = append(diff, h)
+			seen[h] = true
+		}
+	}
+	return diff
+}
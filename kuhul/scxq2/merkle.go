@@ -0,0 +1,129 @@
+package scxq2
+
+import (
+	"sort"
+	"strconv"
+)
+
+// MerkleNode is one node of a Merkle tree built over a map/array/scalar
+// value by MerkleFingerprint. Path is its dotted location from the root
+// ("coolBlocks.foo.params.handler"; array elements use their index, e.g.
+// "tapes.0.name"), Hash is this node's own SCXQ2-formatted hash, and
+// Children holds its map/array children keyed by field name or index so a
+// caller can re-hash or diff just the subtree that changed instead of the
+// whole tree.
+type MerkleNode struct {
+	Path     string                 `json:"path"`
+	Hash     string                 `json:"hash"`
+	Children map[string]*MerkleNode `json:"children,omitempty"`
+}
+
+// MerkleFingerprint recursively hashes data into a MerkleNode tree: a map
+// hashes H(key) || H(child) for each key in sorted order then hashes the
+// concatenation, an array hashes its children's hashes left-to-right, and a
+// scalar hashes its canonical bytes via Fingerprint. The root's Hash is an
+// SCXQ2-formatted string like Fingerprint's; it equals Fingerprint(data) only
+// when data is a scalar, since a map or array root is the fold above. The
+// companion tree lets a caller
+// re-fingerprint or inspect a single subtree without recomputing the rest.
+func MerkleFingerprint(data interface{}) *MerkleNode {
+	return merkleNode("", data)
+}
+
+func merkleNode(path string, v interface{}) *MerkleNode {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		children := make(map[string]*MerkleNode, len(val))
+		var buf []byte
+		for _, key := range sortedKeys(val) {
+			child := merkleNode(joinPath(path, key), val[key])
+			children[key] = child
+			buf = append(buf, hashBytes([]byte(key))...)
+			buf = append(buf, parseHash(child.Hash)...)
+		}
+		return &MerkleNode{Path: path, Hash: formatHash(hashBytes(buf)), Children: children}
+	case []interface{}:
+		children := make(map[string]*MerkleNode, len(val))
+		var buf []byte
+		for i, elem := range val {
+			idx := strconv.Itoa(i)
+			child := merkleNode(joinPath(path, idx), elem)
+			children[idx] = child
+			buf = append(buf, parseHash(child.Hash)...)
+		}
+		return &MerkleNode{Path: path, Hash: formatHash(hashBytes(buf)), Children: children}
+	default:
+		return &MerkleNode{Path: path, Hash: Fingerprint(val)}
+	}
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// Diff compares two MerkleNode trees (e.g. before/after an interpreter
+// mutation to one CoolBlock handler) and returns the minimal set of paths
+// whose hash changed: it only descends into a subtree once the subtree's
+// own hash differs, and it stops descending once it reaches a leaf or a
+// node whose children no longer line up, reporting that node's own path
+// instead of padding the result with every path beneath it.
+func Diff(a, b *MerkleNode) []string {
+	if a == nil && b == nil {
+		return nil
+	}
+	if a == nil {
+		return []string{b.Path}
+	}
+	if b == nil {
+		return []string{a.Path}
+	}
+	if a.Hash == b.Hash {
+		return nil
+	}
+	if len(a.Children) == 0 && len(b.Children) == 0 {
+		return []string{a.Path}
+	}
+
+	seen := make(map[string]bool, len(a.Children)+len(b.Children))
+	for k := range a.Children {
+		seen[k] = true
+	}
+	for k := range b.Children {
+		seen[k] = true
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var changed []string
+	for _, k := range keys {
+		changed = append(changed, Diff(a.Children[k], b.Children[k])...)
+	}
+	if len(changed) == 0 {
+		// Hashes differed but no child accounts for it (e.g. a map became
+		// an array at this path) — report this node itself.
+		return []string{a.Path}
+	}
+	return changed
+}
+
+// Prove verifies a Merkle inclusion proof for a leaf without needing the
+// full tree: folding leafHash up through siblings (in leaf-to-root order,
+// as MerkleFingerprint would have combined them) should reproduce root.
+// path identifies which leaf the proof is about; Prove does not read it.
+func Prove(root string, path string, leafHash string, siblings [][]byte) bool {
+	_ = path
+	current := parseHash(leafHash)
+	for _, sibling := range siblings {
+		combined := make([]byte, 0, len(current)+len(sibling))
+		combined = append(combined, current...)
+		combined = append(combined, sibling...)
+		current = hashBytes(combined)
+	}
+	return formatHash(current) == root
+}
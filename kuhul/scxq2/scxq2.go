@@ -9,6 +9,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -19,10 +20,27 @@ const (
 
 // Fingerprint generates an SCXQ2 fingerprint for any data
 func Fingerprint(data interface{}) string {
-	payload := canonicalize(data)
-	hash := sha256.Sum256([]byte(payload))
-	hashHex := hex.EncodeToString(hash[:])
-	return SCXQ2Version + ":" + hashHex[:32]
+	return formatHash(hashBytes([]byte(canonicalize(data))))
+}
+
+// hashBytes is the raw (unformatted) half of Fingerprint: sha256, truncated
+// to 16 bytes (32 hex chars), the same truncation Fingerprint has always
+// used. MerkleFingerprint builds on this directly so it can fold a node's
+// children's hash bytes into its own hash before formatting.
+func hashBytes(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:16]
+}
+
+// formatHash renders raw hash bytes as a "SCXQ2-v1:<hex>" string.
+func formatHash(h []byte) string {
+	return SCXQ2Version + ":" + hex.EncodeToString(h)
+}
+
+// parseHash is formatHash's inverse.
+func parseHash(s string) []byte {
+	b, _ := hex.DecodeString(strings.TrimPrefix(s, SCXQ2Version+":"))
+	return b
 }
 
 // FingerprintExecution generates a fingerprint for an execution context
@@ -42,6 +60,14 @@ func Verify(data interface{}, fingerprint string) bool {
 	return computed == fingerprint
 }
 
+// CanonicalBytes exposes canonicalize's sorted-key, recursively-normalized
+// JSON encoding as bytes, for callers (like scxq2.fingerprint in packs,
+// which passes it to Chunk) that want Fingerprint's exact canonical form to
+// chunk over instead of hashing it whole.
+func CanonicalBytes(data interface{}) []byte {
+	return []byte(canonicalize(data))
+}
+
 // canonicalize converts data to a canonical JSON string
 func canonicalize(data interface{}) string {
 	// Convert to map for sorting
@@ -0,0 +1,126 @@
+// Package serve exposes a loaded K'UHUL interpreter's C@@L BLOCK handlers as
+// an HTTP microservice, so an embedder doesn't have to hand-roll the same
+// dispatch-over-JSON glue every gin server in this repo already has.
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ollama/ollama/kuhul"
+)
+
+// response is the envelope every endpoint replies with: Result on success,
+// Error on failure. It mirrors the Ok/Result/Error shape the gin handlers in
+// server/kuhul_handlers.go use, so clients that already speak one speak both.
+type response struct {
+	Ok     bool        `json:"ok"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, response{Ok: false, Error: err.Error()})
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	writeJSON(w, http.StatusOK, response{Ok: true, Result: result})
+}
+
+// Serve mounts interp's handlers on addr and blocks until the server returns
+// an error (as http.ListenAndServe does). Routes:
+//
+//	POST /dispatch/{name}  - calls the named C@@L BLOCK handler
+//	GET  /state            - interp.GetState().GetState()
+//	GET  /handlers         - registered handler names
+//	GET  /fingerprint      - SCXQ2 fingerprint of the loaded AST
+func Serve(interp *kuhul.Interpreter, addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dispatch/", dispatchHandler(interp))
+	mux.HandleFunc("/state", stateHandler(interp))
+	mux.HandleFunc("/handlers", handlersHandler(interp))
+	mux.HandleFunc("/fingerprint", fingerprintHandler(interp))
+
+	return http.ListenAndServe(addr, mux)
+}
+
+// dispatchHandler decodes the JSON body into Context.Body and the query
+// string into Context.Query, then calls interp.Dispatch so the handler sees
+// both the same way Interpreter.Dispatch's zero-ctx default would build them.
+func dispatchHandler(interp *kuhul.Interpreter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, errors.New("dispatch requires POST"))
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/dispatch/")
+		if name == "" {
+			writeError(w, http.StatusBadRequest, errors.New("missing handler name"))
+			return
+		}
+
+		body := make(map[string]interface{})
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		ctx := &kuhul.Context{
+			Handler: name,
+			Params:  make(map[string]interface{}),
+			Body:    body,
+			Query:   queryToMap(r.URL.Query()),
+			Runtime: interp.GetState(),
+			Env:     interp.GetState().Variables,
+		}
+
+		result, err := interp.Dispatch(name, ctx)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeResult(w, result)
+	}
+}
+
+func stateHandler(interp *kuhul.Interpreter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, interp.GetState().GetState())
+	}
+}
+
+func handlersHandler(interp *kuhul.Interpreter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, interp.GetState().GetState()["handlers"])
+	}
+}
+
+func fingerprintHandler(interp *kuhul.Interpreter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, kuhul.Fingerprint(interp.GetProgram()))
+	}
+}
+
+// queryToMap flattens a url.Values into a single-value-per-key map, which is
+// what Context.Query expects; repeated keys keep only the first value.
+func queryToMap(values url.Values) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		if len(v) > 0 {
+			out[k] = v[0]
+		}
+	}
+	return out
+}
@@ -0,0 +1,316 @@
+package packs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"strconv"
+	"strings"
+
+	"github.com/ollama/ollama/kuhul/scxq2"
+)
+
+// manifestFile is the name LoadDir looks for in each pack directory (or
+// alongside a single module file passed to LoadDynamic) to read the pack's
+// capabilities before loading it.
+const manifestFile = "pack.toml"
+
+// PackManifest is pack.toml's parsed form: the capabilities a dynamically
+// loaded pack advertises up front, so the registry (or an operator
+// auditing what's installed) doesn't have to load and Init the module just
+// to see its name, version, and handler list.
+type PackManifest struct {
+	Name      string
+	Version   string
+	Handlers  []string
+	Vectors   []string
+	Variables []string
+
+	// Requires lists dependencies on already-registered packs, each of the
+	// form "pack_name >= 1.2.0" (see CheckRequires for the operators
+	// supported).
+	Requires []string
+
+	// Signature, if set, is an "SCXQ2-v1:<hex>" fingerprint
+	// (scxq2.Fingerprint) over the module file's raw bytes; VerifyModule
+	// checks it before LoadDynamic is allowed to load that module.
+	Signature string
+}
+
+// LoadManifest reads and parses the pack.toml next to a pack module: dir
+// may be the module's own directory, or the module path itself (its
+// directory is used).
+func LoadManifest(dir string) (*PackManifest, error) {
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("packs: reading %s: %w", manifestFile, err)
+	}
+	return parsePackManifest(data)
+}
+
+// parsePackManifest parses the restrained subset of TOML pack.toml uses:
+// flat `key = "value"` and `key = ["a", "b"]` lines, with `#` comments and
+// blank lines ignored. A full TOML table/array-of-tables model isn't
+// needed here, so this avoids pulling in a TOML library for one flat file.
+func parsePackManifest(data []byte) (*PackManifest, error) {
+	m := &PackManifest{}
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("packs: %s line %d: expected key = value", manifestFile, n+1)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, "["):
+			items, err := parseTOMLStringArray(value)
+			if err != nil {
+				return nil, fmt.Errorf("packs: %s line %d: %w", manifestFile, n+1, err)
+			}
+			switch key {
+			case "handlers":
+				m.Handlers = items
+			case "vectors":
+				m.Vectors = items
+			case "variables":
+				m.Variables = items
+			case "requires":
+				m.Requires = items
+			default:
+				return nil, fmt.Errorf("packs: %s line %d: unknown array key %q", manifestFile, n+1, key)
+			}
+		default:
+			str, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("packs: %s line %d: expected a quoted string", manifestFile, n+1)
+			}
+			switch key {
+			case "name":
+				m.Name = str
+			case "version":
+				m.Version = str
+			case "signature":
+				m.Signature = str
+			default:
+				return nil, fmt.Errorf("packs: %s line %d: unknown key %q", manifestFile, n+1, key)
+			}
+		}
+	}
+	if m.Name == "" {
+		return nil, fmt.Errorf("packs: %s: missing required \"name\"", manifestFile)
+	}
+	return m, nil
+}
+
+// parseTOMLStringArray parses a single-line `["a", "b", "c"]` value.
+func parseTOMLStringArray(value string) ([]string, error) {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	var items []string
+	for _, raw := range strings.Split(value, ",") {
+		str, err := strconv.Unquote(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("expected a quoted string array element, got %q", raw)
+		}
+		items = append(items, str)
+	}
+	return items, nil
+}
+
+// VerifyModule checks module's bytes against manifest.Signature. A
+// manifest with no Signature set is treated as unsigned and always passes
+// — callers that require signing should reject that case themselves.
+// LoadDir has no such check and loads an unsigned module as is.
+func VerifyModule(manifest *PackManifest, module []byte) bool {
+	if manifest.Signature == "" {
+		return true
+	}
+	return scxq2.Verify(module, manifest.Signature)
+}
+
+// CheckRequires verifies every "pack_name op version" entry in
+// manifest.Requires against the already-registered pack of that name,
+// returning an error naming the first unmet or missing dependency.
+// Supported operators are ==, !=, >=, <=, >, and < on dotted integer
+// versions (e.g. "1.2.0"); a component missing from either side is treated
+// as 0, so "1.2" satisfies ">= 1.2.0".
+func CheckRequires(manifest *PackManifest) error {
+	for _, req := range manifest.Requires {
+		fields := strings.Fields(req)
+		if len(fields) != 3 {
+			return fmt.Errorf("packs: %s: malformed requires entry %q (want \"name op version\")", manifest.Name, req)
+		}
+		name, op, want := fields[0], fields[1], fields[2]
+
+		dep, ok := Get(name)
+		if !ok {
+			return fmt.Errorf("packs: %s requires %s %s %s, but %s is not registered", manifest.Name, name, op, want, name)
+		}
+		if !compareSemver(dep.Version(), op, want) {
+			return fmt.Errorf("packs: %s requires %s %s %s, but %s is at %s", manifest.Name, name, op, want, name, dep.Version())
+		}
+	}
+	return nil
+}
+
+// compareSemver reports whether have satisfies "op want" for dotted
+// integer versions.
+func compareSemver(have, op, want string) bool {
+	cmp := semverCompare(have, want)
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	default:
+		return false
+	}
+}
+
+// semverCompare compares two dotted-integer version strings component by
+// component, returning -1, 0, or 1; a missing trailing component (e.g.
+// comparing "1.2" against "1.2.0") counts as 0.
+func semverCompare(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var av, bv int
+		if i < len(as) {
+			av, _ = strconv.Atoi(strings.TrimSpace(as[i]))
+		}
+		if i < len(bs) {
+			bv, _ = strconv.Atoi(strings.TrimSpace(bs[i]))
+		}
+		if av != bv {
+			if av < bv {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// LoadDynamic loads a single external pack module, dispatching on path's
+// extension: a ".so" file is loaded as a Go plugin (plugin.Open) exposing a
+// `func Pack() packs.Pack` symbol named "Pack"; a ".wasm" file is loaded
+// into a wazero runtime and wrapped so its exported handler functions are
+// callable as ordinary HandlerFuncs (see loadWASMPack). Neither backend
+// registers the result in the global Registry — call Register once
+// LoadDynamic returns a Pack, after checking its manifest with LoadManifest
+// if one is expected.
+func LoadDynamic(path string) (Pack, error) {
+	switch filepath.Ext(path) {
+	case ".so":
+		return loadPluginPack(path)
+	case ".wasm":
+		return loadWASMPack(path)
+	default:
+		return nil, fmt.Errorf("packs: unsupported module extension %q", filepath.Ext(path))
+	}
+}
+
+// loadPluginPack opens path as a Go plugin and looks up its required "Pack"
+// symbol, which must have the type `func() Pack`.
+func loadPluginPack(path string) (Pack, error) {
+	plug, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("packs: opening plugin %s: %w", path, err)
+	}
+	sym, err := plug.Lookup("Pack")
+	if err != nil {
+		return nil, fmt.Errorf("packs: plugin %s: %w", path, err)
+	}
+	factory, ok := sym.(func() Pack)
+	if !ok {
+		return nil, fmt.Errorf("packs: plugin %s: Pack symbol has type %T, want func() packs.Pack", path, sym)
+	}
+	return factory(), nil
+}
+
+// LoadDir auto-discovers packs under dir: each immediate subdirectory
+// containing a pack.toml is loaded via LoadManifest, has its Requires
+// checked against the packs registered so far (so dependency order across
+// subdirectories matters — see os.ReadDir's lexical ordering), has its
+// module file (named after manifest.Name plus .so or .wasm) verified
+// against manifest.Signature when set, and is registered. It returns the
+// names of every pack it registered, in the order they were loaded.
+func LoadDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("packs: reading %s: %w", dir, err)
+	}
+
+	var loaded []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		packDir := filepath.Join(dir, entry.Name())
+		manifestPath := filepath.Join(packDir, manifestFile)
+		if _, err := os.Stat(manifestPath); err != nil {
+			continue // no pack.toml here, not a pack directory
+		}
+
+		manifest, err := LoadManifest(packDir)
+		if err != nil {
+			return loaded, err
+		}
+		if err := CheckRequires(manifest); err != nil {
+			return loaded, err
+		}
+
+		modulePath, err := findPackModule(packDir, manifest.Name)
+		if err != nil {
+			return loaded, err
+		}
+		moduleBytes, err := os.ReadFile(modulePath)
+		if err != nil {
+			return loaded, fmt.Errorf("packs: reading %s: %w", modulePath, err)
+		}
+		if !VerifyModule(manifest, moduleBytes) {
+			return loaded, fmt.Errorf("packs: %s: module %s failed signature verification", manifest.Name, modulePath)
+		}
+
+		pack, err := LoadDynamic(modulePath)
+		if err != nil {
+			return loaded, err
+		}
+		if err := Register(pack); err != nil {
+			return loaded, err
+		}
+		loaded = append(loaded, manifest.Name)
+	}
+	return loaded, nil
+}
+
+// findPackModule locates name's ".so" or ".wasm" module file in dir.
+func findPackModule(dir, name string) (string, error) {
+	for _, ext := range []string{".so", ".wasm"} {
+		candidate := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("packs: %s: no %s.so or %s.wasm in %s", name, name, name, dir)
+}
@@ -0,0 +1,136 @@
+//go:build wasmpacks
+
+package packs
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ollama/ollama/kuhul/runtime"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// wasmPack wraps a wazero-instantiated WASM module as a Pack: each export
+// named in manifest.Handlers becomes a HandlerFunc that marshals ctx.Body
+// to JSON, passes it to the export as a single (ptr, len) argument pair
+// into the module's own linear memory, and unmarshals whatever JSON bytes
+// the export wrote back out the same way. manifest is also the source of
+// Vectors()/Variables(), since wazero calls can't cheaply be threaded
+// through the VectorFunc(args ...interface{}) interface{} signature.
+type wasmPack struct {
+	manifest *PackManifest
+	rt       wazero.Runtime
+	module   api.Module
+}
+
+func (p *wasmPack) Name() string        { return p.manifest.Name }
+func (p *wasmPack) Version() string     { return p.manifest.Version }
+func (p *wasmPack) Description() string { return fmt.Sprintf("WASM pack: %s", p.manifest.Name) }
+
+func (p *wasmPack) Init(state *runtime.RuntimeState) error { return nil }
+
+func (p *wasmPack) Handlers() map[string]HandlerFunc {
+	handlers := make(map[string]HandlerFunc, len(p.manifest.Handlers))
+	for _, name := range p.manifest.Handlers {
+		name := name
+		handlers[name] = func(ctx *runtime.Context) (interface{}, error) {
+			return p.callExport(ctx, name)
+		}
+	}
+	return handlers
+}
+
+func (p *wasmPack) Vectors() map[string]VectorFunc {
+	vectors := make(map[string]VectorFunc, len(p.manifest.Vectors))
+	for _, name := range p.manifest.Vectors {
+		vectors[name] = func(args ...interface{}) interface{} { return nil }
+	}
+	return vectors
+}
+
+func (p *wasmPack) Variables() map[string]interface{} {
+	vars := make(map[string]interface{}, len(p.manifest.Variables))
+	for _, name := range p.manifest.Variables {
+		vars[name] = nil
+	}
+	return vars
+}
+
+// callExport marshals ctx.Body to JSON, writes it into the module's linear
+// memory, invokes export(ptr, len), and reads back the (ptr, len) pair it
+// returns as the JSON-encoded result.
+func (p *wasmPack) callExport(ctx *runtime.Context, export string) (interface{}, error) {
+	fn := p.module.ExportedFunction(export)
+	if fn == nil {
+		return nil, fmt.Errorf("packs: wasm module %s: no export %q", p.manifest.Name, export)
+	}
+
+	body, err := json.Marshal(ctx.Body)
+	if err != nil {
+		return nil, fmt.Errorf("packs: marshaling body for %s: %w", export, err)
+	}
+
+	alloc := p.module.ExportedFunction("alloc")
+	if alloc == nil {
+		return nil, fmt.Errorf("packs: wasm module %s: missing required \"alloc\" export", p.manifest.Name)
+	}
+	res, err := alloc.Call(gocontext.Background(), uint64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("packs: wasm alloc for %s: %w", export, err)
+	}
+	ptr := res[0]
+	if !p.module.Memory().Write(uint32(ptr), body) {
+		return nil, fmt.Errorf("packs: wasm module %s: memory write out of range", p.manifest.Name)
+	}
+
+	out, err := fn.Call(gocontext.Background(), ptr, uint64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("packs: calling %s: %w", export, err)
+	}
+	if len(out) != 2 {
+		return nil, fmt.Errorf("packs: wasm export %s: want (ptr, len) result, got %d values", export, len(out))
+	}
+	resultBytes, ok := p.module.Memory().Read(uint32(out[0]), uint32(out[1]))
+	if !ok {
+		return nil, fmt.Errorf("packs: wasm export %s: result memory out of range", export)
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(resultBytes, &result); err != nil {
+		return nil, fmt.Errorf("packs: unmarshaling result of %s: %w", export, err)
+	}
+	return result, nil
+}
+
+// loadWASMPack reads pack.toml next to path (the ".wasm" module) and
+// instantiates the module in a fresh wazero runtime, returning a Pack whose
+// handlers shim through callExport.
+func loadWASMPack(path string) (Pack, error) {
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	wasmBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("packs: reading %s: %w", path, err)
+	}
+
+	ctx := gocontext.Background()
+	rt := wazero.NewRuntime(ctx)
+	compiled, err := rt.CompileModule(ctx, wasmBytes)
+	if err != nil {
+		rt.Close(ctx)
+		return nil, fmt.Errorf("packs: compiling wasm module %s: %w", path, err)
+	}
+	module, err := rt.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+	if err != nil {
+		rt.Close(ctx)
+		return nil, fmt.Errorf("packs: instantiating wasm module %s: %w", path, err)
+	}
+
+	return &wasmPack{manifest: manifest, rt: rt, module: module}, nil
+}
@@ -0,0 +1,12 @@
+//go:build !wasmpacks
+
+package packs
+
+import "fmt"
+
+// loadWASMPack is only implemented when this binary is built with `-tags
+// wasmpacks` (requires github.com/tetratelabs/wazero); LoadDynamic returns
+// this stub's error for a ".wasm" path otherwise.
+func loadWASMPack(path string) (Pack, error) {
+	return nil, fmt.Errorf("packs: loading %s: built without -tags wasmpacks", path)
+}
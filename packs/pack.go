@@ -13,8 +13,19 @@
 package packs
 
 import (
+	"bufio"
+	"bytes"
+	gocontext "context"
+	"crypto/rand"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	goruntime "runtime"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ollama/ollama/api/xjson"
 	"github.com/ollama/ollama/kuhul/runtime"
@@ -48,6 +59,15 @@ type Pack interface {
 // HandlerFunc is a pack handler function
 type HandlerFunc func(ctx *runtime.Context) (interface{}, error)
 
+// StreamingPack is implemented by a Pack whose handlers can stream partial
+// results (runtime.StreamingHandler) alongside their synchronous
+// HandlerFunc form. InitAll sets Handler.Stream for any handler name
+// StreamHandlers returns, so Dispatch/DispatchAsync keep using the
+// synchronous handler while DispatchStream prefers the streaming one.
+type StreamingPack interface {
+	StreamHandlers() map[string]runtime.StreamingHandler
+}
+
 // VectorFunc is a pack vector function
 type VectorFunc func(args ...interface{}) interface{}
 
@@ -132,6 +152,18 @@ func (r *Registry) InitAll(state *runtime.RuntimeState) error {
 			})
 		}
 
+		// Wire up streaming handlers (if any) onto the Handler just
+		// registered above; a pack that doesn't implement StreamingPack
+		// leaves every Handler.Stream nil, so DispatchStream falls back to
+		// its Execute.
+		if sp, ok := pack.(StreamingPack); ok {
+			for handlerName, stream := range sp.StreamHandlers() {
+				if h, ok := state.GetHandler(handlerName); ok {
+					h.Stream = stream
+				}
+			}
+		}
+
 		// Register vectors
 		for vectorName, _ := range pack.Vectors() {
 			state.RegisterVector(vectorName, &runtime.Vector{
@@ -152,17 +184,108 @@ func (r *Registry) InitAll(state *runtime.RuntimeState) error {
 // PACK: lam.o (Llama/Ollama Runner)
 // ============================================
 
+// lamOStreamBuffer bounds how many runtime.StreamChunks a caller that's
+// fallen behind may have queued before handleInferStream's producer
+// goroutine blocks sending more — the channel itself is the backpressure,
+// so a slow subscribe can't make Ollama's response buffer unboundedly.
+const lamOStreamBuffer = 16
+
+// lamOHeartbeatInterval is how long handleInferStream waits without a token
+// from Ollama before emitting a heartbeat chunk, so an idle subscriber can
+// tell a slow-but-alive stream from one stuck behind a broken pipe.
+const lamOHeartbeatInterval = 15 * time.Second
+
+// LamOHeartbeat is a runtime.StreamChunk.Result value handleInferStream
+// sends every lamOHeartbeatInterval when no token has arrived, so a
+// subscriber can distinguish "still connected, nothing new yet" from an
+// actual empty-string delta.
+type LamOHeartbeat struct{}
+
 // LamOPack provides Llama/Ollama model inference
 type LamOPack struct {
-	state    *runtime.RuntimeState
-	endpoint string
+	state      *runtime.RuntimeState
+	endpoint   string
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	cancels       map[string]gocontext.CancelFunc
+	nextRequestID uint64
 }
 
 // NewLamOPack creates a new lam.o pack
 func NewLamOPack() *LamOPack {
 	return &LamOPack{
 		endpoint: "http://localhost:11434",
+		cancels:  make(map[string]gocontext.CancelFunc),
+	}
+}
+
+// client returns the *http.Client handleInfer/handleInferStream call
+// Ollama through, defaulting to http.DefaultClient when none was set.
+func (p *LamOPack) client() *http.Client {
+	if p.httpClient != nil {
+		return p.httpClient
+	}
+	return http.DefaultClient
+}
+
+// goctxFrom returns ctx.Ctx if the caller set one (see runtime.Context.Ctx),
+// otherwise context.Background, so an HTTP call to Ollama always has a
+// context to cancel even when Dispatch was invoked without a deadline.
+func goctxFrom(ctx *runtime.Context) gocontext.Context {
+	if ctx.Ctx != nil {
+		return ctx.Ctx
 	}
+	return gocontext.Background()
+}
+
+// trackCancel records cancel under requestID so a later lam_o.cancel call
+// naming that id can stop the in-flight stream.
+func (p *LamOPack) trackCancel(requestID string, cancel gocontext.CancelFunc) {
+	p.mu.Lock()
+	p.cancels[requestID] = cancel
+	p.mu.Unlock()
+}
+
+// untrackCancel removes requestID once its stream has ended on its own, so
+// a stale cancel func isn't kept alive (or double-called) after the fact.
+func (p *LamOPack) untrackCancel(requestID string) {
+	p.mu.Lock()
+	delete(p.cancels, requestID)
+	p.mu.Unlock()
+}
+
+// cancelRequest cancels requestID's underlying HTTP request context, if one
+// is still tracked, and reports whether it found one.
+func (p *LamOPack) cancelRequest(requestID string) bool {
+	p.mu.Lock()
+	cancel, ok := p.cancels[requestID]
+	delete(p.cancels, requestID)
+	p.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// ollamaGenerateRequest mirrors the subset of Ollama's POST /api/generate
+// request body this pack needs.
+type ollamaGenerateRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	Stream bool   `json:"stream"`
+}
+
+// ollamaGenerateChunk mirrors one line of Ollama's /api/generate response:
+// the whole body when Stream is false, or one partial-token frame of an
+// NDJSON sequence terminated by Done:true when Stream is true.
+// PromptEvalCount/EvalCount only arrive on the terminal frame.
+type ollamaGenerateChunk struct {
+	Model           string `json:"model"`
+	Response        string `json:"response"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int    `json:"prompt_eval_count,omitempty"`
+	EvalCount       int    `json:"eval_count,omitempty"`
 }
 
 func (p *LamOPack) Name() string        { return "pack_lam_o" }
@@ -182,6 +305,7 @@ func (p *LamOPack) Handlers() map[string]HandlerFunc {
 		"lam_o.embed":       p.handleEmbed,
 		"lam_o.list_models": p.handleListModels,
 		"lam_o.show_model":  p.handleShowModel,
+		"lam_o.cancel":      p.handleCancel,
 	}
 }
 
@@ -189,6 +313,17 @@ func (p *LamOPack) Vectors() map[string]VectorFunc {
 	return map[string]VectorFunc{}
 }
 
+// StreamHandlers implements StreamingPack: lam_o.infer/chat/generate all
+// funnel through handleInferStream, the same way their synchronous forms
+// all funnel through handleInfer.
+func (p *LamOPack) StreamHandlers() map[string]runtime.StreamingHandler {
+	return map[string]runtime.StreamingHandler{
+		"lam_o.infer":    p.handleInferStream,
+		"lam_o.chat":     p.handleInferStream,
+		"lam_o.generate": p.handleInferStream,
+	}
+}
+
 func (p *LamOPack) Variables() map[string]interface{} {
 	return map[string]interface{}{
 		"@lam_o_endpoint":      p.endpoint,
@@ -203,14 +338,174 @@ func (p *LamOPack) handleInfer(ctx *runtime.Context) (interface{}, error) {
 		return xjson.NewErrorResponse("lam.o", err.Error(), 400), nil
 	}
 
-	// TODO: Actual Ollama API call
-	// For now, return a mock response
-	resp := xjson.NewCompletionResponse(req.Model, "lam.o", "[Mock response from "+req.Model+"]")
-	resp.WithTokens(len(req.Prompt)/4, 50)
+	chunk, err := p.generate(goctxFrom(ctx), req.Model, req.Prompt)
+	if err != nil {
+		return xjson.NewErrorResponse("lam.o", err.Error(), 502), nil
+	}
 
+	resp := xjson.NewCompletionResponse(req.Model, "lam.o", chunk.Response)
+	resp.WithTokens(chunk.PromptEvalCount, chunk.EvalCount)
 	return resp, nil
 }
 
+// generate calls Ollama's POST /api/generate with stream:false and decodes
+// its single JSON response body.
+func (p *LamOPack) generate(ctx gocontext.Context, model, prompt string) (*ollamaGenerateChunk, error) {
+	body, err := json.Marshal(ollamaGenerateRequest{Model: model, Prompt: prompt, Stream: false})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client().Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lam.o: ollama %s returned status %d", p.endpoint, resp.StatusCode)
+	}
+
+	var chunk ollamaGenerateChunk
+	if err := json.NewDecoder(resp.Body).Decode(&chunk); err != nil {
+		return nil, err
+	}
+	return &chunk, nil
+}
+
+// handleInferStream is handleInfer's streaming form: it opens Ollama's
+// /api/generate with stream:true and forwards each NDJSON token as its own
+// runtime.StreamChunk, so a K'UHUL program that subscribes to lam_o.infer
+// sees incremental deltas instead of waiting for the whole completion. The
+// request is tracked under a request_id (caller-supplied, or generated) so
+// a later lam_o.cancel for that id can abort it mid-stream; a heartbeat
+// chunk fills any gap longer than lamOHeartbeatInterval between tokens so
+// an idle subscriber can tell the stream is still alive.
+func (p *LamOPack) handleInferStream(ctx *runtime.Context) (<-chan runtime.StreamChunk, error) {
+	req := xjson.CreateInferRequest(ctx.Body)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	requestID, _ := ctx.Body["request_id"].(string)
+	if requestID == "" {
+		requestID = fmt.Sprintf("infer_%d", atomic.AddUint64(&p.nextRequestID, 1))
+	}
+
+	streamCtx, cancel := gocontext.WithCancel(goctxFrom(ctx))
+	p.trackCancel(requestID, cancel)
+
+	body, err := json.Marshal(ollamaGenerateRequest{Model: req.Model, Prompt: req.Prompt, Stream: true})
+	if err != nil {
+		cancel()
+		p.untrackCancel(requestID)
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(streamCtx, http.MethodPost, p.endpoint+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		p.untrackCancel(requestID)
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client().Do(httpReq)
+	if err != nil {
+		cancel()
+		p.untrackCancel(requestID)
+		return nil, err
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	ch := make(chan runtime.StreamChunk, lamOStreamBuffer)
+	go func() {
+		defer close(ch)
+		defer cancel()
+		defer p.untrackCancel(requestID)
+		defer resp.Body.Close()
+
+		var text strings.Builder
+		var promptTokens, evalTokens int
+		heartbeat := time.NewTicker(lamOHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		finish := func() {
+			resp := xjson.NewCompletionResponse(req.Model, "lam.o", text.String())
+			resp.WithTokens(promptTokens, evalTokens)
+			ch <- runtime.StreamChunk{Result: resp, Done: true}
+		}
+
+		for {
+			select {
+			case <-streamCtx.Done():
+				ch <- runtime.StreamChunk{Err: streamCtx.Err(), Done: true}
+				return
+			case <-heartbeat.C:
+				ch <- runtime.StreamChunk{Result: LamOHeartbeat{}}
+			case line, ok := <-lines:
+				if !ok {
+					finish()
+					return
+				}
+				heartbeat.Reset(lamOHeartbeatInterval)
+				if line == "" {
+					continue
+				}
+				var chunk ollamaGenerateChunk
+				if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+					ch <- runtime.StreamChunk{Err: err, Done: true}
+					return
+				}
+				text.WriteString(chunk.Response)
+				if chunk.PromptEvalCount > 0 {
+					promptTokens = chunk.PromptEvalCount
+				}
+				if chunk.EvalCount > 0 {
+					evalTokens = chunk.EvalCount
+				}
+				if chunk.Done {
+					finish()
+					return
+				}
+				ch <- runtime.StreamChunk{Result: chunk.Response}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// handleCancel implements lam_o.cancel: it stops the in-flight
+// handleInferStream request tracked under ctx.Body["request_id"] by
+// canceling its underlying HTTP request context, closing the connection to
+// Ollama and unblocking that stream's producer goroutine.
+func (p *LamOPack) handleCancel(ctx *runtime.Context) (interface{}, error) {
+	requestID, _ := ctx.Body["request_id"].(string)
+	if requestID == "" {
+		return map[string]interface{}{"ok": false, "error": "request_id is required"}, nil
+	}
+	cancelled := p.cancelRequest(requestID)
+	return map[string]interface{}{
+		"ok":         cancelled,
+		"request_id": requestID,
+	}, nil
+}
+
 func (p *LamOPack) handleChat(ctx *runtime.Context) (interface{}, error) {
 	return p.handleInfer(ctx)
 }
@@ -292,6 +587,7 @@ func (p *SCXQ2Pack) Handlers() map[string]HandlerFunc {
 	return map[string]HandlerFunc{
 		"scxq2.fingerprint": p.handleFingerprint,
 		"scxq2.verify":      p.handleVerify,
+		"scxq2.diff":        p.handleDiff,
 		"scxq2.compress":    p.handleCompress,
 		"scxq2.decompress":  p.handleDecompress,
 	}
@@ -315,25 +611,84 @@ func (p *SCXQ2Pack) Variables() map[string]interface{} {
 	}
 }
 
+// handleFingerprint splits data's canonical bytes into content-defined
+// chunks (scxq2.Chunk) and folds their hashes into a Merkle root
+// (scxq2.ChunkMerkleRoot), which is the fingerprint this returns. Each
+// chunk's bytes are stored in p.state's content-addressed ChunkStore, and
+// the fingerprint's ordered chunk hash list is recorded via
+// PutChunkManifest so a later scxq2.diff can compare two fingerprints
+// without re-chunking either payload. Chunks already present in ChunkStore
+// (from an earlier, similar payload) aren't re-stored, so dedup_hit_rate and
+// compression_ratio report how much of this payload was new.
 func (p *SCXQ2Pack) handleFingerprint(ctx *runtime.Context) (interface{}, error) {
 	data := ctx.Body["data"]
-	fp := scxq2.Fingerprint(data)
+
+	chunks := scxq2.Chunk(scxq2.CanonicalBytes(data))
+	fp := scxq2.ChunkMerkleRoot(chunks)
+
+	hashes := make([]string, len(chunks))
+	var newBytes, totalBytes int
+	for i, c := range chunks {
+		hashes[i] = c.Hash
+		totalBytes += len(c.Data)
+		if p.state.PutChunk(c.Hash, c.Data) {
+			newBytes += len(c.Data)
+		}
+	}
+	p.state.PutChunkManifest(fp, hashes)
+
+	dedupHitRate := 0.0
+	compressionRatio := 1.0
+	if totalBytes > 0 {
+		dedupHitRate = float64(totalBytes-newBytes) / float64(totalBytes)
+		compressionRatio = float64(newBytes) / float64(totalBytes)
+	}
+
 	return map[string]interface{}{
-		"ok":          true,
-		"fingerprint": fp,
+		"ok":                true,
+		"fingerprint":       fp,
+		"chunk_count":       len(chunks),
+		"dedup_hit_rate":    dedupHitRate,
+		"compression_ratio": compressionRatio,
 	}, nil
 }
 
+// handleVerify re-chunks and re-hashes data the same way handleFingerprint
+// did, so verification doesn't depend on the original chunking having been
+// recorded anywhere (e.g. a restored RuntimeState whose ChunkStore came
+// from a snapshot with no manifest for this particular fingerprint).
 func (p *SCXQ2Pack) handleVerify(ctx *runtime.Context) (interface{}, error) {
 	data := ctx.Body["data"]
 	fp, _ := ctx.Body["fingerprint"].(string)
-	valid := scxq2.Verify(data, fp)
+	valid := scxq2.ChunkMerkleRoot(scxq2.Chunk(scxq2.CanonicalBytes(data))) == fp
 	return map[string]interface{}{
 		"ok":    true,
 		"valid": valid,
 	}, nil
 }
 
+// handleDiff reports which content-defined chunks differ between two
+// fingerprints scxq2.fingerprint has already produced, by looking up each
+// one's recorded chunk manifest rather than re-chunking their payloads.
+func (p *SCXQ2Pack) handleDiff(ctx *runtime.Context) (interface{}, error) {
+	fpA, _ := ctx.Body["fingerprint_a"].(string)
+	fpB, _ := ctx.Body["fingerprint_b"].(string)
+
+	hashesA, ok := p.state.GetChunkManifest(fpA)
+	if !ok {
+		return map[string]interface{}{"ok": false, "error": "unknown fingerprint_a"}, nil
+	}
+	hashesB, ok := p.state.GetChunkManifest(fpB)
+	if !ok {
+		return map[string]interface{}{"ok": false, "error": "unknown fingerprint_b"}, nil
+	}
+
+	return map[string]interface{}{
+		"ok":             true,
+		"changed_chunks": scxq2.ChunkDiff(hashesA, hashesB),
+	}, nil
+}
+
 func (p *SCXQ2Pack) handleCompress(ctx *runtime.Context) (interface{}, error) {
 	data := ctx.Body["data"]
 	compressed := scxq2.Compress(data)
@@ -359,14 +714,52 @@ func (p *SCXQ2Pack) handleDecompress(ctx *runtime.Context) (interface{}, error)
 // PACK: asx_ram (Memory System)
 // ============================================
 
-// ASXRAMPack provides the ASX memory system
+// asxRAMExpiryInterval is how often ASXRAMPack's background expirer sweeps
+// for TTLs whose deadline has passed.
+const asxRAMExpiryInterval = time.Second
+
+// asxRAMChangedEvent is the events.EventPump event asx_ram.set/delete/txn
+// post to and asx_ram.watch observes.
+const asxRAMChangedEvent = "asx_ram:changed"
+
+// asxRAMWatchBuffer bounds how many change events a slow asx_ram.watch
+// subscriber may have queued before notifyChanged (called synchronously
+// from handleSet/handleDelete/handleTxn) blocks on it — same backpressure
+// convention as lamOStreamBuffer.
+const asxRAMWatchBuffer = 16
+
+// asxRAMChange is the source PostEvent passes for asxRAMChangedEvent.
+type asxRAMChange struct {
+	Key     string
+	Value   interface{}
+	Deleted bool
+}
+
+// ASXRAMPack provides the ASX memory system. ASXRAM itself still lives on
+// RuntimeState (see GetASXRAM/SetASXRAM), but durability is opt-in: setting
+// the manifest's "store" key to "wal" swaps RuntimeState.Store for one that
+// appends every write to a checksummed log before applying it (see
+// runtime.selectStore / store_wal.go) so a crash replays back to the last
+// write instead of losing everything. path/syncMode below are just this
+// pack's record of the configured values, surfaced read-only through
+// Variables so K'UHUL code can inspect what it's running against.
 type ASXRAMPack struct {
 	state *runtime.RuntimeState
+
+	ttlMu sync.Mutex
+	ttl   map[string]time.Time
+
+	path     string
+	syncMode string
 }
 
 // NewASXRAMPack creates a new ASX-RAM pack
 func NewASXRAMPack() *ASXRAMPack {
-	return &ASXRAMPack{}
+	return &ASXRAMPack{
+		ttl:      make(map[string]time.Time),
+		path:     "asxram.wal",
+		syncMode: "always",
+	}
 }
 
 func (p *ASXRAMPack) Name() string        { return "pack_asx_ram" }
@@ -375,9 +768,47 @@ func (p *ASXRAMPack) Description() string { return "ASX-RAM memory system" }
 
 func (p *ASXRAMPack) Init(state *runtime.RuntimeState) error {
 	p.state = state
+	if path, ok := state.Manifest["store_path"].(string); ok && path != "" {
+		p.path = path
+	}
+	if syncMode, ok := state.Manifest["store_sync"].(string); ok && syncMode != "" {
+		p.syncMode = syncMode
+	}
+	go p.runExpirer()
 	return nil
 }
 
+// runExpirer deletes any key whose TTL (see handleSet's ttl_seconds) has
+// passed, roughly once per asxRAMExpiryInterval, for as long as the pack
+// exists — there's no Close on the Pack interface to stop it early.
+func (p *ASXRAMPack) runExpirer() {
+	ticker := time.NewTicker(asxRAMExpiryInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		p.ttlMu.Lock()
+		var expired []string
+		for key, deadline := range p.ttl {
+			if !now.Before(deadline) {
+				expired = append(expired, key)
+				delete(p.ttl, key)
+			}
+		}
+		p.ttlMu.Unlock()
+
+		for _, key := range expired {
+			p.state.SetASXRAM(key, nil)
+			p.notifyChanged(key, nil, true)
+		}
+	}
+}
+
+// notifyChanged posts asxRAMChangedEvent so any asx_ram.watch subscriber
+// whose key_prefix matches gets told about the write.
+func (p *ASXRAMPack) notifyChanged(key string, value interface{}, deleted bool) {
+	p.state.Events.PostEvent(asxRAMChangedEvent, asxRAMChange{Key: key, Value: value, Deleted: deleted})
+}
+
 func (p *ASXRAMPack) Handlers() map[string]HandlerFunc {
 	return map[string]HandlerFunc{
 		"asx_ram.get":    p.handleGet,
@@ -385,6 +816,17 @@ func (p *ASXRAMPack) Handlers() map[string]HandlerFunc {
 		"asx_ram.delete": p.handleDelete,
 		"asx_ram.list":   p.handleList,
 		"asx_ram.clear":  p.handleClear,
+		"asx_ram.txn":    p.handleTxn,
+		"asx_ram.watch":  p.handleWatch,
+	}
+}
+
+// StreamHandlers implements StreamingPack: asx_ram.watch upgrades from its
+// synchronous one-shot key listing (handleWatch) to a live feed of changes
+// under its key_prefix when dispatched through DispatchStream.
+func (p *ASXRAMPack) StreamHandlers() map[string]runtime.StreamingHandler {
+	return map[string]runtime.StreamingHandler{
+		"asx_ram.watch": p.handleWatchStream,
 	}
 }
 
@@ -393,7 +835,10 @@ func (p *ASXRAMPack) Vectors() map[string]VectorFunc {
 }
 
 func (p *ASXRAMPack) Variables() map[string]interface{} {
-	return map[string]interface{}{}
+	return map[string]interface{}{
+		"@asx_ram_path":      p.path,
+		"@asx_ram_sync_mode": p.syncMode,
+	}
 }
 
 func (p *ASXRAMPack) handleGet(ctx *runtime.Context) (interface{}, error) {
@@ -410,6 +855,16 @@ func (p *ASXRAMPack) handleSet(ctx *runtime.Context) (interface{}, error) {
 	key, _ := ctx.Body["key"].(string)
 	value := ctx.Body["value"]
 	p.state.SetASXRAM(key, value)
+
+	p.ttlMu.Lock()
+	if secs, ok := ctx.Body["ttl_seconds"].(float64); ok && secs > 0 {
+		p.ttl[key] = time.Now().Add(time.Duration(secs * float64(time.Second)))
+	} else {
+		delete(p.ttl, key)
+	}
+	p.ttlMu.Unlock()
+
+	p.notifyChanged(key, value, false)
 	return map[string]interface{}{
 		"ok":  true,
 		"key": key,
@@ -419,12 +874,105 @@ func (p *ASXRAMPack) handleSet(ctx *runtime.Context) (interface{}, error) {
 func (p *ASXRAMPack) handleDelete(ctx *runtime.Context) (interface{}, error) {
 	key, _ := ctx.Body["key"].(string)
 	p.state.SetASXRAM(key, nil)
+
+	p.ttlMu.Lock()
+	delete(p.ttl, key)
+	p.ttlMu.Unlock()
+
+	p.notifyChanged(key, nil, true)
 	return map[string]interface{}{
 		"ok":  true,
 		"key": key,
 	}, nil
 }
 
+// handleTxn implements asx_ram.txn: every key in ctx.Body["updates"] is
+// applied atomically. If the current Store is a WAL store (manifest
+// "store": "wal"), all updates land in a single WAL record via the
+// Txn method of the unexported walStore type, reached through this
+// anonymous interface — a type
+// assertion on a concrete type isn't possible across the packs/runtime
+// package boundary, so the interface names just the one method this
+// handler needs. Any other Store falls back to applying each key with its
+// own SetASXRAM call, which is the best atomicity the in-memory/Redis
+// drivers offer.
+func (p *ASXRAMPack) handleTxn(ctx *runtime.Context) (interface{}, error) {
+	updates, _ := ctx.Body["updates"].(map[string]interface{})
+	if len(updates) == 0 {
+		return map[string]interface{}{"ok": true, "applied": 0}, nil
+	}
+
+	type txnStore interface {
+		Txn(updates map[string]interface{}) error
+	}
+	if ws, ok := p.state.Store.(txnStore); ok {
+		if err := ws.Txn(updates); err != nil {
+			return map[string]interface{}{"ok": false, "error": err.Error()}, nil
+		}
+	} else {
+		for key, value := range updates {
+			p.state.SetASXRAM(key, value)
+		}
+	}
+
+	for key, value := range updates {
+		p.notifyChanged(key, value, value == nil)
+	}
+
+	return map[string]interface{}{
+		"ok":      true,
+		"applied": len(updates),
+	}, nil
+}
+
+// handleWatch implements asx_ram.watch's synchronous fallback: the current
+// set of keys under key_prefix, same as asx_ram.list but filtered. A
+// caller using plain Dispatch/DispatchAsync gets this one snapshot instead
+// of the live feed handleWatchStream provides through DispatchStream.
+func (p *ASXRAMPack) handleWatch(ctx *runtime.Context) (interface{}, error) {
+	prefix, _ := ctx.Body["key_prefix"].(string)
+	keys := make([]string, 0)
+	for k := range p.state.ASXRAM {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return map[string]interface{}{
+		"ok":   true,
+		"keys": keys,
+	}, nil
+}
+
+// handleWatchStream implements the streaming half of asx_ram.watch: it
+// subscribes to asxRAMChangedEvent and forwards any change whose key
+// matches key_prefix as a StreamChunk, until ctx's context is canceled.
+func (p *ASXRAMPack) handleWatchStream(ctx *runtime.Context) (<-chan runtime.StreamChunk, error) {
+	prefix, _ := ctx.Body["key_prefix"].(string)
+	watchCtx := goctxFrom(ctx)
+
+	ch := make(chan runtime.StreamChunk, asxRAMWatchBuffer)
+	observer := new(int) // unique identity for AddObserver/RemoveObserver
+
+	p.state.Events.AddObserver(asxRAMChangedEvent, observer, func(event string, source interface{}) {
+		change, ok := source.(asxRAMChange)
+		if !ok || !strings.HasPrefix(change.Key, prefix) {
+			return
+		}
+		select {
+		case ch <- runtime.StreamChunk{Result: change}:
+		case <-watchCtx.Done():
+		}
+	})
+
+	go func() {
+		<-watchCtx.Done()
+		p.state.Events.RemoveObserver(asxRAMChangedEvent, observer)
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
 func (p *ASXRAMPack) handleList(ctx *runtime.Context) (interface{}, error) {
 	keys := make([]string, 0)
 	for k := range p.state.ASXRAM {
@@ -450,14 +998,90 @@ func (p *ASXRAMPack) handleClear(ctx *runtime.Context) (interface{}, error) {
 // PACK: mx2lm (Orchestrator)
 // ============================================
 
+// mx2lmChunkSize is how many ready steps the demuxer hands to the worker
+// pool per batch, so a very wide pipeline doesn't flood the jobs channel in
+// one instant.
+const mx2lmChunkSize = 8
+
+// mx2lmStep is one node of a pipeline's DAG, parsed from the "steps" body
+// param: Handler names a registered C@@L handler (see RuntimeState.Handlers)
+// to run, Input becomes that run's ctx.Body["input"], and DependsOn lists
+// the step IDs (not indexes) that must complete first.
+type mx2lmStep struct {
+	ID        string
+	Handler   string
+	Input     interface{}
+	DependsOn []string
+}
+
+// mx2lmStepTrace is one step's entry in a pipeline's machine-readable
+// trace: enough to reconstruct a Gantt-style visualization or debug a
+// failed step without re-running the pipeline.
+type mx2lmStepTrace struct {
+	ID        string        `json:"id"`
+	Handler   string        `json:"handler"`
+	Status    string        `json:"status"` // "ok" or "error"
+	Output    interface{}   `json:"output,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+}
+
 // MX2LMPack provides the MX2LM orchestrator
 type MX2LMPack struct {
 	state *runtime.RuntimeState
+
+	mu      sync.Mutex
+	cancels map[string]gocontext.CancelFunc
 }
 
 // NewMX2LMPack creates a new MX2LM pack
 func NewMX2LMPack() *MX2LMPack {
-	return &MX2LMPack{}
+	return &MX2LMPack{
+		cancels: make(map[string]gocontext.CancelFunc),
+	}
+}
+
+// trackCancel records cancel under pipelineID so a later mx2lm.cancel call
+// naming that id can stop the in-flight pipeline.
+func (p *MX2LMPack) trackCancel(pipelineID string, cancel gocontext.CancelFunc) {
+	p.mu.Lock()
+	p.cancels[pipelineID] = cancel
+	p.mu.Unlock()
+}
+
+// untrackCancel removes pipelineID once its pipeline has finished on its
+// own, so a stale cancel func isn't kept alive (or double-called) after
+// the fact.
+func (p *MX2LMPack) untrackCancel(pipelineID string) {
+	p.mu.Lock()
+	delete(p.cancels, pipelineID)
+	p.mu.Unlock()
+}
+
+// cancelPipeline cancels pipelineID's underlying context, if one is still
+// tracked, and reports whether it found one.
+func (p *MX2LMPack) cancelPipeline(pipelineID string) bool {
+	p.mu.Lock()
+	cancel, ok := p.cancels[pipelineID]
+	delete(p.cancels, pipelineID)
+	p.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// newPipelineID returns a random RFC 4122 version-4 UUID string, used as
+// mx2lm.pipeline's pipeline_id instead of the previous
+// fmt.Sprintf("pipe_%d", len(steps)), which collided across any two
+// pipelines with the same step count.
+func newPipelineID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
 
 func (p *MX2LMPack) Name() string        { return "pack_mx2lm" }
@@ -473,6 +1097,7 @@ func (p *MX2LMPack) Handlers() map[string]HandlerFunc {
 	return map[string]HandlerFunc{
 		"mx2lm.route":     p.handleRoute,
 		"mx2lm.pipeline":  p.handlePipeline,
+		"mx2lm.cancel":    p.handleCancel,
 		"mx2lm.broadcast": p.handleBroadcast,
 		"mx2lm.status":    p.handleStatus,
 	}
@@ -502,16 +1127,288 @@ func (p *MX2LMPack) handleRoute(ctx *runtime.Context) (interface{}, error) {
 	}, nil
 }
 
+// handlePipeline runs a DAG of steps to completion: it builds the
+// dependency graph from the "steps" body param, schedules ready nodes onto
+// a worker pool sized to goruntime.NumCPU() (see runPipeline), and persists
+// the resulting trace to asx_ram under the pipeline's id so it survives a
+// restart (an asx_ram.get of "mx2lm:pipeline:<id>" can fetch it later;
+// mx2lm.status reports only handler and vector counts).
 func (p *MX2LMPack) handlePipeline(ctx *runtime.Context) (interface{}, error) {
-	steps, _ := ctx.Body["steps"].([]interface{})
+	rawSteps, _ := ctx.Body["steps"].([]interface{})
+	steps, err := parseMX2LMSteps(rawSteps)
+	if err != nil {
+		return map[string]interface{}{"ok": false, "error": err.Error()}, nil
+	}
+
+	pipelineID := newPipelineID()
+	runCtx, cancel := gocontext.WithCancel(goctxFrom(ctx))
+	p.trackCancel(pipelineID, cancel)
+	defer p.untrackCancel(pipelineID)
+	defer cancel()
+
+	traces := p.runPipeline(runCtx, steps)
+
+	status := "completed"
+	for _, t := range traces {
+		if t.Status == "error" {
+			status = "error"
+			break
+		}
+	}
+	if status == "completed" && len(traces) < len(steps) {
+		status = "canceled"
+	}
+
+	p.state.SetASXRAM("mx2lm:pipeline:"+pipelineID, map[string]interface{}{
+		"pipeline_id": pipelineID,
+		"status":      status,
+		"trace":       traces,
+	})
+
 	return map[string]interface{}{
-		"ok":          true,
+		"ok":          status == "completed",
+		"pipeline_id": pipelineID,
+		"status":      status,
 		"steps":       len(steps),
-		"status":      "queued",
-		"pipeline_id": fmt.Sprintf("pipe_%d", len(steps)),
+		"trace":       traces,
+	}, nil
+}
+
+// handleCancel stops an in-flight mx2lm.pipeline run by id, unblocking its
+// runPipeline muxer loop the same way ctx.Done() would at a deadline.
+func (p *MX2LMPack) handleCancel(ctx *runtime.Context) (interface{}, error) {
+	pipelineID, _ := ctx.Body["pipeline_id"].(string)
+	return map[string]interface{}{
+		"ok":       true,
+		"canceled": p.cancelPipeline(pipelineID),
 	}, nil
 }
 
+// parseMX2LMSteps converts the "steps" body param into mx2lmStep values,
+// defaulting a step's id to "stepN" (1-based) when the caller didn't supply
+// one, and validates that every depends_on name refers to another step in
+// the same pipeline and that the resulting graph is acyclic.
+func parseMX2LMSteps(raw []interface{}) ([]mx2lmStep, error) {
+	steps := make([]mx2lmStep, 0, len(raw))
+	ids := make(map[string]bool, len(raw))
+
+	for i, r := range raw {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("step %d: not an object", i)
+		}
+		id, _ := m["id"].(string)
+		if id == "" {
+			id = fmt.Sprintf("step%d", i+1)
+		}
+		if ids[id] {
+			return nil, fmt.Errorf("duplicate step id %q", id)
+		}
+		ids[id] = true
+
+		handler, _ := m["handler"].(string)
+		if handler == "" {
+			return nil, fmt.Errorf("step %q: missing handler", id)
+		}
+
+		var dependsOn []string
+		if deps, ok := m["depends_on"].([]interface{}); ok {
+			for _, d := range deps {
+				if name, ok := d.(string); ok {
+					dependsOn = append(dependsOn, name)
+				}
+			}
+		}
+
+		steps = append(steps, mx2lmStep{
+			ID:        id,
+			Handler:   handler,
+			Input:     m["input"],
+			DependsOn: dependsOn,
+		})
+	}
+
+	for _, s := range steps {
+		for _, dep := range s.DependsOn {
+			if !ids[dep] {
+				return nil, fmt.Errorf("step %q depends_on unknown step %q", s.ID, dep)
+			}
+		}
+	}
+	if err := checkAcyclic(steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// checkAcyclic runs Kahn's algorithm over steps' depends_on edges purely to
+// detect a cycle before runPipeline starts: a cyclic pipeline would
+// otherwise leave runPipeline's muxer loop waiting forever on a result that
+// can never arrive.
+func checkAcyclic(steps []mx2lmStep) error {
+	indegree := make(map[string]int, len(steps))
+	dependents := make(map[string][]string, len(steps))
+	for _, s := range steps {
+		indegree[s.ID] = len(s.DependsOn)
+	}
+	for _, s := range steps {
+		for _, dep := range s.DependsOn {
+			dependents[dep] = append(dependents[dep], s.ID)
+		}
+	}
+
+	var queue []string
+	for id, deg := range indegree {
+		if deg == 0 {
+			queue = append(queue, id)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, next := range dependents[id] {
+			indegree[next]--
+			if indegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	if visited != len(steps) {
+		return fmt.Errorf("pipeline has a dependency cycle")
+	}
+	return nil
+}
+
+// runPipeline is the demuxer/worker/muxer scheduler: a worker pool sized to
+// goruntime.NumCPU() pulls steps off jobs and pushes their traces onto
+// results; the loop below plays both demuxer (batching every step whose
+// dependencies just completed into jobs, mx2lmChunkSize at a time) and
+// muxer (collecting each trace, decrementing its dependents' indegree, and
+// re-dispatching any that just became ready) until every step has run or
+// runCtx is canceled. jobs/results are each sized to len(steps) so a
+// dispatch can never block on a worker that's itself blocked sending a
+// result.
+func (p *MX2LMPack) runPipeline(runCtx gocontext.Context, steps []mx2lmStep) []mx2lmStepTrace {
+	byID := make(map[string]mx2lmStep, len(steps))
+	indegree := make(map[string]int, len(steps))
+	dependents := make(map[string][]string, len(steps))
+	for _, s := range steps {
+		byID[s.ID] = s
+		indegree[s.ID] = len(s.DependsOn)
+	}
+	for _, s := range steps {
+		for _, dep := range s.DependsOn {
+			dependents[dep] = append(dependents[dep], s.ID)
+		}
+	}
+
+	jobs := make(chan mx2lmStep, len(steps))
+	results := make(chan mx2lmStepTrace, len(steps))
+
+	workers := goruntime.NumCPU()
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(steps) {
+		workers = len(steps)
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for step := range jobs {
+				results <- p.runStep(runCtx, step)
+			}
+		}()
+	}
+
+	var ready []string
+	for id, deg := range indegree {
+		if deg == 0 {
+			ready = append(ready, id)
+		}
+	}
+	sort.Strings(ready)
+
+	dispatch := func() {
+		for len(ready) > 0 {
+			batch := ready
+			if len(batch) > mx2lmChunkSize {
+				batch = batch[:mx2lmChunkSize]
+			}
+			for _, id := range batch {
+				jobs <- byID[id]
+			}
+			ready = ready[len(batch):]
+		}
+	}
+	dispatch()
+
+	traces := make([]mx2lmStepTrace, 0, len(steps))
+	for len(traces) < len(steps) {
+		select {
+		case <-runCtx.Done():
+			close(jobs)
+			return traces
+		case trace := <-results:
+			traces = append(traces, trace)
+			for _, next := range dependents[trace.ID] {
+				indegree[next]--
+				if indegree[next] == 0 {
+					ready = append(ready, next)
+				}
+			}
+			sort.Strings(ready)
+			dispatch()
+		}
+	}
+	close(jobs)
+	return traces
+}
+
+// runStep dispatches one pipeline step's handler directly against p.state
+// (the same Handlers lookup Interpreter.Dispatch uses, without its
+// interpreter-level limits since a pack has no Interpreter of its own) and
+// times it, reporting a trace entry whether the handler errors or not.
+func (p *MX2LMPack) runStep(runCtx gocontext.Context, step mx2lmStep) mx2lmStepTrace {
+	started := time.Now()
+	trace := mx2lmStepTrace{ID: step.ID, Handler: step.Handler, StartedAt: started}
+
+	if err := runCtx.Err(); err != nil {
+		trace.Status = "error"
+		trace.Error = err.Error()
+		trace.Duration = time.Since(started)
+		return trace
+	}
+
+	handler, ok := p.state.GetHandler(step.Handler)
+	if !ok {
+		trace.Status = "error"
+		trace.Error = fmt.Sprintf("unknown handler %q", step.Handler)
+		trace.Duration = time.Since(started)
+		return trace
+	}
+
+	output, err := handler.Execute(&runtime.Context{
+		Handler: step.Handler,
+		Body:    map[string]interface{}{"input": step.Input},
+		Runtime: p.state,
+		Ctx:     runCtx,
+	})
+	trace.Duration = time.Since(started)
+	if err != nil {
+		trace.Status = "error"
+		trace.Error = err.Error()
+	} else {
+		trace.Status = "ok"
+		trace.Output = output
+	}
+	return trace
+}
+
 func (p *MX2LMPack) handleBroadcast(ctx *runtime.Context) (interface{}, error) {
 	message := ctx.Body["message"]
 	targets, _ := ctx.Body["targets"].([]interface{})
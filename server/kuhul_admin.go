@@ -0,0 +1,204 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollama/ollama/kuhul"
+	"github.com/ollama/ollama/kuhul/runtime"
+)
+
+// AdminServer answers the K'UHUL endpoints that mutate or expose the shared
+// interpreter's internals — Load, State, Reset, Snapshot, and Restore. They
+// used to sit on the same public gin router as KuhulExecuteHandler, which
+// let any caller that could reach the public API overwrite or inspect the
+// whole runtime; they're split out here onto their own listener, bound to
+// OLLAMA_KUHUL_ADMIN_SOCK and gated by a shared-secret OLLAMA_KUHUL_ADMIN_TOKEN,
+// following the same admin/public split kwil-db's kwil-admin uses.
+type AdminServer struct {
+	token  string
+	engine *gin.Engine
+}
+
+// EnvKuhulAdminSock names the Unix socket path AdminServer.ListenAndServe
+// binds to. EnvKuhulAdminToken names the shared-secret bearer token callers
+// must present as "Authorization: Bearer <token>".
+const (
+	EnvKuhulAdminSock  = "OLLAMA_KUHUL_ADMIN_SOCK"
+	EnvKuhulAdminToken = "OLLAMA_KUHUL_ADMIN_TOKEN"
+)
+
+// NewAdminServer builds an AdminServer reading its token from
+// EnvKuhulAdminToken. It errors rather than falling back to an unauthenticated
+// server if the token isn't set, since this socket carries Load/Reset/Restore
+// — any of which lets a caller replace the whole interpreter.
+func NewAdminServer() (*AdminServer, error) {
+	token := os.Getenv(EnvKuhulAdminToken)
+	if token == "" {
+		return nil, fmt.Errorf("%s must be set to start the K'UHUL admin server", EnvKuhulAdminToken)
+	}
+
+	a := &AdminServer{token: token}
+	a.engine = gin.New()
+	a.engine.Use(gin.Recovery(), a.authMiddleware)
+	a.engine.POST("/load", a.KuhulLoadHandler)
+	a.engine.GET("/state", a.KuhulStateHandler)
+	a.engine.POST("/reset", a.KuhulResetHandler)
+	a.engine.POST("/snapshot", a.KuhulSnapshotHandler)
+	a.engine.POST("/restore", a.KuhulRestoreHandler)
+	return a, nil
+}
+
+// authMiddleware rejects any request whose Authorization header isn't
+// "Bearer <token>", comparing in constant time so a byte-at-a-time timing
+// attack can't narrow down the token.
+func (a *AdminServer) authMiddleware(c *gin.Context) {
+	const prefix = "Bearer "
+	header := c.GetHeader("Authorization")
+	if !strings.HasPrefix(header, prefix) ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, prefix)), []byte(a.token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, KuhulExecuteResponse{
+			Ok:    false,
+			Error: "unauthorized",
+		})
+		return
+	}
+	c.Next()
+}
+
+// ListenAndServe binds the Unix socket named by EnvKuhulAdminSock with 0600
+// perms (removing a stale socket file left by a prior process first) and
+// serves until the listener errors or the process exits.
+func (a *AdminServer) ListenAndServe() error {
+	sockPath := os.Getenv(EnvKuhulAdminSock)
+	if sockPath == "" {
+		return fmt.Errorf("%s must be set to start the K'UHUL admin server", EnvKuhulAdminSock)
+	}
+
+	if err := os.Remove(sockPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing stale admin socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", sockPath)
+	if err != nil {
+		return fmt.Errorf("binding admin socket: %w", err)
+	}
+	if err := os.Chmod(sockPath, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("securing admin socket: %w", err)
+	}
+
+	return http.Serve(listener, a.engine)
+}
+
+// KuhulLoadHandler loads K'UHUL source into the interpreter. It moved here
+// from the public router: Load replaces whatever handlers/vectors/manifest
+// the shared interpreter had, which is an administrative action.
+func (a *AdminServer) KuhulLoadHandler(c *gin.Context) {
+	var req KuhulExecuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, KuhulExecuteResponse{
+			Ok:    false,
+			Error: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	globalKuhulState.mu.Lock()
+	_, errs := globalKuhulState.interpreter.Load(req.Source)
+	globalKuhulState.mu.Unlock()
+
+	if len(errs) > 0 {
+		c.JSON(http.StatusOK, KuhulExecuteResponse{
+			Ok:    false,
+			Error: errs[0],
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, KuhulExecuteResponse{
+		Ok: true,
+	})
+}
+
+// KuhulStateHandler returns the K'UHUL runtime state. It moved here from the
+// public router since BootSteps/Errors/handler and variable names can leak
+// details about the program an embedder doesn't want public callers to see.
+func (a *AdminServer) KuhulStateHandler(c *gin.Context) {
+	globalKuhulState.mu.RLock()
+	state := globalKuhulState.interpreter.GetState()
+	globalKuhulState.mu.RUnlock()
+
+	c.JSON(http.StatusOK, KuhulExecuteResponse{
+		Ok:     true,
+		Result: state.GetState(),
+	})
+}
+
+// KuhulResetHandler replaces the shared interpreter with a fresh one,
+// discarding any Load-ed program and runtime state.
+func (a *AdminServer) KuhulResetHandler(c *gin.Context) {
+	globalKuhulState.mu.Lock()
+	globalKuhulState.interpreter = kuhul.NewInterpreter()
+	globalKuhulState.mu.Unlock()
+
+	c.JSON(http.StatusOK, KuhulExecuteResponse{Ok: true})
+}
+
+// KuhulSnapshotHandler serializes the shared interpreter's runtime state via
+// runtime.RuntimeState.Snapshot, returning the snapshot bytes as Result and
+// its SCXQ2 fingerprint, so a caller can VerifyFingerprint before persisting
+// or transmitting it and hand it back to KuhulRestoreHandler later.
+func (a *AdminServer) KuhulSnapshotHandler(c *gin.Context) {
+	globalKuhulState.mu.RLock()
+	data, fingerprint, err := globalKuhulState.interpreter.GetState().Snapshot()
+	globalKuhulState.mu.RUnlock()
+
+	if err != nil {
+		c.JSON(http.StatusOK, KuhulExecuteResponse{Ok: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, KuhulExecuteResponse{
+		Ok:     true,
+		Result: json.RawMessage(data),
+		SCXQ2:  fingerprint,
+	})
+}
+
+// AdminRestoreRequest carries a snapshot produced by KuhulSnapshotHandler
+// back to KuhulRestoreHandler.
+type AdminRestoreRequest struct {
+	Snapshot json.RawMessage `json:"snapshot"`
+}
+
+// KuhulRestoreHandler rebuilds the shared interpreter's runtime state from a
+// snapshot via runtime.Restore, replacing whatever state it had.
+func (a *AdminServer) KuhulRestoreHandler(c *gin.Context) {
+	var req AdminRestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, KuhulExecuteResponse{
+			Ok:    false,
+			Error: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	rs, err := runtime.Restore(req.Snapshot)
+	if err != nil {
+		c.JSON(http.StatusOK, KuhulExecuteResponse{Ok: false, Error: err.Error()})
+		return
+	}
+
+	globalKuhulState.mu.Lock()
+	globalKuhulState.interpreter.RestoreState(rs)
+	globalKuhulState.mu.Unlock()
+
+	c.JSON(http.StatusOK, KuhulExecuteResponse{Ok: true})
+}
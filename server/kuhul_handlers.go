@@ -2,8 +2,14 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ollama/ollama/api/xjson"
@@ -12,6 +18,14 @@ import (
 	"github.com/ollama/ollama/packs"
 )
 
+// defaultKuhulExecuteTimeout is what KuhulExecuteHandler uses when a
+// request doesn't set timeout_ms. KuhulMaxExecuteTimeout is the ceiling a
+// request's timeout_ms is clamped to, regardless of what it asks for; an
+// embedder can lower (or raise) it at startup.
+const defaultKuhulExecuteTimeout = 10 * time.Second
+
+var KuhulMaxExecuteTimeout = 60 * time.Second
+
 // KuhulState holds the server-wide K'UHUL runtime state
 type KuhulState struct {
 	interpreter *kuhul.Interpreter
@@ -39,6 +53,84 @@ func GetKuhulState() *KuhulState {
 type KuhulExecuteRequest struct {
 	Source string `json:"source"`
 	Mode   string `json:"mode,omitempty"` // "run", "eval", "parse", "tokenize"
+
+	// TimeoutMs bounds how long "run"/"eval" may execute before
+	// KuhulExecuteHandler aborts with Error: "deadline exceeded"; it's
+	// clamped to KuhulMaxExecuteTimeout and defaults to
+	// defaultKuhulExecuteTimeout when unset. "parse"/"tokenize" ignore it,
+	// since they don't run interpreted code.
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+
+	// Sandbox selects a runtime.Limits preset KuhulExecuteHandler enforces
+	// against an isolated, per-request Interpreter instead of the shared
+	// globalKuhulState one: "" or "none" (the default) runs exactly as
+	// before. "readonly" denies handlers with side effects beyond their
+	// return value (see sandboxDenylist). "strict" does the same plus caps
+	// instructions, heap bytes, and output bytes (see strictSandboxLimits).
+	// Only "run"/"eval" honor it; "parse"/"tokenize" ignore it, since they
+	// don't execute the program.
+	Sandbox string `json:"sandbox,omitempty"`
+}
+
+// sandboxDenylist names handlers with side effects beyond computing a
+// result — shelling out, or writing into the CMS/GRAM stores — that
+// KuhulExecuteHandler excludes from a "readonly" or "strict" sandbox's
+// AllowedHandlers, regardless of what a request's source tries to register.
+var sandboxDenylist = map[string]bool{
+	"basher_run":    true,
+	"cms_rlhf_post": true,
+	"gram_observe":  true,
+}
+
+// strictSandboxLimits bounds a "strict" sandbox's resource usage. These are
+// conservative defaults sized for a single request, not a tunable per-call
+// setting; an embedder that needs different caps can fork KuhulExecuteHandler.
+const (
+	strictMaxInstructions int64 = 100_000
+	strictMaxHeapBytes    int64 = 10 << 20 // 10 MiB
+	strictMaxOutputBytes  int   = 1 << 20  // 1 MiB
+)
+
+// sandboxLimits builds the runtime.Limits a Sandbox mode enforces, or nil for
+// "" / "none". allowedHandlers is normally GetState().Handlers' keys after
+// Load, filtered against sandboxDenylist.
+func sandboxLimits(mode string, allowedHandlers map[string]bool) (*runtime.Limits, error) {
+	switch mode {
+	case "", "none":
+		return nil, nil
+	case "readonly":
+		return &runtime.Limits{AllowedHandlers: allowedHandlers}, nil
+	case "strict":
+		return &runtime.Limits{
+			MaxInstructions: strictMaxInstructions,
+			MaxHeapBytes:    strictMaxHeapBytes,
+			MaxOutputBytes:  strictMaxOutputBytes,
+			AllowedHandlers: allowedHandlers,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown sandbox mode: %q", mode)
+	}
+}
+
+// allowedHandlersFor returns the handler names rs registered minus
+// sandboxDenylist, for use as a "readonly"/"strict" sandbox's
+// Limits.AllowedHandlers.
+func allowedHandlersFor(rs *runtime.RuntimeState) map[string]bool {
+	allowed := make(map[string]bool, len(rs.Handlers))
+	for name := range rs.Handlers {
+		if !sandboxDenylist[name] {
+			allowed[name] = true
+		}
+	}
+	return allowed
+}
+
+// KuhulExecuteMetrics reports a sandboxed request's resource usage, set on
+// KuhulExecuteResponse.Metrics when Sandbox is "readonly" or "strict".
+type KuhulExecuteMetrics struct {
+	Instructions int64 `json:"instructions"`
+	HeapBytes    int64 `json:"heap_bytes"`
+	DurationMs   int64 `json:"duration_ms"`
 }
 
 // KuhulExecuteResponse represents a K'UHUL execute response
@@ -55,6 +147,7 @@ type KuhulDispatchRequest struct {
 	Handler string                 `json:"handler"`
 	Params  map[string]interface{} `json:"params,omitempty"`
 	Body    map[string]interface{} `json:"body,omitempty"`
+	Stream  bool                   `json:"stream,omitempty"`
 }
 
 // KuhulExecuteHandler handles K'UHUL code execution
@@ -81,26 +174,43 @@ func (s *Server) KuhulExecuteHandler(c *gin.Context) {
 		mode = "run"
 	}
 
+	timeout := defaultKuhulExecuteTimeout
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+		if timeout > KuhulMaxExecuteTimeout {
+			timeout = KuhulMaxExecuteTimeout
+		}
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
 	var result interface{}
+	var metrics *KuhulExecuteMetrics
 	var err error
 
 	switch mode {
 	case "tokenize":
 		result = kuhul.Tokenize(req.Source)
 	case "parse":
-		program, errors := kuhul.Parse(req.Source)
-		if len(errors) > 0 {
+		program, parseErrors := kuhul.Parse(req.Source)
+		if len(parseErrors) > 0 {
 			c.JSON(http.StatusOK, KuhulExecuteResponse{
 				Ok:    false,
-				Error: errors[0],
+				Error: parseErrors[0],
 			})
 			return
 		}
 		result = program
-	case "eval":
-		result, err = kuhul.Eval(req.Source)
-	case "run":
-		result, err = kuhul.Run(req.Source)
+	case "eval", "run":
+		if req.Sandbox == "" || req.Sandbox == "none" {
+			if mode == "eval" {
+				result, err = kuhul.EvalContext(ctx, req.Source)
+			} else {
+				result, err = kuhul.RunContext(ctx, req.Source)
+			}
+		} else {
+			result, metrics, err = execKuhulSandboxed(ctx, mode, req)
+		}
 	default:
 		c.JSON(http.StatusBadRequest, KuhulExecuteResponse{
 			Ok:    false,
@@ -110,20 +220,70 @@ func (s *Server) KuhulExecuteHandler(c *gin.Context) {
 	}
 
 	if err != nil {
+		errMsg := err.Error()
+		var kerr *kuhul.KuhulError
+		if errors.As(err, &kerr) && kerr.Code == kuhul.ErrDeadlineExceeded {
+			errMsg = "deadline exceeded"
+		}
 		c.JSON(http.StatusOK, KuhulExecuteResponse{
-			Ok:    false,
-			Error: err.Error(),
+			Ok:      false,
+			Error:   errMsg,
+			Metrics: metrics,
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, KuhulExecuteResponse{
-		Ok:     true,
-		Result: result,
-		SCXQ2:  kuhul.Fingerprint(result),
+		Ok:      true,
+		Result:  result,
+		SCXQ2:   kuhul.Fingerprint(result),
+		Metrics: metrics,
 	})
 }
 
+// execKuhulSandboxed runs req.Source against a fresh Interpreter isolated
+// from globalKuhulState, enforcing the runtime.Limits req.Sandbox names.
+// AllowedHandlers can only be computed after Load registers the program's
+// handlers, so Limits go on via Interpreter.SetLimits rather than
+// NewInterpreterWithLimits.
+func execKuhulSandboxed(ctx context.Context, mode string, req KuhulExecuteRequest) (interface{}, *KuhulExecuteMetrics, error) {
+	interp := kuhul.NewInterpreter()
+	if _, errs := interp.Load(req.Source); len(errs) > 0 {
+		return nil, nil, &kuhul.ParseError{Errors: errs}
+	}
+
+	limits, err := sandboxLimits(req.Sandbox, allowedHandlersFor(interp.GetState()))
+	if err != nil {
+		return nil, nil, err
+	}
+	interp.SetLimits(limits)
+
+	start := time.Now()
+	var result interface{}
+	if mode == "eval" {
+		result, err = interp.EvalContext(ctx, req.Source)
+	} else {
+		result, err = interp.RunContext(ctx)
+	}
+	metrics := &KuhulExecuteMetrics{
+		Instructions: interp.Counters().Instructions,
+		HeapBytes:    interp.Counters().HeapBytes,
+		DurationMs:   time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		return nil, metrics, err
+	}
+
+	resultJSON, jsonErr := json.Marshal(result)
+	if jsonErr == nil {
+		if outErr := runtime.CheckOutput(limits, len(resultJSON)); outErr != nil {
+			return nil, metrics, outErr
+		}
+	}
+
+	return result, metrics, nil
+}
+
 // KuhulDispatchHandler handles K'UHUL handler dispatch
 func (s *Server) KuhulDispatchHandler(c *gin.Context) {
 	var req KuhulDispatchRequest
@@ -147,7 +307,29 @@ func (s *Server) KuhulDispatchHandler(c *gin.Context) {
 	interp := globalKuhulState.interpreter
 	globalKuhulState.mu.RUnlock()
 
-	result, err := interp.Dispatch(req.Handler, req.Body)
+	ctx := &runtime.Context{
+		Handler: req.Handler,
+		Params:  req.Params,
+		Body:    req.Body,
+		Query:   make(map[string]interface{}),
+		Runtime: interp.GetState(),
+		Env:     interp.GetState().Variables,
+	}
+
+	if req.Stream {
+		ch, err := interp.DispatchStream(req.Handler, ctx)
+		if err != nil {
+			c.JSON(http.StatusOK, KuhulExecuteResponse{
+				Ok:    false,
+				Error: err.Error(),
+			})
+			return
+		}
+		streamKuhulResult(c, ch)
+		return
+	}
+
+	result, err := interp.Dispatch(req.Handler, ctx)
 	if err != nil {
 		c.JSON(http.StatusOK, KuhulExecuteResponse{
 			Ok:    false,
@@ -163,44 +345,46 @@ func (s *Server) KuhulDispatchHandler(c *gin.Context) {
 	})
 }
 
-// KuhulStateHandler returns the K'UHUL runtime state
-func (s *Server) KuhulStateHandler(c *gin.Context) {
-	globalKuhulState.mu.RLock()
-	state := globalKuhulState.interpreter.GetState()
-	globalKuhulState.mu.RUnlock()
-
-	c.JSON(http.StatusOK, KuhulExecuteResponse{
-		Ok:     true,
-		Result: state.GetState(),
-	})
-}
-
-// KuhulLoadHandler loads K'UHUL source into the interpreter
-func (s *Server) KuhulLoadHandler(c *gin.Context) {
-	var req KuhulExecuteRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, KuhulExecuteResponse{
-			Ok:    false,
-			Error: "Invalid request: " + err.Error(),
-		})
-		return
+// streamKuhulResult drains ch and writes each StreamChunk as one line of
+// KuhulExecuteResponse JSON (NDJSON, or SSE "data: " frames if the caller's
+// Accept header asks for text/event-stream), so a streamed
+// KuhulDispatchHandler response looks like the buffered one, just delivered
+// one chunk at a time instead of all at once.
+func streamKuhulResult(c *gin.Context, ch <-chan runtime.StreamChunk) {
+	sse := strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+	contentType := "application/x-ndjson"
+	if sse {
+		contentType = "text/event-stream"
 	}
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "no-cache")
+
+	flusher, _ := c.Writer.(http.Flusher)
+	for chunk := range ch {
+		resp := KuhulExecuteResponse{Ok: chunk.Err == nil, Result: chunk.Result}
+		if chunk.Err != nil {
+			resp.Error = chunk.Err.Error()
+		}
+		if chunk.Done {
+			resp.SCXQ2 = kuhul.Fingerprint(chunk.Result)
+		}
 
-	globalKuhulState.mu.Lock()
-	_, errors := globalKuhulState.interpreter.Load(req.Source)
-	globalKuhulState.mu.Unlock()
-
-	if len(errors) > 0 {
-		c.JSON(http.StatusOK, KuhulExecuteResponse{
-			Ok:    false,
-			Error: errors[0],
-		})
-		return
+		data, err := json.Marshal(resp)
+		if err != nil {
+			continue
+		}
+		if sse {
+			c.Writer.Write([]byte("data: "))
+			c.Writer.Write(data)
+			c.Writer.Write([]byte("\n\n"))
+		} else {
+			c.Writer.Write(data)
+			c.Writer.Write([]byte("\n"))
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
 	}
-
-	c.JSON(http.StatusOK, KuhulExecuteResponse{
-		Ok: true,
-	})
 }
 
 // XJSONInferHandler handles XJSON @infer requests
@@ -253,7 +437,28 @@ func (s *Server) XJSONInferHandler(c *gin.Context) {
 		}
 	}
 
-	result, err := interp.Dispatch("lam_o.infer", body)
+	ctx := &runtime.Context{
+		Handler: "lam_o.infer",
+		Params:  make(map[string]interface{}),
+		Body:    body,
+		Query:   make(map[string]interface{}),
+		Runtime: interp.GetState(),
+		Env:     interp.GetState().Variables,
+	}
+
+	if req.Stream {
+		ch, err := interp.DispatchStream("lam_o.infer", ctx)
+		if err != nil {
+			errResp := xjson.NewErrorResponse(runner, err.Error(), 500)
+			data, _ := errResp.ToJSON()
+			c.Data(http.StatusInternalServerError, "application/json", data)
+			return
+		}
+		streamXJSONInfer(c, ch, req.Model, runner)
+		return
+	}
+
+	result, err := interp.Dispatch("lam_o.infer", ctx)
 	if err != nil {
 		errResp := xjson.NewErrorResponse(runner, err.Error(), 500)
 		data, _ := errResp.ToJSON()
@@ -261,17 +466,87 @@ func (s *Server) XJSONInferHandler(c *gin.Context) {
 		return
 	}
 
-	// Build response
-	var respText string
+	resp := completionFromResult(req.Model, runner, result)
+	data, _ := resp.ToJSON()
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// completionFromResult normalizes a lam_o.infer result into a
+// CompletionResponse: handleInfer already returns one directly, but a
+// handler that only returns a bare map (e.g. one registered outside the
+// packs.Pack path) is also accepted, reading its "text" key.
+func completionFromResult(model, runner string, result interface{}) *xjson.CompletionResponse {
+	if resp, ok := result.(*xjson.CompletionResponse); ok {
+		return resp
+	}
+
+	var text string
 	if m, ok := result.(map[string]interface{}); ok {
-		if text, ok := m["text"].(string); ok {
-			respText = text
+		if t, ok := m["text"].(string); ok {
+			text = t
 		}
 	}
+	return xjson.NewCompletionResponse(model, runner, text)
+}
 
-	resp := xjson.NewCompletionResponse(req.Model, runner, respText)
-	data, _ := resp.ToJSON()
-	c.Data(http.StatusOK, "application/json", data)
+// streamXJSONInfer drains ch, writing each partial chunk as an @delta XJSON
+// frame (a partial chunk that isn't text, such as packs.LamOHeartbeat, as
+// an @delta with empty text that doesn't advance @index) and the terminal
+// chunk as the @completion frame (NDJSON, or SSE
+// "data: " frames if the caller's Accept header asks for
+// text/event-stream) — mirroring how Ollama's /api/generate streams tokens
+// before a final summary object. Frames are written with a plain
+// json.Marshal rather than CompletionResponse.ToJSON's indented form, since
+// embedded newlines would break NDJSON/SSE line framing.
+func streamXJSONInfer(c *gin.Context, ch <-chan runtime.StreamChunk, model, runner string) {
+	sse := strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+	contentType := "application/x-ndjson"
+	if sse {
+		contentType = "text/event-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "no-cache")
+
+	flusher, _ := c.Writer.(http.Flusher)
+	write := func(data []byte) {
+		if sse {
+			c.Writer.Write([]byte("data: "))
+			c.Writer.Write(data)
+			c.Writer.Write([]byte("\n\n"))
+		} else {
+			c.Writer.Write(data)
+			c.Writer.Write([]byte("\n"))
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	var index int
+	for chunk := range ch {
+		if chunk.Err != nil {
+			data, _ := json.Marshal(xjson.XJSONEnvelope{Error: xjson.NewErrorResponse(runner, chunk.Err.Error(), 500)})
+			write(data)
+			return
+		}
+
+		if !chunk.Done {
+			text, isText := chunk.Result.(string)
+			data, _ := json.Marshal(xjson.XJSONEnvelope{Delta: xjson.NewDeltaResponse(model, runner, text, index)})
+			if isText {
+				index++
+			}
+			write(data)
+			continue
+		}
+
+		resp := completionFromResult(model, runner, chunk.Result)
+		data, _ := json.Marshal(xjson.XJSONEnvelope{Completion: resp})
+		write(data)
+		if chunk.Done {
+			return
+		}
+	}
 }
 
 // PacksListHandler lists all registered packs
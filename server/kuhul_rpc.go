@@ -0,0 +1,263 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollama/ollama/api/kuhulrpc"
+	"github.com/ollama/ollama/api/xjson"
+	"github.com/ollama/ollama/kuhul"
+	"github.com/ollama/ollama/kuhul/runtime"
+)
+
+// kuhulRPCService implements kuhulrpc.KuhulService against globalKuhulState,
+// so the Twirp transport and the gin JSON handlers above share the same
+// dispatch pipeline instead of duplicating it.
+type kuhulRPCService struct{}
+
+// RegisterKuhulRPC mounts a Twirp KuhulService server at kuhulrpc.PathPrefix,
+// so a generated client can reach the same runtime KuhulExecuteHandler and
+// friends serve over plain JSON, picking protobuf- or JSON-over-HTTP without
+// either side duplicating the dispatch logic. It uses NewPublicKuhulServer,
+// so Load and State aren't reachable here — those are AdminServer's job.
+func (s *Server) RegisterKuhulRPC(r gin.IRouter) {
+	r.Any(kuhulrpc.PathPrefix+"*method", gin.WrapH(kuhulrpc.NewPublicKuhulServer(kuhulRPCService{})))
+}
+
+func (kuhulRPCService) Execute(ctx context.Context, req *kuhulrpc.ExecuteRequest) (*kuhulrpc.ExecuteResponse, error) {
+	if req.Source == "" {
+		return &kuhulrpc.ExecuteResponse{Ok: false, Error: "source is required"}, nil
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = "run"
+	}
+
+	timeout := defaultKuhulExecuteTimeout
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+		if timeout > KuhulMaxExecuteTimeout {
+			timeout = KuhulMaxExecuteTimeout
+		}
+	}
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var result interface{}
+	var err error
+
+	switch mode {
+	case "tokenize":
+		result = kuhul.Tokenize(req.Source)
+	case "parse":
+		program, parseErrors := kuhul.Parse(req.Source)
+		if len(parseErrors) > 0 {
+			return &kuhulrpc.ExecuteResponse{Ok: false, Error: parseErrors[0]}, nil
+		}
+		result = program
+	case "eval":
+		result, err = kuhul.EvalContext(execCtx, req.Source)
+	case "run":
+		result, err = kuhul.RunContext(execCtx, req.Source)
+	default:
+		return &kuhulrpc.ExecuteResponse{Ok: false, Error: "Invalid mode: " + mode}, nil
+	}
+
+	if err != nil {
+		return &kuhulrpc.ExecuteResponse{Ok: false, Error: deadlineAwareError(err)}, nil
+	}
+
+	return resultResponse(result)
+}
+
+func (kuhulRPCService) Dispatch(ctx context.Context, req *kuhulrpc.DispatchRequest) (*kuhulrpc.ExecuteResponse, error) {
+	if req.Handler == "" {
+		return &kuhulrpc.ExecuteResponse{Ok: false, Error: "handler is required"}, nil
+	}
+
+	params, err := decodeJSONObject(req.ParamsJSON)
+	if err != nil {
+		return &kuhulrpc.ExecuteResponse{Ok: false, Error: "invalid params_json: " + err.Error()}, nil
+	}
+	body, err := decodeJSONObject(req.BodyJSON)
+	if err != nil {
+		return &kuhulrpc.ExecuteResponse{Ok: false, Error: "invalid body_json: " + err.Error()}, nil
+	}
+
+	globalKuhulState.mu.RLock()
+	interp := globalKuhulState.interpreter
+	globalKuhulState.mu.RUnlock()
+
+	dispatchCtx := &runtime.Context{
+		Handler: req.Handler,
+		Params:  params,
+		Body:    body,
+		Query:   make(map[string]interface{}),
+		Runtime: interp.GetState(),
+		Env:     interp.GetState().Variables,
+		Ctx:     ctx,
+	}
+
+	result, err := interp.Dispatch(req.Handler, dispatchCtx)
+	if err != nil {
+		return &kuhulrpc.ExecuteResponse{Ok: false, Error: deadlineAwareError(err)}, nil
+	}
+
+	return resultResponse(result)
+}
+
+func (kuhulRPCService) Load(_ context.Context, req *kuhulrpc.ExecuteRequest) (*kuhulrpc.ExecuteResponse, error) {
+	globalKuhulState.mu.Lock()
+	_, errs := globalKuhulState.interpreter.Load(req.Source)
+	globalKuhulState.mu.Unlock()
+
+	if len(errs) > 0 {
+		return &kuhulrpc.ExecuteResponse{Ok: false, Error: errs[0]}, nil
+	}
+	return &kuhulrpc.ExecuteResponse{Ok: true}, nil
+}
+
+func (kuhulRPCService) State(_ context.Context, _ *kuhulrpc.StateRequest) (*kuhulrpc.ExecuteResponse, error) {
+	globalKuhulState.mu.RLock()
+	state := globalKuhulState.interpreter.GetState()
+	globalKuhulState.mu.RUnlock()
+
+	return resultResponse(state.GetState())
+}
+
+func (kuhulRPCService) Fingerprint(_ context.Context, req *kuhulrpc.FingerprintRequest) (*kuhulrpc.FingerprintResponse, error) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(req.DataJSON), &data); err != nil {
+		return &kuhulrpc.FingerprintResponse{Ok: false, Error: "invalid data_json: " + err.Error()}, nil
+	}
+
+	return &kuhulrpc.FingerprintResponse{Ok: true, Fingerprint: kuhul.Fingerprint(data)}, nil
+}
+
+func (kuhulRPCService) Infer(ctx context.Context, req *kuhulrpc.InferRequest) (*kuhulrpc.InferResponse, error) {
+	dispatchCtx, runner := inferDispatchContext(ctx, req)
+
+	globalKuhulState.mu.RLock()
+	interp := globalKuhulState.interpreter
+	globalKuhulState.mu.RUnlock()
+
+	result, err := interp.Dispatch("lam_o.infer", dispatchCtx)
+	if err != nil {
+		return &kuhulrpc.InferResponse{Ok: false, Error: err.Error()}, nil
+	}
+
+	resp := completionFromResult(req.Model, runner, result)
+	return inferResponse(resp), nil
+}
+
+func (kuhulRPCService) InferStream(ctx context.Context, req *kuhulrpc.InferRequest, send func(*kuhulrpc.InferStreamChunk) error) error {
+	dispatchCtx, runner := inferDispatchContext(ctx, req)
+
+	globalKuhulState.mu.RLock()
+	interp := globalKuhulState.interpreter
+	globalKuhulState.mu.RUnlock()
+
+	ch, err := interp.DispatchStream("lam_o.infer", dispatchCtx)
+	if err != nil {
+		return send(&kuhulrpc.InferStreamChunk{Error: err.Error()})
+	}
+
+	for chunk := range ch {
+		if chunk.Err != nil {
+			return send(&kuhulrpc.InferStreamChunk{Error: chunk.Err.Error()})
+		}
+		if text, ok := chunk.Result.(string); ok && !chunk.Done {
+			if err := send(&kuhulrpc.InferStreamChunk{Delta: text}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		resp := completionFromResult(req.Model, runner, chunk.Result)
+		if err := send(&kuhulrpc.InferStreamChunk{Done: chunk.Done, Completion: inferResponse(resp)}); err != nil {
+			return err
+		}
+		if chunk.Done {
+			return nil
+		}
+	}
+	return nil
+}
+
+func inferDispatchContext(ctx context.Context, req *kuhulrpc.InferRequest) (*runtime.Context, string) {
+	runner := req.Runner
+	if runner == "" {
+		runner = "lam.o"
+	}
+
+	globalKuhulState.mu.RLock()
+	interp := globalKuhulState.interpreter
+	globalKuhulState.mu.RUnlock()
+
+	body := map[string]interface{}{
+		"model":  req.Model,
+		"prompt": req.Prompt,
+		"mode":   req.Mode,
+		"runner": runner,
+		"params": map[string]interface{}{
+			"temperature": req.Temperature,
+			"top_p":       req.TopP,
+			"max_tokens":  float64(req.MaxTokens),
+		},
+	}
+
+	return &runtime.Context{
+		Handler: "lam_o.infer",
+		Params:  make(map[string]interface{}),
+		Body:    body,
+		Query:   make(map[string]interface{}),
+		Runtime: interp.GetState(),
+		Env:     interp.GetState().Variables,
+		Ctx:     ctx,
+	}, runner
+}
+
+func inferResponse(resp *xjson.CompletionResponse) *kuhulrpc.InferResponse {
+	out := &kuhulrpc.InferResponse{Ok: true, Text: resp.Text, SCXQ2: resp.SCXQ2}
+	if resp.Tokens != nil {
+		out.InputTokens = int32(resp.Tokens.Input)
+		out.OutputTokens = int32(resp.Tokens.Output)
+	}
+	return out
+}
+
+// resultResponse JSON-encodes result into an ExecuteResponse, the shape
+// Execute/Dispatch/Load/State share.
+func resultResponse(result interface{}) (*kuhulrpc.ExecuteResponse, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return &kuhulrpc.ExecuteResponse{Ok: false, Error: err.Error()}, nil
+	}
+	return &kuhulrpc.ExecuteResponse{Ok: true, ResultJSON: string(data), SCXQ2: kuhul.Fingerprint(result)}, nil
+}
+
+func decodeJSONObject(raw string) (map[string]interface{}, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// deadlineAwareError mirrors KuhulExecuteHandler's "deadline exceeded"
+// normalization, so a Twirp client sees the same message the gin handler
+// would for a timed-out Execute/Dispatch call.
+func deadlineAwareError(err error) string {
+	var kerr *kuhul.KuhulError
+	if errors.As(err, &kerr) && kerr.Code == kuhul.ErrDeadlineExceeded {
+		return "deadline exceeded"
+	}
+	return err.Error()
+}